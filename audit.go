@@ -0,0 +1,74 @@
+package manta
+
+import (
+	"context"
+	"time"
+)
+
+const (
+	// AuditSignin is recorded when a user signed in
+	AuditSignin AuditAction = "signin"
+	// AuditSigninFailed is recorded when the credentials are invalid, or
+	// the username or IP is locked out
+	AuditSigninFailed AuditAction = "signinFailed"
+	// AuditSignout is recorded when a user signed out
+	AuditSignout AuditAction = "signout"
+	// AuditTokenUsed is recorded when a token authenticated a request, it
+	// is recorded at most once a minute for the same token and IP.
+	AuditTokenUsed AuditAction = "tokenUsed"
+)
+
+type AuditAction string
+
+func (a AuditAction) String() string {
+	return string(a)
+}
+
+// AuditRecord records who authenticated, when and from where
+type AuditRecord struct {
+	ID     ID          `json:"id"`
+	Action AuditAction `json:"action"`
+	// UserID is empty if the signin failed for an unknown username
+	UserID   ID     `json:"userID,omitempty"`
+	Username string `json:"username,omitempty"`
+	// AuthorizationID is the token used, it is set for AuditTokenUsed only
+	AuthorizationID ID        `json:"authorizationID,omitempty"`
+	IP              string    `json:"ip,omitempty"`
+	UserAgent       string    `json:"userAgent,omitempty"`
+	Reason          string    `json:"reason,omitempty"`
+	Time            time.Time `json:"time"`
+}
+
+// AuditFilter filters audit records, all conditions are optional
+type AuditFilter struct {
+	UserID   *ID
+	Username *string
+	Action   *AuditAction
+	IP       *string
+	// Since and Until are inclusive
+	Since *time.Time
+	Until *time.Time
+}
+
+// Match returns true if the record matches all conditions of the filter
+func (f AuditFilter) Match(rec *AuditRecord) bool {
+	switch {
+	case f.UserID != nil && *f.UserID != rec.UserID,
+		f.Username != nil && *f.Username != rec.Username,
+		f.Action != nil && *f.Action != rec.Action,
+		f.IP != nil && *f.IP != rec.IP,
+		f.Since != nil && rec.Time.Before(*f.Since),
+		f.Until != nil && rec.Time.After(*f.Until):
+		return false
+	default:
+		return true
+	}
+}
+
+type AuditService interface {
+	// AddAuditRecord stores the record, and sets the ID and Time of it
+	AddAuditRecord(ctx context.Context, rec *AuditRecord) error
+
+	// FindAuditRecords returns the records match the filter, ordered by time
+	FindAuditRecords(ctx context.Context, filter AuditFilter, opts FindOptions) ([]*AuditRecord, error)
+}
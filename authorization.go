@@ -155,6 +155,11 @@ type Authorization struct {
 	Permissions []Permission `json:"permissions"`
 }
 
+const (
+	AuthorizationActive   = "active"
+	AuthorizationInactive = "inactive"
+)
+
 type UpdateAuthorization struct {
 	Token  *string
 	Status *string
@@ -311,8 +316,20 @@ func (a *Authorization) Kind() string {
 	return "auth"
 }
 
-func (a *Authorization) PermissionSet() PermissionSet {
-	return a.Permissions
+func (a *Authorization) PermissionSet() (PermissionSet, error) {
+	if !a.Active() {
+		return nil, &Error{
+			Code: EForbidden,
+			Msg:  "authorization is inactive",
+		}
+	}
+
+	return a.Permissions, nil
+}
+
+// Active returns true if the token can be used
+func (a *Authorization) Active() bool {
+	return a.Status == "" || a.Status == AuthorizationActive
 }
 
 // OwnerPermissions are the default permissions for those who own a resource
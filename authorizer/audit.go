@@ -0,0 +1,80 @@
+package authorizer
+
+import (
+	"context"
+
+	"github.com/f1shl3gs/manta"
+)
+
+// AuditService authorizes reading audit records, users can read their own
+// records, and the owners of the instance can read all of them. Adding
+// records is not authorized, since they are added while authenticating.
+type AuditService struct {
+	service manta.AuditService
+}
+
+var _ manta.AuditService = &AuditService{}
+
+func NewAuditService(service manta.AuditService) *AuditService {
+	return &AuditService{
+		service: service,
+	}
+}
+
+func (s *AuditService) AddAuditRecord(ctx context.Context, rec *manta.AuditRecord) error {
+	return s.service.AddAuditRecord(ctx, rec)
+}
+
+func (s *AuditService) FindAuditRecords(ctx context.Context, filter manta.AuditFilter, opts manta.FindOptions) ([]*manta.AuditRecord, error) {
+	var err error
+	if filter.UserID != nil {
+		_, _, err = authorizeUser(ctx, manta.ReadAction, *filter.UserID)
+	} else {
+		_, _, err = authorize(ctx, manta.ReadAction, manta.UsersResourceType, nil, nil)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return s.service.FindAuditRecords(ctx, filter, opts)
+}
+
+// LockoutService authorizes the management of lockouts, which requires the
+// permission to write all users. Checking and counting sign-ins are not
+// authorized, since they are used to sign in.
+type LockoutService struct {
+	service manta.LockoutService
+}
+
+var _ manta.LockoutService = &LockoutService{}
+
+func NewLockoutService(service manta.LockoutService) *LockoutService {
+	return &LockoutService{
+		service: service,
+	}
+}
+
+func (s *LockoutService) AttemptSignin(ctx context.Context, username, ip string) error {
+	return s.service.AttemptSignin(ctx, username, ip)
+}
+
+func (s *LockoutService) SigninSucceeded(ctx context.Context, username, ip string) error {
+	return s.service.SigninSucceeded(ctx, username, ip)
+}
+
+func (s *LockoutService) FindLockouts(ctx context.Context) ([]*manta.Lockout, error) {
+	if _, _, err := authorizeUserAdmin(ctx); err != nil {
+		return nil, err
+	}
+
+	return s.service.FindLockouts(ctx)
+}
+
+func (s *LockoutService) DeleteLockout(ctx context.Context, kind manta.LockoutKind, value string) error {
+	if _, _, err := authorizeUserAdmin(ctx); err != nil {
+		return err
+	}
+
+	return s.service.DeleteLockout(ctx, kind, value)
+}
@@ -19,11 +19,12 @@ func NewConfigService(service manta.ConfigService) *ConfigService {
 }
 
 func (s *ConfigService) CreateConfig(ctx context.Context, conf *manta.Config) error {
-	if _, _, err := authorizeCreate(ctx, manta.ConfigsResourceType, conf.OrgID); err != nil {
+	auth, _, err := authorizeCreate(ctx, manta.ConfigsResourceType, conf.OrgID)
+	if err != nil {
 		return err
 	}
 
-	return s.service.CreateConfig(ctx, conf)
+	return s.service.CreateConfig(manta.WithAuthor(ctx, auth.GetUserID()), conf)
 }
 
 func (s *ConfigService) FindConfigByID(ctx context.Context, id manta.ID) (*manta.Config, error) {
@@ -68,11 +69,12 @@ func (s *ConfigService) UpdateConfig(ctx context.Context, id manta.ID, upd manta
 		return nil, err
 	}
 
-	if _, _, err := authorizeWrite(ctx, manta.ConfigsResourceType, id, conf.OrgID); err != nil {
+	auth, _, err := authorizeWrite(ctx, manta.ConfigsResourceType, id, conf.OrgID)
+	if err != nil {
 		return nil, err
 	}
 
-	return s.service.UpdateConfig(ctx, id, upd)
+	return s.service.UpdateConfig(manta.WithAuthor(ctx, auth.GetUserID()), id, upd)
 }
 
 func (s *ConfigService) DeleteConfig(ctx context.Context, id manta.ID) error {
@@ -87,3 +89,100 @@ func (s *ConfigService) DeleteConfig(ctx context.Context, id manta.ID) error {
 
 	return s.service.DeleteConfig(ctx, id)
 }
+
+func (s *ConfigService) FindConfigRevisions(ctx context.Context, id manta.ID) ([]*manta.ConfigRevision, error) {
+	if _, err := s.FindConfigByID(ctx, id); err != nil {
+		return nil, err
+	}
+
+	return s.service.FindConfigRevisions(ctx, id)
+}
+
+func (s *ConfigService) FindConfigRevision(
+	ctx context.Context,
+	id manta.ID,
+	revision uint64,
+) (*manta.ConfigRevision, error) {
+	if _, err := s.FindConfigByID(ctx, id); err != nil {
+		return nil, err
+	}
+
+	return s.service.FindConfigRevision(ctx, id, revision)
+}
+
+func (s *ConfigService) RollbackConfig(ctx context.Context, id manta.ID, revision uint64) (*manta.Config, error) {
+	conf, err := s.service.FindConfigByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	auth, _, err := authorizeWrite(ctx, manta.ConfigsResourceType, id, conf.OrgID)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.service.RollbackConfig(manta.WithAuthor(ctx, auth.GetUserID()), id, revision)
+}
+
+func (s *ConfigService) FindConfigRollout(ctx context.Context, id manta.ID) (*manta.ConfigRollout, error) {
+	if _, err := s.FindConfigByID(ctx, id); err != nil {
+		return nil, err
+	}
+
+	return s.service.FindConfigRollout(ctx, id)
+}
+
+func (s *ConfigService) PromoteConfigRollout(
+	ctx context.Context,
+	id manta.ID,
+	canary int,
+) (*manta.ConfigRollout, error) {
+	conf, err := s.service.FindConfigByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, _, err := authorizeWrite(ctx, manta.ConfigsResourceType, id, conf.OrgID); err != nil {
+		return nil, err
+	}
+
+	return s.service.PromoteConfigRollout(ctx, id, canary)
+}
+
+func (s *ConfigService) AbortConfigRollout(ctx context.Context, id manta.ID) (*manta.ConfigRollout, error) {
+	conf, err := s.service.FindConfigByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	auth, _, err := authorizeWrite(ctx, manta.ConfigsResourceType, id, conf.OrgID)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.service.AbortConfigRollout(manta.WithAuthor(ctx, auth.GetUserID()), id)
+}
+
+// AckConfig requires read permission only, agents which are able to fetch
+// the config are able to report it.
+func (s *ConfigService) AckConfig(ctx context.Context, ack *manta.ConfigAck) error {
+	if _, err := s.FindConfigByID(ctx, ack.ConfigID); err != nil {
+		return err
+	}
+
+	// the config might be rolled back by the ack
+	auth, err := FromContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	return s.service.AckConfig(manta.WithAuthor(ctx, auth.GetUserID()), ack)
+}
+
+func (s *ConfigService) FindConfigAcks(ctx context.Context, id manta.ID) ([]*manta.ConfigAck, error) {
+	if _, err := s.FindConfigByID(ctx, id); err != nil {
+		return nil, err
+	}
+
+	return s.service.FindConfigAcks(ctx, id)
+}
@@ -58,19 +58,21 @@ func (s *DashboardService) FindDashboards(
 }
 
 func (s *DashboardService) CreateDashboard(ctx context.Context, d *manta.Dashboard) error {
-	if _, _, err := authorizeCreate(ctx, manta.DashboardsResourceType, d.OrgID); err != nil {
+	auth, _, err := authorizeCreate(ctx, manta.DashboardsResourceType, d.OrgID)
+	if err != nil {
 		return err
 	}
 
-	return s.dashboardService.CreateDashboard(ctx, d)
+	return s.dashboardService.CreateDashboard(manta.WithAuthor(ctx, auth.GetUserID()), d)
 }
 
 func (s *DashboardService) UpdateDashboard(ctx context.Context, upd manta.DashboardUpdate) (*manta.Dashboard, error) {
-	if _, _, err := authorizeWrite(ctx, manta.DashboardsResourceType, upd.ID, upd.OrgID); err != nil {
+	auth, _, err := authorizeWrite(ctx, manta.DashboardsResourceType, upd.ID, upd.OrgID)
+	if err != nil {
 		return nil, err
 	}
 
-	return s.dashboardService.UpdateDashboard(ctx, upd)
+	return s.dashboardService.UpdateDashboard(manta.WithAuthor(ctx, auth.GetUserID()), upd)
 }
 
 func (s *DashboardService) AddDashboardCell(ctx context.Context, id manta.ID, cell *manta.Cell) error {
@@ -79,11 +81,12 @@ func (s *DashboardService) AddDashboardCell(ctx context.Context, id manta.ID, ce
 		return err
 	}
 
-	if _, _, err := authorizeWrite(ctx, manta.DashboardsResourceType, id, d.OrgID); err != nil {
+	auth, _, err := authorizeWrite(ctx, manta.DashboardsResourceType, id, d.OrgID)
+	if err != nil {
 		return err
 	}
 
-	return s.dashboardService.AddDashboardCell(ctx, id, cell)
+	return s.dashboardService.AddDashboardCell(manta.WithAuthor(ctx, auth.GetUserID()), id, cell)
 }
 
 // RemoveDashboardCell remove a panel by ID
@@ -93,11 +96,12 @@ func (s *DashboardService) RemoveDashboardCell(ctx context.Context, dashboardID,
 		return err
 	}
 
-	if _, _, err := authorizeWrite(ctx, manta.DashboardsResourceType, dashboardID, d.OrgID); err != nil {
+	auth, _, err := authorizeWrite(ctx, manta.DashboardsResourceType, dashboardID, d.OrgID)
+	if err != nil {
 		return err
 	}
 
-	return s.dashboardService.RemoveDashboardCell(ctx, dashboardID, cellID)
+	return s.dashboardService.RemoveDashboardCell(manta.WithAuthor(ctx, auth.GetUserID()), dashboardID, cellID)
 }
 
 // UpdateDashboardCell update the dashboard cell with the provided ids
@@ -110,11 +114,12 @@ func (s *DashboardService) UpdateDashboardCell(
 		return nil, err
 	}
 
-	if _, _, err := authorizeWrite(ctx, manta.DashboardsResourceType, upd.DashboardID, d.OrgID); err != nil {
+	auth, _, err := authorizeWrite(ctx, manta.DashboardsResourceType, upd.DashboardID, d.OrgID)
+	if err != nil {
 		return nil, err
 	}
 
-	return s.dashboardService.UpdateDashboardCell(ctx, upd)
+	return s.dashboardService.UpdateDashboardCell(manta.WithAuthor(ctx, auth.GetUserID()), upd)
 }
 
 func (s *DashboardService) FindDashboardCellByID(
@@ -138,11 +143,12 @@ func (s *DashboardService) RemoveDashboard(ctx context.Context, id manta.ID) err
 		return err
 	}
 
-	if _, _, err = authorizeWrite(ctx, manta.DashboardsResourceType, d.ID, d.OrgID); err != nil {
+	auth, _, err := authorizeWrite(ctx, manta.DashboardsResourceType, d.ID, d.OrgID)
+	if err != nil {
 		return err
 	}
 
-	return s.dashboardService.RemoveDashboard(ctx, id)
+	return s.dashboardService.RemoveDashboard(manta.WithAuthor(ctx, auth.GetUserID()), id)
 }
 
 func (s *DashboardService) ReplaceDashboardCells(ctx context.Context, did manta.ID, cells []manta.Cell) error {
@@ -151,9 +157,86 @@ func (s *DashboardService) ReplaceDashboardCells(ctx context.Context, did manta.
 		return err
 	}
 
-	if _, _, err = authorizeWrite(ctx, manta.DashboardsResourceType, d.ID, d.OrgID); err != nil {
+	auth, _, err := authorizeWrite(ctx, manta.DashboardsResourceType, d.ID, d.OrgID)
+	if err != nil {
 		return err
 	}
 
-	return s.dashboardService.ReplaceDashboardCells(ctx, did, cells)
+	return s.dashboardService.ReplaceDashboardCells(manta.WithAuthor(ctx, auth.GetUserID()), did, cells)
+}
+
+// authorizeDashboardVersions authorizes the action on the dashboard through
+// its latest version, so it works for deleted dashboards too.
+func (s *DashboardService) authorizeDashboardVersions(
+	ctx context.Context,
+	action manta.Action,
+	id manta.ID,
+) (manta.Authorizer, error) {
+	versions, err := s.dashboardService.FindDashboardVersions(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	auth, _, err := authorize(ctx, action, manta.DashboardsResourceType, &id, &versions[0].OrgID)
+	return auth, err
+}
+
+func (s *DashboardService) FindDashboardVersions(ctx context.Context, id manta.ID) ([]*manta.DashboardVersion, error) {
+	versions, err := s.dashboardService.FindDashboardVersions(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, _, err = authorizeRead(ctx, manta.DashboardsResourceType, id, versions[0].OrgID); err != nil {
+		return nil, err
+	}
+
+	return versions, nil
+}
+
+func (s *DashboardService) FindDashboardVersion(
+	ctx context.Context,
+	id manta.ID,
+	version uint64,
+) (*manta.DashboardVersion, error) {
+	if _, err := s.authorizeDashboardVersions(ctx, manta.ReadAction, id); err != nil {
+		return nil, err
+	}
+
+	return s.dashboardService.FindDashboardVersion(ctx, id, version)
+}
+
+func (s *DashboardService) FindDeletedDashboards(
+	ctx context.Context,
+	filter manta.DashboardFilter,
+) ([]*manta.DashboardVersion, error) {
+	versions, err := s.dashboardService.FindDeletedDashboards(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	filtered := versions[:0]
+	for _, ver := range versions {
+		_, _, err := authorizeRead(ctx, manta.DashboardsResourceType, ver.DashboardID, ver.OrgID)
+		if err != nil && manta.ErrorCode(err) != manta.EUnauthorized {
+			return nil, err
+		}
+
+		if manta.ErrorCode(err) == manta.EUnauthorized {
+			continue
+		}
+
+		filtered = append(filtered, ver)
+	}
+
+	return filtered, nil
+}
+
+func (s *DashboardService) RestoreDashboard(ctx context.Context, id manta.ID, version uint64) (*manta.Dashboard, error) {
+	auth, err := s.authorizeDashboardVersions(ctx, manta.WriteAction, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.dashboardService.RestoreDashboard(manta.WithAuthor(ctx, auth.GetUserID()), id, version)
 }
@@ -211,3 +211,30 @@ func TestDashboardService_FindDashboards(t *testing.T) {
 		})
 	}
 }
+
+func TestDashboardService_Author(t *testing.T) {
+	var author manta.ID
+	s := authorizer.NewDashboardService(&mock.DashboardService{
+		FindDashboardByIDFn: func(ctx context.Context, id manta.ID) (*manta.Dashboard, error) {
+			return &manta.Dashboard{
+				ID:    id,
+				OrgID: 10,
+			}, nil
+		},
+		AddDashboardCellFn: func(ctx context.Context, id manta.ID, cell *manta.Cell) error {
+			author = manta.AuthorFromContext(ctx)
+			return nil
+		},
+	})
+
+	ctx := authorizer.SetAuthorizer(context.Background(), &mock.Authorizer{
+		UserID:   3,
+		AllowAll: true,
+	})
+
+	err := s.AddDashboardCell(ctx, 1, &manta.Cell{})
+	tests.ErrorsEqual(t, err, nil)
+	if author != 3 {
+		t.Fatalf("author should be 3, got %s", author)
+	}
+}
@@ -0,0 +1,108 @@
+package authorizer
+
+import (
+	"context"
+
+	"github.com/f1shl3gs/manta"
+)
+
+// OperationLogService filters out the operation logs of resources the
+// user is not allowed to read.
+type OperationLogService struct {
+	service manta.OperationLogService
+}
+
+var _ manta.OperationLogService = &OperationLogService{}
+
+func NewOperationLogService(service manta.OperationLogService) *OperationLogService {
+	return &OperationLogService{
+		service: service,
+	}
+}
+
+// AddLogEntry is called by services which authorized the change already
+func (s *OperationLogService) AddLogEntry(ctx context.Context, ent manta.OperationLogEntry) error {
+	return s.service.AddLogEntry(ctx, ent)
+}
+
+func (s *OperationLogService) FindOperationLogsByID(
+	ctx context.Context,
+	id manta.ID,
+	opts manta.FindOptions,
+) ([]*manta.OperationLogEntry, int, error) {
+	return s.FindOperationLogs(ctx, manta.OperationLogFilter{ResourceID: &id}, opts)
+}
+
+func (s *OperationLogService) FindOperationLogsByUser(
+	ctx context.Context,
+	userID manta.ID,
+	opts manta.FindOptions,
+) ([]*manta.OperationLogEntry, int, error) {
+	return s.FindOperationLogs(ctx, manta.OperationLogFilter{UserID: &userID}, opts)
+}
+
+// FindOperationLogs returns the operation logs the user is allowed to read,
+// entries are filtered before pagination, so pages are always full, and the
+// number of all readable entries is returned.
+func (s *OperationLogService) FindOperationLogs(
+	ctx context.Context,
+	filter manta.OperationLogFilter,
+	opts manta.FindOptions,
+) ([]*manta.OperationLogEntry, int, error) {
+	entries, _, err := s.service.FindOperationLogs(ctx, filter, manta.FindOptions{
+		SortBy:     opts.SortBy,
+		Descending: opts.Descending,
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	filtered := entries[:0]
+	for _, ent := range entries {
+		_, _, err := authorizeRead(ctx, ent.ResourceType, ent.ResourceID, ent.OrgID)
+		if err != nil && manta.ErrorCode(err) != manta.EUnauthorized {
+			return nil, 0, err
+		}
+
+		if manta.ErrorCode(err) == manta.EUnauthorized {
+			continue
+		}
+
+		filtered = append(filtered, ent)
+	}
+
+	total, offset := len(filtered), opts.Offset
+	if offset < 0 {
+		offset = 0
+	}
+	if offset >= total {
+		return []*manta.OperationLogEntry{}, total, nil
+	}
+
+	filtered = filtered[offset:]
+	if opts.Limit > 0 && len(filtered) > opts.Limit {
+		filtered = filtered[:opts.Limit]
+	}
+
+	return filtered, total, nil
+}
+
+// DeleteOperationLogs requires the permission to write the resource, secrets
+// of different orgs share the resource ID, so every entry is checked.
+func (s *OperationLogService) DeleteOperationLogs(ctx context.Context, resourceID manta.ID) error {
+	entries, _, err := s.service.FindOperationLogs(ctx, manta.OperationLogFilter{
+		ResourceID: &resourceID,
+	}, manta.FindOptions{})
+	if err != nil {
+		return err
+	}
+
+	for _, ent := range entries {
+		_, _, err = authorizeWrite(ctx, ent.ResourceType, ent.ResourceID, ent.OrgID)
+		if err != nil {
+			return err
+		}
+	}
+
+	return s.service.DeleteOperationLogs(ctx, resourceID)
+}
@@ -0,0 +1,97 @@
+package authorizer_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/f1shl3gs/manta"
+	"github.com/f1shl3gs/manta/authorizer"
+	"github.com/f1shl3gs/manta/mock"
+	"github.com/f1shl3gs/manta/tests"
+)
+
+type oplogStore struct {
+	manta.OperationLogService
+
+	entries []*manta.OperationLogEntry
+}
+
+func (s *oplogStore) FindOperationLogs(
+	ctx context.Context,
+	filter manta.OperationLogFilter,
+	opts manta.FindOptions,
+) ([]*manta.OperationLogEntry, int, error) {
+	if opts.Limit != 0 || opts.Offset != 0 {
+		panic("pagination must be applied after authorization")
+	}
+
+	entries := make([]*manta.OperationLogEntry, len(s.entries))
+	copy(entries, s.entries)
+
+	return entries, len(entries), nil
+}
+
+func TestOperationLogService_FindOperationLogs(t *testing.T) {
+	orgID := manta.ID(10)
+	store := &oplogStore{}
+	for i := 0; i < 6; i++ {
+		store.entries = append(store.entries, &manta.OperationLogEntry{
+			Type:         manta.Update,
+			ResourceID:   manta.ID(1 + i%2),
+			ResourceType: manta.DashboardsResourceType,
+			OrgID:        orgID,
+			UserID:       manta.ID(100 + i),
+		})
+	}
+
+	svc := authorizer.NewOperationLogService(store)
+	ctx := authorizer.SetAuthorizer(context.Background(), mock.NewAuthorizer(false, []manta.Permission{
+		{
+			Action: manta.ReadAction,
+			Resource: manta.Resource{
+				Type: manta.DashboardsResourceType,
+				ID:   tests.IDPtr(1),
+			},
+		},
+	}))
+
+	for _, tc := range []struct {
+		name  string
+		opts  manta.FindOptions
+		users []manta.ID
+	}{
+		{
+			name:  "all",
+			users: []manta.ID{100, 102, 104},
+		},
+		{
+			name:  "page",
+			opts:  manta.FindOptions{Offset: 1, Limit: 1},
+			users: []manta.ID{102},
+		},
+		{
+			name:  "last page",
+			opts:  manta.FindOptions{Offset: 2, Limit: 2},
+			users: []manta.ID{104},
+		},
+		{
+			name: "out of range",
+			opts: manta.FindOptions{Offset: 3, Limit: 2},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			entries, n, err := svc.FindOperationLogs(ctx, manta.OperationLogFilter{OrgID: &orgID}, tc.opts)
+			require.NoError(t, err)
+			assert.Equal(t, 3, n)
+
+			users := make([]manta.ID, 0, len(entries))
+			for _, ent := range entries {
+				users = append(users, ent.UserID)
+			}
+			assert.ElementsMatch(t, tc.users, users)
+		})
+	}
+}
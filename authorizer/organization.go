@@ -0,0 +1,66 @@
+package authorizer
+
+import (
+	"context"
+
+	"github.com/f1shl3gs/manta"
+)
+
+// OrganizationService authorizes the changes of organizations, organizations
+// are not filtered when listing, so users can switch between them.
+type OrganizationService struct {
+	service manta.OrganizationService
+}
+
+var _ manta.OrganizationService = &OrganizationService{}
+
+func NewOrganizationService(service manta.OrganizationService) *OrganizationService {
+	return &OrganizationService{
+		service: service,
+	}
+}
+
+func (s *OrganizationService) FindOrganizationByID(ctx context.Context, id manta.ID) (*manta.Organization, error) {
+	return s.service.FindOrganizationByID(ctx, id)
+}
+
+func (s *OrganizationService) FindOrganization(ctx context.Context, filter manta.OrganizationFilter) (*manta.Organization, error) {
+	return s.service.FindOrganization(ctx, filter)
+}
+
+func (s *OrganizationService) FindOrganizations(
+	ctx context.Context,
+	filter manta.OrganizationFilter,
+	opt ...manta.FindOptions,
+) ([]*manta.Organization, int, error) {
+	return s.service.FindOrganizations(ctx, filter, opt...)
+}
+
+func (s *OrganizationService) CreateOrganization(ctx context.Context, org *manta.Organization) error {
+	return s.service.CreateOrganization(ctx, org)
+}
+
+func (s *OrganizationService) UpdateOrganization(
+	ctx context.Context,
+	id manta.ID,
+	u manta.OrganizationUpdate,
+) (*manta.Organization, error) {
+	if _, _, err := authorizeOrg(ctx, manta.WriteAction, id); err != nil {
+		return nil, err
+	}
+
+	return s.service.UpdateOrganization(ctx, id, u)
+}
+
+func (s *OrganizationService) DeleteOrganization(ctx context.Context, id manta.ID) error {
+	if _, _, err := authorizeOrg(ctx, manta.WriteAction, id); err != nil {
+		return err
+	}
+
+	return s.service.DeleteOrganization(ctx, id)
+}
+
+// authorizeOrg authorizes the user in the context to act on the organization itself
+func authorizeOrg(ctx context.Context, action manta.Action, id manta.ID) (manta.Authorizer, manta.Permission, error) {
+	return authorize(ctx, action, manta.OrgsResourceType, &id, nil)
+}
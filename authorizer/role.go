@@ -0,0 +1,62 @@
+package authorizer
+
+import (
+	"context"
+
+	"github.com/f1shl3gs/manta"
+)
+
+// RoleService authorizes the roles of organizations, reading roles requires
+// the permission to read the organization, and changing roles requires the
+// permission to write it.
+type RoleService struct {
+	service manta.RoleService
+}
+
+var _ manta.RoleService = &RoleService{}
+
+func NewRoleService(service manta.RoleService) *RoleService {
+	return &RoleService{
+		service: service,
+	}
+}
+
+func (s *RoleService) FindRole(ctx context.Context, orgID manta.ID, name string) (*manta.Role, error) {
+	if _, _, err := authorizeOrg(ctx, manta.ReadAction, orgID); err != nil {
+		return nil, err
+	}
+
+	return s.service.FindRole(ctx, orgID, name)
+}
+
+func (s *RoleService) FindRoles(ctx context.Context, orgID manta.ID) ([]*manta.Role, error) {
+	if _, _, err := authorizeOrg(ctx, manta.ReadAction, orgID); err != nil {
+		return nil, err
+	}
+
+	return s.service.FindRoles(ctx, orgID)
+}
+
+func (s *RoleService) CreateRole(ctx context.Context, role *manta.Role) error {
+	if _, _, err := authorizeOrg(ctx, manta.WriteAction, role.OrgID); err != nil {
+		return err
+	}
+
+	return s.service.CreateRole(ctx, role)
+}
+
+func (s *RoleService) UpdateRole(ctx context.Context, orgID manta.ID, name string, upd manta.RoleUpdate) (*manta.Role, error) {
+	if _, _, err := authorizeOrg(ctx, manta.WriteAction, orgID); err != nil {
+		return nil, err
+	}
+
+	return s.service.UpdateRole(ctx, orgID, name, upd)
+}
+
+func (s *RoleService) DeleteRole(ctx context.Context, orgID manta.ID, name string) error {
+	if _, _, err := authorizeOrg(ctx, manta.WriteAction, orgID); err != nil {
+		return err
+	}
+
+	return s.service.DeleteRole(ctx, orgID, name)
+}
@@ -0,0 +1,74 @@
+package authorizer
+
+import (
+	"context"
+	"time"
+
+	"github.com/f1shl3gs/manta"
+)
+
+// SessionService authorizes the management of sessions, users can list and
+// revoke their own sessions, and the owners of the instance can revoke the
+// sessions of everyone. Creating, finding and extending sessions are not
+// authorized, since they are used to authenticate.
+type SessionService struct {
+	service manta.SessionService
+}
+
+var _ manta.SessionService = &SessionService{}
+
+func NewSessionService(service manta.SessionService) *SessionService {
+	return &SessionService{
+		service: service,
+	}
+}
+
+func (s *SessionService) CreateSession(ctx context.Context, uid manta.ID, opts ...manta.SessionOptions) (*manta.Session, error) {
+	return s.service.CreateSession(ctx, uid, opts...)
+}
+
+func (s *SessionService) FindSession(ctx context.Context, id manta.ID) (*manta.Session, error) {
+	return s.service.FindSession(ctx, id)
+}
+
+func (s *SessionService) FindSessions(ctx context.Context, uid manta.ID) ([]*manta.Session, error) {
+	// the sessions reveal where the user signed in from
+	if _, _, err := authorizeUser(ctx, manta.WriteAction, uid); err != nil {
+		return nil, err
+	}
+
+	return s.service.FindSessions(ctx, uid)
+}
+
+func (s *SessionService) RevokeSession(ctx context.Context, id manta.ID) error {
+	session, err := s.service.FindSession(ctx, id)
+	if err == manta.ErrSessionNotFound || err == manta.ErrSessionExpired {
+		// nothing to protect
+		return s.service.RevokeSession(ctx, id)
+	}
+	if err != nil {
+		return err
+	}
+
+	if _, _, err = authorizeUser(ctx, manta.WriteAction, session.UserID); err != nil {
+		return err
+	}
+
+	return s.service.RevokeSession(ctx, id)
+}
+
+func (s *SessionService) RevokeSessions(ctx context.Context, uid manta.ID) error {
+	if _, _, err := authorizeUser(ctx, manta.WriteAction, uid); err != nil {
+		return err
+	}
+
+	return s.service.RevokeSessions(ctx, uid)
+}
+
+func (s *SessionService) RenewSession(ctx context.Context, id manta.ID, expiration time.Time) error {
+	return s.service.RenewSession(ctx, id, expiration)
+}
+
+func (s *SessionService) ExtendSession(ctx context.Context, id manta.ID) error {
+	return s.service.ExtendSession(ctx, id)
+}
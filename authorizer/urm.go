@@ -0,0 +1,63 @@
+package authorizer
+
+import (
+	"context"
+
+	"github.com/f1shl3gs/manta"
+)
+
+// UserResourceMappingService authorizes the mappings of organizations, which
+// are the members of them. Reading members requires the permission to read
+// the organization, and changing members requires the permission to write it.
+type UserResourceMappingService struct {
+	service manta.UserResourceMappingService
+}
+
+var _ manta.UserResourceMappingService = &UserResourceMappingService{}
+
+func NewUserResourceMappingService(service manta.UserResourceMappingService) *UserResourceMappingService {
+	return &UserResourceMappingService{
+		service: service,
+	}
+}
+
+func (s *UserResourceMappingService) FindUserResourceMappings(
+	ctx context.Context,
+	filter manta.UserResourceMappingFilter,
+	opts ...manta.FindOptions,
+) ([]*manta.UserResourceMapping, int, error) {
+	if filter.ResourceType != manta.OrgsResourceType || !filter.ResourceID.Valid() {
+		return nil, 0, errOrgMappingOnly
+	}
+
+	if _, _, err := authorizeOrg(ctx, manta.ReadAction, filter.ResourceID); err != nil {
+		return nil, 0, err
+	}
+
+	return s.service.FindUserResourceMappings(ctx, filter, opts...)
+}
+
+func (s *UserResourceMappingService) CreateUserResourceMapping(ctx context.Context, m *manta.UserResourceMapping) error {
+	if m.ResourceType != manta.OrgsResourceType {
+		return errOrgMappingOnly
+	}
+
+	if _, _, err := authorizeOrg(ctx, manta.WriteAction, m.ResourceID); err != nil {
+		return err
+	}
+
+	return s.service.CreateUserResourceMapping(ctx, m)
+}
+
+func (s *UserResourceMappingService) DeleteUserResourceMapping(ctx context.Context, resourceID, userID manta.ID) error {
+	if _, _, err := authorizeOrg(ctx, manta.WriteAction, resourceID); err != nil {
+		return err
+	}
+
+	return s.service.DeleteUserResourceMapping(ctx, resourceID, userID)
+}
+
+var errOrgMappingOnly = &manta.Error{
+	Code: manta.EInvalid,
+	Msg:  "only mappings of organizations are supported",
+}
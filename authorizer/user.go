@@ -0,0 +1,150 @@
+package authorizer
+
+import (
+	"context"
+	"time"
+
+	"github.com/f1shl3gs/manta"
+)
+
+// UserService authorizes the management of users, users can update
+// themselves, creating, disabling and deleting users requires the permission
+// to write all users, which the owners of the instance have. Users are not
+// filtered when listing, so members can be added to organizations.
+type UserService struct {
+	service manta.UserService
+}
+
+var _ manta.UserService = &UserService{}
+
+func NewUserService(service manta.UserService) *UserService {
+	return &UserService{
+		service: service,
+	}
+}
+
+func (s *UserService) FindUserByID(ctx context.Context, id manta.ID) (*manta.User, error) {
+	return s.service.FindUserByID(ctx, id)
+}
+
+func (s *UserService) FindUser(ctx context.Context, filter manta.UserFilter) (*manta.User, error) {
+	return s.service.FindUser(ctx, filter)
+}
+
+func (s *UserService) FindUsers(ctx context.Context, filter manta.UserFilter, opts ...manta.FindOptions) ([]*manta.User, error) {
+	return s.service.FindUsers(ctx, filter, opts...)
+}
+
+func (s *UserService) CreateUser(ctx context.Context, user *manta.User) error {
+	if _, _, err := authorizeUserAdmin(ctx); err != nil {
+		return err
+	}
+
+	return s.service.CreateUser(ctx, user)
+}
+
+func (s *UserService) UpdateUser(ctx context.Context, id manta.ID, upd manta.UserUpdate) (*manta.User, error) {
+	var err error
+	if upd.Status != nil {
+		// users cannot enable or disable themselves
+		_, _, err = authorizeUserAdmin(ctx)
+	} else {
+		_, _, err = authorizeUser(ctx, manta.WriteAction, id)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return s.service.UpdateUser(ctx, id, upd)
+}
+
+func (s *UserService) DeleteUser(ctx context.Context, id manta.ID) error {
+	if _, _, err := authorizeUserAdmin(ctx); err != nil {
+		return err
+	}
+
+	return s.service.DeleteUser(ctx, id)
+}
+
+// PasswordService authorizes the changes of passwords, comparing passwords
+// is not authorized since it is used to sign in.
+type PasswordService struct {
+	service manta.PasswordService
+}
+
+var _ manta.PasswordService = &PasswordService{}
+
+func NewPasswordService(service manta.PasswordService) *PasswordService {
+	return &PasswordService{
+		service: service,
+	}
+}
+
+func (s *PasswordService) SetPassword(ctx context.Context, uid manta.ID, password string) error {
+	if _, _, err := authorizeUserAdmin(ctx); err != nil {
+		return err
+	}
+
+	return s.service.SetPassword(ctx, uid, password)
+}
+
+func (s *PasswordService) ComparePassword(ctx context.Context, uid manta.ID, password string) error {
+	return s.service.ComparePassword(ctx, uid, password)
+}
+
+func (s *PasswordService) HasPassword(ctx context.Context, uid manta.ID) (bool, error) {
+	return s.service.HasPassword(ctx, uid)
+}
+
+func (s *PasswordService) CompareAndSetPassword(ctx context.Context, uid manta.ID, old, new string) error {
+	if _, _, err := authorizeUser(ctx, manta.WriteAction, uid); err != nil {
+		return err
+	}
+
+	return s.service.CompareAndSetPassword(ctx, uid, old, new)
+}
+
+func (s *PasswordService) DeletePassword(ctx context.Context, uid manta.ID) error {
+	if _, _, err := authorizeUserAdmin(ctx); err != nil {
+		return err
+	}
+
+	return s.service.DeletePassword(ctx, uid)
+}
+
+// PasswordResetService authorizes issuing password reset tokens, resetting
+// password is not authorized since the token is the credential.
+type PasswordResetService struct {
+	service manta.PasswordResetService
+}
+
+var _ manta.PasswordResetService = &PasswordResetService{}
+
+func NewPasswordResetService(service manta.PasswordResetService) *PasswordResetService {
+	return &PasswordResetService{
+		service: service,
+	}
+}
+
+func (s *PasswordResetService) CreatePasswordReset(ctx context.Context, uid manta.ID, ttl time.Duration) (*manta.PasswordReset, error) {
+	if _, _, err := authorizeUserAdmin(ctx); err != nil {
+		return nil, err
+	}
+
+	return s.service.CreatePasswordReset(ctx, uid, ttl)
+}
+
+func (s *PasswordResetService) ResetPassword(ctx context.Context, token, password string) error {
+	return s.service.ResetPassword(ctx, token, password)
+}
+
+// authorizeUser authorizes the user in the context to act on the user
+func authorizeUser(ctx context.Context, action manta.Action, id manta.ID) (manta.Authorizer, manta.Permission, error) {
+	return authorize(ctx, action, manta.UsersResourceType, &id, nil)
+}
+
+// authorizeUserAdmin authorizes the user in the context to manage all users
+func authorizeUserAdmin(ctx context.Context) (manta.Authorizer, manta.Permission, error) {
+	return authorize(ctx, manta.WriteAction, manta.UsersResourceType, nil, nil)
+}
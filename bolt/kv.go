@@ -26,6 +26,7 @@ type KVStore struct {
 	path string
 	db   *bolt.DB
 	log  *zap.Logger
+	hub  *kv.WatchHub
 
 	noSync bool
 }
@@ -46,6 +47,7 @@ func NewKVStore(log *zap.Logger, path string, opts ...KVOption) *KVStore {
 	store := &KVStore{
 		path: path,
 		log:  log.Named("bolt"),
+		hub:  kv.NewWatchHub(),
 	}
 
 	for _, opt := range opts {
@@ -157,12 +159,38 @@ func (s *KVStore) Update(ctx context.Context, fn func(tx kv.Tx) error) error {
 	span, ctx := tracing.StartSpanFromContext(ctx)
 	defer span.Finish()
 
-	return s.db.Update(func(tx *bolt.Tx) error {
-		return fn(&Tx{
-			ctx: ctx,
-			tx:  tx,
-		})
+	var events []kv.Event
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		wtx := &Tx{
+			ctx:    ctx,
+			tx:     tx,
+			events: &events,
+		}
+
+		if err := fn(wtx); err != nil {
+			return err
+		}
+
+		rev := uint64(tx.ID())
+		for i := range events {
+			events[i].Revision = rev
+		}
+
+		return nil
 	})
+	if err != nil {
+		return err
+	}
+
+	s.hub.Publish(events)
+
+	return nil
+}
+
+// Watch implements kv.Watcher, events are published after the update
+// transactions are committed.
+func (s *KVStore) Watch(ctx context.Context, bucket, prefix []byte) (<-chan kv.Event, error) {
+	return s.hub.Watch(ctx, bucket, prefix)
 }
 
 // CreateBucket creates a bucket in the underlying boltdb store if it
@@ -201,6 +229,9 @@ func (s *KVStore) Backup(ctx context.Context, w io.Writer) error {
 type Tx struct {
 	tx  *bolt.Tx
 	ctx context.Context
+
+	// events records the changes of update transaction
+	events *[]kv.Event
 }
 
 // Context returns the context for the transaction.
@@ -220,13 +251,34 @@ func (tx *Tx) Bucket(b []byte) (kv.Bucket, error) {
 		return nil, fmt.Errorf("bucket %q: %w", string(b), kv.ErrBucketNotFound)
 	}
 	return &Bucket{
+		name:   b,
 		bucket: bkt,
+		events: tx.events,
 	}, nil
 }
 
 // Bucket implements kv.Bucket.
 type Bucket struct {
+	name   []byte
 	bucket *bolt.Bucket
+	events *[]kv.Event
+}
+
+func (b *Bucket) record(typ kv.EventType, key, value []byte) {
+	if b.events == nil {
+		return
+	}
+
+	ev := kv.Event{
+		Type:   typ,
+		Bucket: append([]byte(nil), b.name...),
+		Key:    append([]byte(nil), key...),
+	}
+	if typ == kv.EventPut {
+		ev.Value = append([]byte(nil), value...)
+	}
+
+	*b.events = append(*b.events, ev)
 }
 
 // Get retrieves the value at the provided key.
@@ -260,6 +312,9 @@ func (b *Bucket) Put(key []byte, value []byte) error {
 	if err == bolt.ErrTxNotWritable {
 		return kv.ErrTxNotWritable
 	}
+	if err == nil {
+		b.record(kv.EventPut, key, value)
+	}
 	return err
 }
 
@@ -269,6 +324,9 @@ func (b *Bucket) Delete(key []byte) error {
 	if err == bolt.ErrTxNotWritable {
 		return kv.ErrTxNotWritable
 	}
+	if err == nil {
+		b.record(kv.EventDelete, key, nil)
+	}
 	return err
 }
 
@@ -7,6 +7,8 @@ import (
 	"os"
 	"testing"
 
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"go.uber.org/zap/zaptest"
 
 	"github.com/f1shl3gs/manta/bolt"
@@ -74,3 +76,73 @@ func mustCreateBucket(t testing.TB, store kv.SchemaStore, bucket []byte) {
 		t.Fatal(err)
 	}
 }
+
+func TestWatch(t *testing.T) {
+	s, closeFn, err := NewTestKVStore(t)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closeFn()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	bucket := []byte("bucket")
+	mustCreateBucket(t, s, bucket)
+
+	ch, err := s.Watch(ctx, bucket, []byte("foo"))
+	require.NoError(t, err)
+
+	update := func(fn func(b kv.Bucket) error) error {
+		return s.Update(ctx, func(tx kv.Tx) error {
+			b, err := tx.Bucket(bucket)
+			if err != nil {
+				return err
+			}
+
+			return fn(b)
+		})
+	}
+
+	err = update(func(b kv.Bucket) error {
+		if err := b.Put([]byte("bar"), []byte("v0")); err != nil {
+			return err
+		}
+
+		return b.Put([]byte("foo"), []byte("v1"))
+	})
+	require.NoError(t, err)
+
+	// changes of failed transactions are not published
+	err = update(func(b kv.Bucket) error {
+		if err := b.Put([]byte("foo"), []byte("v2")); err != nil {
+			return err
+		}
+
+		return errors.New("rollback")
+	})
+	require.Error(t, err)
+
+	err = update(func(b kv.Bucket) error {
+		return b.Delete([]byte("foo"))
+	})
+	require.NoError(t, err)
+
+	ev := <-ch
+	assert.Equal(t, kv.EventPut, ev.Type)
+	assert.Equal(t, "foo", string(ev.Key))
+	assert.Equal(t, "v1", string(ev.Value))
+	put := ev.Revision
+
+	ev = <-ch
+	assert.Equal(t, kv.EventDelete, ev.Type)
+	assert.Equal(t, "foo", string(ev.Key))
+	assert.Nil(t, ev.Value)
+	assert.Greater(t, ev.Revision, put)
+
+	select {
+	case ev = <-ch:
+		t.Fatalf("unexpected event %+v", ev)
+	default:
+	}
+}
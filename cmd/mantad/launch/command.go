@@ -11,6 +11,8 @@ import (
 	"github.com/spf13/cobra"
 	"github.com/spf13/pflag"
 	"github.com/spf13/viper"
+
+	"github.com/f1shl3gs/manta/pkg/keyring"
 )
 
 func Command() *cobra.Command {
@@ -57,6 +59,55 @@ func Command() *cobra.Command {
 	return cmd
 }
 
+// SecretCommand returns the command to manage secrets, it accepts the
+// same flags as mantad, so the store can be opened as usual.
+func SecretCommand() *cobra.Command {
+	launcher := &Launcher{}
+
+	rotate := &cobra.Command{
+		Use:   "rotate",
+		Short: "Re-encrypt all secrets with new data keys wrapped by the current master key",
+		Long: `Re-encrypt all secrets with new data keys wrapped by the current master key.
+
+Set the new master key with --secret.key or --secret.key-file, and the previous
+ones with --secret.previous-key-files. mantad must be stopped before rotation.`,
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			launcher.rotateSecrets = true
+			return launcher.run()
+		},
+	}
+
+	bindOptions(rotate, launcher.Options())
+
+	generateKey := &cobra.Command{
+		Use:   "generate-key",
+		Short: "Print a new base64 encoded master key to encrypt secrets",
+		Long: `Print a new base64 encoded master key to encrypt secrets.
+
+Keep the key out of the store path, and pass it to mantad with --secret.key,
+the environment variable MANTA_SECRET_KEY or --secret.key-file.`,
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			key, err := keyring.GenerateKey()
+			if err != nil {
+				return err
+			}
+
+			_, err = fmt.Fprintln(cmd.OutOrStdout(), string(keyring.EncodeKey(key)))
+			return err
+		},
+	}
+
+	cmd := &cobra.Command{
+		Use:   "secret",
+		Short: "Manage secrets",
+	}
+	cmd.AddCommand(rotate, generateKey)
+
+	return cmd
+}
+
 type Option struct {
 	DestP interface{} // pointer to the destination
 
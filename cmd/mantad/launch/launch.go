@@ -2,6 +2,8 @@ package launch
 
 import (
 	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"math"
 	"net"
 	"net/http"
@@ -15,16 +17,22 @@ import (
 	"github.com/f1shl3gs/manta/bolt"
 	"github.com/f1shl3gs/manta/checks"
 	httpservice "github.com/f1shl3gs/manta/http"
+	"github.com/f1shl3gs/manta/identity"
+	"github.com/f1shl3gs/manta/identity/ldap"
+	"github.com/f1shl3gs/manta/identity/oidc"
 	"github.com/f1shl3gs/manta/kv"
 	"github.com/f1shl3gs/manta/kv/migration"
 	"github.com/f1shl3gs/manta/multitsdb"
 	"github.com/f1shl3gs/manta/oplog"
 	"github.com/f1shl3gs/manta/pkg/cgroups"
+	"github.com/f1shl3gs/manta/pkg/keyring"
 	"github.com/f1shl3gs/manta/pkg/log"
 	"github.com/f1shl3gs/manta/pkg/signals"
+	"github.com/f1shl3gs/manta/pkg/tlsutil"
 	"github.com/f1shl3gs/manta/raftstore"
 	"github.com/f1shl3gs/manta/raftstore/pb"
 	"github.com/f1shl3gs/manta/scrape"
+	"github.com/f1shl3gs/manta/secret"
 	"github.com/f1shl3gs/manta/task/backend"
 	"github.com/f1shl3gs/manta/task/backend/coordinator"
 	"github.com/f1shl3gs/manta/task/backend/executor"
@@ -62,6 +70,77 @@ type Launcher struct {
 	// storage
 	StorageDir string
 
+	// tls
+	TLSCert        string
+	TLSKey         string
+	TLSCA          string
+	TLSClientAuth  bool
+	TLSAllowedSANs []string
+
+	// secret
+	SecretKey          string
+	SecretKeyFile      string
+	SecretPreviousKeys []string
+	SecretProviders    []string
+	SecretFileDir      string
+	SecretVaultAddr    string
+	SecretVaultToken   string
+	SecretVaultMount   string
+	SecretVaultPrefix  string
+
+	// oidc
+	OIDCIssuer         string
+	OIDCClientID       string
+	OIDCClientSecret   string
+	OIDCRedirectURL    string
+	OIDCScopes         []string
+	OIDCGroupsClaim    string
+	OIDCAutoProvision  bool
+	OIDCLinkLocalUsers bool
+	OIDCGroupMappings  map[string]string
+
+	// ldap
+	LDAPURL                   string
+	LDAPStartTLS              bool
+	LDAPTLSCA                 string
+	LDAPTLSInsecureSkipVerify bool
+	LDAPBindDN                string
+	LDAPBindPassword          string
+	LDAPBaseDN                string
+	LDAPUserFilter            string
+	LDAPUsernameAttribute     string
+	LDAPEmailAttribute        string
+	LDAPGroupBaseDN           string
+	LDAPGroupFilter           string
+	LDAPGroupAttribute        string
+	LDAPAutoProvision         bool
+	LDAPLinkLocalUsers        bool
+	LDAPGroupMappings         map[string]string
+	LDAPSyncInterval          time.Duration
+
+	// session
+	SessionIdleTimeout     time.Duration
+	SessionAbsoluteTimeout time.Duration
+
+	// signin lockouts and auditing
+	SigninMaxUsernameFailures int
+	SigninMaxIPFailures       int
+	SigninLockout             time.Duration
+	SigninMaxLockout          time.Duration
+	AuditRetention            time.Duration
+
+	// SweepInterval is the interval to delete expired sessions, lockouts
+	// and audit records
+	SweepInterval time.Duration
+
+	// rotateSecrets re-encrypts all secrets after migration, and exit
+	rotateSecrets bool
+
+	// oplog
+	OplogRetention        time.Duration
+	OplogRetentionEntries int
+	OplogCompactInterval  time.Duration
+
 	// pprof
 	ProfileDir       string
 	ProfileInterval  string
@@ -111,6 +190,286 @@ func (l *Launcher) Options() []Option {
 			Default: "data",
 			Desc:    "storage is disabled by default",
 		},
+		{
+			DestP: &l.TLSCert,
+			Flag:  "tls.cert",
+			Desc:  "certificate file to serve HTTP/gRPC with TLS and to connect to raft peers",
+		},
+		{
+			DestP: &l.TLSKey,
+			Flag:  "tls.key",
+			Desc:  "private key file of the certificate",
+		},
+		{
+			DestP: &l.TLSCA,
+			Flag:  "tls.ca",
+			Desc:  "CA file to verify certificates of clients and raft peers, system CA is used if not set, it is required by --tls.client-auth",
+		},
+		{
+			DestP:   &l.TLSClientAuth,
+			Flag:    "tls.client-auth",
+			Default: false,
+			Desc:    "require clients and raft peers to present a certificate signed by the CA of --tls.ca (mutual TLS)",
+		},
+		{
+			DestP: &l.TLSAllowedSANs,
+			Flag:  "tls.allowed-sans",
+			Desc:  "SANs the certificates of clients and raft peers must match one of, DNS names support a wildcard left-most label like *.example.com",
+		},
+		{
+			DestP: &l.SecretKey,
+			Flag:  "secret.key",
+			Desc:  "base64 encoded 32 bytes master key to encrypt secrets, prefer the environment variable MANTA_SECRET_KEY",
+		},
+		{
+			DestP: &l.SecretKeyFile,
+			Flag:  "secret.key-file",
+			Desc:  "file contains the base64 encoded master key, either the key or the key file is required to start, and all nodes of a cluster must use the same key",
+		},
+		{
+			DestP: &l.SecretPreviousKeys,
+			Flag:  "secret.previous-key-files",
+			Desc:  "files contain previous master keys, which are used to decrypt secrets during rotation",
+		},
+		{
+			DestP:   &l.SecretProviders,
+			Flag:    "secret.providers",
+			Default: []string{"kv"},
+			Desc:    "providers to resolve secrets in order, the first one has the secret wins, available providers are kv, file and vault",
+		},
+		{
+			DestP: &l.SecretFileDir,
+			Flag:  "secret.file.dir",
+			Desc:  "dir of the file provider, secrets are read from <dir>/<orgID>/<key>",
+		},
+		{
+			DestP: &l.SecretVaultAddr,
+			Flag:  "secret.vault.addr",
+			Desc:  "address of the Vault server, which provides KV v2 secrets engine",
+		},
+		{
+			DestP: &l.SecretVaultToken,
+			Flag:  "secret.vault.token",
+			Desc:  "token to access Vault, prefer the environment variable MANTA_SECRET_VAULT_TOKEN",
+		},
+		{
+			DestP:   &l.SecretVaultMount,
+			Flag:    "secret.vault.mount",
+			Default: "secret",
+			Desc:    "mount path of the KV v2 secrets engine",
+		},
+		{
+			DestP:   &l.SecretVaultPrefix,
+			Flag:    "secret.vault.prefix",
+			Default: "manta",
+			Desc:    "secrets are read from <mount>/data/<prefix>/<orgID>/<key>",
+		},
+		{
+			DestP: &l.OIDCIssuer,
+			Flag:  "oidc.issuer",
+			Desc:  "URL of the OpenID provider, OpenID Connect login is disabled if not set",
+		},
+		{
+			DestP: &l.OIDCClientID,
+			Flag:  "oidc.client-id",
+			Desc:  "client id registered at the OpenID provider",
+		},
+		{
+			DestP: &l.OIDCClientSecret,
+			Flag:  "oidc.client-secret",
+			Desc:  "client secret registered at the OpenID provider, prefer the environment variable MANTA_OIDC_CLIENT_SECRET",
+		},
+		{
+			DestP: &l.OIDCRedirectURL,
+			Flag:  "oidc.redirect-url",
+			Desc:  "external URL of the callback, e.g. https://manta.example.com/api/v1/oidc/callback",
+		},
+		{
+			DestP:   &l.OIDCScopes,
+			Flag:    "oidc.scopes",
+			Default: []string{"openid", "profile", "email"},
+			Desc:    "scopes requested from the OpenID provider",
+		},
+		{
+			DestP:   &l.OIDCGroupsClaim,
+			Flag:    "oidc.groups-claim",
+			Default: "groups",
+			Desc:    "claim of the id token listing the groups of user",
+		},
+		{
+			DestP:   &l.OIDCAutoProvision,
+			Flag:    "oidc.auto-provision",
+			Default: false,
+			Desc:    "create users signed in the first time, otherwise users must be created in advance with verified email or name as username",
+		},
+		{
+			DestP:   &l.OIDCLinkLocalUsers,
+			Flag:    "oidc.link-local-users",
+			Default: false,
+			Desc:    "link users who have a password to the OpenID Connect user with the same name, users without password are always linked",
+		},
+		{
+			DestP: &l.OIDCGroupMappings,
+			Flag:  "oidc.group-mappings",
+			Desc:  "map groups to org:role, role is owner, member or a role of the org, e.g. admins=prod:owner, mapped orgs are synced at every sign in",
+		},
+		{
+			DestP: &l.LDAPURL,
+			Flag:  "ldap.url",
+			Desc:  "URL of the LDAP server, e.g. ldaps://ldap.example.com:636, LDAP authentication is disabled if not set",
+		},
+		{
+			DestP:   &l.LDAPStartTLS,
+			Flag:    "ldap.start-tls",
+			Default: false,
+			Desc:    "upgrade the ldap:// connection with StartTLS",
+		},
+		{
+			DestP: &l.LDAPTLSCA,
+			Flag:  "ldap.tls.ca",
+			Desc:  "CA file to verify the certificate of the LDAP server, system CA is used if not set",
+		},
+		{
+			DestP:   &l.LDAPTLSInsecureSkipVerify,
+			Flag:    "ldap.tls.insecure-skip-verify",
+			Default: false,
+			Desc:    "skip verifying the certificate of the LDAP server, for testing only",
+		},
+		{
+			DestP: &l.LDAPBindDN,
+			Flag:  "ldap.bind-dn",
+			Desc:  "DN of the service account to search users and groups, anonymous if not set",
+		},
+		{
+			DestP: &l.LDAPBindPassword,
+			Flag:  "ldap.bind-password",
+			Desc:  "password of the service account, prefer the environment variable MANTA_LDAP_BIND_PASSWORD",
+		},
+		{
+			DestP: &l.LDAPBaseDN,
+			Flag:  "ldap.base-dn",
+			Desc:  "base DN to search users from, e.g. ou=people,dc=example,dc=com",
+		},
+		{
+			DestP:   &l.LDAPUserFilter,
+			Flag:    "ldap.user-filter",
+			Default: "(uid=%s)",
+			Desc:    "filter to find the user, every %s is replaced by the username",
+		},
+		{
+			DestP:   &l.LDAPUsernameAttribute,
+			Flag:    "ldap.username-attribute",
+			Default: "uid",
+		},
+		{
+			DestP:   &l.LDAPEmailAttribute,
+			Flag:    "ldap.email-attribute",
+			Default: "mail",
+		},
+		{
+			DestP: &l.LDAPGroupBaseDN,
+			Flag:  "ldap.group-base-dn",
+			Desc:  "base DN to search groups from, groups are not synced if not set",
+		},
+		{
+			DestP:   &l.LDAPGroupFilter,
+			Flag:    "ldap.group-filter",
+			Default: "(member=%s)",
+			Desc:    "filter to find the groups of user, every %s is replaced by the DN of the user",
+		},
+		{
+			DestP:   &l.LDAPGroupAttribute,
+			Flag:    "ldap.group-attribute",
+			Default: "cn",
+			Desc:    "attribute of the group name",
+		},
+		{
+			DestP:   &l.LDAPAutoProvision,
+			Flag:    "ldap.auto-provision",
+			Default: true,
+			Desc:    "create users signed in the first time, otherwise users must be created in advance with email or username",
+		},
+		{
+			DestP:   &l.LDAPLinkLocalUsers,
+			Flag:    "ldap.link-local-users",
+			Default: false,
+			Desc:    "link users who have a password to the LDAP user with the same name, users without password are always linked",
+		},
+		{
+			DestP: &l.LDAPGroupMappings,
+			Flag:  "ldap.group-mappings",
+			Desc:  "map groups to org:role, role is owner, member or a role of the org, e.g. admins=prod:owner",
+		},
+		{
+			DestP:   &l.LDAPSyncInterval,
+			Flag:    "ldap.sync-interval",
+			Default: time.Hour,
+			Desc:    "interval to sync the groups of users signed in by LDAP",
+		},
+		{
+			DestP:   &l.SessionIdleTimeout,
+			Flag:    "session.idle-timeout",
+			Default: 12 * time.Hour,
+			Desc:    "sessions not used for this long expire",
+		},
+		{
+			DestP:   &l.SessionAbsoluteTimeout,
+			Flag:    "session.absolute-timeout",
+			Default: 7 * 24 * time.Hour,
+			Desc:    "sessions expire after this long since sign in, no matter whether they are used",
+		},
+		{
+			DestP:   &l.SigninMaxUsernameFailures,
+			Flag:    "signin.max-username-failures",
+			Default: 5,
+			Desc:    "failed sign-ins of a username before it is locked out",
+		},
+		{
+			DestP:   &l.SigninMaxIPFailures,
+			Flag:    "signin.max-ip-failures",
+			Default: 20,
+			Desc:    "failed sign-ins from an IP before it is locked out",
+		},
+		{
+			DestP:   &l.SigninLockout,
+			Flag:    "signin.lockout",
+			Default: time.Minute,
+			Desc:    "lockout after too many failed sign-ins, it doubles on every further failure",
+		},
+		{
+			DestP:   &l.SigninMaxLockout,
+			Flag:    "signin.max-lockout",
+			Default: time.Hour,
+			Desc:    "the longest lockout, failures older than it are forgotten",
+		},
+		{
+			DestP:   &l.AuditRetention,
+			Flag:    "audit.retention",
+			Default: 90 * 24 * time.Hour,
+			Desc:    "how long audit records of sign-ins and token uses are kept, 0 means forever",
+		},
+		{
+			DestP:   &l.SweepInterval,
+			Flag:    "sweep-interval",
+			Default: time.Hour,
+			Desc:    "interval to delete expired sessions, lockouts and audit records",
+		},
+		{
+			DestP: &l.OplogRetention,
+			Flag:  "oplog.retention",
+			Desc:  "how long operation logs are kept, 0 means forever",
+		},
+		{
+			DestP: &l.OplogRetentionEntries,
+			Flag:  "oplog.retention-entries",
+			Desc:  "how many operation logs are kept for each resource, 0 means no limit",
+		},
+		{
+			DestP:   &l.OplogCompactInterval,
+			Flag:    "oplog.compact-interval",
+			Default: time.Hour,
+			Desc:    "interval to delete expired operation logs",
+		},
 		{
 			DestP:   &l.ProfileDir,
 			Flag:    "profile.dir",
@@ -131,6 +490,186 @@ func (l *Launcher) logger() (*zap.Logger, error) {
 	return lcf.New(os.Stdout)
 }
 
+// keyring loads the master keys to encrypt secrets, the primary key must be
+// configured, it is never generated next to the data it protects.
+func (l *Launcher) keyring(logger *zap.Logger) (*keyring.Keyring, error) {
+	var (
+		primary []byte
+		err     error
+	)
+
+	switch {
+	case l.SecretKey != "":
+		primary, err = keyring.ParseKey([]byte(l.SecretKey))
+	case l.SecretKeyFile != "":
+		primary, err = keyring.LoadKeyFile(l.SecretKeyFile)
+	default:
+		// keys generated in the store path by previous versions are still
+		// loaded, but they should be moved away from the data they protect
+		path := filepath.Join(l.StorePath, "secret.key")
+		primary, err = keyring.LoadKeyFile(path)
+		if os.IsNotExist(err) {
+			return nil, errors.New("master key to encrypt secrets is required, set it with --secret.key or --secret.key-file, a new key can be generated by `mantad secret generate-key`")
+		}
+		if err == nil {
+			logger.Warn("Master key is loaded from the store path, move it out of the store and set --secret.key-file",
+				zap.String("path", path))
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	previous := make([][]byte, 0, len(l.SecretPreviousKeys))
+	for _, path := range l.SecretPreviousKeys {
+		key, err := keyring.LoadKeyFile(path)
+		if err != nil {
+			return nil, err
+		}
+
+		previous = append(previous, key)
+	}
+
+	return keyring.New(primary, previous...)
+}
+
+// secretService chains the secret providers, kv is used to store secrets
+// anyway.
+func (l *Launcher) secretService(service manta.SecretService) (manta.SecretService, error) {
+	providers := make([]manta.SecretLoader, 0, len(l.SecretProviders))
+	for _, name := range l.SecretProviders {
+		switch name {
+		case "kv":
+			providers = append(providers, service)
+		case "file":
+			if l.SecretFileDir == "" {
+				return nil, errors.New("secret.file.dir is required by the file secret provider")
+			}
+
+			providers = append(providers, secret.NewFileProvider(l.SecretFileDir))
+		case "vault":
+			provider, err := secret.NewVaultProvider(secret.VaultConfig{
+				Address: l.SecretVaultAddr,
+				Token:   l.SecretVaultToken,
+				Mount:   l.SecretVaultMount,
+				Prefix:  l.SecretVaultPrefix,
+			})
+			if err != nil {
+				return nil, err
+			}
+
+			providers = append(providers, provider)
+		default:
+			return nil, errors.Errorf("unknown secret provider %q", name)
+		}
+	}
+
+	return secret.NewService(service, providers...), nil
+}
+
+// oidc returns the OpenID Connect provider and provisioner, nil if the
+// issuer is not configured.
+func (l *Launcher) oidc(logger *zap.Logger, service *kv.Service) (*oidc.Provider, *identity.Provisioner, error) {
+	if l.OIDCIssuer == "" {
+		return nil, nil, nil
+	}
+
+	mappings, err := identity.ParseGroupMappings(l.OIDCGroupMappings)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	provider, err := oidc.New(oidc.Config{
+		Issuer:       l.OIDCIssuer,
+		ClientID:     l.OIDCClientID,
+		ClientSecret: l.OIDCClientSecret,
+		RedirectURL:  l.OIDCRedirectURL,
+		Scopes:       l.OIDCScopes,
+		GroupsClaim:  l.OIDCGroupsClaim,
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	provisioner := identity.NewProvisioner(
+		logger.With(zap.String("service", "oidc")),
+		identity.Config{
+			AutoProvision:  l.OIDCAutoProvision,
+			LinkLocalUsers: l.OIDCLinkLocalUsers,
+			GroupMappings:  mappings,
+		},
+		service, service, service, service, service,
+	)
+
+	logger.Info("OpenID Connect login is enabled",
+		zap.String("issuer", l.OIDCIssuer),
+		zap.Bool("autoProvision", l.OIDCAutoProvision))
+
+	return provider, provisioner, nil
+}
+
+// ldap returns the LDAP provider and provisioner, nil if the url is not
+// configured.
+func (l *Launcher) ldap(logger *zap.Logger, service *kv.Service) (*ldap.Provider, *identity.Provisioner, error) {
+	if l.LDAPURL == "" {
+		return nil, nil, nil
+	}
+
+	mappings, err := identity.ParseGroupMappings(l.LDAPGroupMappings)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	tlsConfig := &tls.Config{
+		InsecureSkipVerify: l.LDAPTLSInsecureSkipVerify,
+	}
+	if l.LDAPTLSCA != "" {
+		data, err := os.ReadFile(l.LDAPTLSCA)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		tlsConfig.RootCAs = x509.NewCertPool()
+		if !tlsConfig.RootCAs.AppendCertsFromPEM(data) {
+			return nil, nil, errors.Errorf("no certificate found in %q", l.LDAPTLSCA)
+		}
+	}
+
+	provider, err := ldap.New(ldap.Config{
+		URL:               l.LDAPURL,
+		StartTLS:          l.LDAPStartTLS,
+		TLS:               tlsConfig,
+		BindDN:            l.LDAPBindDN,
+		BindPassword:      l.LDAPBindPassword,
+		BaseDN:            l.LDAPBaseDN,
+		UserFilter:        l.LDAPUserFilter,
+		UsernameAttribute: l.LDAPUsernameAttribute,
+		EmailAttribute:    l.LDAPEmailAttribute,
+		GroupBaseDN:       l.LDAPGroupBaseDN,
+		GroupFilter:       l.LDAPGroupFilter,
+		GroupAttribute:    l.LDAPGroupAttribute,
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	provisioner := identity.NewProvisioner(
+		logger.With(zap.String("service", "ldap")),
+		identity.Config{
+			AutoProvision:  l.LDAPAutoProvision,
+			LinkLocalUsers: l.LDAPLinkLocalUsers,
+			GroupMappings:  mappings,
+		},
+		service, service, service, service, service,
+	)
+
+	logger.Info("LDAP authentication is enabled",
+		zap.String("url", l.LDAPURL),
+		zap.Bool("autoProvision", l.LDAPAutoProvision))
+
+	return provider, provisioner, nil
+}
+
 func (l *Launcher) run() error {
 	// setup logger
 	logger, err := l.logger()
@@ -169,6 +708,38 @@ func (l *Launcher) run() error {
 		return err
 	}
 
+	// starting services
+	ctx := signals.WithStandardSignals(context.Background())
+	group, ctx := errgroup.WithContext(ctx)
+
+	tlsConfig := tlsutil.Config{
+		CertFile:    l.TLSCert,
+		KeyFile:     l.TLSKey,
+		CAFile:      l.TLSCA,
+		ClientAuth:  l.TLSClientAuth,
+		AllowedSANs: l.TLSAllowedSANs,
+	}
+
+	var tlsManager *tlsutil.Manager
+	if tlsConfig.Enabled() {
+		tlsManager, err = tlsutil.New(tlsConfig, logger)
+		if err != nil {
+			return errors.Wrap(err, "load certificates failed")
+		}
+
+		group.Go(func() error {
+			tlsManager.Run(ctx)
+			return nil
+		})
+
+		logger.Info("TLS is enabled",
+			zap.Bool("clientAuth", tlsConfig.ClientAuth))
+
+		// HTTP and gRPC share the listener, so TLS must be terminated
+		// before cmux
+		listener = tls.NewListener(listener, tlsManager.ServerConfig())
+	}
+
 	muxer := cmux.New(listener)
 	muxer.HandleError(func(err error) bool {
 		logger.Error("cmux handle failed",
@@ -176,10 +747,6 @@ func (l *Launcher) run() error {
 		return true
 	})
 
-	// starting services
-	ctx := signals.WithStandardSignals(context.Background())
-	group, ctx := errgroup.WithContext(ctx)
-
 	var (
 		kvStore        kv.SchemaStore
 		flusher        httpservice.Flusher
@@ -202,11 +769,16 @@ func (l *Launcher) run() error {
 
 		defer bs.Close()
 	case "raftstore":
-		rs, err := raftstore.New(&raftstore.Config{
+		rcf := &raftstore.Config{
 			DataDir:      filepath.Join(l.StorePath, "raft"),
 			Listen:       l.Listen,
 			DefragOnBoot: false,
-		}, logger)
+		}
+		if tlsManager != nil {
+			rcf.PeerTLS = tlsManager.ClientConfig()
+		}
+
+		rs, err := raftstore.New(rcf, logger)
 		if err != nil {
 			return err
 		}
@@ -224,14 +796,41 @@ func (l *Launcher) run() error {
 		return errors.Errorf("unknown store type %q", l.Store)
 	}
 
+	secretKeys, err := l.keyring(logger)
+	if err != nil {
+		return errors.Wrap(err, "load secret keys failed")
+	}
+
 	kvStore = kv.NewMetricService(kvStore, promRegistry)
-	migrator := migration.New(logger, kvStore, migration.All...)
+	migrator := migration.New(logger, kvStore, migration.Specs(secretKeys)...)
 	err = migrator.Up(ctx)
 	if err != nil {
 		return errors.Wrap(err, "migrate failed")
 	}
 
-	service := kv.NewService(logger, kvStore)
+	service := kv.NewService(logger, kvStore,
+		kv.WithKeyring(secretKeys),
+		kv.WithSessionTimeout(l.SessionIdleTimeout, l.SessionAbsoluteTimeout),
+		kv.WithLockoutPolicy(manta.LockoutPolicy{
+			MaxUsernameFailures: l.SigninMaxUsernameFailures,
+			MaxIPFailures:       l.SigninMaxIPFailures,
+			Duration:            l.SigninLockout,
+			MaxDuration:         l.SigninMaxLockout,
+		}),
+		kv.WithAuditRetention(l.AuditRetention))
+
+	if l.rotateSecrets {
+		n, err := service.RotateSecretKeys(ctx)
+		if err != nil {
+			return errors.Wrap(err, "rotate secret keys failed")
+		}
+
+		logger.Info("Rotate secret keys success",
+			zap.Int("secrets", n),
+			zap.String("masterKey", secretKeys.PrimaryID()))
+
+		return nil
+	}
 
 	var (
 		orgService                  manta.OrganizationService         = service
@@ -243,6 +842,56 @@ func (l *Launcher) run() error {
 		notificationEndpointService manta.NotificationEndpointService = service
 	)
 
+	compactor := oplog.NewCompactor(
+		logger.With(zap.String("service", "oplog")),
+		service,
+		manta.OperationLogRetention{
+			MaxAge:     l.OplogRetention,
+			MaxEntries: l.OplogRetentionEntries,
+		},
+		l.OplogCompactInterval,
+		promRegistry,
+	)
+	group.Go(func() error {
+		return compactor.Run(ctx)
+	})
+
+	group.Go(func() error {
+		return service.Sweep(ctx, l.SweepInterval)
+	})
+
+	secretService, err = l.secretService(secretService)
+	if err != nil {
+		return errors.Wrap(err, "setup secret providers failed")
+	}
+
+	// secret fields of notification endpoints are resolved by the provider chain
+	notificationEndpointService = secret.NewNotificationEndpointService(notificationEndpointService, secretService)
+
+	oidcProvider, oidcProvisioner, err := l.oidc(logger, service)
+	if err != nil {
+		return errors.Wrap(err, "setup oidc failed")
+	}
+
+	ldapProvider, ldapProvisioner, err := l.ldap(logger, service)
+	if err != nil {
+		return errors.Wrap(err, "setup ldap failed")
+	}
+
+	if ldapProvider != nil && len(l.LDAPGroupMappings) != 0 && l.LDAPGroupBaseDN != "" {
+		syncer := ldap.NewSyncer(
+			logger.With(zap.String("service", "ldap")),
+			ldapProvider,
+			ldapProvisioner,
+			service,
+			l.LDAPSyncInterval,
+		)
+
+		group.Go(func() error {
+			return syncer.Run(ctx)
+		})
+	}
+
 	var tenantStorage multitsdb.TenantStorage
 	{
 		tsdbOpts := &tsdb.Options{
@@ -276,11 +925,15 @@ func (l *Launcher) run() error {
 		tenantStorage = mtsdb
 	}
 
-	scrapeTargetService, err := scrape.New(ctx, logger, orgService, service, tenantStorage)
+	scrapeTargetService, err := scrape.New(ctx, logger, orgService, service, tenantStorage, kvStore)
 	if err != nil {
 		return errors.Wrap(err, "create scrape service failed")
 	}
 
+	group.Go(func() error {
+		return scrapeTargetService.Run(ctx)
+	})
+
 	var targetRetrievers multitsdb.TenantTargetRetriever = scrapeTargetService
 
 	var checkService manta.CheckService
@@ -324,6 +977,11 @@ func (l *Launcher) run() error {
 		if err = backend.NotifyCoordinatorOfExisting(ctx, logger, service, coord); err != nil {
 			return err
 		}
+
+		taskWatcher := middleware.NewTaskWatcher(logger, kvStore, taskService, coord)
+		group.Go(func() error {
+			return taskWatcher.Run(ctx)
+		})
 	}
 
 	{
@@ -355,22 +1013,32 @@ func (l *Launcher) run() error {
 			BackupService:               kvStore,
 			CheckService:                authorizer.NewCheckService(checkService),
 			TaskService:                 taskService,
-			OrganizationService:         service,
-			UserService:                 service,
-			PasswordService:             service,
+			OrganizationService:         authorizer.NewOrganizationService(orgService),
+			UserService:                 authorizer.NewUserService(service),
+			PasswordService:             authorizer.NewPasswordService(service),
+			PasswordResetService:        authorizer.NewPasswordResetService(service),
 			AuthorizationService:        service,
 			DashboardService:            authorizer.NewDashboardService(dashboardService),
-			SessionService:              service,
+			SessionService:              authorizer.NewSessionService(service),
 			Flusher:                     flusher,
 			ConfigService:               authorizer.NewConfigService(configService),
 			ScrapeTargetService:         authorizer.NewScrapeTargetService(scrapeTargetService),
 			RegistryService:             service,
 			SecretService:               authorizer.NewSecretService(secretService),
 			NotificationEndpointService: authorizer.NewNotificationEndpointService(notificationEndpointService),
-			OperationLogService:         oplogService,
+			OperationLogService:         authorizer.NewOperationLogService(oplogService),
+			UserResourceMappingService:  authorizer.NewUserResourceMappingService(service),
+			RoleService:                 authorizer.NewRoleService(service),
+			AuditService:                authorizer.NewAuditService(service),
+			LockoutService:              authorizer.NewLockoutService(service),
+			OIDCProvider:                oidcProvider,
+			OIDCProvisioner:             oidcProvisioner,
+			LDAPProvider:                ldapProvider,
+			LDAPProvisioner:             ldapProvisioner,
 			TenantStorage:               tenantStorage,
 			TenantTargetRetriever:       targetRetrievers,
 			ClusterService:              clusterService,
+			Watcher:                     kvStore,
 		})
 
 		group.Go(func() error {
@@ -4,6 +4,7 @@ import (
 	"os"
 
 	"github.com/f1shl3gs/manta/cmd/mantad/launch"
+	"github.com/f1shl3gs/manta/cmd/mantad/raft"
 	"github.com/f1shl3gs/manta/cmd/mantad/version"
 )
 
@@ -11,6 +12,8 @@ func main() {
 	rootCmd := launch.Command()
 
 	rootCmd.AddCommand(version.Command())
+	rootCmd.AddCommand(raft.Command())
+	rootCmd.AddCommand(launch.SecretCommand())
 
 	if err := rootCmd.Execute(); err != nil {
 		os.Exit(1)
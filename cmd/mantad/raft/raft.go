@@ -0,0 +1,180 @@
+package raft
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"text/tabwriter"
+
+	"github.com/f1shl3gs/manta/raftstore"
+	"github.com/f1shl3gs/manta/raftstore/pb"
+
+	"github.com/spf13/cobra"
+	"go.etcd.io/raft/v3/raftpb"
+	"go.uber.org/zap"
+)
+
+func Command() *cobra.Command {
+	var dataDir string
+
+	cmd := &cobra.Command{
+		Use:   "raft",
+		Short: "Offline tools to inspect and recover raft data, mantad must be stopped",
+	}
+
+	cmd.PersistentFlags().StringVar(&dataDir, "data-dir", "manta/raft", "Raft data dir, which is <store.path>/raft")
+
+	cmd.AddCommand(
+		inspectCommand(&dataDir),
+		dumpCommand(&dataDir),
+		forceNewClusterCommand(&dataDir),
+	)
+
+	return cmd
+}
+
+func inspectCommand(dataDir *string) *cobra.Command {
+	return &cobra.Command{
+		Use:          "inspect",
+		Short:        "Show wal metadata, hard state, snapshot and membership",
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			info, err := raftstore.Inspect(*dataDir, zap.NewNop())
+			if err != nil {
+				return err
+			}
+
+			w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 4, 2, ' ', 0)
+			fmt.Fprintf(w, "Node ID:\t%s\n", formatID(info.NodeID))
+			fmt.Fprintf(w, "Checkpoint:\t%d\n", info.Checkpoint)
+			fmt.Fprintf(w, "Term:\t%d\n", info.HardState.Term)
+			fmt.Fprintf(w, "Vote:\t%s\n", formatID(info.HardState.Vote))
+			fmt.Fprintf(w, "Commit:\t%d\n", info.HardState.Commit)
+			fmt.Fprintf(w, "Snapshot index:\t%d\n", info.Snapshot.Index)
+			fmt.Fprintf(w, "Snapshot term:\t%d\n", info.Snapshot.Term)
+			fmt.Fprintf(w, "Snapshot conf state:\t%s\n", info.Snapshot.ConfState.String())
+			fmt.Fprintf(w, "First index:\t%d\n", info.FirstIndex)
+			fmt.Fprintf(w, "Last index:\t%d\n", info.LastIndex)
+			fmt.Fprintf(w, "Log files:\t%d\n", info.LogFiles)
+			fmt.Fprintf(w, "Members:\t\n")
+			for _, m := range info.Members {
+				fmt.Fprintf(w, "  %s\t%s\n", formatID(m.ID), m.Addr)
+			}
+
+			return w.Flush()
+		},
+	}
+}
+
+func dumpCommand(dataDir *string) *cobra.Command {
+	var (
+		from   uint64
+		values bool
+	)
+
+	cmd := &cobra.Command{
+		Use:          "dump",
+		Short:        "Dump wal entries as decoded requests",
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			w := cmd.OutOrStdout()
+
+			return raftstore.Dump(*dataDir, from, func(ent raftpb.Entry) error {
+				printEntry(w, ent, values)
+				return nil
+			}, zap.NewNop())
+		},
+	}
+
+	cmd.Flags().Uint64Var(&from, "from", 0, "Dump entries from this index")
+	cmd.Flags().BoolVar(&values, "values", false, "Print values of put operations")
+
+	return cmd
+}
+
+func forceNewClusterCommand(dataDir *string) *cobra.Command {
+	var listen string
+
+	cmd := &cobra.Command{
+		Use:   "force-new-cluster",
+		Short: "Force a single-node cluster from the local state",
+		Long: `Force a single-node cluster from the local state, it is the last resort
+when the quorum is lost permanently.
+
+Committed entries are applied to the local state, and the membership is
+replaced by the local member only. Uncommitted entries are discarded, and
+the previous wal files are moved to a backup dir next to the data dir.
+Other members must be wiped and re-added to the new cluster.`,
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			logger, err := zap.NewDevelopment()
+			if err != nil {
+				return err
+			}
+
+			member, err := raftstore.ForceNewCluster(*dataDir, listen, logger)
+			if err != nil {
+				return err
+			}
+
+			fmt.Fprintf(cmd.OutOrStdout(), "New cluster with member %s at %s\n", formatID(member.ID), member.Addr)
+
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&listen, "listen", "", "New address of the member, the previous one is kept if not set")
+
+	return cmd
+}
+
+func printEntry(w io.Writer, ent raftpb.Entry, values bool) {
+	fmt.Fprintf(w, "index=%d term=%d type=%s", ent.Index, ent.Term, ent.Type)
+
+	decoded, err := raftstore.DecodeEntry(ent)
+	if err != nil {
+		fmt.Fprintf(w, " error=%q\n", err)
+		return
+	}
+
+	switch v := decoded.(type) {
+	case nil:
+		fmt.Fprintln(w, " empty")
+	case *raftpb.ConfChange:
+		fmt.Fprintf(w, " conf-change=%s node=%s context=%q\n", v.Type, formatID(v.NodeID), v.Context)
+	case *raftpb.ConfChangeV2:
+		fmt.Fprintf(w, " conf-change-v2=%s\n", v.String())
+	case *pb.InternalRequest:
+		fmt.Fprintf(w, " id=%d", v.ID)
+
+		switch {
+		case v.CreateBucket != nil:
+			fmt.Fprintf(w, " create-bucket=%q\n", v.CreateBucket.Name)
+		case v.DeleteBucket != nil:
+			fmt.Fprintf(w, " delete-bucket=%q\n", v.DeleteBucket.Name)
+		case v.Compact != nil:
+			fmt.Fprintf(w, " compact=%d\n", v.Compact.Version)
+		case v.Snapshot != nil:
+			fmt.Fprintf(w, " snapshot=%d\n", v.Snapshot.Index)
+		case v.Txn != nil:
+			fmt.Fprintf(w, " txn compares=%d\n", len(v.Txn.Compares))
+			for _, op := range v.Txn.Successes {
+				fmt.Fprintf(w, "  %s bucket=%q key=%q", op.Type, op.Bucket, op.Key)
+				if op.Type == pb.Put {
+					if values {
+						fmt.Fprintf(w, " value=%q", op.Value)
+					} else {
+						fmt.Fprintf(w, " size=%d", len(op.Value))
+					}
+				}
+				fmt.Fprintln(w)
+			}
+		default:
+			fmt.Fprintln(w)
+		}
+	}
+}
+
+func formatID(id uint64) string {
+	return strconv.FormatUint(id, 16)
+}
@@ -2,18 +2,56 @@ package manta
 
 import (
 	"context"
+	"strconv"
+	"strings"
 	"time"
 )
 
+// ConfigFormat is the format of config data, it is used to check the
+// syntax of data before it is saved.
+type ConfigFormat string
+
+const (
+	// ConfigFormatPlain is opaque text, it is not validated
+	ConfigFormatPlain ConfigFormat = "plain"
+	ConfigFormatYAML  ConfigFormat = "yaml"
+	ConfigFormatTOML  ConfigFormat = "toml"
+	ConfigFormatJSON  ConfigFormat = "json"
+)
+
+// Valid returns true if the format is known, empty format is treated as plain
+func (f ConfigFormat) Valid() bool {
+	switch f {
+	case "", ConfigFormatPlain, ConfigFormatYAML, ConfigFormatTOML, ConfigFormatJSON:
+		return true
+	default:
+		return false
+	}
+}
+
 type Config struct {
 	ID      ID        `json:"id"`
 	Created time.Time `json:"created"`
 	Updated time.Time `json:"updated"`
+	// Revision increases every time the config is updated, so watchers can
+	// tell whether they have seen it.
+	Revision uint64 `json:"revision"`
 
 	OrgID ID     `json:"orgID"`
 	Name  string `json:"name"`
 	Desc  string `json:"desc"`
 	Data  string `json:"data"`
+
+	Format ConfigFormat `json:"format,omitempty"`
+	// Schema is an optional JSON schema, data of structured formats
+	// must be valid against it.
+	Schema string `json:"schema,omitempty"`
+	// Template renders data as Go template when it is fetched, see package
+	// config/render for the variables and functions. The syntax of format
+	// and the schema are not checked for templates.
+	Template bool `json:"template,omitempty"`
+	// Rollout delivers updates to canary instances first if it is set
+	Rollout *RolloutPolicy `json:"rollout,omitempty"`
 }
 
 func (c *Config) GetID() ID {
@@ -25,9 +63,15 @@ func (c *Config) GetOrgID() ID {
 }
 
 type ConfigUpdate struct {
-	Name *string `json:"name,omitempty"`
-	Desc *string `json:"desc,omitempty"`
-	Data *string `json:"data,omitempty"`
+	Name     *string       `json:"name,omitempty"`
+	Desc     *string       `json:"desc,omitempty"`
+	Data     *string       `json:"data,omitempty"`
+	Format   *ConfigFormat `json:"format,omitempty"`
+	Schema   *string       `json:"schema,omitempty"`
+	Template *bool         `json:"template,omitempty"`
+	// Rollout replaces the rollout policy, an empty policy turns off
+	// the rollout mode.
+	Rollout *RolloutPolicy `json:"rollout,omitempty"`
 }
 
 func (upd *ConfigUpdate) Apply(cf *Config) {
@@ -42,12 +86,97 @@ func (upd *ConfigUpdate) Apply(cf *Config) {
 	if upd.Data != nil {
 		cf.Data = *upd.Data
 	}
+
+	if upd.Format != nil {
+		cf.Format = *upd.Format
+	}
+
+	if upd.Schema != nil {
+		cf.Schema = *upd.Schema
+	}
+
+	if upd.Template != nil {
+		cf.Template = *upd.Template
+	}
+
+	if upd.Rollout != nil {
+		if upd.Rollout.Empty() {
+			cf.Rollout = nil
+		} else {
+			cf.Rollout = upd.Rollout
+		}
+	}
+}
+
+// ConfigError describes a problem found in config data
+type ConfigError struct {
+	// Line and Column are 1-based, they are 0 if the position is unknown
+	Line   int `json:"line,omitempty"`
+	Column int `json:"column,omitempty"`
+	// Path is the JSON pointer of the value violates the schema
+	Path    string `json:"path,omitempty"`
+	Message string `json:"message"`
+}
+
+func (e ConfigError) Error() string {
+	var sb strings.Builder
+
+	if e.Line > 0 {
+		sb.WriteString("line ")
+		sb.WriteString(strconv.Itoa(e.Line))
+		if e.Column > 0 {
+			sb.WriteString(", column ")
+			sb.WriteString(strconv.Itoa(e.Column))
+		}
+		sb.WriteString(": ")
+	}
+
+	if e.Path != "" {
+		sb.WriteString(e.Path)
+		sb.WriteString(": ")
+	}
+
+	sb.WriteString(e.Message)
+
+	return sb.String()
+}
+
+// ConfigValidation is the result of validating config data
+type ConfigValidation struct {
+	Valid  bool          `json:"valid"`
+	Errors []ConfigError `json:"errors,omitempty"`
 }
 
 type ConfigFilter struct {
 	OrgID ID
 }
 
+// ConfigRevision is a snapshot of the config, one is kept every time the
+// config is created, updated or rolled back.
+type ConfigRevision struct {
+	ConfigID ID        `json:"configID"`
+	Revision uint64    `json:"revision"`
+	Created  time.Time `json:"created"`
+	// UserID is the author of this revision
+	UserID ID `json:"userID,omitempty"`
+	// RollbackFrom is the revision this one rolled back to
+	RollbackFrom uint64 `json:"rollbackFrom,omitempty"`
+
+	Name     string       `json:"name"`
+	Desc     string       `json:"desc"`
+	Data     string       `json:"data"`
+	Format   ConfigFormat `json:"format,omitempty"`
+	Template bool         `json:"template,omitempty"`
+}
+
+// ConfigDiff is the unified diff of the data of two revisions
+type ConfigDiff struct {
+	ConfigID ID     `json:"configID"`
+	From     uint64 `json:"from"`
+	To       uint64 `json:"to"`
+	Diff     string `json:"diff"`
+}
+
 type ConfigService interface {
 	CreateConfig(ctx context.Context, conf *Config) error
 
@@ -58,4 +187,16 @@ type ConfigService interface {
 	UpdateConfig(ctx context.Context, id ID, upd ConfigUpdate) (*Config, error)
 
 	DeleteConfig(ctx context.Context, id ID) error
+
+	// FindConfigRevisions returns the revisions of the config, newest first
+	FindConfigRevisions(ctx context.Context, id ID) ([]*ConfigRevision, error)
+
+	// FindConfigRevision returns a specific revision of the config
+	FindConfigRevision(ctx context.Context, id ID, revision uint64) (*ConfigRevision, error)
+
+	// RollbackConfig restores the config to the revision, a new revision
+	// is created, so the rollback can be reverted too.
+	RollbackConfig(ctx context.Context, id ID, revision uint64) (*Config, error)
+
+	ConfigRolloutService
 }
@@ -0,0 +1,47 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/f1shl3gs/manta"
+
+	"github.com/pmezard/go-difflib/difflib"
+)
+
+// Diff returns the unified diff of the data of two revisions
+func Diff(from, to *manta.ConfigRevision) (*manta.ConfigDiff, error) {
+	text, err := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        splitLines(from.Data),
+		B:        splitLines(to.Data),
+		FromFile: fmt.Sprintf("revision %d", from.Revision),
+		ToFile:   fmt.Sprintf("revision %d", to.Revision),
+		Context:  3,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &manta.ConfigDiff{
+		ConfigID: to.ConfigID,
+		From:     from.Revision,
+		To:       to.Revision,
+		Diff:     text,
+	}, nil
+}
+
+// splitLines splits text into lines with the trailing newline kept,
+// a newline is added to the last line if missing.
+func splitLines(text string) []string {
+	if text == "" {
+		return nil
+	}
+
+	lines := strings.SplitAfter(text, "\n")
+	if lines[len(lines)-1] == "" {
+		return lines[:len(lines)-1]
+	}
+
+	lines[len(lines)-1] += "\n"
+	return lines
+}
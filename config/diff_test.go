@@ -0,0 +1,28 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/f1shl3gs/manta"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiff(t *testing.T) {
+	diff, err := Diff(
+		&manta.ConfigRevision{ConfigID: 1, Revision: 1, Data: "a: 1\nb: 2\n"},
+		&manta.ConfigRevision{ConfigID: 1, Revision: 3, Data: "a: 1\nb: 3\n"},
+	)
+	require.NoError(t, err)
+
+	assert.Equal(t, uint64(1), diff.From)
+	assert.Equal(t, uint64(3), diff.To)
+	assert.Equal(t, `--- revision 1
++++ revision 3
+@@ -1,2 +1,2 @@
+ a: 1
+-b: 2
++b: 3
+`, diff.Diff)
+}
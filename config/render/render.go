@@ -0,0 +1,172 @@
+// Package render renders config data as Go templates, so one config can
+// serve many instances which differ only in hostname, tags and credentials.
+//
+// The template data is Data, e.g. {{ .Hostname }}, {{ .Tags.region }} and
+// {{ .Vars.port }}, besides the builtin functions of text/template, the
+// following functions are available:
+//
+//	secret "key"          the value of the org secret
+//	default "value" x     value if x is empty, otherwise x
+package render
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"regexp"
+	"strconv"
+	"text/template"
+
+	"github.com/f1shl3gs/manta"
+)
+
+var (
+	// text/template reports positions in messages only, e.g.
+	// `template: config:3: unexpected "}" in operand`
+	errorPattern = regexp.MustCompile(`^template: [^:]*:(\d+):(?:(\d+):)? (.*)$`)
+)
+
+// Data is the data the template executes with
+type Data struct {
+	// Hostname is the hostname of the instance
+	Hostname string
+	// Tags are the tags of the instance
+	Tags map[string]string
+	// Instance is the registered instance fetching the config, it is nil
+	// if the instance is not specified.
+	Instance *manta.Instance
+	// Vars are the variables provided by the instance
+	Vars map[string]string
+}
+
+// NewData returns the template data of the instance and the variables,
+// instance could be nil, the hostname variable is used as hostname then.
+func NewData(ins *manta.Instance, vars map[string]string) Data {
+	data := Data{
+		Instance: ins,
+		Vars:     vars,
+		Hostname: vars["hostname"],
+	}
+
+	if ins != nil {
+		data.Hostname = ins.Hostname
+		data.Tags = ins.Tags
+	}
+
+	if data.Tags == nil {
+		data.Tags = map[string]string{}
+	}
+
+	if data.Vars == nil {
+		data.Vars = map[string]string{}
+	}
+
+	return data
+}
+
+func funcs(ctx context.Context, loader manta.SecretLoader, orgID manta.ID) template.FuncMap {
+	return template.FuncMap{
+		"secret": func(key string) (string, error) {
+			if loader == nil {
+				return "", &manta.Error{
+					Code: manta.EUnavailable,
+					Msg:  "secrets are not available",
+				}
+			}
+
+			secret, err := loader.LoadSecret(ctx, orgID, key)
+			if err != nil {
+				return "", err
+			}
+
+			return secret.Value, nil
+		},
+		"default": func(value string, x any) any {
+			if x == nil {
+				return value
+			}
+
+			if s, ok := x.(string); ok && s == "" {
+				return value
+			}
+
+			return x
+		},
+	}
+}
+
+func parse(data string, fm template.FuncMap) (*template.Template, error) {
+	return template.New("config").
+		Option("missingkey=error").
+		Funcs(fm).
+		Parse(data)
+}
+
+// Parse checks the syntax of the template, the problems are returned with
+// line and column if they are known.
+func Parse(data string) []manta.ConfigError {
+	_, err := parse(data, funcs(context.Background(), nil, 0))
+	if err == nil {
+		return nil
+	}
+
+	return []manta.ConfigError{configError(err)}
+}
+
+// Render executes the data of config as template, a copy of the config
+// with rendered data is returned. Secrets are loaded from the org of the
+// config with loader.
+func Render(
+	ctx context.Context,
+	cf *manta.Config,
+	data Data,
+	loader manta.SecretLoader,
+) (*manta.Config, error) {
+	tmpl, err := parse(cf.Data, funcs(ctx, loader, cf.OrgID))
+	if err != nil {
+		return nil, &manta.Error{
+			Code: manta.EInvalid,
+			Msg:  "parse config template failed, " + configError(err).Error(),
+		}
+	}
+
+	buf := bytes.NewBuffer(nil)
+	if err = tmpl.Execute(buf, data); err != nil {
+		return nil, renderError(err)
+	}
+
+	rendered := *cf
+	rendered.Data = buf.String()
+
+	return &rendered, nil
+}
+
+// renderError keeps the code of errors returned by functions, e.g. secret
+// not found, other errors are caused by the template or the variables.
+func renderError(err error) error {
+	var me *manta.Error
+	if errors.As(err, &me) {
+		return &manta.Error{
+			Code: me.Code,
+			Msg:  "render config failed, " + me.Error(),
+		}
+	}
+
+	return &manta.Error{
+		Code: manta.EInvalid,
+		Msg:  "render config failed, " + configError(err).Error(),
+	}
+}
+
+func configError(err error) manta.ConfigError {
+	msg := err.Error()
+	ce := manta.ConfigError{Message: msg}
+
+	if m := errorPattern.FindStringSubmatch(msg); m != nil {
+		ce.Line, _ = strconv.Atoi(m[1])
+		ce.Column, _ = strconv.Atoi(m[2])
+		ce.Message = m[3]
+	}
+
+	return ce
+}
@@ -0,0 +1,119 @@
+package render
+
+import (
+	"context"
+	"testing"
+
+	"github.com/f1shl3gs/manta"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type secretLoader map[string]string
+
+func (l secretLoader) LoadSecret(_ context.Context, orgID manta.ID, k string) (*manta.Secret, error) {
+	value, ok := l[k]
+	if !ok {
+		return nil, &manta.Error{
+			Code: manta.ENotFound,
+			Msg:  "secret not found",
+		}
+	}
+
+	return &manta.Secret{
+		Key:   k,
+		OrgID: orgID,
+		Value: value,
+	}, nil
+}
+
+func TestRender(t *testing.T) {
+	ctx := context.Background()
+	loader := secretLoader{"db_password": "s3cr3t"}
+	ins := &manta.Instance{
+		UUID:     "0b8f5c2e",
+		Hostname: "web-01",
+		Tags:     map[string]string{"region": "us-east-1"},
+	}
+
+	for name, tc := range map[string]struct {
+		data   string
+		ins    *manta.Instance
+		vars   map[string]string
+		expect string
+		code   string
+	}{
+		"instance": {
+			data:   "host: {{ .Hostname }}\nregion: {{ .Tags.region }}",
+			ins:    ins,
+			expect: "host: web-01\nregion: us-east-1",
+		},
+		"hostname variable": {
+			data:   "host: {{ .Hostname }}",
+			vars:   map[string]string{"hostname": "web-02"},
+			expect: "host: web-02",
+		},
+		"instance overrides hostname variable": {
+			data:   "host: {{ .Hostname }}",
+			ins:    ins,
+			vars:   map[string]string{"hostname": "web-02"},
+			expect: "host: web-01",
+		},
+		"variables": {
+			data:   "listen: :{{ .Vars.port }}",
+			vars:   map[string]string{"port": "9100"},
+			expect: "listen: :9100",
+		},
+		"default": {
+			data:   `listen: :{{ index .Vars "port" | default "8080" }}`,
+			expect: "listen: :8080",
+		},
+		"secret": {
+			data:   `password: {{ secret "db_password" }}`,
+			expect: "password: s3cr3t",
+		},
+		"secret not found": {
+			data: `password: {{ secret "unknown" }}`,
+			code: manta.ENotFound,
+		},
+		"missing variable": {
+			data: "listen: :{{ .Vars.port }}",
+			code: manta.EInvalid,
+		},
+		"missing tag": {
+			data: "region: {{ .Tags.region }}",
+			code: manta.EInvalid,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			cf := &manta.Config{
+				ID:       1,
+				OrgID:    2,
+				Data:     tc.data,
+				Template: true,
+			}
+
+			rendered, err := Render(ctx, cf, NewData(tc.ins, tc.vars), loader)
+			if tc.code != "" {
+				require.Error(t, err)
+				assert.Equal(t, tc.code, manta.ErrorCode(err))
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tc.expect, rendered.Data)
+			// the original config is untouched
+			assert.Equal(t, tc.data, cf.Data)
+		})
+	}
+}
+
+func TestParse(t *testing.T) {
+	assert.Empty(t, Parse(`password: {{ secret "db_password" }}`))
+
+	errs := Parse("listen: :8080\nhost: {{ .Hostname }\n")
+	require.Len(t, errs, 1)
+	assert.Equal(t, 2, errs[0].Line)
+	assert.NotEmpty(t, errs[0].Message)
+}
@@ -0,0 +1,89 @@
+package config
+
+import (
+	"sort"
+	"time"
+
+	"github.com/f1shl3gs/manta"
+)
+
+// RolloutStatus shows which instances run which revision of the config, the
+// instances are the live instances of the registry and the instances which
+// reported the config. rollout is nil if the config was never rolled out.
+func RolloutStatus(
+	cf *manta.Config,
+	rollout *manta.ConfigRollout,
+	instances []*manta.Instance,
+	acks []*manta.ConfigAck,
+) *manta.ConfigRolloutStatus {
+	var (
+		now      = time.Now()
+		inFlight = rollout != nil && rollout.Status == manta.RolloutInProgress
+		applied  = make(map[string]*manta.ConfigAck, len(acks))
+		list     = make([]manta.ConfigInstanceStatus, 0, len(instances))
+	)
+
+	for _, ack := range acks {
+		applied[ack.Instance] = ack
+	}
+
+	for _, ins := range instances {
+		if ins.ExpiredAt(now) {
+			continue
+		}
+
+		is := manta.ConfigInstanceStatus{
+			Instance: ins.UUID,
+			Hostname: ins.Hostname,
+			Target:   rollout != nil && rollout.Targets(ins),
+			Desired:  cf.Revision,
+		}
+
+		if inFlight {
+			is.Desired = rollout.RevisionFor(ins)
+		}
+
+		if ack, ok := applied[ins.UUID]; ok {
+			is.Applied = ack.Revision
+			is.Success = ack.Success
+			is.Message = ack.Message
+			is.Acked = ack.Updated
+			delete(applied, ins.UUID)
+		}
+
+		list = append(list, is)
+	}
+
+	// instances expired or not registered, but reported
+	for _, ack := range applied {
+		is := manta.ConfigInstanceStatus{
+			Instance: ack.Instance,
+			Desired:  cf.Revision,
+			Applied:  ack.Revision,
+			Success:  ack.Success,
+			Message:  ack.Message,
+			Acked:    ack.Updated,
+		}
+
+		if inFlight {
+			is.Desired = rollout.From
+		}
+
+		list = append(list, is)
+	}
+
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Hostname != list[j].Hostname {
+			return list[i].Hostname < list[j].Hostname
+		}
+
+		return list[i].Instance < list[j].Instance
+	})
+
+	return &manta.ConfigRolloutStatus{
+		ConfigID:  cf.ID,
+		Revision:  cf.Revision,
+		Rollout:   rollout,
+		Instances: list,
+	}
+}
@@ -1,11 +1,11 @@
 package config
 
 import (
+	"bytes"
 	"context"
-	"sync"
 
 	"github.com/f1shl3gs/manta"
-	"github.com/f1shl3gs/manta/pkg/broadcast"
+	"github.com/f1shl3gs/manta/kv"
 
 	"go.uber.org/zap"
 )
@@ -13,90 +13,236 @@ import (
 type CoordinatingConfigService struct {
 	manta.ConfigService
 
-	logger *zap.Logger
-
-	mtx          sync.RWMutex
-	broadcasters map[manta.ID]*broadcast.Broadcaster[*manta.Config]
+	logger  *zap.Logger
+	watcher kv.Watcher
 }
 
 func NewCoordinatingVertexService(
 	configService manta.ConfigService,
+	watcher kv.Watcher,
 	logger *zap.Logger,
 ) *CoordinatingConfigService {
 	cs := &CoordinatingConfigService{
 		ConfigService: configService,
 		logger:        logger,
-		broadcasters:  make(map[manta.ID]*broadcast.Broadcaster[*manta.Config]),
+		watcher:       watcher,
 	}
 
 	return cs
 }
 
-func (s *CoordinatingConfigService) Sub(id manta.ID) *broadcast.Queue[*manta.Config] {
-	s.mtx.Lock()
-	defer s.mtx.Unlock()
+// Resolve returns the config the instance should run, it is the revision
+// decided by the rollout in progress, or the latest revision. ins is nil if
+// the instance is unknown, it runs the stable revision during rollouts.
+func (s *CoordinatingConfigService) Resolve(
+	ctx context.Context,
+	id manta.ID,
+	ins *manta.Instance,
+) (*manta.Config, error) {
+	cf, err := s.ConfigService.FindConfigByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	rollout, err := s.ConfigService.FindConfigRollout(ctx, id)
+	if err != nil {
+		if manta.ErrorCode(err) == manta.ENotFound {
+			return cf, nil
+		}
 
-	b, exist := s.broadcasters[id]
-	if !exist {
-		b = broadcast.New[*manta.Config]()
-		s.broadcasters[id] = b
+		return nil, err
 	}
 
-	return b.Sub()
-}
+	if rollout.Status != manta.RolloutInProgress {
+		return cf, nil
+	}
 
-func (s *CoordinatingConfigService) CreateConfign(ctx context.Context, cf *manta.Config) error {
-	return s.ConfigService.CreateConfig(ctx, cf)
-}
+	revision := rollout.RevisionFor(ins)
+	if revision == cf.Revision {
+		return cf, nil
+	}
+
+	rev, err := s.ConfigService.FindConfigRevision(ctx, id, revision)
+	if err != nil {
+		return nil, err
+	}
+
+	resolved := *cf
+	resolved.Revision = rev.Revision
+	resolved.Updated = rev.Created
+	resolved.Name = rev.Name
+	resolved.Desc = rev.Desc
+	resolved.Data = rev.Data
+	resolved.Format = rev.Format
+	resolved.Template = rev.Template
 
-func (s *CoordinatingConfigService) FindConfigByID(ctx context.Context, id manta.ID) (*manta.Config, error) {
-	return s.ConfigService.FindConfigByID(ctx, id)
+	return &resolved, nil
 }
 
-func (s *CoordinatingConfigService) FindConfigs(
+// Watch is WatchInstance with unknown instance
+func (s *CoordinatingConfigService) Watch(
 	ctx context.Context,
-	filter manta.ConfigFilter,
-) ([]*manta.Config, error) {
-	return s.ConfigService.FindConfigs(ctx, filter)
+	id manta.ID,
+	revision uint64,
+) (<-chan *manta.Config, error) {
+	return s.WatchInstance(ctx, id, nil, revision)
 }
 
-func (s *CoordinatingConfigService) UpdateConfig(
+// WatchInstance returns a channel which receives the config every time the
+// revision the instance should run changes, no matter which node the update
+// or the rollout is made on. Configs with revision not greater than the given
+// revision are skipped, so a watcher can resume from the last revision it has
+// seen, the current config is sent first if revision is 0. The channel is
+// closed when the config is deleted or ctx is done.
+func (s *CoordinatingConfigService) WatchInstance(
 	ctx context.Context,
 	id manta.ID,
-	upd manta.ConfigUpdate,
-) (*manta.Config, error) {
-	cf, err := s.ConfigService.UpdateConfig(ctx, id, upd)
+	ins *manta.Instance,
+	revision uint64,
+) (<-chan *manta.Config, error) {
+	if s.watcher == nil {
+		return nil, &manta.Error{
+			Code: manta.EUnavailable,
+			Msg:  "watching is not supported by the store",
+		}
+	}
+
+	pk, err := id.Encode()
 	if err != nil {
 		return nil, err
 	}
 
-	s.mtx.RLock()
-	defer s.mtx.RUnlock()
+	ctx, cancel := context.WithCancel(ctx)
 
-	b := s.broadcasters[id]
-	if b != nil {
-		b.Pub(cf)
+	// watching first then get, so we won't miss any updates
+	events, err := s.watch(ctx, pk)
+	if err != nil {
+		cancel()
+		return nil, err
 	}
 
-	return cf, nil
-}
-
-func (s *CoordinatingConfigService) DeleteConfig(ctx context.Context, id manta.ID) error {
-	err := s.ConfigService.DeleteConfig(ctx, id)
+	first, err := s.Resolve(ctx, id, ins)
 	if err != nil {
-		return err
+		cancel()
+		return nil, err
 	}
 
-	s.mtx.Lock()
-	defer s.mtx.Unlock()
+	ch := make(chan *manta.Config, 1)
+	if revision == 0 || first.Revision > revision {
+		ch <- first
+		revision = first.Revision
+	}
+
+	go func() {
+		defer cancel()
+		defer close(ch)
+
+		for {
+			var (
+				ev kv.Event
+				ok bool
+			)
+
+			select {
+			case <-ctx.Done():
+				return
+			case ev, ok = <-events:
+			}
+
+			switch {
+			case !ok:
+				if ctx.Err() != nil {
+					return
+				}
+
+				// the store closed the watcher, changes might be lost,
+				// so watch again and reload the config
+				events, err = s.watch(ctx, pk)
+				if err != nil {
+					s.logger.Warn("rewatch config failed",
+						zap.Stringer("id", id),
+						zap.Error(err))
+					return
+				}
+
+			case ev.Type == kv.EventDelete && bytes.Equal(ev.Bucket, kv.ConfigBucket):
+				return
+			}
+
+			cf, err := s.Resolve(ctx, id, ins)
+			if err != nil {
+				if ctx.Err() == nil && !kv.IsNotFound(err) {
+					s.logger.Warn("resolve watched config failed",
+						zap.Stringer("id", id),
+						zap.Error(err))
+				}
+
+				return
+			}
+
+			if cf.Revision <= revision {
+				// seen already
+				continue
+			}
+			revision = cf.Revision
+
+			select {
+			case <-ctx.Done():
+				return
+			case ch <- cf:
+			}
+		}
+	}()
+
+	return ch, nil
+}
 
-	b := s.broadcasters[id]
-	if b == nil {
-		return nil
+// watch watches the changes of the config and its rollout, the returned
+// channel is closed if any of the watchers is closed.
+func (s *CoordinatingConfigService) watch(ctx context.Context, pk []byte) (<-chan kv.Event, error) {
+	ctx, cancel := context.WithCancel(ctx)
+
+	configs, err := s.watcher.Watch(ctx, kv.ConfigBucket, pk)
+	if err != nil {
+		cancel()
+		return nil, err
 	}
 
-	delete(s.broadcasters, id)
-	b.Close()
+	rollouts, err := s.watcher.Watch(ctx, kv.ConfigRolloutsBucket, pk)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
 
-	return nil
+	ch := make(chan kv.Event)
+	go func() {
+		defer cancel()
+		defer close(ch)
+
+		for {
+			var (
+				ev kv.Event
+				ok bool
+			)
+
+			select {
+			case <-ctx.Done():
+				return
+			case ev, ok = <-configs:
+			case ev, ok = <-rollouts:
+			}
+
+			if !ok {
+				return
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case ch <- ev:
+			}
+		}
+	}()
+
+	return ch, nil
 }
@@ -0,0 +1,190 @@
+package config
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/f1shl3gs/manta"
+	"github.com/f1shl3gs/manta/bolt"
+	"github.com/f1shl3gs/manta/kv"
+	"github.com/f1shl3gs/manta/kv/migration"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+)
+
+func newTestService(t *testing.T) *CoordinatingConfigService {
+	ctx := context.Background()
+	logger := zaptest.NewLogger(t)
+
+	store := bolt.NewKVStore(logger, t.TempDir()+"/manta.bolt", bolt.WithNoSync)
+	require.NoError(t, store.Open(ctx))
+	t.Cleanup(func() { _ = store.Close() })
+
+	err := migration.New(logger, store, migration.All...).Up(ctx)
+	require.NoError(t, err)
+
+	return NewCoordinatingVertexService(kv.NewService(logger, store), store, logger)
+}
+
+func receive(t *testing.T, ch <-chan *manta.Config) *manta.Config {
+	t.Helper()
+
+	select {
+	case cf := <-ch:
+		return cf
+	case <-time.After(time.Second):
+		t.Fatal("wait for config timeout")
+		return nil
+	}
+}
+
+func TestWatch(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	svc := newTestService(t)
+	cf := &manta.Config{
+		OrgID: 1,
+		Name:  "agent",
+		Data:  "v1",
+	}
+	err := svc.CreateConfig(ctx, cf)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(1), cf.Revision)
+
+	update := func(data string) {
+		_, err := svc.UpdateConfig(ctx, cf.ID, manta.ConfigUpdate{Data: &data})
+		require.NoError(t, err)
+	}
+
+	t.Run("from the start", func(t *testing.T) {
+		ch, err := svc.Watch(ctx, cf.ID, 0)
+		require.NoError(t, err)
+
+		got := receive(t, ch)
+		assert.Equal(t, "v1", got.Data)
+
+		update("v2")
+		got = receive(t, ch)
+		assert.Equal(t, "v2", got.Data)
+		assert.Equal(t, uint64(2), got.Revision)
+	})
+
+	t.Run("resume", func(t *testing.T) {
+		// revision 2 is seen, so nothing is sent until next update
+		ch, err := svc.Watch(ctx, cf.ID, 2)
+		require.NoError(t, err)
+
+		update("v3")
+		got := receive(t, ch)
+		assert.Equal(t, "v3", got.Data)
+		assert.Equal(t, uint64(3), got.Revision)
+
+		// missed updates are sent right away
+		ch, err = svc.Watch(ctx, cf.ID, 1)
+		require.NoError(t, err)
+		got = receive(t, ch)
+		assert.Equal(t, uint64(3), got.Revision)
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		ch, err := svc.Watch(ctx, cf.ID, 3)
+		require.NoError(t, err)
+
+		err = svc.DeleteConfig(ctx, cf.ID)
+		require.NoError(t, err)
+
+		select {
+		case _, ok := <-ch:
+			assert.False(t, ok)
+		case <-time.After(time.Second):
+			t.Fatal("channel is not closed after config deleted")
+		}
+	})
+}
+
+func TestValidateOnWrite(t *testing.T) {
+	ctx := context.Background()
+	svc := newTestService(t)
+
+	cf := &manta.Config{
+		OrgID:  1,
+		Name:   "agent",
+		Format: manta.ConfigFormatYAML,
+		Data:   "foo: [1",
+	}
+	err := svc.CreateConfig(ctx, cf)
+	assert.Equal(t, manta.EInvalid, manta.ErrorCode(err))
+
+	cf.Data = "foo: 1"
+	err = svc.CreateConfig(ctx, cf)
+	require.NoError(t, err)
+
+	// the merged config is validated
+	schema := `{"properties": {"foo": {"type": "string"}}}`
+	_, err = svc.UpdateConfig(ctx, cf.ID, manta.ConfigUpdate{Schema: &schema})
+	assert.Equal(t, manta.EInvalid, manta.ErrorCode(err))
+
+	data := "foo: bar"
+	updated, err := svc.UpdateConfig(ctx, cf.ID, manta.ConfigUpdate{Schema: &schema, Data: &data})
+	require.NoError(t, err)
+	assert.Equal(t, uint64(2), updated.Revision)
+
+	// rollback to a revision violates the current schema is rejected
+	_, err = svc.RollbackConfig(ctx, cf.ID, 1)
+	assert.Equal(t, manta.EInvalid, manta.ErrorCode(err))
+}
+
+func TestWatchInstance(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	svc := newTestService(t)
+	cf := &manta.Config{
+		OrgID: 1,
+		Name:  "agent",
+		Data:  "v1",
+		Rollout: &manta.RolloutPolicy{
+			Selector: map[string]string{"group": "canary"},
+			Canary:   100,
+		},
+	}
+	err := svc.CreateConfig(ctx, cf)
+	require.NoError(t, err)
+
+	canary := &manta.Instance{UUID: "canary", Tags: map[string]string{"group": "canary"}}
+	stable := &manta.Instance{UUID: "stable", Tags: map[string]string{"group": "main"}}
+
+	canaryCh, err := svc.WatchInstance(ctx, cf.ID, canary, 0)
+	require.NoError(t, err)
+	assert.Equal(t, "v1", receive(t, canaryCh).Data)
+
+	stableCh, err := svc.WatchInstance(ctx, cf.ID, stable, 0)
+	require.NoError(t, err)
+	assert.Equal(t, "v1", receive(t, stableCh).Data)
+
+	data := "v2"
+	_, err = svc.UpdateConfig(ctx, cf.ID, manta.ConfigUpdate{Data: &data})
+	require.NoError(t, err)
+
+	got := receive(t, canaryCh)
+	assert.Equal(t, "v2", got.Data)
+	assert.Equal(t, uint64(2), got.Revision)
+
+	// unknown instances run the stable revision too
+	resolved, err := svc.Resolve(ctx, cf.ID, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "v1", resolved.Data)
+	assert.Equal(t, uint64(1), resolved.Revision)
+
+	// promoting completes the rollout, since all targets run the new revision
+	_, err = svc.PromoteConfigRollout(ctx, cf.ID, 0)
+	require.NoError(t, err)
+
+	got = receive(t, stableCh)
+	assert.Equal(t, "v2", got.Data)
+	assert.Equal(t, uint64(2), got.Revision)
+}
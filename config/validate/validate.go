@@ -0,0 +1,325 @@
+// Package validate checks the syntax of config data, and validates it against
+// the JSON schema of the config.
+package validate
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/f1shl3gs/manta"
+	"github.com/f1shl3gs/manta/config/render"
+	"github.com/f1shl3gs/manta/pkg/jsonschema"
+
+	"github.com/pelletier/go-toml/v2"
+	"gopkg.in/yaml.v3"
+)
+
+var (
+	// yaml.v3 reports positions in messages only, e.g. "yaml: line 2: did not find expected key"
+	yamlLinePattern = regexp.MustCompile(`^(?:yaml: )?line (\d+)(?:, column (\d+))?: (.*)$`)
+)
+
+// Config validates the data of config with its format and schema, an
+// error with code EInvalid is returned if the config is invalid.
+func Config(cf *manta.Config) error {
+	result, err := Check(cf)
+	if err != nil {
+		return err
+	}
+
+	if result.Valid {
+		return nil
+	}
+
+	msgs := make([]string, 0, len(result.Errors))
+	for _, e := range result.Errors {
+		msgs = append(msgs, e.Error())
+	}
+
+	return &manta.Error{
+		Code: manta.EInvalid,
+		Msg:  "invalid config data, " + strings.Join(msgs, "; "),
+	}
+}
+
+// Check validates the config, only the syntax of template is checked if the
+// config is a template, since the data is unknown until it is rendered.
+func Check(cf *manta.Config) (*manta.ConfigValidation, error) {
+	if !cf.Template {
+		return Data(cf.Format, cf.Data, cf.Schema)
+	}
+
+	if !cf.Format.Valid() {
+		return nil, &manta.Error{
+			Code: manta.EInvalid,
+			Msg:  "unknown config format " + strconv.Quote(string(cf.Format)),
+		}
+	}
+
+	if _, err := compileSchema(cf.Format, cf.Schema); err != nil {
+		return nil, err
+	}
+
+	errs := render.Parse(cf.Data)
+
+	return &manta.ConfigValidation{
+		Valid:  len(errs) == 0,
+		Errors: errs,
+	}, nil
+}
+
+// compileSchema returns nil if the schema is empty, schemas with keywords
+// not supported are rejected.
+func compileSchema(format manta.ConfigFormat, schema string) (*jsonschema.Schema, error) {
+	if schema == "" {
+		return nil, nil
+	}
+
+	if format == "" || format == manta.ConfigFormatPlain {
+		return nil, &manta.Error{
+			Code: manta.EInvalid,
+			Msg:  "schema requires yaml, toml or json format",
+		}
+	}
+
+	sch, err := jsonschema.Compile([]byte(schema))
+	if err != nil {
+		return nil, &manta.Error{
+			Code: manta.EInvalid,
+			Msg:  err.Error(),
+		}
+	}
+
+	return sch, nil
+}
+
+// Data checks the syntax of data, and validates it against the schema if
+// the schema is not empty. An error is returned if the format or the schema
+// is invalid, problems of data are returned in the result.
+func Data(format manta.ConfigFormat, data, schema string) (*manta.ConfigValidation, error) {
+	if !format.Valid() {
+		return nil, &manta.Error{
+			Code: manta.EInvalid,
+			Msg:  "unknown config format " + strconv.Quote(string(format)),
+		}
+	}
+
+	sch, err := compileSchema(format, schema)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		docs []any
+		errs []manta.ConfigError
+	)
+
+	switch format {
+	case manta.ConfigFormatYAML:
+		docs, errs = parseYAML(data)
+	case manta.ConfigFormatJSON:
+		docs, errs = parseJSON(data)
+	case manta.ConfigFormatTOML:
+		docs, errs = parseTOML(data)
+	default:
+		// plain text is opaque
+	}
+
+	if len(errs) == 0 && sch != nil {
+		roots := yamlNodes(format, data)
+
+		for i, doc := range docs {
+			for _, e := range sch.Validate(doc) {
+				ce := manta.ConfigError{
+					Path:    e.Path,
+					Message: e.Message,
+				}
+
+				if i < len(roots) {
+					if node := lookup(roots[i], e.Path); node != nil {
+						ce.Line, ce.Column = node.Line, node.Column
+					}
+				}
+
+				errs = append(errs, ce)
+			}
+		}
+	}
+
+	return &manta.ConfigValidation{
+		Valid:  len(errs) == 0,
+		Errors: errs,
+	}, nil
+}
+
+func parseYAML(data string) ([]any, []manta.ConfigError) {
+	var docs []any
+
+	dec := yaml.NewDecoder(strings.NewReader(data))
+	for {
+		var doc any
+		err := dec.Decode(&doc)
+		if err == io.EOF {
+			return docs, nil
+		}
+
+		if err != nil {
+			return nil, yamlErrors(err)
+		}
+
+		docs = append(docs, doc)
+	}
+}
+
+func yamlErrors(err error) []manta.ConfigError {
+	var msgs []string
+
+	var te *yaml.TypeError
+	if errors.As(err, &te) {
+		msgs = te.Errors
+	} else {
+		msgs = []string{err.Error()}
+	}
+
+	errs := make([]manta.ConfigError, 0, len(msgs))
+	for _, msg := range msgs {
+		ce := manta.ConfigError{Message: msg}
+
+		if m := yamlLinePattern.FindStringSubmatch(msg); m != nil {
+			ce.Line, _ = strconv.Atoi(m[1])
+			ce.Column, _ = strconv.Atoi(m[2])
+			ce.Message = m[3]
+		}
+
+		errs = append(errs, ce)
+	}
+
+	return errs
+}
+
+func parseJSON(data string) ([]any, []manta.ConfigError) {
+	var doc any
+
+	err := json.Unmarshal([]byte(data), &doc)
+	if err == nil {
+		return []any{doc}, nil
+	}
+
+	ce := manta.ConfigError{Message: err.Error()}
+
+	var se *json.SyntaxError
+	if errors.As(err, &se) {
+		ce.Line, ce.Column = position(data, se.Offset)
+	}
+
+	return nil, []manta.ConfigError{ce}
+}
+
+func parseTOML(data string) ([]any, []manta.ConfigError) {
+	var doc map[string]any
+
+	err := toml.Unmarshal([]byte(data), &doc)
+	if err == nil {
+		return []any{doc}, nil
+	}
+
+	ce := manta.ConfigError{Message: err.Error()}
+
+	var de *toml.DecodeError
+	if errors.As(err, &de) {
+		ce.Line, ce.Column = de.Position()
+	}
+
+	return nil, []manta.ConfigError{ce}
+}
+
+// position converts the offset of the first invalid byte to line and column
+func position(data string, offset int64) (int, int) {
+	if offset > int64(len(data)) {
+		offset = int64(len(data))
+	}
+
+	before := data[:offset]
+	line := strings.Count(before, "\n") + 1
+	column := len(before) - strings.LastIndexByte(before, '\n')
+	if offset > 0 && column > 1 {
+		// offset is after the invalid byte
+		column -= 1
+	}
+
+	return line, column
+}
+
+// yamlNodes parses data as YAML node trees, so the position of schema
+// violations can be found by the JSON pointer. JSON is YAML too, so both
+// are handled, positions of TOML values are not tracked.
+func yamlNodes(format manta.ConfigFormat, data string) []*yaml.Node {
+	if format != manta.ConfigFormatYAML && format != manta.ConfigFormatJSON {
+		return nil
+	}
+
+	var roots []*yaml.Node
+	dec := yaml.NewDecoder(strings.NewReader(data))
+	for {
+		node := &yaml.Node{}
+		if err := dec.Decode(node); err != nil {
+			return roots
+		}
+
+		roots = append(roots, node)
+	}
+}
+
+// lookup returns the node pointed by the JSON pointer, nil is returned
+// if not found.
+func lookup(node *yaml.Node, pointer string) *yaml.Node {
+	if node.Kind == yaml.DocumentNode && len(node.Content) > 0 {
+		node = node.Content[0]
+	}
+
+	if pointer == "" {
+		return node
+	}
+
+	for _, token := range strings.Split(pointer[1:], "/") {
+		token = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+
+		for node.Kind == yaml.AliasNode {
+			node = node.Alias
+		}
+
+		switch node.Kind {
+		case yaml.MappingNode:
+			var next *yaml.Node
+			for i := 0; i+1 < len(node.Content); i += 2 {
+				if node.Content[i].Value == token {
+					next = node.Content[i+1]
+					break
+				}
+			}
+
+			if next == nil {
+				return nil
+			}
+
+			node = next
+
+		case yaml.SequenceNode:
+			idx, err := strconv.Atoi(token)
+			if err != nil || idx < 0 || idx >= len(node.Content) {
+				return nil
+			}
+
+			node = node.Content[idx]
+
+		default:
+			return nil
+		}
+	}
+
+	return node
+}
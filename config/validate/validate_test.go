@@ -0,0 +1,127 @@
+package validate
+
+import (
+	"testing"
+
+	"github.com/f1shl3gs/manta"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testSchema = `{
+  "type": "object",
+  "required": ["listen"],
+  "properties": {
+    "listen": {"type": "string"},
+    "workers": {"type": "integer", "minimum": 1}
+  }
+}`
+
+func TestSyntax(t *testing.T) {
+	for name, tc := range map[string]struct {
+		format manta.ConfigFormat
+		data   string
+		line   int
+		column int
+	}{
+		"yaml": {
+			format: manta.ConfigFormatYAML,
+			data:   "listen: :8080\nworkers: 1\n  debug: true\n",
+			line:   3,
+		},
+		"json": {
+			format: manta.ConfigFormatJSON,
+			data:   "{\n  \"listen\": \":8080\",\n  \"workers\" 1\n}",
+			line:   3,
+			column: 13,
+		},
+		"toml": {
+			format: manta.ConfigFormatTOML,
+			data:   "listen = \":8080\"\nworkers = \n",
+			line:   2,
+			column: 11,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			result, err := Data(tc.format, tc.data, "")
+			require.NoError(t, err)
+			assert.False(t, result.Valid)
+			require.Len(t, result.Errors, 1)
+			assert.Equal(t, tc.line, result.Errors[0].Line, result.Errors[0].Message)
+			assert.Equal(t, tc.column, result.Errors[0].Column, result.Errors[0].Message)
+		})
+	}
+}
+
+func TestSchema(t *testing.T) {
+	result, err := Data(manta.ConfigFormatYAML, "listen: :8080\nworkers: 4\n", testSchema)
+	require.NoError(t, err)
+	assert.True(t, result.Valid)
+
+	result, err = Data(manta.ConfigFormatYAML, "name: foo\nworkers: 0\n", testSchema)
+	require.NoError(t, err)
+	assert.False(t, result.Valid)
+	assert.Equal(t, []manta.ConfigError{
+		{Line: 1, Column: 1, Message: `missing required property "listen"`},
+		{Line: 2, Column: 10, Path: "/workers", Message: "value must be >= 1"},
+	}, result.Errors)
+
+	result, err = Data(manta.ConfigFormatTOML, "listen = 8080\n", testSchema)
+	require.NoError(t, err)
+	assert.Equal(t, []manta.ConfigError{
+		{Path: "/listen", Message: "expected string, but got integer"},
+	}, result.Errors)
+
+	// schema is meaningless for plain text
+	_, err = Data(manta.ConfigFormatPlain, "foo", testSchema)
+	assert.Equal(t, manta.EInvalid, manta.ErrorCode(err))
+
+	_, err = Data(manta.ConfigFormatJSON, "{}", "{")
+	assert.Equal(t, manta.EInvalid, manta.ErrorCode(err))
+}
+
+func TestConfig(t *testing.T) {
+	err := Config(&manta.Config{Data: "anything {"})
+	assert.NoError(t, err)
+
+	err = Config(&manta.Config{Format: "ini"})
+	assert.Equal(t, manta.EInvalid, manta.ErrorCode(err))
+
+	err = Config(&manta.Config{Format: manta.ConfigFormatJSON, Data: "{"})
+	assert.Equal(t, manta.EInvalid, manta.ErrorCode(err))
+	assert.Contains(t, err.Error(), "line 1, column 1")
+
+	// the schema is rejected when the config is saved, templates too
+	for _, template := range []bool{false, true} {
+		err = Config(&manta.Config{
+			Format:   manta.ConfigFormatJSON,
+			Schema:   `{"type": "string", "format": "email"}`,
+			Data:     `"foo"`,
+			Template: template,
+		})
+		assert.Equal(t, manta.EInvalid, manta.ErrorCode(err))
+		assert.Contains(t, err.Error(), `keyword "format" is not supported`)
+	}
+}
+
+func TestTemplate(t *testing.T) {
+	cf := &manta.Config{
+		Format:   manta.ConfigFormatYAML,
+		Schema:   testSchema,
+		Data:     "listen: {{ .Vars.listen }}\n",
+		Template: true,
+	}
+
+	// the data is unknown until rendered, only the template is checked
+	assert.NoError(t, Config(cf))
+
+	cf.Data = "listen: {{ .Vars.listen }\n"
+	result, err := Check(cf)
+	require.NoError(t, err)
+	assert.False(t, result.Valid)
+	assert.Equal(t, 1, result.Errors[0].Line)
+
+	err = Config(cf)
+	assert.Equal(t, manta.EInvalid, manta.ErrorCode(err))
+}
@@ -0,0 +1,178 @@
+package manta
+
+import (
+	"context"
+	"hash/fnv"
+	"time"
+)
+
+// RolloutPolicy turns on the rollout mode of config, updates are delivered
+// to the canary instances first instead of all instances, and promoted to
+// the others manually or automatically.
+type RolloutPolicy struct {
+	// Selector selects the target instances by tags, all instances are
+	// targets if it is empty. Instances not selected keep running the
+	// stable revision until the rollout is completed.
+	Selector map[string]string `json:"selector,omitempty"`
+	// Canary is the percentage of targets receive the new revision first,
+	// an empty policy, whose canary is 0, turns off the rollout mode.
+	Canary int `json:"canary"`
+	// AutoPromote promotes the rollout once all instances running the new
+	// revision report success.
+	AutoPromote bool `json:"autoPromote,omitempty"`
+	// AutoRollback rolls the config back to the stable revision once an
+	// instance reports failure of the new revision.
+	AutoRollback bool `json:"autoRollback,omitempty"`
+}
+
+// Empty returns true if the policy turns off the rollout mode
+func (p *RolloutPolicy) Empty() bool {
+	return p.Canary == 0 && len(p.Selector) == 0 && !p.AutoPromote && !p.AutoRollback
+}
+
+func (p *RolloutPolicy) Validate() error {
+	if p.Canary < 1 || p.Canary > 100 {
+		return &Error{
+			Code: EInvalid,
+			Msg:  "canary must be in range 1 to 100",
+		}
+	}
+
+	return nil
+}
+
+type RolloutStatus string
+
+const (
+	RolloutInProgress RolloutStatus = "inProgress"
+	RolloutCompleted  RolloutStatus = "completed"
+	RolloutRolledBack RolloutStatus = "rolledBack"
+)
+
+// ConfigRollout is the rollout of the latest revision of config, there is
+// at most one rollout for each config, updates made during the rollout are
+// rolled out by it too.
+type ConfigRollout struct {
+	ConfigID ID            `json:"configID"`
+	OrgID    ID            `json:"orgID"`
+	Created  time.Time     `json:"created"`
+	Updated  time.Time     `json:"updated"`
+	Status   RolloutStatus `json:"status"`
+	// Message tells why the rollout is rolled back
+	Message string `json:"message,omitempty"`
+
+	// From is the stable revision
+	From uint64 `json:"from"`
+	// To is the revision rolling out
+	To uint64 `json:"to"`
+
+	Selector     map[string]string `json:"selector,omitempty"`
+	Canary       int               `json:"canary"`
+	AutoPromote  bool              `json:"autoPromote,omitempty"`
+	AutoRollback bool              `json:"autoRollback,omitempty"`
+}
+
+func (r *ConfigRollout) GetID() ID {
+	return r.ConfigID
+}
+
+func (r *ConfigRollout) GetOrgID() ID {
+	return r.OrgID
+}
+
+// Targets returns true if the instance is selected by the rollout
+func (r *ConfigRollout) Targets(ins *Instance) bool {
+	if ins == nil {
+		return false
+	}
+
+	for k, v := range r.Selector {
+		if ins.Tags[k] != v {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Canaries returns true if the instance receives the new revision, the
+// instances are picked by the hash of config id and instance uuid, so the
+// canaries of a config are stable as the percentage grows.
+func (r *ConfigRollout) Canaries(ins *Instance) bool {
+	if !r.Targets(ins) {
+		return false
+	}
+
+	if r.Canary >= 100 {
+		return true
+	}
+
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(r.ConfigID.String()))
+	_, _ = h.Write([]byte(ins.UUID))
+
+	return int(h.Sum32()%100) < r.Canary
+}
+
+// RevisionFor returns the revision the instance should run during the
+// rollout, instance is nil if it is unknown, and it runs the stable revision.
+func (r *ConfigRollout) RevisionFor(ins *Instance) uint64 {
+	if r.Canaries(ins) {
+		return r.To
+	}
+
+	return r.From
+}
+
+// ConfigAck is reported by instances once they applied the config
+type ConfigAck struct {
+	ConfigID ID        `json:"configID"`
+	Instance string    `json:"instance"`
+	Revision uint64    `json:"revision"`
+	Success  bool      `json:"success"`
+	Message  string    `json:"message,omitempty"`
+	Updated  time.Time `json:"updated"`
+}
+
+// ConfigInstanceStatus shows the revision an instance runs
+type ConfigInstanceStatus struct {
+	Instance string `json:"instance"`
+	Hostname string `json:"hostname,omitempty"`
+	// Target is true if the instance is selected by the rollout
+	Target bool `json:"target"`
+	// Desired is the revision the instance should run
+	Desired uint64 `json:"desired"`
+	// Applied is the last revision the instance reported
+	Applied uint64    `json:"applied,omitempty"`
+	Success bool      `json:"success"`
+	Message string    `json:"message,omitempty"`
+	Acked   time.Time `json:"acked,omitempty"`
+}
+
+// ConfigRolloutStatus shows the rollout and which instances run which revision
+type ConfigRolloutStatus struct {
+	ConfigID  ID                     `json:"configID"`
+	Revision  uint64                 `json:"revision"`
+	Rollout   *ConfigRollout         `json:"rollout,omitempty"`
+	Instances []ConfigInstanceStatus `json:"instances"`
+}
+
+type ConfigRolloutService interface {
+	// FindConfigRollout returns the rollout of the config
+	FindConfigRollout(ctx context.Context, id ID) (*ConfigRollout, error)
+
+	// PromoteConfigRollout delivers the new revision to canary percent of the
+	// targets, all targets if canary is 0. The rollout is completed if all
+	// targets have been promoted already.
+	PromoteConfigRollout(ctx context.Context, id ID, canary int) (*ConfigRollout, error)
+
+	// AbortConfigRollout rolls the config back to the stable revision
+	AbortConfigRollout(ctx context.Context, id ID) (*ConfigRollout, error)
+
+	// AckConfig records the revision an instance applied, the rollout might
+	// be promoted or rolled back by it.
+	AckConfig(ctx context.Context, ack *ConfigAck) error
+
+	// FindConfigAcks returns the acks of all instances
+	FindConfigAcks(ctx context.Context, id ID) ([]*ConfigAck, error)
+}
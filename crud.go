@@ -1,6 +1,9 @@
 package manta
 
-import "time"
+import (
+	"context"
+	"time"
+)
 
 type CRUDSetter interface {
 	SetCreated(ts time.Time)
@@ -11,3 +14,20 @@ type CRUDGetter interface {
 	GetCreated() time.Time
 	GetUpdated() time.Time
 }
+
+type authorKey struct{}
+
+// WithAuthor returns a context carries the user who makes the change,
+// the services which keep revisions of resources record it as the author.
+// It is set by the authorizer decorators, so the storage doesn't have to
+// know how the request is authorized.
+func WithAuthor(ctx context.Context, userID ID) context.Context {
+	return context.WithValue(ctx, authorKey{}, userID)
+}
+
+// AuthorFromContext returns the author of the change, it is invalid if
+// not set
+func AuthorFromContext(ctx context.Context) ID {
+	id, _ := ctx.Value(authorKey{}).(ID)
+	return id
+}
@@ -16,6 +16,10 @@ type Dashboard struct {
 	Desc    string    `json:"desc,omitempty"`
 	OrgID   ID        `json:"orgID"`
 	Cells   []Cell    `json:"cells,omitempty"`
+	// Variables are interpolated into the queries of cells
+	Variables []Variable `json:"variables,omitempty"`
+	// Version increases every time the dashboard is saved
+	Version uint64 `json:"version"`
 }
 
 type ViewProperties interface {
@@ -68,6 +72,9 @@ type Cell struct {
 	//	*SingleStat
 	//	*LinePlusSingleStat
 	//	*Markdown
+	//	*Table
+	//	*Heatmap
+	//	*Histogram
 	ViewProperties ViewProperties `json:"viewProperties,omitempty"`
 }
 
@@ -156,6 +163,97 @@ func (s *MarkdownViewProperties) GetType() string {
 	return s.Type
 }
 
+// TableColumn configures how a column of the query result is rendered, the
+// name is the label name, or "time" and "value"
+type TableColumn struct {
+	Name        string      `json:"name"`
+	DisplayName string      `json:"displayName,omitempty"`
+	Hidden      bool        `json:"hidden,omitempty"`
+	Width       int32       `json:"width,omitempty"`
+	Format      FieldFormat `json:"format,omitempty"`
+}
+
+// FieldFormat formats the values of a column
+type FieldFormat struct {
+	Prefix        string        `json:"prefix,omitempty"`
+	Suffix        string        `json:"suffix,omitempty"`
+	DecimalPlaces DecimalPlaces `json:"decimalPlaces,omitempty"`
+}
+
+// TableSort sorts the rows by the column
+type TableSort struct {
+	Column     string `json:"column"`
+	Descending bool   `json:"descending,omitempty"`
+}
+
+type TableOptions struct {
+	VerticalTimeAxis bool `json:"verticalTimeAxis,omitempty"`
+	FixFirstColumn   bool `json:"fixFirstColumn,omitempty"`
+	// Wrapping is one of "truncate", "wrap" and "single-line"
+	Wrapping string `json:"wrapping,omitempty"`
+}
+
+type TableViewProperties struct {
+	Type              string           `json:"type,omitempty"`
+	Note              string           `json:"note,omitempty"`
+	Queries           []Query          `json:"queries"`
+	ShowNoteWhenEmpty bool             `json:"showNoteWhenEmpty,omitempty"`
+	TableOptions      TableOptions     `json:"tableOptions"`
+	Columns           []TableColumn    `json:"columns,omitempty"`
+	SortBy            *TableSort       `json:"sortBy,omitempty"`
+	TimeFormat        string           `json:"timeFormat,omitempty"`
+	DecimalPlaces     DecimalPlaces    `json:"decimalPlaces"`
+	Colors            []DashboardColor `json:"colors,omitempty"`
+}
+
+func (t *TableViewProperties) GetType() string {
+	return t.Type
+}
+
+// HeatmapViewProperties renders the values in cells of BinSize pixels,
+// the buckets of Prometheus histograms are usually used as YColumn, e.g. "le"
+type HeatmapViewProperties struct {
+	Type              string           `json:"type,omitempty"`
+	Note              string           `json:"note,omitempty"`
+	Queries           []Query          `json:"queries"`
+	ShowNoteWhenEmpty bool             `json:"showNoteWhenEmpty,omitempty"`
+	Axes              Axes             `json:"axes"`
+	TimeFormat        string           `json:"timeFormat,omitempty"`
+	XColumn           string           `json:"xColumn,omitempty"`
+	YColumn           string           `json:"yColumn,omitempty"`
+	BinSize           int32            `json:"binSize,omitempty"`
+	Colors            []DashboardColor `json:"colors,omitempty"`
+}
+
+func (h *HeatmapViewProperties) GetType() string {
+	return h.Type
+}
+
+// HistogramBuckets decides how the values are bucketed, either Count
+// buckets of the same width in the domain, or the buckets split by Bounds
+type HistogramBuckets struct {
+	Count  int32     `json:"count,omitempty"`
+	Bounds []float64 `json:"bounds,omitempty"`
+}
+
+type HistogramViewProperties struct {
+	Type              string           `json:"type,omitempty"`
+	Note              string           `json:"note,omitempty"`
+	Queries           []Query          `json:"queries"`
+	ShowNoteWhenEmpty bool             `json:"showNoteWhenEmpty,omitempty"`
+	XColumn           string           `json:"xColumn,omitempty"`
+	FillColumns       []string         `json:"fillColumns,omitempty"`
+	XDomain           []float64        `json:"xDomain,omitempty"`
+	XAxisLabel        string           `json:"xAxisLabel,omitempty"`
+	Position          string           `json:"position,omitempty"`
+	Buckets           HistogramBuckets `json:"buckets"`
+	Colors            []DashboardColor `json:"colors,omitempty"`
+}
+
+func (h *HistogramViewProperties) GetType() string {
+	return h.Type
+}
+
 type DashboardFilter struct {
 	OrgID ID
 }
@@ -164,8 +262,9 @@ type DashboardUpdate struct {
 	ID    ID `json:"ID"`
 	OrgID ID `json:"orgID"`
 
-	Name *string `json:"name,omitempty"`
-	Desc *string `json:"desc,omitempty"`
+	Name      *string     `json:"name,omitempty"`
+	Desc      *string     `json:"desc,omitempty"`
+	Variables *[]Variable `json:"variables,omitempty"`
 }
 
 func (upd DashboardUpdate) Apply(dash *Dashboard) {
@@ -176,6 +275,10 @@ func (upd DashboardUpdate) Apply(dash *Dashboard) {
 	if upd.Desc != nil {
 		dash.Desc = *upd.Desc
 	}
+
+	if upd.Variables != nil {
+		dash.Variables = *upd.Variables
+	}
 }
 
 type DashboardCellUpdate struct {
@@ -232,9 +335,18 @@ func (upd *DashboardCellUpdate) UnmarshalJSON(bytes []byte) error {
 		return err
 	}
 
-	vp, err := unmarshalCellPropertiesJSON(a.ViewProperties)
-	if err != nil {
-		return err
+	var vp ViewProperties
+	if a.ViewProperties != nil {
+		vp, err = unmarshalCellPropertiesJSON(a.ViewProperties)
+		if err != nil {
+			return err
+		}
+
+		if validator, ok := vp.(Validator); ok {
+			if err = validator.Validate(); err != nil {
+				return errors.Wrap(err, "invalid ViewProperties")
+			}
+		}
 	}
 
 	upd.Name = a.Name
@@ -271,6 +383,22 @@ type DashboardService interface {
 	RemoveDashboard(ctx context.Context, id ID) error
 
 	ReplaceDashboardCells(ctx context.Context, dashboardID ID, cells []Cell) error
+
+	// FindDashboardVersions returns the versions of the dashboard, newest
+	// first, the dashboard might be deleted
+	FindDashboardVersions(ctx context.Context, id ID) ([]*DashboardVersion, error)
+
+	// FindDashboardVersion returns a specific version of the dashboard
+	FindDashboardVersion(ctx context.Context, id ID, version uint64) (*DashboardVersion, error)
+
+	// FindDeletedDashboards returns the last versions of the deleted
+	// dashboards, which can be restored
+	FindDeletedDashboards(ctx context.Context, filter DashboardFilter) ([]*DashboardVersion, error)
+
+	// RestoreDashboard restores the dashboard to the version, a new version
+	// is created, so the restore can be reverted too. Deleted dashboards
+	// are recreated with the same ID.
+	RestoreDashboard(ctx context.Context, id ID, version uint64) (*Dashboard, error)
 }
 
 func (m *Cell) Validate() error {
@@ -377,6 +505,34 @@ func unmarshalCellPropertiesJSON(b []byte) (ViewProperties, error) {
 		}
 
 		return &lpss, nil
+	case "markdown":
+		var markdown MarkdownViewProperties
+		if err := json.Unmarshal(b, &markdown); err != nil {
+			return nil, err
+		}
+
+		return &markdown, nil
+	case "table":
+		var table TableViewProperties
+		if err := json.Unmarshal(b, &table); err != nil {
+			return nil, err
+		}
+
+		return &table, nil
+	case "heatmap":
+		var heatmap HeatmapViewProperties
+		if err := json.Unmarshal(b, &heatmap); err != nil {
+			return nil, err
+		}
+
+		return &heatmap, nil
+	case "histogram":
+		var histogram HistogramViewProperties
+		if err := json.Unmarshal(b, &histogram); err != nil {
+			return nil, err
+		}
+
+		return &histogram, nil
 
 	default:
 		return nil, errors.New("unknown viewProperties type")
@@ -414,3 +570,111 @@ func (l *LinePlusSingleStatViewProperties) Validate() error {
 
 	return nil
 }
+
+// maxMarkdownLength limits the content of markdown cells, since the whole
+// dashboard is stored as one value
+const maxMarkdownLength = 64 * 1024
+
+func (m *MarkdownViewProperties) Validate() error {
+	if len(m.Content) > maxMarkdownLength {
+		return errors.Errorf("content of markdown cannot be longer than %d bytes", maxMarkdownLength)
+	}
+
+	return nil
+}
+
+func (d DecimalPlaces) Validate() error {
+	if d.Digits < 0 {
+		return errors.New("digits of decimal places cannot be negative")
+	}
+
+	return nil
+}
+
+func (t *TableViewProperties) Validate() error {
+	if len(t.Queries) == 0 {
+		return errors.New("queries is required")
+	}
+
+	switch t.TableOptions.Wrapping {
+	case "", "truncate", "wrap", "single-line":
+	default:
+		return errors.Errorf("unknown wrapping %q", t.TableOptions.Wrapping)
+	}
+
+	if err := t.DecimalPlaces.Validate(); err != nil {
+		return err
+	}
+
+	names := make(map[string]struct{}, len(t.Columns))
+	for _, column := range t.Columns {
+		if column.Name == "" {
+			return errors.New("name of column is required")
+		}
+
+		if _, ok := names[column.Name]; ok {
+			return errors.Errorf("column %q is configured more than once", column.Name)
+		}
+		names[column.Name] = struct{}{}
+
+		if column.Width < 0 {
+			return errors.Errorf("width of column %q cannot be negative", column.Name)
+		}
+
+		if err := column.Format.DecimalPlaces.Validate(); err != nil {
+			return errors.Wrapf(err, "invalid format of column %q", column.Name)
+		}
+	}
+
+	if t.SortBy != nil && t.SortBy.Column == "" {
+		return errors.New("column to sort by is required")
+	}
+
+	return nil
+}
+
+func (h *HeatmapViewProperties) Validate() error {
+	if len(h.Queries) == 0 {
+		return errors.New("queries is required")
+	}
+
+	if h.BinSize < 0 {
+		return errors.New("bin size cannot be negative")
+	}
+
+	return nil
+}
+
+func (h *HistogramViewProperties) Validate() error {
+	if len(h.Queries) == 0 {
+		return errors.New("queries is required")
+	}
+
+	switch h.Position {
+	case "", "overlaid", "stacked":
+	default:
+		return errors.Errorf("unknown position %q", h.Position)
+	}
+
+	if len(h.XDomain) != 0 {
+		if len(h.XDomain) != 2 || h.XDomain[0] >= h.XDomain[1] {
+			return errors.New("xDomain must be the min and max, and min must be less than max")
+		}
+	}
+
+	if h.Buckets.Count < 0 {
+		return errors.New("count of buckets cannot be negative")
+	}
+
+	if h.Buckets.Count != 0 && len(h.Buckets.Bounds) != 0 {
+		return errors.New("count and bounds of buckets cannot be set at the same time")
+	}
+
+	for i := 1; i < len(h.Buckets.Bounds); i++ {
+		if h.Buckets.Bounds[i-1] >= h.Buckets.Bounds[i] {
+			return errors.New("bounds of buckets must be in increasing order")
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,132 @@
+package manta
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCell_UnmarshalViewProperties(t *testing.T) {
+	tests := []struct {
+		name  string
+		props string
+		want  ViewProperties
+		err   bool
+	}{
+		{
+			name:  "markdown",
+			props: `{"type":"markdown","content":"# Title"}`,
+			want:  &MarkdownViewProperties{Type: "markdown", Content: "# Title"},
+		},
+		{
+			name:  "table",
+			props: `{"type":"table","queries":[{"text":"up"}],"tableOptions":{"wrapping":"wrap"},"columns":[{"name":"instance","displayName":"Instance"},{"name":"value","format":{"suffix":"%","decimalPlaces":{"isEnforced":true,"digits":2}}}],"sortBy":{"column":"value","descending":true}}`,
+			want: &TableViewProperties{
+				Type:         "table",
+				Queries:      []Query{{Text: "up"}},
+				TableOptions: TableOptions{Wrapping: "wrap"},
+				Columns: []TableColumn{
+					{Name: "instance", DisplayName: "Instance"},
+					{Name: "value", Format: FieldFormat{
+						Suffix:        "%",
+						DecimalPlaces: DecimalPlaces{IsEnforced: true, Digits: 2},
+					}},
+				},
+				SortBy: &TableSort{Column: "value", Descending: true},
+			},
+		},
+		{
+			name:  "table without queries",
+			props: `{"type":"table"}`,
+			err:   true,
+		},
+		{
+			name:  "table with duplicated columns",
+			props: `{"type":"table","queries":[{"text":"up"}],"columns":[{"name":"job"},{"name":"job"}]}`,
+			err:   true,
+		},
+		{
+			name:  "table with unknown wrapping",
+			props: `{"type":"table","queries":[{"text":"up"}],"tableOptions":{"wrapping":"foo"}}`,
+			err:   true,
+		},
+		{
+			name:  "table sorted by empty column",
+			props: `{"type":"table","queries":[{"text":"up"}],"sortBy":{"column":""}}`,
+			err:   true,
+		},
+		{
+			name:  "heatmap",
+			props: `{"type":"heatmap","queries":[{"text":"rate(http_request_duration_seconds_bucket[5m])"}],"yColumn":"le","binSize":10}`,
+			want: &HeatmapViewProperties{
+				Type:    "heatmap",
+				Queries: []Query{{Text: "rate(http_request_duration_seconds_bucket[5m])"}},
+				YColumn: "le",
+				BinSize: 10,
+			},
+		},
+		{
+			name:  "heatmap with negative bin size",
+			props: `{"type":"heatmap","queries":[{"text":"up"}],"binSize":-1}`,
+			err:   true,
+		},
+		{
+			name:  "histogram",
+			props: `{"type":"histogram","queries":[{"text":"up"}],"xDomain":[0,100],"position":"stacked","buckets":{"bounds":[0.1,0.5,1]}}`,
+			want: &HistogramViewProperties{
+				Type:     "histogram",
+				Queries:  []Query{{Text: "up"}},
+				XDomain:  []float64{0, 100},
+				Position: "stacked",
+				Buckets:  HistogramBuckets{Bounds: []float64{0.1, 0.5, 1}},
+			},
+		},
+		{
+			name:  "histogram with invalid domain",
+			props: `{"type":"histogram","queries":[{"text":"up"}],"xDomain":[100,0]}`,
+			err:   true,
+		},
+		{
+			name:  "histogram with count and bounds",
+			props: `{"type":"histogram","queries":[{"text":"up"}],"buckets":{"count":10,"bounds":[1,2]}}`,
+			err:   true,
+		},
+		{
+			name:  "histogram with unordered bounds",
+			props: `{"type":"histogram","queries":[{"text":"up"}],"buckets":{"bounds":[2,1]}}`,
+			err:   true,
+		},
+		{
+			name:  "unknown",
+			props: `{"type":"foo"}`,
+			err:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var cell Cell
+			err := json.Unmarshal([]byte(`{"id":"0a712029e1880000","w":4,"h":4,"viewProperties":`+tt.props+`}`), &cell)
+			if tt.err {
+				assert.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, cell.ViewProperties)
+		})
+	}
+}
+
+func TestDashboardCellUpdate_UnmarshalJSON(t *testing.T) {
+	var upd DashboardCellUpdate
+	err := json.Unmarshal([]byte(`{"name":"foo"}`), &upd)
+	require.NoError(t, err)
+	assert.Equal(t, "foo", *upd.Name)
+	assert.Nil(t, upd.ViewProperties)
+
+	err = json.Unmarshal([]byte(`{"viewProperties":{"type":"table"}}`), &upd)
+	assert.Error(t, err)
+}
@@ -0,0 +1,146 @@
+package manta
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"time"
+)
+
+// DashboardVersion is a snapshot of the dashboard, one is kept every time
+// the dashboard is saved, restored or deleted. Versions are kept after the
+// dashboard is deleted, so it can be restored.
+type DashboardVersion struct {
+	DashboardID ID        `json:"dashboardID"`
+	Version     uint64    `json:"version"`
+	Created     time.Time `json:"created"`
+	// UserID is the author of this version
+	UserID  ID     `json:"userID,omitempty"`
+	Message string `json:"message,omitempty"`
+	// RestoredFrom is the version this one restored
+	RestoredFrom uint64 `json:"restoredFrom,omitempty"`
+	// Deleted is true if the dashboard is deleted by this version
+	Deleted bool `json:"deleted,omitempty"`
+
+	OrgID     ID         `json:"orgID"`
+	Name      string     `json:"name"`
+	Desc      string     `json:"desc,omitempty"`
+	Cells     []Cell     `json:"cells,omitempty"`
+	Variables []Variable `json:"variables,omitempty"`
+}
+
+// DashboardCellChange is a cell changed between two versions
+type DashboardCellChange struct {
+	ID   ID   `json:"id"`
+	From Cell `json:"from"`
+	To   Cell `json:"to"`
+}
+
+// DashboardDiff is the changes between two versions of the dashboard
+type DashboardDiff struct {
+	DashboardID ID     `json:"dashboardID"`
+	From        uint64 `json:"from"`
+	To          uint64 `json:"to"`
+	// Fields are the changed fields of the dashboard, e.g. name, desc
+	// and variables
+	Fields  []string              `json:"fields"`
+	Added   []Cell                `json:"added"`
+	Removed []Cell                `json:"removed"`
+	Changed []DashboardCellChange `json:"changed"`
+}
+
+// DiffDashboardVersions returns the changes from version from to version
+// to, cells are matched by ID, and compared by their JSON encoding.
+func DiffDashboardVersions(from, to *DashboardVersion) (*DashboardDiff, error) {
+	diff := &DashboardDiff{
+		DashboardID: to.DashboardID,
+		From:        from.Version,
+		To:          to.Version,
+		Fields:      []string{},
+		Added:       []Cell{},
+		Removed:     []Cell{},
+		Changed:     []DashboardCellChange{},
+	}
+
+	if from.Name != to.Name {
+		diff.Fields = append(diff.Fields, "name")
+	}
+
+	if from.Desc != to.Desc {
+		diff.Fields = append(diff.Fields, "desc")
+	}
+
+	changed, err := jsonChanged(from.Variables, to.Variables)
+	if err != nil {
+		return nil, err
+	}
+	if changed {
+		diff.Fields = append(diff.Fields, "variables")
+	}
+
+	before := make(map[ID]Cell, len(from.Cells))
+	for _, cell := range from.Cells {
+		before[cell.ID] = cell
+	}
+
+	for _, cell := range to.Cells {
+		prev, ok := before[cell.ID]
+		if !ok {
+			diff.Added = append(diff.Added, cell)
+			continue
+		}
+
+		delete(before, cell.ID)
+
+		changed, err = jsonChanged(prev, cell)
+		if err != nil {
+			return nil, err
+		}
+
+		if changed {
+			diff.Changed = append(diff.Changed, DashboardCellChange{
+				ID:   cell.ID,
+				From: prev,
+				To:   cell,
+			})
+		}
+	}
+
+	// keep the order of cells in version from
+	for _, cell := range from.Cells {
+		if _, ok := before[cell.ID]; ok {
+			diff.Removed = append(diff.Removed, cell)
+		}
+	}
+
+	return diff, nil
+}
+
+func jsonChanged(a, b any) (bool, error) {
+	x, err := json.Marshal(a)
+	if err != nil {
+		return false, err
+	}
+
+	y, err := json.Marshal(b)
+	if err != nil {
+		return false, err
+	}
+
+	return !bytes.Equal(x, y), nil
+}
+
+type versionMessageKey struct{}
+
+// WithVersionMessage returns a context carries the message of the version
+// created by the change made with it
+func WithVersionMessage(ctx context.Context, message string) context.Context {
+	return context.WithValue(ctx, versionMessageKey{}, message)
+}
+
+// VersionMessageFromContext returns the message of the version, it is empty
+// if not set
+func VersionMessageFromContext(ctx context.Context) string {
+	message, _ := ctx.Value(versionMessageKey{}).(string)
+	return message
+}
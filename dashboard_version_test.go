@@ -0,0 +1,53 @@
+package manta
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiffDashboardVersions(t *testing.T) {
+	from := &DashboardVersion{
+		DashboardID: 1,
+		Version:     1,
+		Name:        "node",
+		Cells: []Cell{
+			{ID: 1, Name: "cpu", W: 1, H: 1},
+			{ID: 2, Name: "memory", W: 1, H: 1},
+			{ID: 3, Name: "disk", W: 1, H: 1},
+		},
+	}
+	to := &DashboardVersion{
+		DashboardID: 1,
+		Version:     3,
+		Name:        "node exporter",
+		Variables:   []Variable{{Name: "job", Type: VariableLabelValues, Label: "job"}},
+		Cells: []Cell{
+			{ID: 1, Name: "cpu", W: 1, H: 1},
+			{ID: 2, Name: "memory", W: 2, H: 1},
+			{ID: 4, Name: "network", W: 1, H: 1},
+		},
+	}
+
+	diff, err := DiffDashboardVersions(from, to)
+	require.NoError(t, err)
+
+	assert.Equal(t, ID(1), diff.DashboardID)
+	assert.Equal(t, uint64(1), diff.From)
+	assert.Equal(t, uint64(3), diff.To)
+	assert.Equal(t, []string{"name", "variables"}, diff.Fields)
+	assert.Equal(t, []Cell{to.Cells[2]}, diff.Added)
+	assert.Equal(t, []Cell{from.Cells[2]}, diff.Removed)
+	assert.Equal(t, []DashboardCellChange{
+		{ID: 2, From: from.Cells[1], To: to.Cells[1]},
+	}, diff.Changed)
+
+	// no changes
+	diff, err = DiffDashboardVersions(to, to)
+	require.NoError(t, err)
+	assert.Empty(t, diff.Fields)
+	assert.Empty(t, diff.Added)
+	assert.Empty(t, diff.Removed)
+	assert.Empty(t, diff.Changed)
+}
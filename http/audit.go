@@ -0,0 +1,164 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/f1shl3gs/manta"
+	"github.com/f1shl3gs/manta/http/router"
+)
+
+const (
+	auditPrefix      = apiV1Prefix + "/audit"
+	lockoutsPrefix   = apiV1Prefix + "/lockouts"
+	lockoutWithValue = lockoutsPrefix + "/:kind/:value"
+)
+
+type AuditHandler struct {
+	*router.Router
+
+	logger         *zap.Logger
+	auditService   manta.AuditService
+	lockoutService manta.LockoutService
+}
+
+func NewAuditHandler(backend *Backend, logger *zap.Logger) *AuditHandler {
+	h := &AuditHandler{
+		Router:         backend.router,
+		logger:         logger.With(zap.String("handler", "audit")),
+		auditService:   backend.AuditService,
+		lockoutService: backend.LockoutService,
+	}
+
+	h.HandlerFunc(http.MethodGet, auditPrefix, h.handleFind)
+	h.HandlerFunc(http.MethodGet, lockoutsPrefix, h.handleListLockouts)
+	h.HandlerFunc(http.MethodDelete, lockoutWithValue, h.handleDeleteLockout)
+
+	return h
+}
+
+// addAuditRecord adds the record, failures are logged only, so they never
+// fail the authentication
+func addAuditRecord(ctx context.Context, logger *zap.Logger, service manta.AuditService, rec *manta.AuditRecord) {
+	if err := service.AddAuditRecord(ctx, rec); err != nil {
+		logger.Warn("add audit record failed",
+			zap.Error(err),
+			zap.String("action", rec.Action.String()))
+	}
+}
+
+// handleFind returns the audit records match the filter of queries
+func (h *AuditHandler) handleFind(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	filter, err := decodeAuditFilter(r)
+	if err != nil {
+		h.HandleHTTPError(ctx, err, w)
+		return
+	}
+
+	opts, err := manta.DecodeFindOptions(r)
+	if err != nil {
+		h.HandleHTTPError(ctx, err, w)
+		return
+	}
+
+	records, err := h.auditService.FindAuditRecords(ctx, filter, opts)
+	if err != nil {
+		h.HandleHTTPError(ctx, err, w)
+		return
+	}
+
+	if err = h.EncodeResponse(ctx, w, http.StatusOK, records); err != nil {
+		logEncodingError(h.logger, r, err)
+	}
+}
+
+func (h *AuditHandler) handleListLockouts(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	lockouts, err := h.lockoutService.FindLockouts(ctx)
+	if err != nil {
+		h.HandleHTTPError(ctx, err, w)
+		return
+	}
+
+	if err = h.EncodeResponse(ctx, w, http.StatusOK, lockouts); err != nil {
+		logEncodingError(h.logger, r, err)
+	}
+}
+
+func (h *AuditHandler) handleDeleteLockout(w http.ResponseWriter, r *http.Request) {
+	var (
+		ctx   = r.Context()
+		kind  = manta.LockoutKind(extractParamFromContext(ctx, "kind"))
+		value = extractParamFromContext(ctx, "value")
+	)
+
+	if err := h.lockoutService.DeleteLockout(ctx, kind, value); err != nil {
+		h.HandleHTTPError(ctx, err, w)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func decodeAuditFilter(r *http.Request) (manta.AuditFilter, error) {
+	var (
+		filter = manta.AuditFilter{}
+		query  = r.URL.Query()
+	)
+
+	if text := query.Get("userID"); text != "" {
+		id := new(manta.ID)
+		if err := id.DecodeFromString(text); err != nil {
+			return filter, &manta.Error{
+				Code: manta.EInvalid,
+				Msg:  "invalid userID",
+				Err:  err,
+			}
+		}
+
+		filter.UserID = id
+	}
+
+	for name, ptr := range map[string]**time.Time{
+		"since": &filter.Since,
+		"until": &filter.Until,
+	} {
+		text := query.Get(name)
+		if text == "" {
+			continue
+		}
+
+		ts, err := time.Parse(time.RFC3339Nano, text)
+		if err != nil {
+			return filter, &manta.Error{
+				Code: manta.EInvalid,
+				Msg:  "invalid " + name + ", RFC3339 time is required",
+				Err:  err,
+			}
+		}
+
+		*ptr = &ts
+	}
+
+	for name, ptr := range map[string]**string{
+		"username": &filter.Username,
+		"ip":       &filter.IP,
+	} {
+		if text := query.Get(name); text != "" {
+			*ptr = &text
+		}
+	}
+
+	if text := query.Get("action"); text != "" {
+		action := manta.AuditAction(text)
+		filter.Action = &action
+	}
+
+	return filter, nil
+}
@@ -3,6 +3,8 @@ package http
 import (
 	"context"
 	"net/http"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/julienschmidt/httprouter"
@@ -10,6 +12,7 @@ import (
 
 	"github.com/f1shl3gs/manta"
 	"github.com/f1shl3gs/manta/authorizer"
+	"github.com/f1shl3gs/manta/kv"
 	"github.com/f1shl3gs/manta/pkg/tracing"
 )
 
@@ -19,7 +22,9 @@ type AuthenticationHandler struct {
 	AuthorizationService manta.AuthorizationService
 	UserService          manta.UserService
 	SessionService       manta.SessionService
+	AuditService         manta.AuditService
 
+	tokenUses    *tokenUses
 	noAuthRouter *httprouter.Router
 	handler      http.Handler
 	errorHandler manta.HTTPErrorHandler
@@ -42,7 +47,7 @@ func (h *AuthenticationHandler) ServeHTTP(w http.ResponseWriter, r *http.Request
 
 	switch probeAuthType(r) {
 	case "token":
-		// TODO: implement
+		a, err = h.extractAuthorization(ctx, r)
 	case "session":
 		a, err = h.extractSession(ctx, r)
 	default:
@@ -53,7 +58,8 @@ func (h *AuthenticationHandler) ServeHTTP(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	if err == manta.ErrSessionExpired || err == manta.ErrSessionNotFound {
+	if err == manta.ErrSessionExpired || err == manta.ErrSessionNotFound ||
+		manta.ErrorCode(err) == manta.EUnauthorized {
 		h.handleUnauthorized(w, r, err)
 		return
 	}
@@ -85,11 +91,74 @@ func probeAuthType(r *http.Request) string {
 		}
 	}
 
-	if r.URL.Query().Has("token") {
-		return "token"
+	return ""
+}
+
+var errInvalidToken = &manta.Error{
+	Code: manta.EUnauthorized,
+	Msg:  "invalid token",
+}
+
+// extractToken returns the token of "Authorization: Token xxx" or
+// "Authorization: Bearer xxx". Tokens in the URL are not accepted, since
+// they end up in access logs and browser history.
+func extractToken(r *http.Request) string {
+	scheme, token, found := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !found || !(strings.EqualFold(scheme, "Token") || strings.EqualFold(scheme, "Bearer")) {
+		return ""
 	}
 
-	return ""
+	return strings.TrimSpace(token)
+}
+
+func (h *AuthenticationHandler) extractAuthorization(ctx context.Context, r *http.Request) (manta.Authorizer, error) {
+	token := extractToken(r)
+	if token == "" {
+		return nil, errInvalidToken
+	}
+
+	auth, err := h.AuthorizationService.FindAuthorizationByToken(ctx, token)
+	if kv.IsNotFound(err) {
+		return nil, errInvalidToken
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if !auth.Active() {
+		return nil, errInvalidToken
+	}
+
+	user, err := h.UserService.FindUserByID(ctx, auth.UID)
+	if manta.ErrorCode(err) == manta.ENotFound {
+		return nil, errInvalidToken
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if !user.Active() {
+		return nil, manta.ErrUserDisabled
+	}
+
+	opts := sessionOptions(r)
+	if h.tokenUses.record(auth.ID, opts.IP, time.Now()) {
+		err = h.AuditService.AddAuditRecord(ctx, &manta.AuditRecord{
+			Action:          manta.AuditTokenUsed,
+			UserID:          user.ID,
+			Username:        user.Name,
+			AuthorizationID: auth.ID,
+			IP:              opts.IP,
+			UserAgent:       opts.UserAgent,
+		})
+		if err != nil {
+			h.logger.Warn("add audit record of token use failed",
+				zap.Error(err),
+				zap.String("authorizationId", auth.ID.String()))
+		}
+	}
+
+	return auth, nil
 }
 
 func (h *AuthenticationHandler) extractSession(ctx context.Context, r *http.Request) (manta.Authorizer, error) {
@@ -109,9 +178,9 @@ func (h *AuthenticationHandler) extractSession(ctx context.Context, r *http.Requ
 		return nil, err
 	}
 
-	err = h.SessionService.RenewSession(ctx, id, time.Now().Add(12*time.Hour))
+	err = h.SessionService.ExtendSession(ctx, id)
 	if err != nil {
-		h.logger.Error("renew session failed",
+		h.logger.Error("extend session failed",
 			zap.Error(err),
 			zap.String("userId", id.String()))
 	}
@@ -128,3 +197,50 @@ func (h *AuthenticationHandler) handleUnauthorized(w http.ResponseWriter, r *htt
 
 	w.WriteHeader(http.StatusUnauthorized)
 }
+
+// tokenUseAuditInterval limits how often the uses of a token are recorded,
+// so not every request writes an audit record
+const tokenUseAuditInterval = time.Minute
+
+type tokenUse struct {
+	id manta.ID
+	ip string
+}
+
+// tokenUses remembers when the uses of tokens were recorded last time
+type tokenUses struct {
+	interval time.Duration
+
+	mtx  sync.Mutex
+	seen map[tokenUse]time.Time
+}
+
+func newTokenUses(interval time.Duration) *tokenUses {
+	return &tokenUses{
+		interval: interval,
+		seen:     make(map[tokenUse]time.Time),
+	}
+}
+
+// record returns true if the use of the token from the ip should be
+// recorded, which is not recorded in the interval
+func (u *tokenUses) record(id manta.ID, ip string, now time.Time) bool {
+	u.mtx.Lock()
+	defer u.mtx.Unlock()
+
+	key := tokenUse{id: id, ip: ip}
+	if last, ok := u.seen[key]; ok && now.Sub(last) < u.interval {
+		return false
+	}
+
+	if len(u.seen) >= 1024 {
+		for k, last := range u.seen {
+			if now.Sub(last) >= u.interval {
+				delete(u.seen, k)
+			}
+		}
+	}
+
+	u.seen[key] = now
+	return true
+}
@@ -14,8 +14,11 @@ import (
 )
 
 const (
-	clusterServicePrefix = apiV1Prefix + "/cluster"
-	clusterServiceIDPath = clusterServicePrefix + "/:id"
+	clusterServicePrefix       = apiV1Prefix + "/cluster"
+	clusterServiceStatusPath   = clusterServicePrefix + "/status"
+	clusterServiceIDPath       = clusterServicePrefix + "/:id"
+	clusterServicePromotePath  = clusterServiceIDPath + "/promote"
+	clusterServiceTransferPath = clusterServiceIDPath + "/transfer"
 )
 
 type ClusterServiceHandler struct {
@@ -37,8 +40,11 @@ func NewClusterServiceHandler(logger *zap.Logger, backend *Backend) {
 	}
 
 	h.HandlerFunc(http.MethodGet, clusterServicePrefix, h.list)
+	h.HandlerFunc(http.MethodGet, clusterServiceStatusPath, h.status)
 	h.HandlerFunc(http.MethodPost, clusterServicePrefix, h.add)
 	h.HandlerFunc(http.MethodDelete, clusterServiceIDPath, h.delete)
+	h.HandlerFunc(http.MethodPost, clusterServicePromotePath, h.promote)
+	h.HandlerFunc(http.MethodPost, clusterServiceTransferPath, h.transfer)
 }
 
 func (h *ClusterServiceHandler) list(w http.ResponseWriter, r *http.Request) {
@@ -50,6 +56,15 @@ func (h *ClusterServiceHandler) list(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+func (h *ClusterServiceHandler) status(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	status := h.raftService.Status()
+
+	if err := h.EncodeResponse(ctx, w, http.StatusOK, status); err != nil {
+		logEncodingError(h.logger, r, err)
+	}
+}
+
 func (h *ClusterServiceHandler) add(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	member := raftstorepb.Member{}
@@ -66,7 +81,7 @@ func (h *ClusterServiceHandler) add(w http.ResponseWriter, r *http.Request) {
 
 	err = h.raftService.Add(ctx, member)
 	if err != nil {
-		h.HandleHTTPError(ctx, err, w)
+		h.HandleHTTPError(ctx, clusterError(err), w)
 		return
 	}
 
@@ -76,16 +91,74 @@ func (h *ClusterServiceHandler) add(w http.ResponseWriter, r *http.Request) {
 func (h *ClusterServiceHandler) delete(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
-	text := extractParamFromContext(r.Context(), "id")
-	id, err := strconv.ParseUint(text, 16, 64)
+	id, err := memberIDFromPath(r)
 	if err != nil {
-		h.HandleHTTPError(ctx, &manta.Error{Code: manta.EInvalid, Msg: "invalid node id", Err: err}, w)
+		h.HandleHTTPError(ctx, err, w)
 		return
 	}
 
 	err = h.raftService.Remove(ctx, id)
+	if err != nil {
+		h.HandleHTTPError(ctx, clusterError(err), w)
+		return
+	}
+}
+
+func (h *ClusterServiceHandler) promote(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	id, err := memberIDFromPath(r)
 	if err != nil {
 		h.HandleHTTPError(ctx, err, w)
 		return
 	}
+
+	err = h.raftService.Promote(ctx, id)
+	if err != nil {
+		h.HandleHTTPError(ctx, clusterError(err), w)
+		return
+	}
+}
+
+func (h *ClusterServiceHandler) transfer(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	id, err := memberIDFromPath(r)
+	if err != nil {
+		h.HandleHTTPError(ctx, err, w)
+		return
+	}
+
+	err = h.raftService.TransferLeadership(ctx, id)
+	if err != nil {
+		h.HandleHTTPError(ctx, clusterError(err), w)
+		return
+	}
+}
+
+func memberIDFromPath(r *http.Request) (uint64, error) {
+	text := extractParamFromContext(r.Context(), "id")
+	id, err := strconv.ParseUint(text, 16, 64)
+	if err != nil {
+		return 0, &manta.Error{Code: manta.EInvalid, Msg: "invalid node id", Err: err}
+	}
+
+	return id, nil
+}
+
+// clusterError converts errors of raftstore to manta.Error, so the
+// client can get a proper status code
+func clusterError(err error) error {
+	switch err {
+	case raftstore.ErrMemberNotFound:
+		return &manta.Error{Code: manta.ENotFound, Msg: err.Error()}
+	case raftstore.ErrMemberNotLearner, raftstore.ErrTransfereeIsLearner:
+		return &manta.Error{Code: manta.EInvalid, Msg: err.Error()}
+	case raftstore.ErrLearnerNotReady, raftstore.ErrUnhealthy:
+		return &manta.Error{Code: manta.EConflict, Msg: err.Error()}
+	case raftstore.ErrNotLeader, raftstore.ErrTimeoutLeaderTransfer, raftstore.ErrStopped:
+		return &manta.Error{Code: manta.EUnavailable, Msg: err.Error()}
+	default:
+		return err
+	}
 }
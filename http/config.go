@@ -2,22 +2,41 @@ package http
 
 import (
 	"encoding/json"
+	"fmt"
 	"io"
 	"net/http"
 	"net/http/httputil"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
 	"go.uber.org/zap"
 
 	"github.com/f1shl3gs/manta"
 	"github.com/f1shl3gs/manta/config"
+	"github.com/f1shl3gs/manta/config/render"
+	"github.com/f1shl3gs/manta/config/validate"
 	"github.com/f1shl3gs/manta/http/router"
 )
 
 const (
 	configPrefix = apiV1Prefix + `/configs`
 	configWithID = configPrefix + `/:id`
+
+	configRevisionsPrefix = configWithID + `/revisions`
+	configRevisionPath    = configRevisionsPrefix + `/:revision`
+	configDiffPath        = configWithID + `/diff`
+	configRollbackPath    = configWithID + `/rollback`
+
+	configRolloutPath        = configWithID + `/rollout`
+	configRolloutPromotePath = configRolloutPath + `/promote`
+	configRolloutAbortPath   = configRolloutPath + `/abort`
+	configAckPath            = configWithID + `/ack`
+)
+
+const (
+	sseKeepaliveInterval = 15 * time.Second
 )
 
 var (
@@ -30,15 +49,19 @@ var (
 type ConfigHandler struct {
 	*router.Router
 
-	logger        *zap.Logger
-	configService *config.CoordinatingConfigService
+	logger          *zap.Logger
+	configService   *config.CoordinatingConfigService
+	secretService   manta.SecretService
+	registryService manta.RegistryService
 }
 
 func NewConfigService(backend *Backend, logger *zap.Logger) {
 	h := &ConfigHandler{
-		Router:        backend.router,
-		logger:        logger.With(zap.String("handle", "config")),
-		configService: config.NewCoordinatingVertexService(backend.ConfigService, logger),
+		Router:          backend.router,
+		logger:          logger.With(zap.String("handle", "config")),
+		configService:   config.NewCoordinatingVertexService(backend.ConfigService, backend.Watcher, logger),
+		secretService:   backend.SecretService,
+		registryService: backend.RegistryService,
 	}
 
 	backend.PromRegistry.MustRegister(watchStreams)
@@ -48,6 +71,14 @@ func NewConfigService(backend *Backend, logger *zap.Logger) {
 	h.HandlerFunc(http.MethodGet, configWithID, h.getConfig)
 	h.HandlerFunc(http.MethodPatch, configWithID, h.updateConfig)
 	h.HandlerFunc(http.MethodDelete, configWithID, h.deleteConfig)
+	h.HandlerFunc(http.MethodGet, configRevisionsPrefix, h.listRevisions)
+	h.HandlerFunc(http.MethodGet, configRevisionPath, h.getRevision)
+	h.HandlerFunc(http.MethodGet, configDiffPath, h.diffRevisions)
+	h.HandlerFunc(http.MethodPost, configRollbackPath, h.rollbackConfig)
+	h.HandlerFunc(http.MethodGet, configRolloutPath, h.getRollout)
+	h.HandlerFunc(http.MethodPost, configRolloutPromotePath, h.promoteRollout)
+	h.HandlerFunc(http.MethodPost, configRolloutAbortPath, h.abortRollout)
+	h.HandlerFunc(http.MethodPost, configAckPath, h.ackConfig)
 }
 
 func (h *ConfigHandler) listConfigs(w http.ResponseWriter, r *http.Request) {
@@ -79,6 +110,14 @@ func (h *ConfigHandler) getConfig(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	ins, err := h.instance(r)
+	if err != nil {
+		h.HandleHTTPError(ctx, err, w)
+		return
+	}
+
+	tmplData := h.templateData(r, ins)
+
 	var encodeResp = func(cf *manta.Config, writer io.Writer) error {
 		var (
 			data []byte
@@ -100,7 +139,13 @@ func (h *ConfigHandler) getConfig(w http.ResponseWriter, r *http.Request) {
 
 	if r.URL.Query().Get("watch") != "true" {
 		// Just get config, not watching
-		cf, err := h.configService.FindConfigByID(ctx, id)
+		cf, err := h.configService.Resolve(ctx, id, ins)
+		if err != nil {
+			h.HandleHTTPError(ctx, err, w)
+			return
+		}
+
+		cf, err = h.render(r, cf, tmplData)
 		if err != nil {
 			h.HandleHTTPError(ctx, err, w)
 			return
@@ -116,11 +161,13 @@ func (h *ConfigHandler) getConfig(w http.ResponseWriter, r *http.Request) {
 	watchStreams.Inc()
 	defer watchStreams.Dec()
 
-	// watching first then get, so we won't miss any updates (no promise)
-	queue := h.configService.Sub(id)
-	defer queue.Close()
+	revision, err := revisionFromRequest(r)
+	if err != nil {
+		h.HandleHTTPError(ctx, err, w)
+		return
+	}
 
-	first, err := h.configService.FindConfigByID(ctx, id)
+	configs, err := h.configService.WatchInstance(ctx, id, ins, revision)
 	if err != nil {
 		h.HandleHTTPError(ctx, err, w)
 		return
@@ -132,29 +179,28 @@ func (h *ConfigHandler) getConfig(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if isEventStream(r) {
+		h.streamEvents(w, r, flusher, configs, tmplData)
+		return
+	}
+
 	w.Header().Set("Content-Type", "application/json")
 	w.Header().Set("Transfer-Encoding", "chunked")
 	w.Header().Set("X-Content-Type-Options", "nosniff")
 	w.WriteHeader(http.StatusOK)
 	flusher.Flush()
 
-	var (
-		cf     *manta.Config
-		closed bool
-		writer = httputil.NewChunkedWriter(w)
-	)
-
+	writer := httputil.NewChunkedWriter(w)
 	defer writer.Close()
 
-	for {
-		if first != nil {
-			cf = first
-			first = nil
-		}
-
+	// the channel is closed when the config is deleted or the client is gone
+	for cf := range configs {
 		// what we watched for is configuratin, not the data field,
 		// so false notification might happenned.
-		err = encodeResp(cf, writer)
+		cf, err = h.render(r, cf, tmplData)
+		if err == nil {
+			err = encodeResp(cf, writer)
+		}
 		if err != nil {
 			h.logger.Warn("watch failed",
 				zap.String("client", r.RemoteAddr),
@@ -163,19 +209,157 @@ func (h *ConfigHandler) getConfig(w http.ResponseWriter, r *http.Request) {
 		}
 
 		flusher.Flush()
+	}
+}
 
-		// wait for new channel
-		select {
-		case <-ctx.Done():
-			return
+// streamEvents sends configs as Server-Sent Events, the revision is used as
+// event id, so browsers resume from it with the Last-Event-ID header when
+// reconnecting.
+func (h *ConfigHandler) streamEvents(
+	w http.ResponseWriter,
+	r *http.Request,
+	flusher http.Flusher,
+	configs <-chan *manta.Config,
+	tmplData render.Data,
+) {
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	keepalive := time.NewTicker(sseKeepaliveInterval)
+	defer keepalive.Stop()
 
-		case cf, closed = <-queue.Ch():
-			if !closed {
-				// config deleted, so the channel is closed too
+	for {
+		var err error
+
+		select {
+		case cf, ok := <-configs:
+			if !ok {
+				// config deleted or the client is gone
 				return
 			}
+
+			var data []byte
+			cf, err = h.render(r, cf, tmplData)
+			if err == nil {
+				data, err = json.Marshal(cf)
+			}
+			if err == nil {
+				_, err = fmt.Fprintf(w, "id: %d\nevent: config\ndata: %s\n\n", cf.Revision, data)
+			}
+
+		case <-keepalive.C:
+			// comments are ignored by clients, it keeps proxies from
+			// closing the idle connection
+			_, err = io.WriteString(w, ": keepalive\n\n")
+		}
+
+		if err != nil {
+			h.logger.Warn("watch failed",
+				zap.String("client", r.RemoteAddr),
+				zap.Error(err))
+			return
+		}
+
+		flusher.Flush()
+	}
+}
+
+// reservedQueries are the queries used by the config handler, they are not
+// template variables.
+var reservedQueries = map[string]struct{}{
+	"watch":    {},
+	"revision": {},
+	"stream":   {},
+	"instance": {},
+}
+
+// instance returns the registered instance whose uuid is the instance query,
+// nil is returned if the query is not set.
+func (h *ConfigHandler) instance(r *http.Request) (*manta.Instance, error) {
+	uuid := r.URL.Query().Get("instance")
+	if uuid == "" {
+		return nil, nil
+	}
+
+	instances, err := h.registryService.Catalog(r.Context())
+	if err != nil {
+		return nil, err
+	}
+
+	for _, ins := range instances {
+		if ins.UUID == uuid {
+			return ins, nil
+		}
+	}
+
+	return nil, &manta.Error{
+		Code: manta.ENotFound,
+		Msg:  "instance not found",
+	}
+}
+
+// templateData returns the data to render config templates with, variables
+// are read from queries.
+func (h *ConfigHandler) templateData(r *http.Request, ins *manta.Instance) render.Data {
+	var (
+		query = r.URL.Query()
+		vars  = make(map[string]string, len(query))
+	)
+
+	for key, values := range query {
+		if _, ok := reservedQueries[key]; ok || len(values) == 0 {
+			continue
+		}
+
+		vars[key] = values[0]
+	}
+
+	return render.NewData(ins, vars)
+}
+
+// render renders the data of config if it is a template
+func (h *ConfigHandler) render(r *http.Request, cf *manta.Config, data render.Data) (*manta.Config, error) {
+	if !cf.Template {
+		return cf, nil
+	}
+
+	return render.Render(r.Context(), cf, data, h.secretService)
+}
+
+// revisionFromRequest returns the revision to resume watching from, it is
+// read from the revision query or the Last-Event-ID header of SSE clients.
+func revisionFromRequest(r *http.Request) (uint64, error) {
+	text := r.URL.Query().Get("revision")
+	if text == "" {
+		text = r.Header.Get("Last-Event-ID")
+	}
+
+	if text == "" {
+		return 0, nil
+	}
+
+	return parseRevision(text)
+}
+
+func parseRevision(text string) (uint64, error) {
+	revision, err := strconv.ParseUint(text, 10, 64)
+	if err != nil {
+		return 0, &manta.Error{
+			Code: manta.EInvalid,
+			Msg:  "invalid revision",
+			Err:  err,
 		}
 	}
+
+	return revision, nil
+}
+
+func isEventStream(r *http.Request) bool {
+	return r.URL.Query().Get("stream") == "sse" ||
+		strings.Contains(r.Header.Get("Accept"), "text/event-stream")
 }
 
 func (h *ConfigHandler) updateConfig(w http.ResponseWriter, r *http.Request) {
@@ -195,6 +379,18 @@ func (h *ConfigHandler) updateConfig(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if isDryRun(r) {
+		cf, err := h.configService.FindConfigByID(ctx, id)
+		if err != nil {
+			h.HandleHTTPError(ctx, err, w)
+			return
+		}
+
+		upd.Apply(cf)
+		h.dryRun(w, r, cf)
+		return
+	}
+
 	if cf, err := h.configService.UpdateConfig(ctx, id, upd); err != nil {
 		h.HandleHTTPError(ctx, err, w)
 	} else {
@@ -232,6 +428,11 @@ func (h *ConfigHandler) createConfig(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if isDryRun(r) {
+		h.dryRun(w, r, &c)
+		return
+	}
+
 	if err = h.configService.CreateConfig(ctx, &c); err != nil {
 		h.HandleHTTPError(ctx, err, w)
 		return
@@ -241,3 +442,288 @@ func (h *ConfigHandler) createConfig(w http.ResponseWriter, r *http.Request) {
 		logEncodingError(h.logger, r, err)
 	}
 }
+
+func (h *ConfigHandler) listRevisions(w http.ResponseWriter, r *http.Request) {
+	var ctx = r.Context()
+
+	id, err := idFromPath(r)
+	if err != nil {
+		h.HandleHTTPError(ctx, err, w)
+		return
+	}
+
+	revisions, err := h.configService.FindConfigRevisions(ctx, id)
+	if err != nil {
+		h.HandleHTTPError(ctx, err, w)
+		return
+	}
+
+	if err = h.EncodeResponse(ctx, w, http.StatusOK, revisions); err != nil {
+		logEncodingError(h.logger, r, err)
+	}
+}
+
+func (h *ConfigHandler) getRevision(w http.ResponseWriter, r *http.Request) {
+	var ctx = r.Context()
+
+	id, err := idFromPath(r)
+	if err != nil {
+		h.HandleHTTPError(ctx, err, w)
+		return
+	}
+
+	revision, err := parseRevision(extractParamFromContext(ctx, "revision"))
+	if err != nil {
+		h.HandleHTTPError(ctx, err, w)
+		return
+	}
+
+	rev, err := h.configService.FindConfigRevision(ctx, id, revision)
+	if err != nil {
+		h.HandleHTTPError(ctx, err, w)
+		return
+	}
+
+	if err = h.EncodeResponse(ctx, w, http.StatusOK, rev); err != nil {
+		logEncodingError(h.logger, r, err)
+	}
+}
+
+// diffRevisions diffs the data of revision from and to, to is the current
+// revision if not specified.
+func (h *ConfigHandler) diffRevisions(w http.ResponseWriter, r *http.Request) {
+	var (
+		ctx   = r.Context()
+		query = r.URL.Query()
+	)
+
+	id, err := idFromPath(r)
+	if err != nil {
+		h.HandleHTTPError(ctx, err, w)
+		return
+	}
+
+	from, err := parseRevision(query.Get("from"))
+	if err != nil {
+		h.HandleHTTPError(ctx, err, w)
+		return
+	}
+
+	var to uint64
+	if text := query.Get("to"); text != "" {
+		to, err = parseRevision(text)
+	} else {
+		var cf *manta.Config
+		if cf, err = h.configService.FindConfigByID(ctx, id); err == nil {
+			to = cf.Revision
+		}
+	}
+	if err != nil {
+		h.HandleHTTPError(ctx, err, w)
+		return
+	}
+
+	fromRev, err := h.configService.FindConfigRevision(ctx, id, from)
+	if err != nil {
+		h.HandleHTTPError(ctx, err, w)
+		return
+	}
+
+	toRev, err := h.configService.FindConfigRevision(ctx, id, to)
+	if err != nil {
+		h.HandleHTTPError(ctx, err, w)
+		return
+	}
+
+	diff, err := config.Diff(fromRev, toRev)
+	if err != nil {
+		h.HandleHTTPError(ctx, err, w)
+		return
+	}
+
+	if err = h.EncodeResponse(ctx, w, http.StatusOK, diff); err != nil {
+		logEncodingError(h.logger, r, err)
+	}
+}
+
+func (h *ConfigHandler) rollbackConfig(w http.ResponseWriter, r *http.Request) {
+	var (
+		ctx = r.Context()
+		req struct {
+			Revision uint64 `json:"revision"`
+		}
+	)
+
+	id, err := idFromPath(r)
+	if err != nil {
+		h.HandleHTTPError(ctx, err, w)
+		return
+	}
+
+	if err = json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.HandleHTTPError(ctx, &manta.Error{
+			Code: manta.EInvalid,
+			Msg:  "decode rollback request failed",
+			Err:  err,
+		}, w)
+		return
+	}
+
+	cf, err := h.configService.RollbackConfig(ctx, id, req.Revision)
+	if err != nil {
+		h.HandleHTTPError(ctx, err, w)
+		return
+	}
+
+	if err = h.EncodeResponse(ctx, w, http.StatusOK, cf); err != nil {
+		logEncodingError(h.logger, r, err)
+	}
+}
+
+// getRollout returns the rollout of the config, and which instances run
+// which revision.
+func (h *ConfigHandler) getRollout(w http.ResponseWriter, r *http.Request) {
+	var ctx = r.Context()
+
+	id, err := idFromPath(r)
+	if err != nil {
+		h.HandleHTTPError(ctx, err, w)
+		return
+	}
+
+	cf, err := h.configService.FindConfigByID(ctx, id)
+	if err != nil {
+		h.HandleHTTPError(ctx, err, w)
+		return
+	}
+
+	rollout, err := h.configService.FindConfigRollout(ctx, id)
+	if err != nil && manta.ErrorCode(err) != manta.ENotFound {
+		h.HandleHTTPError(ctx, err, w)
+		return
+	}
+
+	acks, err := h.configService.FindConfigAcks(ctx, id)
+	if err != nil {
+		h.HandleHTTPError(ctx, err, w)
+		return
+	}
+
+	instances, err := h.registryService.Catalog(ctx)
+	if err != nil {
+		h.HandleHTTPError(ctx, err, w)
+		return
+	}
+
+	status := config.RolloutStatus(cf, rollout, instances, acks)
+	if err = h.EncodeResponse(ctx, w, http.StatusOK, status); err != nil {
+		logEncodingError(h.logger, r, err)
+	}
+}
+
+// promoteRollout promotes the rollout to the canary percent of the request,
+// the body is optional, all targets are promoted if canary is not set.
+func (h *ConfigHandler) promoteRollout(w http.ResponseWriter, r *http.Request) {
+	var (
+		ctx = r.Context()
+		req struct {
+			Canary int `json:"canary"`
+		}
+	)
+
+	id, err := idFromPath(r)
+	if err != nil {
+		h.HandleHTTPError(ctx, err, w)
+		return
+	}
+
+	if err = json.NewDecoder(r.Body).Decode(&req); err != nil && err != io.EOF {
+		h.HandleHTTPError(ctx, &manta.Error{
+			Code: manta.EInvalid,
+			Msg:  "decode promote request failed",
+			Err:  err,
+		}, w)
+		return
+	}
+
+	rollout, err := h.configService.PromoteConfigRollout(ctx, id, req.Canary)
+	if err != nil {
+		h.HandleHTTPError(ctx, err, w)
+		return
+	}
+
+	if err = h.EncodeResponse(ctx, w, http.StatusOK, rollout); err != nil {
+		logEncodingError(h.logger, r, err)
+	}
+}
+
+func (h *ConfigHandler) abortRollout(w http.ResponseWriter, r *http.Request) {
+	var ctx = r.Context()
+
+	id, err := idFromPath(r)
+	if err != nil {
+		h.HandleHTTPError(ctx, err, w)
+		return
+	}
+
+	rollout, err := h.configService.AbortConfigRollout(ctx, id)
+	if err != nil {
+		h.HandleHTTPError(ctx, err, w)
+		return
+	}
+
+	if err = h.EncodeResponse(ctx, w, http.StatusOK, rollout); err != nil {
+		logEncodingError(h.logger, r, err)
+	}
+}
+
+// ackConfig records the revision applied by the agent
+func (h *ConfigHandler) ackConfig(w http.ResponseWriter, r *http.Request) {
+	var (
+		ctx = r.Context()
+		ack = &manta.ConfigAck{}
+	)
+
+	id, err := idFromPath(r)
+	if err != nil {
+		h.HandleHTTPError(ctx, err, w)
+		return
+	}
+
+	if err = json.NewDecoder(r.Body).Decode(ack); err != nil {
+		h.HandleHTTPError(ctx, &manta.Error{
+			Code: manta.EInvalid,
+			Msg:  "decode ack failed",
+			Err:  err,
+		}, w)
+		return
+	}
+
+	ack.ConfigID = id
+	if err = h.configService.AckConfig(ctx, ack); err != nil {
+		h.HandleHTTPError(ctx, err, w)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// dryRun validates the config without saving it, problems of the data are
+// returned with line and column.
+func (h *ConfigHandler) dryRun(w http.ResponseWriter, r *http.Request, cf *manta.Config) {
+	ctx := r.Context()
+
+	result, err := validate.Check(cf)
+	if err != nil {
+		h.HandleHTTPError(ctx, err, w)
+		return
+	}
+
+	if err = h.EncodeResponse(ctx, w, http.StatusOK, result); err != nil {
+		logEncodingError(h.logger, r, err)
+	}
+}
+
+func isDryRun(r *http.Request) bool {
+	return r.URL.Query().Get("dryRun") == "true"
+}
@@ -1,11 +1,16 @@
 package http
 
 import (
+	"context"
 	"encoding/json"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/f1shl3gs/manta"
 	"github.com/f1shl3gs/manta/http/router"
+	"github.com/f1shl3gs/manta/variable"
 
 	"github.com/pkg/errors"
 	"go.uber.org/zap"
@@ -15,8 +20,14 @@ const (
 	dashboardsPrefix     = apiV1Prefix + `/dashboards`
 	dashboardsWithID     = dashboardsPrefix + `/:id`
 	dashboardChangesPath = dashboardsWithID + `/changes`
+	dashboardVarsPath    = dashboardsWithID + `/variables`
 	dashboardCellsPrefix = dashboardsWithID + `/cells`
 	dashboardCellIDPath  = dashboardCellsPrefix + `/:cellId`
+
+	dashboardVersionsPrefix = dashboardsWithID + `/versions`
+	dashboardVersionPath    = dashboardVersionsPrefix + `/:version`
+	dashboardDiffPath       = dashboardsWithID + `/diff`
+	dashboardRestorePath    = dashboardsWithID + `/restore`
 )
 
 type DashboardsHandler struct {
@@ -26,6 +37,7 @@ type DashboardsHandler struct {
 	organizationService manta.OrganizationService
 	dashboardService    manta.DashboardService
 	operationLogService manta.OperationLogService
+	resolver            *variable.Resolver
 }
 
 func NewDashboardsHandler(backend *Backend, logger *zap.Logger) *DashboardsHandler {
@@ -35,6 +47,7 @@ func NewDashboardsHandler(backend *Backend, logger *zap.Logger) *DashboardsHandl
 		organizationService: backend.OrganizationService,
 		dashboardService:    backend.DashboardService,
 		operationLogService: backend.OperationLogService,
+		resolver:            variable.NewResolver(backend.TenantStorage, backend.queryEngine),
 	}
 
 	h.HandlerFunc(http.MethodGet, dashboardsPrefix, h.listDashboard)
@@ -50,6 +63,12 @@ func NewDashboardsHandler(backend *Backend, logger *zap.Logger) *DashboardsHandl
 	h.HandlerFunc(http.MethodDelete, dashboardCellIDPath, h.deleteCell)
 
 	h.HandlerFunc(http.MethodGet, dashboardChangesPath, h.changes)
+	h.HandlerFunc(http.MethodGet, dashboardVarsPath, h.resolveVariables)
+
+	h.HandlerFunc(http.MethodGet, dashboardVersionsPrefix, h.listVersions)
+	h.HandlerFunc(http.MethodGet, dashboardVersionPath, h.getVersion)
+	h.HandlerFunc(http.MethodGet, dashboardDiffPath, h.diffVersions)
+	h.HandlerFunc(http.MethodPost, dashboardRestorePath, h.restoreDashboard)
 
 	return h
 }
@@ -63,7 +82,12 @@ func (h *DashboardsHandler) listDashboard(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	dashboards, err := h.dashboardService.FindDashboards(ctx, manta.DashboardFilter{OrgID: orgID})
+	var dashboards any
+	if r.URL.Query().Get("deleted") == "true" {
+		dashboards, err = h.dashboardService.FindDeletedDashboards(ctx, manta.DashboardFilter{OrgID: orgID})
+	} else {
+		dashboards, err = h.dashboardService.FindDashboards(ctx, manta.DashboardFilter{OrgID: orgID})
+	}
 	if err != nil {
 		h.HandleHTTPError(ctx, err, w)
 		return
@@ -87,6 +111,17 @@ func decodeDashboard(r *http.Request) (*manta.Dashboard, error) {
 	return &dashboard, nil
 }
 
+// versionContext carries the message of the dashboard version created by
+// the request, it is read from the query "message"
+func versionContext(r *http.Request) context.Context {
+	ctx := r.Context()
+	if message := r.URL.Query().Get("message"); message != "" {
+		ctx = manta.WithVersionMessage(ctx, message)
+	}
+
+	return ctx
+}
+
 func (h *DashboardsHandler) getDashboard(w http.ResponseWriter, r *http.Request) {
 	var ctx = r.Context()
 
@@ -108,7 +143,7 @@ func (h *DashboardsHandler) getDashboard(w http.ResponseWriter, r *http.Request)
 }
 
 func (h *DashboardsHandler) createDashboard(w http.ResponseWriter, r *http.Request) {
-	ctx := r.Context()
+	ctx := versionContext(r)
 
 	dashboard, err := decodeDashboard(r)
 	if err != nil {
@@ -127,7 +162,7 @@ func (h *DashboardsHandler) createDashboard(w http.ResponseWriter, r *http.Reque
 }
 
 func (h *DashboardsHandler) deletedashboard(w http.ResponseWriter, r *http.Request) {
-	var ctx = r.Context()
+	var ctx = versionContext(r)
 
 	id, err := idFromPath(r)
 	if err != nil {
@@ -161,7 +196,7 @@ func decodeDashboardUpdate(r *http.Request) (manta.DashboardUpdate, error) {
 
 func (h *DashboardsHandler) updateMeta(w http.ResponseWriter, r *http.Request) {
 	var (
-		ctx = r.Context()
+		ctx = versionContext(r)
 	)
 
 	upd, err := decodeDashboardUpdate(r)
@@ -193,7 +228,7 @@ func decodeCreateCell(r *http.Request) (*manta.Cell, error) {
 }
 
 func (h *DashboardsHandler) addCell(w http.ResponseWriter, r *http.Request) {
-	var ctx = r.Context()
+	var ctx = versionContext(r)
 
 	id, err := idFromPath(r)
 	if err != nil {
@@ -270,7 +305,7 @@ func decodeCellUpdate(r *http.Request) (*manta.DashboardCellUpdate, error) {
 }
 
 func (h *DashboardsHandler) updateCell(w http.ResponseWriter, r *http.Request) {
-	ctx := r.Context()
+	ctx := versionContext(r)
 
 	upd, err := decodeCellUpdate(r)
 	if err != nil {
@@ -318,7 +353,7 @@ func decodeCells(r *http.Request) ([]manta.Cell, error) {
 }
 
 func (h *DashboardsHandler) replaceCells(w http.ResponseWriter, r *http.Request) {
-	ctx := r.Context()
+	ctx := versionContext(r)
 
 	id, err := idFromPath(r)
 	if err != nil {
@@ -350,7 +385,7 @@ func (h *DashboardsHandler) replaceCells(w http.ResponseWriter, r *http.Request)
 }
 
 func (h *DashboardsHandler) deleteCell(w http.ResponseWriter, r *http.Request) {
-	var ctx = r.Context()
+	var ctx = versionContext(r)
 
 	did, err := idFromPath(r)
 	if err != nil {
@@ -383,3 +418,223 @@ func (h *DashboardsHandler) changes(w http.ResponseWriter, r *http.Request) {
 		logEncodingError(h.logger, r, err)
 	}
 }
+
+// variablePrefix is the prefix of the queries selecting options of
+// variables, e.g. var-job=node&var-job=prometheus
+const variablePrefix = "var-"
+
+type resolvedDashboard struct {
+	Variables []manta.VariableValue `json:"variables"`
+	Cells     []manta.Cell          `json:"cells"`
+}
+
+// resolveVariables resolves the variables of the dashboard in the time range,
+// and returns the cells whose queries are interpolated
+func (h *DashboardsHandler) resolveVariables(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	id, err := idFromPath(r)
+	if err != nil {
+		h.HandleHTTPError(ctx, err, w)
+		return
+	}
+
+	now := time.Now()
+	end, err := parseTimeParam(r, "end", now)
+	if err != nil {
+		h.HandleHTTPError(ctx, &manta.Error{Code: manta.EInvalid, Msg: "invalid end", Err: err}, w)
+		return
+	}
+
+	start, err := parseTimeParam(r, "start", end.Add(-time.Hour))
+	if err != nil {
+		h.HandleHTTPError(ctx, &manta.Error{Code: manta.EInvalid, Msg: "invalid start", Err: err}, w)
+		return
+	}
+
+	if end.Before(start) {
+		h.HandleHTTPError(ctx, &manta.Error{Code: manta.EInvalid, Msg: "end is before start"}, w)
+		return
+	}
+
+	selected := make(map[string][]string)
+	for key, values := range r.URL.Query() {
+		if name := strings.TrimPrefix(key, variablePrefix); name != key {
+			selected[name] = values
+		}
+	}
+
+	d, err := h.dashboardService.FindDashboardByID(ctx, id)
+	if err != nil {
+		h.HandleHTTPError(ctx, err, w)
+		return
+	}
+
+	values, err := h.resolver.Resolve(ctx, d.OrgID, d.Variables, selected, start, end)
+	if err != nil {
+		h.HandleHTTPError(ctx, err, w)
+		return
+	}
+
+	err = h.EncodeResponse(ctx, w, http.StatusOK, resolvedDashboard{
+		Variables: values,
+		Cells:     variable.InterpolateCells(d.Cells, values),
+	})
+	if err != nil {
+		logEncodingError(h.logger, r, err)
+	}
+}
+
+func (h *DashboardsHandler) listVersions(w http.ResponseWriter, r *http.Request) {
+	var ctx = r.Context()
+
+	id, err := idFromPath(r)
+	if err != nil {
+		h.HandleHTTPError(ctx, err, w)
+		return
+	}
+
+	versions, err := h.dashboardService.FindDashboardVersions(ctx, id)
+	if err != nil {
+		h.HandleHTTPError(ctx, err, w)
+		return
+	}
+
+	if err = h.EncodeResponse(ctx, w, http.StatusOK, versions); err != nil {
+		logEncodingError(h.logger, r, err)
+	}
+}
+
+func parseDashboardVersion(text string) (uint64, error) {
+	version, err := strconv.ParseUint(text, 10, 64)
+	if err != nil {
+		return 0, &manta.Error{
+			Code: manta.EInvalid,
+			Msg:  "invalid version",
+			Err:  err,
+		}
+	}
+
+	return version, nil
+}
+
+func (h *DashboardsHandler) getVersion(w http.ResponseWriter, r *http.Request) {
+	var ctx = r.Context()
+
+	id, err := idFromPath(r)
+	if err != nil {
+		h.HandleHTTPError(ctx, err, w)
+		return
+	}
+
+	version, err := parseDashboardVersion(extractParamFromContext(ctx, "version"))
+	if err != nil {
+		h.HandleHTTPError(ctx, err, w)
+		return
+	}
+
+	ver, err := h.dashboardService.FindDashboardVersion(ctx, id, version)
+	if err != nil {
+		h.HandleHTTPError(ctx, err, w)
+		return
+	}
+
+	if err = h.EncodeResponse(ctx, w, http.StatusOK, ver); err != nil {
+		logEncodingError(h.logger, r, err)
+	}
+}
+
+// diffVersions diffs the cells of version from and to, to is the latest
+// version if not specified.
+func (h *DashboardsHandler) diffVersions(w http.ResponseWriter, r *http.Request) {
+	var (
+		ctx   = r.Context()
+		query = r.URL.Query()
+	)
+
+	id, err := idFromPath(r)
+	if err != nil {
+		h.HandleHTTPError(ctx, err, w)
+		return
+	}
+
+	from, err := parseDashboardVersion(query.Get("from"))
+	if err != nil {
+		h.HandleHTTPError(ctx, err, w)
+		return
+	}
+
+	fromVer, err := h.dashboardService.FindDashboardVersion(ctx, id, from)
+	if err != nil {
+		h.HandleHTTPError(ctx, err, w)
+		return
+	}
+
+	var toVer *manta.DashboardVersion
+	if text := query.Get("to"); text != "" {
+		var to uint64
+		if to, err = parseDashboardVersion(text); err == nil {
+			toVer, err = h.dashboardService.FindDashboardVersion(ctx, id, to)
+		}
+	} else {
+		var versions []*manta.DashboardVersion
+		if versions, err = h.dashboardService.FindDashboardVersions(ctx, id); err == nil {
+			toVer = versions[0]
+		}
+	}
+	if err != nil {
+		h.HandleHTTPError(ctx, err, w)
+		return
+	}
+
+	diff, err := manta.DiffDashboardVersions(fromVer, toVer)
+	if err != nil {
+		h.HandleHTTPError(ctx, err, w)
+		return
+	}
+
+	if err = h.EncodeResponse(ctx, w, http.StatusOK, diff); err != nil {
+		logEncodingError(h.logger, r, err)
+	}
+}
+
+// restoreDashboard restores the dashboard to the version, deleted dashboards
+// are recreated
+func (h *DashboardsHandler) restoreDashboard(w http.ResponseWriter, r *http.Request) {
+	var (
+		ctx = r.Context()
+		req struct {
+			Version uint64 `json:"version"`
+			Message string `json:"message"`
+		}
+	)
+
+	id, err := idFromPath(r)
+	if err != nil {
+		h.HandleHTTPError(ctx, err, w)
+		return
+	}
+
+	if err = json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.HandleHTTPError(ctx, &manta.Error{
+			Code: manta.EInvalid,
+			Msg:  "decode restore request failed",
+			Err:  err,
+		}, w)
+		return
+	}
+
+	if req.Message != "" {
+		ctx = manta.WithVersionMessage(ctx, req.Message)
+	}
+
+	d, err := h.dashboardService.RestoreDashboard(ctx, id, req.Version)
+	if err != nil {
+		h.HandleHTTPError(ctx, err, w)
+		return
+	}
+
+	if err = h.EncodeResponse(ctx, w, http.StatusOK, d); err != nil {
+		logEncodingError(h.logger, r, err)
+	}
+}
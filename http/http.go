@@ -7,12 +7,17 @@ import (
 	"strings"
 
 	"github.com/julienschmidt/httprouter"
+	"github.com/prometheus/prometheus/promql"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 
 	"github.com/f1shl3gs/manta"
 	"github.com/f1shl3gs/manta/http/middleware"
 	"github.com/f1shl3gs/manta/http/router"
+	"github.com/f1shl3gs/manta/identity"
+	"github.com/f1shl3gs/manta/identity/ldap"
+	"github.com/f1shl3gs/manta/identity/oidc"
+	"github.com/f1shl3gs/manta/kv"
 	"github.com/f1shl3gs/manta/multitsdb"
 	"github.com/f1shl3gs/manta/raftstore"
 	"github.com/f1shl3gs/manta/telemetry/prom"
@@ -30,6 +35,7 @@ type Backend struct {
 	Flusher Flusher
 
 	router       *router.Router
+	queryEngine  *promql.Engine
 	PromRegistry *prom.Registry
 
 	BackupService               manta.BackupService
@@ -37,6 +43,7 @@ type Backend struct {
 	DashboardService            manta.DashboardService
 	UserService                 manta.UserService
 	PasswordService             manta.PasswordService
+	PasswordResetService        manta.PasswordResetService
 	AuthorizationService        manta.AuthorizationService
 	SessionService              manta.SessionService
 	OnBoardingService           manta.OnBoardingService
@@ -48,12 +55,29 @@ type Backend struct {
 	NotificationEndpointService manta.NotificationEndpointService
 	SecretService               manta.SecretService
 	TemplateService             manta.TemplateService
+	UserResourceMappingService  manta.UserResourceMappingService
+	RoleService                 manta.RoleService
 	OperationLogService         manta.OperationLogService
+	AuditService                manta.AuditService
+	LockoutService              manta.LockoutService
+
+	// OIDCProvider enables the OpenID Connect login if set, users are
+	// provisioned by OIDCProvisioner
+	OIDCProvider    *oidc.Provider
+	OIDCProvisioner *identity.Provisioner
+
+	// LDAPProvider authenticates users in LDAP if set, users are
+	// provisioned by LDAPProvisioner
+	LDAPProvider    *ldap.Provider
+	LDAPProvisioner *identity.Provisioner
 
 	TenantStorage         multitsdb.TenantStorage
 	TenantTargetRetriever multitsdb.TenantTargetRetriever
 
 	ClusterService raftstore.ClusterService
+
+	// Watcher notifies changes committed to the store
+	Watcher kv.Watcher
 }
 
 type Service struct {
@@ -104,10 +128,12 @@ func New(logger *zap.Logger, backend *Backend) *Service {
 	if logger.Core().Enabled(zapcore.DebugLevel) {
 		backend.router.Use(middleware.Logging(logger))
 	}
+	backend.queryEngine = newQueryEngine(logger)
 
 	NewOrganizationHandler(backend, logger)
+	NewRoleHandler(backend, logger)
 	NewSetupHandler(backend, logger)
-	NewSessionHandler(backend.router, logger, backend.UserService, backend.PasswordService, backend.SessionService)
+	NewSessionHandler(backend, logger)
 	NewFlushHandler(logger, backend)
 	NewDashboardsHandler(backend, logger)
 	NewUserHandler(backend, logger)
@@ -120,14 +146,20 @@ func New(logger *zap.Logger, backend *Backend) *Service {
 	NewSecretHandler(logger, backend)
 	NewNotificationEendpointHandler(logger, backend)
 	NewClusterServiceHandler(logger, backend)
-	NewOperationLogHandler(backend)
+	NewOperationLogHandler(backend, logger)
+	NewAuditHandler(backend, logger)
 	NewBuildInfoHandler(backend, logger)
+	if backend.OIDCProvider != nil {
+		NewOIDCHandler(backend, logger)
+	}
 
 	ah := &AuthenticationHandler{
 		logger:               logger.With(zap.String("handler", "authentication")),
 		AuthorizationService: backend.AuthorizationService,
 		UserService:          backend.UserService,
 		SessionService:       backend.SessionService,
+		AuditService:         backend.AuditService,
+		tokenUses:            newTokenUses(tokenUseAuditInterval),
 		noAuthRouter:         httprouter.New(),
 		handler:              backend.router,
 		errorHandler:         backend.router,
@@ -136,6 +168,9 @@ func New(logger *zap.Logger, backend *Backend) *Service {
 	ah.RegisterNoAuthRoute(http.MethodPost, setupPath)
 	ah.RegisterNoAuthRoute(http.MethodGet, setupPath)
 	ah.RegisterNoAuthRoute(http.MethodPost, signinPath)
+	ah.RegisterNoAuthRoute(http.MethodPost, passwordResetPath)
+	ah.RegisterNoAuthRoute(http.MethodGet, oidcLoginPath)
+	ah.RegisterNoAuthRoute(http.MethodGet, oidcCallbackPath)
 	ah.RegisterNoAuthRoute(http.MethodGet, "/")
 	ah.RegisterNoAuthRoute(http.MethodGet, "/debug/*wild")
 	// TODO: add auth in the future
@@ -30,13 +30,17 @@ func NewTestHTTPService(t *testing.T) (*Service, *Backend) {
 
 	service := kv.NewService(logger, store)
 	backend := &Backend{
-		router:              router.New(),
-		OrganizationService: service,
-		UserService:         service,
-		OnBoardingService:   service,
-		PasswordService:     service,
-		SessionService:      service,
-		PromRegistry:        prom.NewRegistry(logger),
+		router:               router.New(),
+		OrganizationService:  service,
+		UserService:          service,
+		OnBoardingService:    service,
+		PasswordService:      service,
+		SessionService:       service,
+		AuthorizationService: service,
+		AuditService:         service,
+		LockoutService:       service,
+		PromRegistry:         prom.NewRegistry(logger),
+		Watcher:              store,
 	}
 
 	// This is very trick, this will deletedashboard the data file, and
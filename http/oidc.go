@@ -0,0 +1,185 @@
+package http
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+
+	"go.uber.org/zap"
+
+	"github.com/f1shl3gs/manta"
+	"github.com/f1shl3gs/manta/http/router"
+	"github.com/f1shl3gs/manta/identity"
+	"github.com/f1shl3gs/manta/identity/oidc"
+)
+
+const (
+	oidcPrefix       = apiV1Prefix + "/oidc"
+	oidcLoginPath    = oidcPrefix + "/login"
+	oidcCallbackPath = oidcPrefix + "/callback"
+
+	// oidcCookieKey keeps the state, nonce and code verifier of the login
+	// attempt until the OpenID provider redirects back
+	oidcCookieKey    = "manta_oidc"
+	oidcCookieMaxAge = 10 * 60
+
+	oidcProviderName = "oidc"
+)
+
+type OIDCHandler struct {
+	*router.Router
+
+	logger         *zap.Logger
+	provider       *oidc.Provider
+	provisioner    *identity.Provisioner
+	sessionService manta.SessionService
+	auditService   manta.AuditService
+}
+
+func NewOIDCHandler(backend *Backend, logger *zap.Logger) *OIDCHandler {
+	h := &OIDCHandler{
+		Router:         backend.router,
+		logger:         logger.With(zap.String("handler", "oidc")),
+		provider:       backend.OIDCProvider,
+		provisioner:    backend.OIDCProvisioner,
+		sessionService: backend.SessionService,
+		auditService:   backend.AuditService,
+	}
+
+	h.HandlerFunc(http.MethodGet, oidcLoginPath, h.handleLogin)
+	h.HandlerFunc(http.MethodGet, oidcCallbackPath, h.handleCallback)
+
+	return h
+}
+
+// handleLogin redirects the user to the OpenID provider
+func (h *OIDCHandler) handleLogin(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	req, err := oidc.NewAuthRequest()
+	if err != nil {
+		h.HandleHTTPError(ctx, err, w)
+		return
+	}
+
+	u, err := h.provider.AuthCodeURL(ctx, req)
+	if err != nil {
+		h.HandleHTTPError(ctx, err, w)
+		return
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     oidcCookieKey,
+		Value:    strings.Join([]string{req.State, req.Nonce, req.Verifier}, "."),
+		Path:     oidcPrefix,
+		MaxAge:   oidcCookieMaxAge,
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		// the callback is a cross site redirect from the OpenID provider
+		SameSite: http.SameSiteLaxMode,
+	})
+
+	http.Redirect(w, r, u, http.StatusFound)
+}
+
+func (h *OIDCHandler) authRequest(r *http.Request) (*oidc.AuthRequest, error) {
+	cookie, err := r.Cookie(oidcCookieKey)
+	if err != nil {
+		return nil, &manta.Error{
+			Code: manta.EUnauthorized,
+			Msg:  "oidc login not started or expired",
+		}
+	}
+
+	parts := strings.Split(cookie.Value, ".")
+	if len(parts) != 3 {
+		return nil, &manta.Error{
+			Code: manta.EUnauthorized,
+			Msg:  "invalid oidc cookie",
+		}
+	}
+
+	state := r.URL.Query().Get("state")
+	if subtle.ConstantTimeCompare([]byte(state), []byte(parts[0])) != 1 {
+		return nil, &manta.Error{
+			Code: manta.EUnauthorized,
+			Msg:  "oidc state not match",
+		}
+	}
+
+	return &oidc.AuthRequest{
+		State:    parts[0],
+		Nonce:    parts[1],
+		Verifier: parts[2],
+	}, nil
+}
+
+// handleCallback exchanges the authorization code, signs in the user linked
+// to the subject, and redirects to the home page.
+func (h *OIDCHandler) handleCallback(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	// the cookie is single use
+	http.SetCookie(w, &http.Cookie{
+		Name:   oidcCookieKey,
+		Path:   oidcPrefix,
+		MaxAge: -1,
+	})
+
+	query := r.URL.Query()
+	if reason := query.Get("error"); reason != "" {
+		h.HandleHTTPError(ctx, &manta.Error{
+			Code: manta.EUnauthorized,
+			Msg:  "oidc login failed, " + reason + " " + query.Get("error_description"),
+		}, w)
+		return
+	}
+
+	req, err := h.authRequest(r)
+	if err != nil {
+		h.HandleHTTPError(ctx, err, w)
+		return
+	}
+
+	claims, err := h.provider.Exchange(ctx, query.Get("code"), req)
+	if err != nil {
+		h.HandleHTTPError(ctx, err, w)
+		return
+	}
+
+	name := claims.PreferredUsername
+	if name == "" {
+		name = claims.Name
+	}
+
+	user, err := h.provisioner.Provision(ctx, &identity.External{
+		Provider:      oidcProviderName,
+		Subject:       claims.Subject,
+		Email:         claims.Email,
+		EmailVerified: claims.EmailVerified,
+		Name:          name,
+		Groups:        claims.Groups,
+	})
+	if err != nil {
+		h.HandleHTTPError(ctx, err, w)
+		return
+	}
+
+	opts := sessionOptions(r)
+	session, err := h.sessionService.CreateSession(ctx, user.ID, opts)
+	if err != nil {
+		h.HandleHTTPError(ctx, err, w)
+		return
+	}
+
+	addAuditRecord(ctx, h.logger, h.auditService, &manta.AuditRecord{
+		Action:    manta.AuditSignin,
+		UserID:    user.ID,
+		Username:  user.Name,
+		IP:        opts.IP,
+		UserAgent: opts.UserAgent,
+	})
+
+	setSessionCookie(w, session.ID)
+	http.Redirect(w, r, "/", http.StatusFound)
+}
@@ -1,16 +1,21 @@
 package http
 
 import (
+	"encoding/json"
 	"net/http"
+	"time"
 
 	"github.com/f1shl3gs/manta"
 	"github.com/f1shl3gs/manta/http/router"
+	"github.com/f1shl3gs/manta/oplog"
 
 	"go.uber.org/zap"
 )
 
 const (
-	operationLogPath = apiV1Prefix + `/oplogs/:id`
+	operationLogsPrefix = apiV1Prefix + `/oplogs`
+	operationLogPath    = operationLogsPrefix + `/:id`
+	operationLogRevert  = operationLogsPrefix + `/revert`
 )
 
 type OperationLogHandler struct {
@@ -18,15 +23,26 @@ type OperationLogHandler struct {
 	logger *zap.Logger
 
 	oplogService manta.OperationLogService
+	reverter     *oplog.Reverter
 }
 
-func NewOperationLogHandler(backend *Backend) {
+func NewOperationLogHandler(backend *Backend, logger *zap.Logger) {
 	h := &OperationLogHandler{
 		Router:       backend.router,
+		logger:       logger.With(zap.String("handler", "oplog")),
 		oplogService: backend.OperationLogService,
+		reverter: oplog.NewReverter(
+			backend.OperationLogService,
+			backend.CheckService,
+			backend.DashboardService,
+			backend.ConfigService,
+			backend.NotificationEndpointService,
+		),
 	}
 
+	h.HandlerFunc(http.MethodGet, operationLogsPrefix, h.handleFind)
 	h.HandlerFunc(http.MethodGet, operationLogPath, h.handleList)
+	h.HandlerFunc(http.MethodPost, operationLogRevert, h.handleRevert)
 }
 
 func (h *OperationLogHandler) handleList(w http.ResponseWriter, r *http.Request) {
@@ -54,6 +70,138 @@ func (h *OperationLogHandler) handleList(w http.ResponseWriter, r *http.Request)
 	}
 }
 
+// handleFind returns the operation logs match the filter of queries, the
+// timeline of an org is returned if only orgID is set.
+func (h *OperationLogHandler) handleFind(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	filter, err := decodeOperationLogFilter(r)
+	if err != nil {
+		h.HandleHTTPError(ctx, err, w)
+		return
+	}
+
+	opts, err := manta.DecodeFindOptions(r)
+	if err != nil {
+		h.HandleHTTPError(ctx, err, w)
+		return
+	}
+
+	logs, _, err := h.oplogService.FindOperationLogs(ctx, filter, opts)
+	if err != nil {
+		h.HandleHTTPError(ctx, err, w)
+		return
+	}
+
+	if wantDiff(r) {
+		if err = oplog.AttachDiffs(ctx, h.oplogService, logs); err != nil {
+			h.HandleHTTPError(ctx, err, w)
+			return
+		}
+	}
+
+	if err = h.EncodeResponse(ctx, w, http.StatusOK, logs); err != nil {
+		logEncodingError(h.logger, r, err)
+	}
+}
+
+// handleRevert restores the resource to the body of the entry, which is
+// identified by the resource id and the time of the entry.
+func (h *OperationLogHandler) handleRevert(w http.ResponseWriter, r *http.Request) {
+	var (
+		ctx = r.Context()
+		req struct {
+			ResourceID manta.ID  `json:"resourceID"`
+			Time       time.Time `json:"time"`
+		}
+	)
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.HandleHTTPError(ctx, &manta.Error{
+			Code: manta.EInvalid,
+			Msg:  "decode revert request failed",
+			Err:  err,
+		}, w)
+		return
+	}
+
+	resource, err := h.reverter.Revert(ctx, req.ResourceID, req.Time)
+	if err != nil {
+		h.HandleHTTPError(ctx, err, w)
+		return
+	}
+
+	if err = h.EncodeResponse(ctx, w, http.StatusOK, resource); err != nil {
+		logEncodingError(h.logger, r, err)
+	}
+}
+
+func decodeOperationLogFilter(r *http.Request) (manta.OperationLogFilter, error) {
+	var (
+		filter = manta.OperationLogFilter{}
+		query  = r.URL.Query()
+	)
+
+	for name, ptr := range map[string]**manta.ID{
+		"orgID":      &filter.OrgID,
+		"resourceID": &filter.ResourceID,
+		"userID":     &filter.UserID,
+	} {
+		text := query.Get(name)
+		if text == "" {
+			continue
+		}
+
+		id := new(manta.ID)
+		if err := id.DecodeFromString(text); err != nil {
+			return filter, &manta.Error{
+				Code: manta.EInvalid,
+				Msg:  "invalid " + name,
+				Err:  err,
+			}
+		}
+
+		*ptr = id
+	}
+
+	for name, ptr := range map[string]**time.Time{
+		"since": &filter.Since,
+		"until": &filter.Until,
+	} {
+		text := query.Get(name)
+		if text == "" {
+			continue
+		}
+
+		ts, err := time.Parse(time.RFC3339Nano, text)
+		if err != nil {
+			return filter, &manta.Error{
+				Code: manta.EInvalid,
+				Msg:  "invalid " + name + ", RFC3339 time is required",
+				Err:  err,
+			}
+		}
+
+		*ptr = &ts
+	}
+
+	if text := query.Get("resourceType"); text != "" {
+		rt := manta.ResourceType(text)
+		filter.ResourceType = &rt
+	}
+
+	if text := query.Get("type"); text != "" {
+		typ := manta.ChangeType(text)
+		filter.Type = &typ
+	}
+
+	return filter, nil
+}
+
+func wantDiff(r *http.Request) bool {
+	return r.URL.Query().Get("diff") == "true"
+}
+
 func findOplogByResourceID(
 	r *http.Request,
 	oplogService manta.OperationLogService,
@@ -70,5 +218,16 @@ func findOplogByResourceID(
 		return nil, 0, err
 	}
 
-	return oplogService.FindOperationLogsByID(ctx, id, opts)
+	logs, n, err := oplogService.FindOperationLogsByID(ctx, id, opts)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if wantDiff(r) {
+		if err = oplog.AttachDiffs(ctx, oplogService, logs); err != nil {
+			return nil, 0, err
+		}
+	}
+
+	return logs, n, nil
 }
@@ -1,6 +1,7 @@
 package http
 
 import (
+	"context"
 	"encoding/json"
 	"net/http"
 
@@ -13,7 +14,10 @@ import (
 const (
 	organizationPrefix = apiV1Prefix + "/organizations"
 
-	organizationWithID = organizationPrefix + "/:orgID"
+	organizationWithID = organizationPrefix + "/:id"
+
+	organizationMembers    = organizationWithID + "/members"
+	organizationMemberWith = organizationMembers + "/:userID"
 )
 
 type OrganizationHandler struct {
@@ -21,6 +25,8 @@ type OrganizationHandler struct {
 
 	logger              *zap.Logger
 	organizationService manta.OrganizationService
+	userService         manta.UserService
+	urmService          manta.UserResourceMappingService
 }
 
 func NewOrganizationHandler(backend *Backend, logger *zap.Logger) *OrganizationHandler {
@@ -28,13 +34,21 @@ func NewOrganizationHandler(backend *Backend, logger *zap.Logger) *OrganizationH
 		Router:              backend.router,
 		logger:              logger.With(zap.String("handler", "organization")),
 		organizationService: backend.OrganizationService,
+		userService:         backend.UserService,
+		urmService:          backend.UserResourceMappingService,
 	}
 
 	h.HandlerFunc(http.MethodGet, organizationPrefix, h.listOrganizations)
 	h.HandlerFunc(http.MethodGet, organizationWithID, h.getOrganization)
 	h.HandlerFunc(http.MethodPost, organizationPrefix, h.createOrganization)
+	h.HandlerFunc(http.MethodPatch, organizationWithID, h.updateOrganization)
 	h.HandlerFunc(http.MethodDelete, organizationWithID, h.deleteOrganization)
 
+	// members
+	h.HandlerFunc(http.MethodGet, organizationMembers, h.listMembers)
+	h.HandlerFunc(http.MethodPost, organizationMembers, h.addMember)
+	h.HandlerFunc(http.MethodDelete, organizationMemberWith, h.removeMember)
+
 	return h
 }
 
@@ -77,8 +91,250 @@ func (h *OrganizationHandler) getOrganization(w http.ResponseWriter, r *http.Req
 	}
 }
 
+func (h *OrganizationHandler) updateOrganization(w http.ResponseWriter, r *http.Request) {
+	var (
+		ctx = r.Context()
+		upd struct {
+			Name *string `json:"name"`
+			Desc *string `json:"desc"`
+		}
+	)
+
+	id, err := idFromPath(r)
+	if err != nil {
+		h.HandleHTTPError(ctx, err, w)
+		return
+	}
+
+	if err = json.NewDecoder(r.Body).Decode(&upd); err != nil {
+		h.HandleHTTPError(ctx, &manta.Error{
+			Code: manta.EInvalid,
+			Msg:  "decode organization update failed",
+			Err:  err,
+		}, w)
+		return
+	}
+
+	org, err := h.organizationService.UpdateOrganization(ctx, id, manta.OrganizationUpdate{
+		Name:        upd.Name,
+		Description: upd.Desc,
+	})
+	if err != nil {
+		h.HandleHTTPError(ctx, err, w)
+		return
+	}
+
+	if err = h.EncodeResponse(ctx, w, http.StatusOK, org); err != nil {
+		logEncodingError(h.logger, r, err)
+	}
+}
+
+// deleteOrganization deletes the organization and all resources of it
 func (h *OrganizationHandler) deleteOrganization(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	id, err := idFromPath(r)
+	if err != nil {
+		h.HandleHTTPError(ctx, err, w)
+		return
+	}
+
+	if err = h.organizationService.DeleteOrganization(ctx, id); err != nil {
+		h.HandleHTTPError(ctx, err, w)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+type member struct {
+	UserID   manta.ID       `json:"userID"`
+	Name     string         `json:"name,omitempty"`
+	UserType manta.UserType `json:"userType"`
+	Role     string         `json:"role,omitempty"`
+}
+
+func (h *OrganizationHandler) findMembers(ctx context.Context, orgID manta.ID) ([]*manta.UserResourceMapping, error) {
+	mappings, _, err := h.urmService.FindUserResourceMappings(ctx, manta.UserResourceMappingFilter{
+		ResourceID:   orgID,
+		ResourceType: manta.OrgsResourceType,
+	}, manta.FindOptions{})
+
+	return mappings, err
+}
+
+func (h *OrganizationHandler) listMembers(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	id, err := idFromPath(r)
+	if err != nil {
+		h.HandleHTTPError(ctx, err, w)
+		return
+	}
+
+	mappings, err := h.findMembers(ctx, id)
+	if err != nil {
+		h.HandleHTTPError(ctx, err, w)
+		return
+	}
+
+	members := make([]member, 0, len(mappings))
+	for _, m := range mappings {
+		mb := member{
+			UserID:   m.UserID,
+			UserType: m.UserType,
+			Role:     m.Role,
+		}
+
+		user, err := h.userService.FindUserByID(ctx, m.UserID)
+		if err != nil && manta.ErrorCode(err) != manta.ENotFound {
+			h.HandleHTTPError(ctx, err, w)
+			return
+		}
+
+		if user != nil {
+			mb.Name = user.Name
+		}
+
+		members = append(members, mb)
+	}
+
+	if err = h.EncodeResponse(ctx, w, http.StatusOK, members); err != nil {
+		logEncodingError(h.logger, r, err)
+	}
+}
+
+// addMember invites an existing user, which is identified by ID or name, to
+// the organization, the user type and role are updated if the user is a
+// member already.
+func (h *OrganizationHandler) addMember(w http.ResponseWriter, r *http.Request) {
+	var (
+		ctx = r.Context()
+		req struct {
+			UserID   *manta.ID      `json:"userID"`
+			Name     string         `json:"name"`
+			UserType manta.UserType `json:"userType"`
+			Role     string         `json:"role"`
+		}
+	)
+
+	id, err := idFromPath(r)
+	if err != nil {
+		h.HandleHTTPError(ctx, err, w)
+		return
+	}
+
+	if err = json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.HandleHTTPError(ctx, &manta.Error{
+			Code: manta.EInvalid,
+			Msg:  "decode member failed",
+			Err:  err,
+		}, w)
+		return
+	}
+
+	if req.UserType == "" {
+		req.UserType = manta.Member
+	}
+
+	if err = req.UserType.Valid(); err != nil {
+		h.HandleHTTPError(ctx, &manta.Error{
+			Code: manta.EInvalid,
+			Msg:  "invalid user type",
+			Err:  err,
+		}, w)
+		return
+	}
+
+	var user *manta.User
+	switch {
+	case req.UserID != nil:
+		user, err = h.userService.FindUserByID(ctx, *req.UserID)
+	case req.Name != "":
+		user, err = h.userService.FindUser(ctx, manta.UserFilter{Name: &req.Name})
+	default:
+		err = &manta.Error{
+			Code: manta.EInvalid,
+			Msg:  "userID or name is required",
+		}
+	}
+	if err != nil {
+		h.HandleHTTPError(ctx, err, w)
+		return
+	}
+
+	err = h.urmService.CreateUserResourceMapping(ctx, &manta.UserResourceMapping{
+		UserID:       user.ID,
+		UserType:     req.UserType,
+		MappingType:  manta.UserMappingType,
+		ResourceType: manta.OrgsResourceType,
+		ResourceID:   id,
+		Role:         req.Role,
+	})
+	if err != nil {
+		h.HandleHTTPError(ctx, err, w)
+		return
+	}
+
+	err = h.EncodeResponse(ctx, w, http.StatusCreated, member{
+		UserID:   user.ID,
+		Name:     user.Name,
+		UserType: req.UserType,
+		Role:     req.Role,
+	})
+	if err != nil {
+		logEncodingError(h.logger, r, err)
+	}
+}
+
+// removeMember removes the user from the organization, the last owner can't
+// be removed, otherwise nobody can manage the organization, which is ensured
+// by the UserResourceMappingService.
+func (h *OrganizationHandler) removeMember(w http.ResponseWriter, r *http.Request) {
+	var (
+		ctx    = r.Context()
+		userID manta.ID
+	)
+
+	id, err := idFromPath(r)
+	if err != nil {
+		h.HandleHTTPError(ctx, err, w)
+		return
+	}
+
+	if err = userID.DecodeFromString(extractParamFromContext(ctx, "userID")); err != nil {
+		h.HandleHTTPError(ctx, err, w)
+		return
+	}
+
+	mappings, err := h.findMembers(ctx, id)
+	if err != nil {
+		h.HandleHTTPError(ctx, err, w)
+		return
+	}
+
+	found := false
+	for _, m := range mappings {
+		if m.UserID == userID {
+			found = true
+			break
+		}
+	}
+
+	if !found {
+		h.HandleHTTPError(ctx, &manta.Error{
+			Code: manta.ENotFound,
+			Msg:  "member not found",
+		}, w)
+		return
+	}
+
+	if err = h.urmService.DeleteUserResourceMapping(ctx, id, userID); err != nil {
+		h.HandleHTTPError(ctx, err, w)
+		return
+	}
 
+	w.WriteHeader(http.StatusNoContent)
 }
 
 func (h *OrganizationHandler) createOrganization(w http.ResponseWriter, r *http.Request) {
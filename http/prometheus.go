@@ -67,8 +67,10 @@ type PromAPIHandler struct {
 	tenantTargetRetriever multitsdb.TenantTargetRetriever
 }
 
-func NewPromAPIHandler(backend *Backend, logger *zap.Logger) {
-	engOpts := promql.EngineOpts{
+// newQueryEngine creates the PromQL engine shared by handlers, there must be
+// only one engine, since it registers metrics
+func newQueryEngine(logger *zap.Logger) *promql.Engine {
+	return promql.NewEngine(promql.EngineOpts{
 		Logger:        log.NewZapToGokitLogAdapter(logger.With(zap.String("handler", "prom_api"))),
 		Reg:           prometheus.DefaultRegisterer,
 		MaxSamples:    50000000,
@@ -77,16 +79,17 @@ func NewPromAPIHandler(backend *Backend, logger *zap.Logger) {
 		NoStepSubqueryIntervalFn: func(rangeMillis int64) int64 {
 			return time.Minute.Milliseconds()
 		},
-	}
-	engine := promql.NewEngine(engOpts)
+	})
+}
 
+func NewPromAPIHandler(backend *Backend, logger *zap.Logger) {
 	h := &PromAPIHandler{
 		Router: backend.router,
 		logger: logger.With(zap.String("handler", "prometheus")),
 
 		tenantStorage:         backend.TenantStorage,
 		tenantTargetRetriever: backend.TenantTargetRetriever,
-		engine:                engine,
+		engine:                backend.queryEngine,
 		now:                   time.Now,
 	}
 
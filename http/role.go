@@ -0,0 +1,158 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"go.uber.org/zap"
+
+	"github.com/f1shl3gs/manta"
+	"github.com/f1shl3gs/manta/http/router"
+)
+
+const (
+	organizationRoles    = organizationWithID + "/roles"
+	organizationRoleWith = organizationRoles + "/:name"
+)
+
+type RoleHandler struct {
+	*router.Router
+
+	logger      *zap.Logger
+	roleService manta.RoleService
+}
+
+func NewRoleHandler(backend *Backend, logger *zap.Logger) *RoleHandler {
+	h := &RoleHandler{
+		Router:      backend.router,
+		logger:      logger.With(zap.String("handler", "role")),
+		roleService: backend.RoleService,
+	}
+
+	h.HandlerFunc(http.MethodGet, organizationRoles, h.handleList)
+	h.HandlerFunc(http.MethodPost, organizationRoles, h.handleCreate)
+	h.HandlerFunc(http.MethodGet, organizationRoleWith, h.handleGet)
+	h.HandlerFunc(http.MethodPatch, organizationRoleWith, h.handleUpdate)
+	h.HandlerFunc(http.MethodDelete, organizationRoleWith, h.handleDelete)
+
+	return h
+}
+
+func (h *RoleHandler) handleList(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	orgID, err := idFromPath(r)
+	if err != nil {
+		h.HandleHTTPError(ctx, err, w)
+		return
+	}
+
+	roles, err := h.roleService.FindRoles(ctx, orgID)
+	if err != nil {
+		h.HandleHTTPError(ctx, err, w)
+		return
+	}
+
+	if err = h.EncodeResponse(ctx, w, http.StatusOK, roles); err != nil {
+		logEncodingError(h.logger, r, err)
+	}
+}
+
+func (h *RoleHandler) handleCreate(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	orgID, err := idFromPath(r)
+	if err != nil {
+		h.HandleHTTPError(ctx, err, w)
+		return
+	}
+
+	role := &manta.Role{}
+	if err = json.NewDecoder(r.Body).Decode(role); err != nil {
+		h.HandleHTTPError(ctx, &manta.Error{
+			Code: manta.EInvalid,
+			Msg:  "decode role failed",
+			Err:  err,
+		}, w)
+		return
+	}
+
+	role.OrgID = orgID
+	if err = h.roleService.CreateRole(ctx, role); err != nil {
+		h.HandleHTTPError(ctx, err, w)
+		return
+	}
+
+	if err = h.EncodeResponse(ctx, w, http.StatusCreated, role); err != nil {
+		logEncodingError(h.logger, r, err)
+	}
+}
+
+func (h *RoleHandler) handleGet(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	orgID, err := idFromPath(r)
+	if err != nil {
+		h.HandleHTTPError(ctx, err, w)
+		return
+	}
+
+	role, err := h.roleService.FindRole(ctx, orgID, extractParamFromContext(ctx, "name"))
+	if err != nil {
+		h.HandleHTTPError(ctx, err, w)
+		return
+	}
+
+	if err = h.EncodeResponse(ctx, w, http.StatusOK, role); err != nil {
+		logEncodingError(h.logger, r, err)
+	}
+}
+
+func (h *RoleHandler) handleUpdate(w http.ResponseWriter, r *http.Request) {
+	var (
+		ctx = r.Context()
+		upd manta.RoleUpdate
+	)
+
+	orgID, err := idFromPath(r)
+	if err != nil {
+		h.HandleHTTPError(ctx, err, w)
+		return
+	}
+
+	if err = json.NewDecoder(r.Body).Decode(&upd); err != nil {
+		h.HandleHTTPError(ctx, &manta.Error{
+			Code: manta.EInvalid,
+			Msg:  "decode role update failed",
+			Err:  err,
+		}, w)
+		return
+	}
+
+	role, err := h.roleService.UpdateRole(ctx, orgID, extractParamFromContext(ctx, "name"), upd)
+	if err != nil {
+		h.HandleHTTPError(ctx, err, w)
+		return
+	}
+
+	if err = h.EncodeResponse(ctx, w, http.StatusOK, role); err != nil {
+		logEncodingError(h.logger, r, err)
+	}
+}
+
+func (h *RoleHandler) handleDelete(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	orgID, err := idFromPath(r)
+	if err != nil {
+		h.HandleHTTPError(ctx, err, w)
+		return
+	}
+
+	if err = h.roleService.DeleteRole(ctx, orgID, extractParamFromContext(ctx, "name")); err != nil {
+		h.HandleHTTPError(ctx, err, w)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
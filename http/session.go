@@ -3,12 +3,17 @@ package http
 import (
 	"context"
 	"encoding/json"
+	"net"
 	"net/http"
 
 	"go.uber.org/zap"
 
 	"github.com/f1shl3gs/manta"
+	"github.com/f1shl3gs/manta/authorizer"
 	"github.com/f1shl3gs/manta/http/router"
+	"github.com/f1shl3gs/manta/identity"
+	"github.com/f1shl3gs/manta/identity/ldap"
+	"github.com/f1shl3gs/manta/kv"
 )
 
 const (
@@ -26,21 +31,24 @@ type SessionHandler struct {
 	userService     manta.UserService
 	passwordService manta.PasswordService
 	sessionService  manta.SessionService
+	auditService    manta.AuditService
+	lockoutService  manta.LockoutService
+
+	ldapProvider    *ldap.Provider
+	ldapProvisioner *identity.Provisioner
 }
 
-func NewSessionHandler(
-	router *router.Router,
-	logger *zap.Logger,
-	userService manta.UserService,
-	passwordService manta.PasswordService,
-	sessionService manta.SessionService,
-) *SessionHandler {
+func NewSessionHandler(backend *Backend, logger *zap.Logger) *SessionHandler {
 	h := &SessionHandler{
-		Router:          router,
+		Router:          backend.router,
 		logger:          logger.With(zap.String("handler", "session")),
-		userService:     userService,
-		passwordService: passwordService,
-		sessionService:  sessionService,
+		userService:     backend.UserService,
+		passwordService: backend.PasswordService,
+		sessionService:  backend.SessionService,
+		auditService:    backend.AuditService,
+		lockoutService:  backend.LockoutService,
+		ldapProvider:    backend.LDAPProvider,
+		ldapProvisioner: backend.LDAPProvisioner,
 	}
 
 	h.HandlerFunc(http.MethodPost, signinPath, h.handleSignin)
@@ -77,36 +85,114 @@ func (h *SessionHandler) handleSignin(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	u, err := h.userService.FindUser(ctx, manta.UserFilter{
-		Name: &sr.Username,
-	})
+	opts := sessionOptions(r)
+	failed := func(reason string) {
+		addAuditRecord(ctx, h.logger, h.auditService, &manta.AuditRecord{
+			Action:    manta.AuditSigninFailed,
+			Username:  sr.Username,
+			IP:        opts.IP,
+			UserAgent: opts.UserAgent,
+			Reason:    reason,
+		})
+	}
 
+	err = h.lockoutService.AttemptSignin(ctx, sr.Username, opts.IP)
 	if err != nil {
+		if err == manta.ErrSigninLocked {
+			failed("locked out")
+		}
+
 		h.HandleHTTPError(ctx, err, w)
 		return
 	}
 
-	err = h.passwordService.ComparePassword(ctx, u.ID, sr.Password)
+	u, err := h.authenticate(ctx, sr)
+	if manta.ErrorCode(err) == manta.EUnauthorized {
+		// the attempt is counted as a failure already
+		failed("invalid username or password")
+
+		// don't tell whether the user exists
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
 	if err != nil {
-		if err == manta.ErrPasswordNotMatch {
-			w.WriteHeader(http.StatusUnauthorized)
-			return
+		if manta.ErrorCode(err) == manta.EForbidden {
+			failed(manta.ErrorMessage(err))
 		}
 
 		h.HandleHTTPError(ctx, err, w)
 		return
 	}
 
-	session, err := h.sessionService.CreateSession(ctx, u.ID)
+	session, err := h.sessionService.CreateSession(ctx, u.ID, opts)
 	if err != nil {
 		h.HandleHTTPError(ctx, err, w)
 		return
 	}
 
-	err = h.encodeCookie(ctx, w, session.ID)
+	if err = h.lockoutService.SigninSucceeded(ctx, sr.Username, opts.IP); err != nil {
+		h.logger.Warn("clear failed signins failed",
+			zap.Error(err))
+	}
+
+	addAuditRecord(ctx, h.logger, h.auditService, &manta.AuditRecord{
+		Action:    manta.AuditSignin,
+		UserID:    u.ID,
+		Username:  u.Name,
+		IP:        opts.IP,
+		UserAgent: opts.UserAgent,
+	})
+
+	setSessionCookie(w, session.ID)
+}
+
+var errInvalidCredentials = &manta.Error{
+	Code: manta.EUnauthorized,
+	Msg:  "invalid username or password",
+}
+
+// authenticate returns the user if the password matches. Users in LDAP are
+// authenticated by LDAP and provisioned, the rest by their local password.
+func (h *SessionHandler) authenticate(ctx context.Context, sr *signinReq) (*manta.User, error) {
+	if h.ldapProvider != nil {
+		ext, err := h.ldapProvider.Authenticate(ctx, sr.Username, sr.Password)
+		switch manta.ErrorCode(err) {
+		case "":
+			return h.ldapProvisioner.Provision(ctx, ext)
+		case manta.ENotFound:
+		case manta.EUnavailable:
+			// local users, e.g. the onboarded one, can still sign in
+			h.logger.Warn("LDAP is unavailable, fall back to local password",
+				zap.Error(err))
+		default:
+			return nil, err
+		}
+	}
+
+	u, err := h.userService.FindUser(ctx, manta.UserFilter{
+		Name: &sr.Username,
+	})
+	if manta.ErrorCode(err) == manta.ENotFound {
+		return nil, errInvalidCredentials
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	err = h.passwordService.ComparePassword(ctx, u.ID, sr.Password)
+	// users provisioned by identity providers have no password
+	if err == manta.ErrPasswordNotMatch || kv.IsNotFound(err) {
+		return nil, errInvalidCredentials
+	}
 	if err != nil {
-		h.HandleHTTPError(ctx, err, w)
+		return nil, err
 	}
+
+	if !u.Active() {
+		return nil, manta.ErrUserDisabled
+	}
+
+	return u, nil
 }
 
 func (h *SessionHandler) handleSignout(w http.ResponseWriter, r *http.Request) {
@@ -133,10 +219,34 @@ func (h *SessionHandler) handleSignout(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if a, err := authorizer.FromContext(ctx); err == nil {
+		opts := sessionOptions(r)
+		addAuditRecord(ctx, h.logger, h.auditService, &manta.AuditRecord{
+			Action:    manta.AuditSignout,
+			UserID:    a.GetUserID(),
+			IP:        opts.IP,
+			UserAgent: opts.UserAgent,
+		})
+	}
+
 	w.WriteHeader(http.StatusNoContent)
 }
 
-func (h *SessionHandler) encodeCookie(ctx context.Context, w http.ResponseWriter, id manta.ID) error {
+// sessionOptions returns the client of the request, proxy headers are not
+// trusted, since they can be forged.
+func sessionOptions(r *http.Request) manta.SessionOptions {
+	ip, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		ip = r.RemoteAddr
+	}
+
+	return manta.SessionOptions{
+		UserAgent: r.UserAgent(),
+		IP:        ip,
+	}
+}
+
+func setSessionCookie(w http.ResponseWriter, id manta.ID) {
 	http.SetCookie(w, &http.Cookie{
 		Name:     SessionCookieKey,
 		Value:    id.String(),
@@ -144,8 +254,6 @@ func (h *SessionHandler) encodeCookie(ctx context.Context, w http.ResponseWriter
 		// Secure:   true,
 		SameSite: http.SameSiteStrictMode,
 	})
-
-	return nil
 }
 
 func (h *SessionHandler) handleViewer(w http.ResponseWriter, r *http.Request) {
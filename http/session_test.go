@@ -0,0 +1,235 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"github.com/f1shl3gs/manta"
+	"github.com/f1shl3gs/manta/bolt"
+	"github.com/f1shl3gs/manta/http/router"
+	"github.com/f1shl3gs/manta/kv"
+	"github.com/f1shl3gs/manta/kv/migration"
+)
+
+// newTestAuthenticationHandler serves the session and user handlers only,
+// the metrics of the full service can be registered once per process
+func newTestAuthenticationHandler(t *testing.T) (http.Handler, *Backend) {
+	logger := zaptest.NewLogger(t)
+	store := bolt.NewKVStore(logger, t.TempDir()+"/manta.db", bolt.WithNoSync)
+	err := store.Open(context.Background())
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = store.Close()
+	})
+
+	err = migration.New(logger, store, migration.All...).Up(context.Background())
+	require.NoError(t, err)
+
+	service := kv.NewService(logger, store)
+	backend := &Backend{
+		router:               router.New(),
+		UserService:          service,
+		PasswordService:      service,
+		AuthorizationService: service,
+		SessionService:       service,
+		OnBoardingService:    service,
+		AuditService:         service,
+		LockoutService:       service,
+	}
+
+	NewSessionHandler(backend, logger)
+	NewUserHandler(backend, logger)
+
+	ah := &AuthenticationHandler{
+		logger:               logger,
+		AuthorizationService: backend.AuthorizationService,
+		UserService:          backend.UserService,
+		SessionService:       backend.SessionService,
+		AuditService:         backend.AuditService,
+		tokenUses:            newTokenUses(tokenUseAuditInterval),
+		noAuthRouter:         httprouter.New(),
+		handler:              backend.router,
+		errorHandler:         backend.router,
+	}
+	ah.RegisterNoAuthRoute(http.MethodPost, signinPath)
+
+	return ah, backend
+}
+
+func TestSignin(t *testing.T) {
+	var (
+		ctx              = context.Background()
+		username         = "foo"
+		password         = "password"
+		service, backend = newTestAuthenticationHandler(t)
+	)
+
+	_, err := backend.OnBoardingService.Setup(ctx, &manta.OnBoardingRequest{
+		Username:     username,
+		Password:     password,
+		Organization: "org",
+	})
+	require.NoError(t, err)
+
+	signin := func(password string) int {
+		buf := bytes.NewBuffer(nil)
+		err := json.NewEncoder(buf).Encode(&signinReq{
+			Username: username,
+			Password: password,
+		})
+		require.NoError(t, err)
+
+		r := httptest.NewRequest(http.MethodPost, signinPath, buf)
+		w := httptest.NewRecorder()
+		service.ServeHTTP(w, r)
+
+		return w.Code
+	}
+
+	require.Equal(t, http.StatusOK, signin(password))
+
+	// the username is locked out after 5 failures, even the password is right
+	for i := 0; i < 5; i++ {
+		require.Equal(t, http.StatusUnauthorized, signin("wrong"))
+	}
+	require.Equal(t, http.StatusTooManyRequests, signin(password))
+
+	err = backend.LockoutService.DeleteLockout(ctx, manta.LockoutUsername, username)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, signin(password))
+
+	records, err := backend.AuditService.FindAuditRecords(ctx, manta.AuditFilter{
+		Username: &username,
+	}, manta.FindOptions{})
+	require.NoError(t, err)
+
+	actions := make([]manta.AuditAction, 0, len(records))
+	for _, rec := range records {
+		require.Equal(t, "192.0.2.1", rec.IP)
+		actions = append(actions, rec.Action)
+	}
+	require.Equal(t, []manta.AuditAction{
+		manta.AuditSignin,
+		manta.AuditSigninFailed,
+		manta.AuditSigninFailed,
+		manta.AuditSigninFailed,
+		manta.AuditSigninFailed,
+		manta.AuditSigninFailed,
+		manta.AuditSigninFailed,
+		manta.AuditSignin,
+	}, actions)
+	require.Equal(t, "locked out", records[6].Reason)
+}
+
+func TestTokenAuthentication(t *testing.T) {
+	var (
+		ctx              = context.Background()
+		service, backend = newTestAuthenticationHandler(t)
+	)
+
+	user := &manta.User{Name: "foo"}
+	err := backend.UserService.CreateUser(ctx, user)
+	require.NoError(t, err)
+
+	auth := &manta.Authorization{
+		UID:         user.ID,
+		Status:      manta.AuthorizationActive,
+		Permissions: manta.MePermissions(user.ID),
+	}
+	err = backend.AuthorizationService.CreateAuthorization(ctx, auth)
+	require.NoError(t, err)
+
+	get := func(authorization string) int {
+		r := httptest.NewRequest(http.MethodGet, UserPrefix+"/"+user.ID.String(), nil)
+		r.Header.Set("Authorization", authorization)
+		w := httptest.NewRecorder()
+		service.ServeHTTP(w, r)
+
+		return w.Code
+	}
+
+	require.Equal(t, http.StatusOK, get("Token "+auth.Token))
+	require.Equal(t, http.StatusOK, get("Bearer "+auth.Token))
+	require.Equal(t, http.StatusUnauthorized, get("Token invalid"))
+	require.Equal(t, http.StatusUnauthorized, get("Basic "+auth.Token))
+
+	// tokens in the URL are not accepted
+	r := httptest.NewRequest(http.MethodGet, UserPrefix+"/"+user.ID.String()+"?token="+auth.Token, nil)
+	w := httptest.NewRecorder()
+	service.ServeHTTP(w, r)
+	require.Equal(t, http.StatusUnauthorized, w.Code)
+
+	// uses of the token are recorded once a minute
+	action := manta.AuditTokenUsed
+	records, err := backend.AuditService.FindAuditRecords(ctx, manta.AuditFilter{
+		Action: &action,
+	}, manta.FindOptions{})
+	require.NoError(t, err)
+	require.Len(t, records, 1)
+	require.Equal(t, auth.ID, records[0].AuthorizationID)
+	require.Equal(t, user.ID, records[0].UserID)
+
+	inactive := manta.AuthorizationInactive
+	_, err = backend.AuthorizationService.UpdateAuthorization(ctx, auth.ID, manta.UpdateAuthorization{
+		Status: &inactive,
+	})
+	require.NoError(t, err)
+	require.Equal(t, http.StatusUnauthorized, get("Token "+auth.Token))
+}
+
+func TestUserSessions(t *testing.T) {
+	var (
+		ctx              = context.Background()
+		service, backend = newTestAuthenticationHandler(t)
+	)
+
+	user := &manta.User{Name: "foo"}
+	err := backend.UserService.CreateUser(ctx, user)
+	require.NoError(t, err)
+
+	current, err := backend.SessionService.CreateSession(ctx, user.ID)
+	require.NoError(t, err)
+	other, err := backend.SessionService.CreateSession(ctx, user.ID)
+	require.NoError(t, err)
+
+	do := func(method, path string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(method, path, nil)
+		r.AddCookie(&http.Cookie{Name: SessionCookieKey, Value: current.ID.String()})
+		w := httptest.NewRecorder()
+		service.ServeHTTP(w, r)
+
+		return w
+	}
+
+	path := UserPrefix + "/" + user.ID.String() + "/sessions"
+	w := do(http.MethodGet, path)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	// the session IDs are the cookies, only the handles are returned
+	require.NotContains(t, w.Body.String(), current.ID.String())
+	require.NotContains(t, w.Body.String(), other.ID.String())
+
+	var sessions []sessionResponse
+	err = json.NewDecoder(w.Body).Decode(&sessions)
+	require.NoError(t, err)
+	require.Len(t, sessions, 2)
+	for _, s := range sessions {
+		require.Equal(t, s.ID == current.Handle(), s.Current)
+	}
+
+	require.Equal(t, http.StatusNotFound, do(http.MethodDelete, path+"/"+other.ID.String()).Code)
+	require.Equal(t, http.StatusNoContent, do(http.MethodDelete, path+"/"+other.Handle()).Code)
+
+	_, err = backend.SessionService.FindSession(ctx, other.ID)
+	require.Error(t, err)
+	_, err = backend.SessionService.FindSession(ctx, current.ID)
+	require.NoError(t, err)
+}
@@ -70,7 +70,7 @@ func (h *SetupHandler) onBoarding(w http.ResponseWriter, r *http.Request) {
 		zap.String("username", br.Username),
 	)
 
-	if sess, err := h.sessionService.CreateSession(ctx, result.User.ID); err != nil {
+	if sess, err := h.sessionService.CreateSession(ctx, result.User.ID, sessionOptions(r)); err != nil {
 		h.logger.Error("Create initial session failed", zap.Error(err))
 		h.HandleHTTPError(ctx, err, w)
 	} else {
@@ -1,9 +1,13 @@
 package http
 
 import (
+	"encoding/json"
+	"io"
 	"net/http"
+	"strings"
 	"time"
 
+	"github.com/mileusna/useragent"
 	"go.uber.org/zap"
 
 	"github.com/f1shl3gs/manta"
@@ -12,25 +16,50 @@ import (
 
 const (
 	UserPrefix = apiV1Prefix + "/users"
+
+	userWithID        = UserPrefix + "/:id"
+	userPassword      = userWithID + "/password"
+	userPasswordReset = userPassword + "/reset"
+	userSessions      = userWithID + "/sessions"
+	userSessionWithID = userSessions + "/:sessionID"
+
+	// passwordResetPath is not authenticated, the reset token is the credential
+	passwordResetPath = apiV1Prefix + "/password/reset"
+
+	defaultPasswordResetTTL = 24 * time.Hour
 )
 
 type UsersHandler struct {
 	*router.Router
 	logger *zap.Logger
 
-	userService    manta.UserService
-	sessionService manta.SessionService
+	userService          manta.UserService
+	passwordService      manta.PasswordService
+	passwordResetService manta.PasswordResetService
+	sessionService       manta.SessionService
 }
 
 func NewUserHandler(backend *Backend, logger *zap.Logger) *UsersHandler {
 	h := &UsersHandler{
-		Router:         backend.router,
-		logger:         logger.With(zap.String("handler", "users")),
-		userService:    backend.UserService,
-		sessionService: backend.SessionService,
+		Router:               backend.router,
+		logger:               logger.With(zap.String("handler", "users")),
+		userService:          backend.UserService,
+		passwordService:      backend.PasswordService,
+		passwordResetService: backend.PasswordResetService,
+		sessionService:       backend.SessionService,
 	}
 
 	h.HandlerFunc(http.MethodGet, UserPrefix, h.list)
+	h.HandlerFunc(http.MethodPost, UserPrefix, h.create)
+	h.HandlerFunc(http.MethodGet, userWithID, h.get)
+	h.HandlerFunc(http.MethodPatch, userWithID, h.update)
+	h.HandlerFunc(http.MethodDelete, userWithID, h.delete)
+	h.HandlerFunc(http.MethodPut, userPassword, h.changePassword)
+	h.HandlerFunc(http.MethodPost, userPasswordReset, h.createPasswordReset)
+	h.HandlerFunc(http.MethodPost, passwordResetPath, h.resetPassword)
+	h.HandlerFunc(http.MethodGet, userSessions, h.listSessions)
+	h.HandlerFunc(http.MethodDelete, userSessions, h.revokeSessions)
+	h.HandlerFunc(http.MethodDelete, userSessionWithID, h.revokeSession)
 
 	return h
 }
@@ -53,9 +82,11 @@ func (h *UsersHandler) list(w http.ResponseWriter, r *http.Request) {
 	// TODO: implement something like GetBatchXXXX to improve performance ?
 	for i, u := range users {
 		resps[i].User = u
-		session, err := h.sessionService.FindSession(ctx, u.ID)
-		if err == nil {
-			resps[i].LastSeen = &session.LastSeen
+		// sessions are sorted by last seen, and only the user itself
+		// and admins can see them
+		sessions, err := h.sessionService.FindSessions(ctx, u.ID)
+		if err == nil && len(sessions) != 0 {
+			resps[i].LastSeen = &sessions[0].LastSeen
 		}
 	}
 
@@ -63,3 +94,349 @@ func (h *UsersHandler) list(w http.ResponseWriter, r *http.Request) {
 		logEncodingError(h.logger, r, err)
 	}
 }
+
+// create creates a user, the password is optional, users without password
+// can sign in after their password is reset.
+func (h *UsersHandler) create(w http.ResponseWriter, r *http.Request) {
+	var (
+		ctx = r.Context()
+		req struct {
+			Name     string `json:"name"`
+			Password string `json:"password"`
+		}
+	)
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.HandleHTTPError(ctx, &manta.Error{
+			Code: manta.EInvalid,
+			Msg:  "decode user failed",
+			Err:  err,
+		}, w)
+		return
+	}
+
+	user := &manta.User{Name: req.Name}
+	if err := h.userService.CreateUser(ctx, user); err != nil {
+		h.HandleHTTPError(ctx, err, w)
+		return
+	}
+
+	if req.Password != "" {
+		if err := h.passwordService.SetPassword(ctx, user.ID, req.Password); err != nil {
+			h.HandleHTTPError(ctx, err, w)
+			return
+		}
+	}
+
+	if err := h.EncodeResponse(ctx, w, http.StatusCreated, user); err != nil {
+		logEncodingError(h.logger, r, err)
+	}
+}
+
+func (h *UsersHandler) get(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	id, err := idFromPath(r)
+	if err != nil {
+		h.HandleHTTPError(ctx, err, w)
+		return
+	}
+
+	user, err := h.userService.FindUserByID(ctx, id)
+	if err != nil {
+		h.HandleHTTPError(ctx, err, w)
+		return
+	}
+
+	if err = h.EncodeResponse(ctx, w, http.StatusOK, user); err != nil {
+		logEncodingError(h.logger, r, err)
+	}
+}
+
+// update renames the user, or disables/enables it by status
+func (h *UsersHandler) update(w http.ResponseWriter, r *http.Request) {
+	var (
+		ctx = r.Context()
+		upd manta.UserUpdate
+	)
+
+	id, err := idFromPath(r)
+	if err != nil {
+		h.HandleHTTPError(ctx, err, w)
+		return
+	}
+
+	if err = json.NewDecoder(r.Body).Decode(&upd); err != nil {
+		h.HandleHTTPError(ctx, &manta.Error{
+			Code: manta.EInvalid,
+			Msg:  "decode user update failed",
+			Err:  err,
+		}, w)
+		return
+	}
+
+	user, err := h.userService.UpdateUser(ctx, id, upd)
+	if err != nil {
+		h.HandleHTTPError(ctx, err, w)
+		return
+	}
+
+	if err = h.EncodeResponse(ctx, w, http.StatusOK, user); err != nil {
+		logEncodingError(h.logger, r, err)
+	}
+}
+
+func (h *UsersHandler) delete(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	id, err := idFromPath(r)
+	if err != nil {
+		h.HandleHTTPError(ctx, err, w)
+		return
+	}
+
+	if err = h.userService.DeleteUser(ctx, id); err != nil {
+		h.HandleHTTPError(ctx, err, w)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// changePassword sets the new password if the old one matches
+func (h *UsersHandler) changePassword(w http.ResponseWriter, r *http.Request) {
+	var (
+		ctx = r.Context()
+		req struct {
+			OldPassword string `json:"oldPassword"`
+			Password    string `json:"password"`
+		}
+	)
+
+	id, err := idFromPath(r)
+	if err != nil {
+		h.HandleHTTPError(ctx, err, w)
+		return
+	}
+
+	if err = json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.HandleHTTPError(ctx, &manta.Error{
+			Code: manta.EInvalid,
+			Msg:  "decode password change failed",
+			Err:  err,
+		}, w)
+		return
+	}
+
+	err = h.passwordService.CompareAndSetPassword(ctx, id, req.OldPassword, req.Password)
+	if err == manta.ErrPasswordNotMatch {
+		err = &manta.Error{
+			Code: manta.EForbidden,
+			Msg:  "old password not match",
+		}
+	}
+	if err != nil {
+		h.HandleHTTPError(ctx, err, w)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// createPasswordReset issues a reset token for the user, the token is only
+// returned once, admins hand it over to the user.
+func (h *UsersHandler) createPasswordReset(w http.ResponseWriter, r *http.Request) {
+	var (
+		ctx = r.Context()
+		req struct {
+			TTL manta.Duration `json:"ttl"`
+		}
+	)
+
+	id, err := idFromPath(r)
+	if err != nil {
+		h.HandleHTTPError(ctx, err, w)
+		return
+	}
+
+	// the body is optional
+	if err = json.NewDecoder(r.Body).Decode(&req); err != nil && err != io.EOF {
+		h.HandleHTTPError(ctx, &manta.Error{
+			Code: manta.EInvalid,
+			Msg:  "decode password reset failed",
+			Err:  err,
+		}, w)
+		return
+	}
+
+	ttl := time.Duration(req.TTL)
+	if ttl == 0 {
+		ttl = defaultPasswordResetTTL
+	}
+
+	reset, err := h.passwordResetService.CreatePasswordReset(ctx, id, ttl)
+	if err != nil {
+		h.HandleHTTPError(ctx, err, w)
+		return
+	}
+
+	if err = h.EncodeResponse(ctx, w, http.StatusCreated, reset); err != nil {
+		logEncodingError(h.logger, r, err)
+	}
+}
+
+func (h *UsersHandler) resetPassword(w http.ResponseWriter, r *http.Request) {
+	var (
+		ctx = r.Context()
+		req struct {
+			Token    string `json:"token"`
+			Password string `json:"password"`
+		}
+	)
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.HandleHTTPError(ctx, &manta.Error{
+			Code: manta.EInvalid,
+			Msg:  "decode password reset failed",
+			Err:  err,
+		}, w)
+		return
+	}
+
+	if err := h.passwordResetService.ResetPassword(ctx, req.Token, req.Password); err != nil {
+		h.HandleHTTPError(ctx, err, w)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+type sessionResponse struct {
+	// ID is the handle of the session, the session ID is the secret of
+	// the cookie, so it is never returned
+	ID        string    `json:"id"`
+	Created   time.Time `json:"created"`
+	LastSeen  time.Time `json:"lastSeen"`
+	ExpiresAt time.Time `json:"expiresAt"`
+	IP        string    `json:"ip,omitempty"`
+	UserAgent string    `json:"userAgent,omitempty"`
+	Browser   string    `json:"browser,omitempty"`
+	OS        string    `json:"os,omitempty"`
+	Device    string    `json:"device,omitempty"`
+	// Current is true if the session is used by the request
+	Current bool `json:"current"`
+}
+
+func newSessionResponse(session *manta.Session, current manta.ID) sessionResponse {
+	ua := useragent.Parse(session.UserAgent)
+
+	return sessionResponse{
+		ID:        session.Handle(),
+		Created:   session.Created,
+		LastSeen:  session.LastSeen,
+		ExpiresAt: session.ExpiresAt,
+		IP:        session.IP,
+		UserAgent: session.UserAgent,
+		Browser:   strings.TrimSpace(ua.Name + " " + ua.Version),
+		OS:        strings.TrimSpace(ua.OS + " " + ua.OSVersion),
+		Device:    ua.Device,
+		Current:   session.ID == current,
+	}
+}
+
+// currentSession returns the id of the session used by the request, it is
+// invalid if the request is not authenticated by session
+func currentSession(r *http.Request) manta.ID {
+	var id manta.ID
+
+	if cookie, err := r.Cookie(SessionCookieKey); err == nil {
+		_ = id.DecodeFromString(cookie.Value)
+	}
+
+	return id
+}
+
+// listSessions returns the active sessions of the user, the latest seen
+// comes first
+func (h *UsersHandler) listSessions(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	id, err := idFromPath(r)
+	if err != nil {
+		h.HandleHTTPError(ctx, err, w)
+		return
+	}
+
+	sessions, err := h.sessionService.FindSessions(ctx, id)
+	if err != nil {
+		h.HandleHTTPError(ctx, err, w)
+		return
+	}
+
+	current := currentSession(r)
+	resps := make([]sessionResponse, 0, len(sessions))
+	for _, session := range sessions {
+		resps = append(resps, newSessionResponse(session, current))
+	}
+
+	if err = h.EncodeResponse(ctx, w, http.StatusOK, resps); err != nil {
+		logEncodingError(h.logger, r, err)
+	}
+}
+
+// revokeSessions signs the user out everywhere, admins use it to force
+// users to sign out
+func (h *UsersHandler) revokeSessions(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	id, err := idFromPath(r)
+	if err != nil {
+		h.HandleHTTPError(ctx, err, w)
+		return
+	}
+
+	if err = h.sessionService.RevokeSessions(ctx, id); err != nil {
+		h.HandleHTTPError(ctx, err, w)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// revokeSession signs out the session, which is identified by its handle
+func (h *UsersHandler) revokeSession(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	id, err := idFromPath(r)
+	if err != nil {
+		h.HandleHTTPError(ctx, err, w)
+		return
+	}
+
+	handle := extractParamFromContext(ctx, "sessionID")
+
+	sessions, err := h.sessionService.FindSessions(ctx, id)
+	if err != nil {
+		h.HandleHTTPError(ctx, err, w)
+		return
+	}
+
+	for _, session := range sessions {
+		if session.Handle() != handle {
+			continue
+		}
+
+		if err = h.sessionService.RevokeSession(ctx, session.ID); err != nil {
+			h.HandleHTTPError(ctx, err, w)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	h.HandleHTTPError(ctx, &manta.Error{
+		Code: manta.ENotFound,
+		Msg:  "session not found",
+	}, w)
+}
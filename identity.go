@@ -0,0 +1,41 @@
+package manta
+
+import (
+	"context"
+	"time"
+)
+
+var (
+	ErrUserIdentityNotFound = &Error{
+		Code: ENotFound,
+		Msg:  "user identity not found",
+	}
+)
+
+// UserIdentity links a user to the subject of an external identity provider,
+// e.g. OIDC or LDAP, so the user is found even if the name changed in the
+// identity provider.
+type UserIdentity struct {
+	Provider string    `json:"provider"`
+	Subject  string    `json:"subject"`
+	UserID   ID        `json:"userID"`
+	Created  time.Time `json:"created"`
+}
+
+// UserIdentityFilter represents a set of filter that restrict the returned results.
+type UserIdentityFilter struct {
+	UserID   *ID
+	Provider *string
+}
+
+type UserIdentityService interface {
+	// FindUserIdentity returns the identity of the subject of the provider
+	FindUserIdentity(ctx context.Context, provider, subject string) (*UserIdentity, error)
+
+	// FindUserIdentities returns the identities that match filter
+	FindUserIdentities(ctx context.Context, filter UserIdentityFilter) ([]*UserIdentity, error)
+
+	// CreateUserIdentity links the subject to the user, the identities are
+	// deleted with the user
+	CreateUserIdentity(ctx context.Context, ident *UserIdentity) error
+}
@@ -0,0 +1,277 @@
+// Package ldap authenticates users against an LDAP directory and syncs
+// their groups to organizations.
+package ldap
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/go-ldap/ldap/v3"
+
+	"github.com/f1shl3gs/manta"
+	"github.com/f1shl3gs/manta/identity"
+)
+
+// ProviderName is the provider of the user identities linked by LDAP,
+// the subject is the DN of the user.
+const ProviderName = "ldap"
+
+const (
+	defaultTimeout           = 10 * time.Second
+	defaultUserFilter        = "(uid=%s)"
+	defaultUsernameAttribute = "uid"
+	defaultEmailAttribute    = "mail"
+	defaultGroupFilter       = "(member=%s)"
+	defaultGroupAttribute    = "cn"
+)
+
+// Config is the configuration of Provider
+type Config struct {
+	// URL of the LDAP server, e.g. ldap://ldap.example.com:389 or
+	// ldaps://ldap.example.com:636
+	URL string
+
+	// StartTLS upgrades the plain connection to TLS
+	StartTLS bool
+
+	// TLS is used by ldaps and StartTLS, the system CA is used if nil
+	TLS *tls.Config
+
+	// BindDN and BindPassword of the service account to search users and
+	// groups, the search is anonymous if BindDN is empty
+	BindDN       string
+	BindPassword string
+
+	// BaseDN to search users from
+	BaseDN string
+
+	// UserFilter finds the user, every %s is replaced by the escaped
+	// username, default is "(uid=%s)"
+	UserFilter string
+
+	// UsernameAttribute and EmailAttribute of the user entry, default are
+	// "uid" and "mail"
+	UsernameAttribute string
+	EmailAttribute    string
+
+	// GroupBaseDN to search groups from, groups are not searched if empty
+	GroupBaseDN string
+
+	// GroupFilter finds the groups of user, every %s is replaced by the
+	// escaped DN of the user, default is "(member=%s)"
+	GroupFilter string
+
+	// GroupAttribute is the name of group, default is "cn"
+	GroupAttribute string
+
+	Timeout time.Duration
+}
+
+type Provider struct {
+	cf Config
+}
+
+func New(cf Config) (*Provider, error) {
+	if !strings.HasPrefix(cf.URL, "ldap://") && !strings.HasPrefix(cf.URL, "ldaps://") {
+		return nil, fmt.Errorf("invalid ldap url %q", cf.URL)
+	}
+	if cf.BaseDN == "" {
+		return nil, fmt.Errorf("ldap base dn is required")
+	}
+
+	if cf.UserFilter == "" {
+		cf.UserFilter = defaultUserFilter
+	}
+	if cf.UsernameAttribute == "" {
+		cf.UsernameAttribute = defaultUsernameAttribute
+	}
+	if cf.EmailAttribute == "" {
+		cf.EmailAttribute = defaultEmailAttribute
+	}
+	if cf.GroupFilter == "" {
+		cf.GroupFilter = defaultGroupFilter
+	}
+	if cf.GroupAttribute == "" {
+		cf.GroupAttribute = defaultGroupAttribute
+	}
+	if cf.Timeout == 0 {
+		cf.Timeout = defaultTimeout
+	}
+
+	return &Provider{cf: cf}, nil
+}
+
+var errInvalidCredentials = &manta.Error{
+	Code: manta.EUnauthorized,
+	Msg:  "invalid ldap credentials",
+}
+
+// Authenticate binds as the user with the password, and returns the user
+// with its groups. ENotFound is returned if the user is not in the directory.
+func (p *Provider) Authenticate(ctx context.Context, username, password string) (*identity.External, error) {
+	// an empty password is an unauthenticated bind, which always succeeds
+	if username == "" || password == "" {
+		return nil, errInvalidCredentials
+	}
+
+	conn, err := p.dial()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	filter := strings.ReplaceAll(p.cf.UserFilter, "%s", ldap.EscapeFilter(username))
+	entry, err := p.searchUser(conn, p.cf.BaseDN, ldap.ScopeWholeSubtree, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	err = conn.Bind(entry.DN, password)
+	if ldap.IsErrorWithCode(err, ldap.LDAPResultInvalidCredentials) {
+		return nil, errInvalidCredentials
+	}
+	if err != nil {
+		return nil, unavailable("bind ldap user failed", err)
+	}
+
+	// the user might not be allowed to search groups
+	if err = p.bind(conn); err != nil {
+		return nil, err
+	}
+
+	return p.external(conn, entry)
+}
+
+// Lookup returns the user of the DN with its groups, ENotFound is returned
+// if the user is removed from the directory.
+func (p *Provider) Lookup(ctx context.Context, dn string) (*identity.External, error) {
+	conn, err := p.dial()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	entry, err := p.searchUser(conn, dn, ldap.ScopeBaseObject, "(objectClass=*)")
+	if err != nil {
+		return nil, err
+	}
+
+	return p.external(conn, entry)
+}
+
+func unavailable(msg string, err error) error {
+	return &manta.Error{
+		Code: manta.EUnavailable,
+		Msg:  msg,
+		Err:  err,
+	}
+}
+
+// dial connects to the server and binds as the service account
+func (p *Provider) dial() (*ldap.Conn, error) {
+	conn, err := ldap.DialURL(p.cf.URL, ldap.DialWithTLSConfig(p.cf.TLS))
+	if err != nil {
+		return nil, unavailable("connect ldap server failed", err)
+	}
+
+	conn.SetTimeout(p.cf.Timeout)
+
+	if p.cf.StartTLS {
+		if err = conn.StartTLS(p.cf.TLS); err != nil {
+			conn.Close()
+			return nil, unavailable("start tls failed", err)
+		}
+	}
+
+	if err = p.bind(conn); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	return conn, nil
+}
+
+func (p *Provider) bind(conn *ldap.Conn) error {
+	if p.cf.BindDN == "" {
+		return nil
+	}
+
+	if err := conn.Bind(p.cf.BindDN, p.cf.BindPassword); err != nil {
+		return unavailable("bind ldap service account failed", err)
+	}
+
+	return nil
+}
+
+func (p *Provider) searchUser(conn *ldap.Conn, baseDN string, scope int, filter string) (*ldap.Entry, error) {
+	req := ldap.NewSearchRequest(
+		baseDN, scope, ldap.NeverDerefAliases, 2, 0, false,
+		filter,
+		[]string{p.cf.UsernameAttribute, p.cf.EmailAttribute},
+		nil,
+	)
+
+	result, err := conn.Search(req)
+	if ldap.IsErrorWithCode(err, ldap.LDAPResultNoSuchObject) {
+		return nil, &manta.Error{
+			Code: manta.ENotFound,
+			Msg:  "ldap user not found",
+		}
+	}
+	if err != nil {
+		return nil, unavailable("search ldap user failed", err)
+	}
+
+	switch len(result.Entries) {
+	case 0:
+		return nil, &manta.Error{
+			Code: manta.ENotFound,
+			Msg:  "ldap user not found",
+		}
+	case 1:
+		return result.Entries[0], nil
+	default:
+		return nil, &manta.Error{
+			Code: manta.EConflict,
+			Msg:  "ldap user filter matches more than one user",
+		}
+	}
+}
+
+func (p *Provider) external(conn *ldap.Conn, entry *ldap.Entry) (*identity.External, error) {
+	ext := &identity.External{
+		Provider: ProviderName,
+		Subject:  entry.DN,
+		Email:    entry.GetAttributeValue(p.cf.EmailAttribute),
+		// the attributes are managed by the directory, not the users
+		EmailVerified: true,
+		Name:          entry.GetAttributeValue(p.cf.UsernameAttribute),
+	}
+
+	if p.cf.GroupBaseDN == "" {
+		return ext, nil
+	}
+
+	req := ldap.NewSearchRequest(
+		p.cf.GroupBaseDN, ldap.ScopeWholeSubtree, ldap.NeverDerefAliases, 0, 0, false,
+		strings.ReplaceAll(p.cf.GroupFilter, "%s", ldap.EscapeFilter(entry.DN)),
+		[]string{p.cf.GroupAttribute},
+		nil,
+	)
+
+	result, err := conn.Search(req)
+	if err != nil {
+		return nil, unavailable("search ldap groups failed", err)
+	}
+
+	for _, group := range result.Entries {
+		if name := group.GetAttributeValue(p.cf.GroupAttribute); name != "" {
+			ext.Groups = append(ext.Groups, name)
+		}
+	}
+
+	return ext, nil
+}
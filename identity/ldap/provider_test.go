@@ -0,0 +1,292 @@
+package ldap
+
+import (
+	"context"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+
+	ber "github.com/go-asn1-ber/asn1-ber"
+	"github.com/go-ldap/ldap/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/f1shl3gs/manta"
+)
+
+const (
+	testBaseDN      = "dc=example,dc=com"
+	testUserDN      = "uid=alice,ou=people," + testBaseDN
+	testGroupBaseDN = "ou=groups," + testBaseDN
+	testServiceDN   = "cn=manta," + testBaseDN
+)
+
+// standIn is an in-process LDAP server, which supports simple bind and
+// search with equality, presence, and, or filters only.
+type standIn struct {
+	ln net.Listener
+
+	mtx       sync.Mutex
+	passwords map[string]string
+	entries   map[string]map[string][]string
+}
+
+func newStandIn(t *testing.T) *standIn {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	s := &standIn{
+		ln: ln,
+		passwords: map[string]string{
+			testServiceDN: "service",
+			testUserDN:    "secret",
+		},
+		entries: map[string]map[string][]string{
+			testUserDN: {
+				"objectClass": {"inetOrgPerson"},
+				"uid":         {"alice"},
+				"mail":        {"alice@example.com"},
+			},
+			"cn=admins," + testGroupBaseDN: {
+				"objectClass": {"groupOfNames"},
+				"cn":          {"admins"},
+				"member":      {testUserDN},
+			},
+			"cn=devs," + testGroupBaseDN: {
+				"objectClass": {"groupOfNames"},
+				"cn":          {"devs"},
+				"member":      {"uid=bob,ou=people," + testBaseDN},
+			},
+		},
+	}
+
+	go s.serve()
+	t.Cleanup(func() { _ = ln.Close() })
+
+	return s
+}
+
+func (s *standIn) URL() string {
+	return "ldap://" + s.ln.Addr().String()
+}
+
+func (s *standIn) setAttribute(dn, attr string, values ...string) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	s.entries[dn][attr] = values
+}
+
+func (s *standIn) serve() {
+	for {
+		conn, err := s.ln.Accept()
+		if err != nil {
+			return
+		}
+
+		go s.handle(conn)
+	}
+}
+
+func (s *standIn) handle(conn net.Conn) {
+	defer conn.Close()
+
+	for {
+		packet, err := ber.ReadPacket(conn)
+		if err != nil || len(packet.Children) < 2 {
+			return
+		}
+
+		msgID := packet.Children[0].Value.(int64)
+		op := packet.Children[1]
+
+		switch op.Tag {
+		case ldap.ApplicationBindRequest:
+			dn := op.Children[1].Value.(string)
+			password := op.Children[2].Data.String()
+
+			code := int64(ldap.LDAPResultSuccess)
+			s.mtx.Lock()
+			if expected, ok := s.passwords[dn]; !ok || expected != password {
+				code = ldap.LDAPResultInvalidCredentials
+			}
+			s.mtx.Unlock()
+
+			_, _ = conn.Write(result(msgID, ldap.ApplicationBindResponse, code).Bytes())
+
+		case ldap.ApplicationSearchRequest:
+			for _, entry := range s.search(msgID, op) {
+				_, _ = conn.Write(entry.Bytes())
+			}
+
+		default:
+			return
+		}
+	}
+}
+
+func envelope(msgID int64, op *ber.Packet) *ber.Packet {
+	packet := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "LDAP Response")
+	packet.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagInteger, msgID, "MessageID"))
+	packet.AppendChild(op)
+
+	return packet
+}
+
+func result(msgID int64, tag ber.Tag, code int64) *ber.Packet {
+	op := ber.Encode(ber.ClassApplication, ber.TypeConstructed, tag, nil, "Result")
+	op.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagEnumerated, code, "ResultCode"))
+	op.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, "", "MatchedDN"))
+	op.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, "", "DiagnosticMessage"))
+
+	return envelope(msgID, op)
+}
+
+// search returns the entries and the done packets of the search request
+func (s *standIn) search(msgID int64, op *ber.Packet) []*ber.Packet {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	var (
+		packets []*ber.Packet
+		baseDN  = strings.ToLower(op.Children[0].Value.(string))
+		scope   = op.Children[1].Value.(int64)
+		filter  = op.Children[6]
+	)
+
+	if scope == ldap.ScopeBaseObject {
+		if _, ok := s.entries[baseDN]; !ok {
+			return []*ber.Packet{result(msgID, ldap.ApplicationSearchResultDone, ldap.LDAPResultNoSuchObject)}
+		}
+	}
+
+	for dn, attrs := range s.entries {
+		if scope == ldap.ScopeBaseObject && dn != baseDN ||
+			!strings.HasSuffix(dn, baseDN) ||
+			!match(filter, attrs) {
+			continue
+		}
+
+		entry := ber.Encode(ber.ClassApplication, ber.TypeConstructed, ldap.ApplicationSearchResultEntry, nil, "Entry")
+		entry.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, dn, "DN"))
+		list := ber.NewSequence("Attributes")
+		for name, values := range attrs {
+			attr := ber.NewSequence("Attribute")
+			attr.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, name, "Type"))
+			set := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSet, nil, "Values")
+			for _, value := range values {
+				set.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, value, "Value"))
+			}
+			attr.AppendChild(set)
+			list.AppendChild(attr)
+		}
+		entry.AppendChild(list)
+
+		packets = append(packets, envelope(msgID, entry))
+	}
+
+	return append(packets, result(msgID, ldap.ApplicationSearchResultDone, ldap.LDAPResultSuccess))
+}
+
+func match(filter *ber.Packet, attrs map[string][]string) bool {
+	switch filter.Tag {
+	case ldap.FilterAnd:
+		for _, child := range filter.Children {
+			if !match(child, attrs) {
+				return false
+			}
+		}
+		return true
+
+	case ldap.FilterOr:
+		for _, child := range filter.Children {
+			if match(child, attrs) {
+				return true
+			}
+		}
+		return false
+
+	case ldap.FilterEqualityMatch:
+		name := filter.Children[0].Value.(string)
+		expected := filter.Children[1].Value.(string)
+		for _, value := range attrs[name] {
+			if strings.EqualFold(value, expected) {
+				return true
+			}
+		}
+		return false
+
+	case ldap.FilterPresent:
+		_, ok := attrs[filter.Data.String()]
+		return ok
+
+	default:
+		return false
+	}
+}
+
+func newTestProvider(t *testing.T, s *standIn) *Provider {
+	provider, err := New(Config{
+		URL:          s.URL(),
+		BindDN:       testServiceDN,
+		BindPassword: "service",
+		BaseDN:       testBaseDN,
+		GroupBaseDN:  testGroupBaseDN,
+	})
+	require.NoError(t, err)
+
+	return provider
+}
+
+func TestProvider(t *testing.T) {
+	ctx := context.Background()
+	s := newStandIn(t)
+	provider := newTestProvider(t, s)
+
+	ext, err := provider.Authenticate(ctx, "alice", "secret")
+	require.NoError(t, err)
+	assert.Equal(t, ProviderName, ext.Provider)
+	assert.Equal(t, testUserDN, ext.Subject)
+	assert.Equal(t, "alice", ext.Name)
+	assert.Equal(t, "alice@example.com", ext.Email)
+	assert.Equal(t, []string{"admins"}, ext.Groups)
+
+	for name, credentials := range map[string][2]string{
+		"invalid password": {"alice", "invalid"},
+		"empty password":   {"alice", ""},
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := provider.Authenticate(ctx, credentials[0], credentials[1])
+			assert.Equal(t, manta.EUnauthorized, manta.ErrorCode(err))
+		})
+	}
+
+	t.Run("user not found", func(t *testing.T) {
+		_, err := provider.Authenticate(ctx, "bob", "secret")
+		assert.Equal(t, manta.ENotFound, manta.ErrorCode(err))
+
+		_, err = provider.Lookup(ctx, "uid=bob,ou=people,"+testBaseDN)
+		assert.Equal(t, manta.ENotFound, manta.ErrorCode(err))
+	})
+
+	t.Run("lookup", func(t *testing.T) {
+		ext, err := provider.Lookup(ctx, testUserDN)
+		require.NoError(t, err)
+		assert.Equal(t, "alice", ext.Name)
+		assert.Equal(t, []string{"admins"}, ext.Groups)
+	})
+
+	t.Run("service account", func(t *testing.T) {
+		provider, err := New(Config{
+			URL:          s.URL(),
+			BindDN:       testServiceDN,
+			BindPassword: "invalid",
+			BaseDN:       testBaseDN,
+		})
+		require.NoError(t, err)
+
+		_, err = provider.Authenticate(ctx, "alice", "secret")
+		assert.Equal(t, manta.EUnavailable, manta.ErrorCode(err))
+	})
+}
@@ -0,0 +1,90 @@
+package ldap
+
+import (
+	"context"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/f1shl3gs/manta"
+	"github.com/f1shl3gs/manta/identity"
+)
+
+// Syncer syncs the groups of users signed in by LDAP periodically, so
+// changes of groups take effect without signing in again.
+type Syncer struct {
+	logger              *zap.Logger
+	provider            *Provider
+	provisioner         *identity.Provisioner
+	userIdentityService manta.UserIdentityService
+	interval            time.Duration
+}
+
+func NewSyncer(
+	logger *zap.Logger,
+	provider *Provider,
+	provisioner *identity.Provisioner,
+	userIdentityService manta.UserIdentityService,
+	interval time.Duration,
+) *Syncer {
+	return &Syncer{
+		logger:              logger,
+		provider:            provider,
+		provisioner:         provisioner,
+		userIdentityService: userIdentityService,
+		interval:            interval,
+	}
+}
+
+func (s *Syncer) Run(ctx context.Context) error {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+
+		if err := s.Sync(ctx); err != nil && ctx.Err() == nil {
+			s.logger.Warn("Sync ldap groups failed",
+				zap.Error(err))
+		}
+	}
+}
+
+// Sync syncs the groups of all users linked to LDAP once, users removed
+// from the directory lose access to the mapped organizations.
+func (s *Syncer) Sync(ctx context.Context) error {
+	provider := ProviderName
+	idents, err := s.userIdentityService.FindUserIdentities(ctx, manta.UserIdentityFilter{
+		Provider: &provider,
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, ident := range idents {
+		var groups []string
+
+		ext, err := s.provider.Lookup(ctx, ident.Subject)
+		switch manta.ErrorCode(err) {
+		case "":
+			groups = ext.Groups
+		case manta.ENotFound:
+			s.logger.Info("LDAP user not found, revoke mapped organizations",
+				zap.String("dn", ident.Subject),
+				zap.String("user", ident.UserID.String()))
+		default:
+			// keep the memberships if the directory is unavailable
+			return err
+		}
+
+		if err = s.provisioner.SyncGroups(ctx, ident.UserID, groups); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,87 @@
+package ldap
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"github.com/f1shl3gs/manta"
+	"github.com/f1shl3gs/manta/bolt"
+	"github.com/f1shl3gs/manta/identity"
+	"github.com/f1shl3gs/manta/kv"
+	"github.com/f1shl3gs/manta/kv/migration"
+)
+
+func TestSyncer(t *testing.T) {
+	ctx := context.Background()
+	logger := zaptest.NewLogger(t)
+
+	store := bolt.NewKVStore(logger, t.TempDir()+"/manta.db", bolt.WithNoSync)
+	require.NoError(t, store.Open(ctx))
+	t.Cleanup(func() { _ = store.Close() })
+	require.NoError(t, migration.New(logger, store, migration.All...).Up(ctx))
+	svc := kv.NewService(logger, store)
+
+	org := &manta.Organization{Name: "prod"}
+	require.NoError(t, svc.CreateOrganization(ctx, org))
+
+	// the last owner is never removed
+	owner := &manta.User{Name: "owner"}
+	require.NoError(t, svc.CreateUser(ctx, owner))
+	require.NoError(t, svc.CreateUserResourceMapping(ctx, &manta.UserResourceMapping{
+		UserID:       owner.ID,
+		UserType:     manta.Owner,
+		MappingType:  manta.UserMappingType,
+		ResourceType: manta.OrgsResourceType,
+		ResourceID:   org.ID,
+	}))
+
+	mappings, err := identity.ParseGroupMappings(map[string]string{"admins": "prod:owner"})
+	require.NoError(t, err)
+	provisioner := identity.NewProvisioner(logger, identity.Config{
+		AutoProvision: true,
+		GroupMappings: mappings,
+	}, svc, svc, svc, svc, svc)
+
+	s := newStandIn(t)
+	provider := newTestProvider(t, s)
+
+	ext, err := provider.Authenticate(ctx, "alice", "secret")
+	require.NoError(t, err)
+	user, err := provisioner.Provision(ctx, ext)
+	require.NoError(t, err)
+	assert.Equal(t, "alice@example.com", user.Name)
+
+	isOwner := func() bool {
+		urms, _, err := svc.FindUserResourceMappings(ctx, manta.UserResourceMappingFilter{
+			UserID:   user.ID,
+			UserType: manta.Owner,
+		}, manta.FindOptions{})
+		require.NoError(t, err)
+
+		for _, urm := range urms {
+			if urm.ResourceID == org.ID {
+				return true
+			}
+		}
+
+		return false
+	}
+	require.True(t, isOwner())
+
+	syncer := NewSyncer(logger, provider, provisioner, svc, time.Hour)
+
+	// removed from the group
+	s.setAttribute("cn=admins,"+testGroupBaseDN, "member")
+	require.NoError(t, syncer.Sync(ctx))
+	assert.False(t, isOwner())
+
+	// added back
+	s.setAttribute("cn=admins,"+testGroupBaseDN, "member", testUserDN)
+	require.NoError(t, syncer.Sync(ctx))
+	assert.True(t, isOwner())
+}
@@ -0,0 +1,311 @@
+// Package oidc implements the OpenID Connect authorization code flow with
+// PKCE, id tokens are verified with the keys published by the issuer.
+package oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/f1shl3gs/manta"
+)
+
+const (
+	defaultTimeout     = 10 * time.Second
+	defaultGroupsClaim = "groups"
+)
+
+var defaultScopes = []string{"openid", "profile", "email"}
+
+// Config is the configuration of Provider
+type Config struct {
+	// Issuer is the URL of the OpenID provider, the configuration is
+	// discovered from <issuer>/.well-known/openid-configuration
+	Issuer string
+
+	ClientID     string
+	ClientSecret string
+
+	// RedirectURL is the URL of the callback handler registered at the
+	// OpenID provider
+	RedirectURL string
+
+	// Scopes requested, default is "openid profile email"
+	Scopes []string
+
+	// GroupsClaim is the claim of id token listing the groups of user,
+	// default is "groups"
+	GroupsClaim string
+
+	Timeout time.Duration
+}
+
+// discovery is the subset of the OpenID provider metadata in use
+type discovery struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JwksURI               string `json:"jwks_uri"`
+}
+
+// Provider is a relying party of an OpenID provider, the metadata and keys
+// are fetched lazily, so the provider can be created even if the OpenID
+// provider is unavailable for now.
+type Provider struct {
+	cf     Config
+	client *http.Client
+
+	mtx       sync.Mutex
+	discovery *discovery
+	keys      map[string]interface{}
+}
+
+func New(cf Config) (*Provider, error) {
+	if _, err := url.Parse(cf.Issuer); err != nil || cf.Issuer == "" {
+		return nil, fmt.Errorf("invalid oidc issuer %q", cf.Issuer)
+	}
+	if cf.ClientID == "" {
+		return nil, fmt.Errorf("oidc client id is required")
+	}
+	if _, err := url.Parse(cf.RedirectURL); err != nil || cf.RedirectURL == "" {
+		return nil, fmt.Errorf("invalid oidc redirect url %q", cf.RedirectURL)
+	}
+
+	cf.Issuer = strings.TrimSuffix(cf.Issuer, "/")
+	if len(cf.Scopes) == 0 {
+		cf.Scopes = defaultScopes
+	}
+	if cf.GroupsClaim == "" {
+		cf.GroupsClaim = defaultGroupsClaim
+	}
+	if cf.Timeout == 0 {
+		cf.Timeout = defaultTimeout
+	}
+
+	return &Provider{
+		cf: cf,
+		client: &http.Client{
+			Timeout: cf.Timeout,
+		},
+	}, nil
+}
+
+// AuthRequest holds the secrets of a login attempt, they must be kept by
+// the user agent, e.g. in a cookie, until the OpenID provider redirects back.
+type AuthRequest struct {
+	State    string
+	Nonce    string
+	Verifier string
+}
+
+func randomString() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+// NewAuthRequest generates random state, nonce and PKCE code verifier
+func NewAuthRequest() (*AuthRequest, error) {
+	var (
+		req = &AuthRequest{}
+		err error
+	)
+
+	for _, s := range []*string{&req.State, &req.Nonce, &req.Verifier} {
+		*s, err = randomString()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return req, nil
+}
+
+// codeChallenge returns the S256 code challenge of the verifier
+func codeChallenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// AuthCodeURL returns the URL of the OpenID provider to redirect the user to
+func (p *Provider) AuthCodeURL(ctx context.Context, req *AuthRequest) (string, error) {
+	d, err := p.discover(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	u, err := url.Parse(d.AuthorizationEndpoint)
+	if err != nil {
+		return "", &manta.Error{
+			Code: manta.EInternal,
+			Msg:  "invalid oidc authorization endpoint",
+			Err:  err,
+		}
+	}
+
+	values := u.Query()
+	values.Set("response_type", "code")
+	values.Set("client_id", p.cf.ClientID)
+	values.Set("redirect_uri", p.cf.RedirectURL)
+	values.Set("scope", strings.Join(p.cf.Scopes, " "))
+	values.Set("state", req.State)
+	values.Set("nonce", req.Nonce)
+	values.Set("code_challenge", codeChallenge(req.Verifier))
+	values.Set("code_challenge_method", "S256")
+	u.RawQuery = values.Encode()
+
+	return u.String(), nil
+}
+
+// Claims of the verified id token
+type Claims struct {
+	Subject           string
+	Email             string
+	EmailVerified     bool
+	Name              string
+	PreferredUsername string
+	Groups            []string
+}
+
+type tokenResponse struct {
+	IDToken          string `json:"id_token"`
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+}
+
+// Exchange redeems the authorization code with the code verifier of the
+// request, and returns the claims of the verified id token.
+func (p *Provider) Exchange(ctx context.Context, code string, req *AuthRequest) (*Claims, error) {
+	d, err := p.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {p.cf.RedirectURL},
+		"code_verifier": {req.Verifier},
+	}
+
+	hr, err := http.NewRequestWithContext(ctx, http.MethodPost, d.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+
+	hr.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	hr.Header.Set("Accept", "application/json")
+	hr.SetBasicAuth(url.QueryEscape(p.cf.ClientID), url.QueryEscape(p.cf.ClientSecret))
+
+	resp, err := p.client.Do(hr)
+	if err != nil {
+		return nil, &manta.Error{
+			Code: manta.EUnavailable,
+			Msg:  "exchange oidc authorization code failed",
+			Err:  err,
+		}
+	}
+	defer resp.Body.Close()
+
+	var body tokenResponse
+	if err = json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, &manta.Error{
+			Code: manta.EUnavailable,
+			Msg:  fmt.Sprintf("decode oidc token response failed, status code %d", resp.StatusCode),
+			Err:  err,
+		}
+	}
+
+	if resp.StatusCode != http.StatusOK || body.Error != "" {
+		// invalid_grant, e.g. the code is expired or the verifier not match
+		return nil, &manta.Error{
+			Code: manta.EUnauthorized,
+			Msg:  fmt.Sprintf("exchange oidc authorization code failed, %s %s", body.Error, body.ErrorDescription),
+		}
+	}
+
+	if body.IDToken == "" {
+		return nil, &manta.Error{
+			Code: manta.EUnauthorized,
+			Msg:  "oidc token response has no id token",
+		}
+	}
+
+	return p.verify(ctx, body.IDToken, req.Nonce)
+}
+
+func (p *Provider) discover(ctx context.Context) (*discovery, error) {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+
+	if p.discovery != nil {
+		return p.discovery, nil
+	}
+
+	d := &discovery{}
+	if err := p.getJSON(ctx, p.cf.Issuer+"/.well-known/openid-configuration", d); err != nil {
+		return nil, err
+	}
+
+	if strings.TrimSuffix(d.Issuer, "/") != p.cf.Issuer {
+		return nil, &manta.Error{
+			Code: manta.EInternal,
+			Msg:  fmt.Sprintf("oidc issuer %q not match the configured %q", d.Issuer, p.cf.Issuer),
+		}
+	}
+
+	if d.AuthorizationEndpoint == "" || d.TokenEndpoint == "" || d.JwksURI == "" {
+		return nil, &manta.Error{
+			Code: manta.EInternal,
+			Msg:  "incomplete oidc provider metadata",
+		}
+	}
+
+	p.discovery = d
+	return d, nil
+}
+
+func (p *Provider) getJSON(ctx context.Context, u string, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return &manta.Error{
+			Code: manta.EUnavailable,
+			Msg:  "request oidc provider failed",
+			Err:  err,
+		}
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return &manta.Error{
+			Code: manta.EUnavailable,
+			Msg:  fmt.Sprintf("request %s failed, status code %d", u, resp.StatusCode),
+		}
+	}
+
+	if err = json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return &manta.Error{
+			Code: manta.EUnavailable,
+			Msg:  fmt.Sprintf("decode response of %s failed", u),
+			Err:  err,
+		}
+	}
+
+	return nil
+}
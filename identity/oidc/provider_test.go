@@ -0,0 +1,239 @@
+package oidc
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/f1shl3gs/manta"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testClientID     = "manta"
+	testClientSecret = "secret"
+)
+
+// stubIdP is an OpenID provider issuing RS256 signed id tokens, the codes
+// are issued by authorize instead of a login page.
+type stubIdP struct {
+	*httptest.Server
+	t   *testing.T
+	key *rsa.PrivateKey
+
+	mtx   sync.Mutex
+	codes map[string]url.Values
+	// claims overrides the claims of the next id token
+	claims map[string]interface{}
+}
+
+func newStubIdP(t *testing.T) *stubIdP {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	idp := &stubIdP{
+		t:     t,
+		key:   key,
+		codes: map[string]url.Values{},
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 idp.URL,
+			"authorization_endpoint": idp.URL + "/authorize",
+			"token_endpoint":         idp.URL + "/token",
+			"jwks_uri":               idp.URL + "/keys",
+		})
+	})
+	mux.HandleFunc("/keys", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"keys": []map[string]string{
+				{
+					"kty": "RSA",
+					"kid": "test",
+					"use": "sig",
+					"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+					"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+				},
+			},
+		})
+	})
+	mux.HandleFunc("/token", idp.handleToken)
+
+	idp.Server = httptest.NewServer(mux)
+	t.Cleanup(idp.Close)
+
+	return idp
+}
+
+// authorize returns the code the user agent is redirected back with
+func (idp *stubIdP) authorize(authURL string) string {
+	u, err := url.Parse(authURL)
+	require.NoError(idp.t, err)
+
+	params := u.Query()
+	require.Equal(idp.t, "S256", params.Get("code_challenge_method"))
+	require.Equal(idp.t, testClientID, params.Get("client_id"))
+
+	idp.mtx.Lock()
+	defer idp.mtx.Unlock()
+
+	code := "code-" + params.Get("state")
+	idp.codes[code] = params
+	return code
+}
+
+func (idp *stubIdP) handleToken(w http.ResponseWriter, r *http.Request) {
+	id, secret, ok := r.BasicAuth()
+	if !ok || id != testClientID || secret != testClientSecret {
+		w.WriteHeader(http.StatusUnauthorized)
+		_, _ = w.Write([]byte(`{"error":"invalid_client"}`))
+		return
+	}
+
+	idp.mtx.Lock()
+	params, ok := idp.codes[r.PostFormValue("code")]
+	delete(idp.codes, r.PostFormValue("code"))
+	idp.mtx.Unlock()
+
+	sum := sha256.Sum256([]byte(r.PostFormValue("code_verifier")))
+	if !ok || params.Get("code_challenge") != base64.RawURLEncoding.EncodeToString(sum[:]) {
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte(`{"error":"invalid_grant"}`))
+		return
+	}
+
+	claims := map[string]interface{}{
+		"iss":    idp.URL,
+		"sub":    "subject",
+		"aud":    []string{testClientID},
+		"exp":    time.Now().Add(time.Hour).Unix(),
+		"nonce":  params.Get("nonce"),
+		"email":  "foo@example.com",
+		"groups": []string{"admins", "devs"},
+	}
+	for k, v := range idp.claims {
+		claims[k] = v
+	}
+
+	_ = json.NewEncoder(w).Encode(map[string]string{
+		"access_token": "access",
+		"token_type":   "Bearer",
+		"id_token":     idp.sign(claims),
+	})
+}
+
+func (idp *stubIdP) sign(claims map[string]interface{}) string {
+	hdr, err := json.Marshal(map[string]string{"alg": "RS256", "kid": "test", "typ": "JWT"})
+	require.NoError(idp.t, err)
+	payload, err := json.Marshal(claims)
+	require.NoError(idp.t, err)
+
+	signed := base64.RawURLEncoding.EncodeToString(hdr) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	digest := sha256.Sum256([]byte(signed))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, idp.key, crypto.SHA256, digest[:])
+	require.NoError(idp.t, err)
+
+	return signed + "." + base64.RawURLEncoding.EncodeToString(sig)
+}
+
+func TestProvider(t *testing.T) {
+	ctx := context.Background()
+	idp := newStubIdP(t)
+
+	provider, err := New(Config{
+		Issuer:       idp.URL,
+		ClientID:     testClientID,
+		ClientSecret: testClientSecret,
+		RedirectURL:  "http://localhost/api/v1/oidc/callback",
+	})
+	require.NoError(t, err)
+
+	login := func(t *testing.T) (string, *AuthRequest) {
+		req, err := NewAuthRequest()
+		require.NoError(t, err)
+
+		authURL, err := provider.AuthCodeURL(ctx, req)
+		require.NoError(t, err)
+
+		return idp.authorize(authURL), req
+	}
+
+	t.Run("exchange", func(t *testing.T) {
+		code, req := login(t)
+
+		claims, err := provider.Exchange(ctx, code, req)
+		require.NoError(t, err)
+		assert.Equal(t, "subject", claims.Subject)
+		assert.Equal(t, "foo@example.com", claims.Email)
+		assert.False(t, claims.EmailVerified)
+		assert.Equal(t, []string{"admins", "devs"}, claims.Groups)
+
+		// codes are single use
+		_, err = provider.Exchange(ctx, code, req)
+		assert.Equal(t, manta.EUnauthorized, manta.ErrorCode(err))
+	})
+
+	t.Run("email verified", func(t *testing.T) {
+		for _, verified := range []interface{}{true, "true"} {
+			idp.claims = map[string]interface{}{"email_verified": verified}
+			code, req := login(t)
+
+			claims, err := provider.Exchange(ctx, code, req)
+			require.NoError(t, err)
+			assert.True(t, claims.EmailVerified)
+		}
+		idp.claims = nil
+	})
+
+	t.Run("verifier not match", func(t *testing.T) {
+		code, req := login(t)
+		req.Verifier = "another"
+
+		_, err := provider.Exchange(ctx, code, req)
+		assert.Equal(t, manta.EUnauthorized, manta.ErrorCode(err))
+	})
+
+	t.Run("nonce not match", func(t *testing.T) {
+		code, req := login(t)
+		req.Nonce = "another"
+
+		_, err := provider.Exchange(ctx, code, req)
+		assert.Equal(t, manta.EUnauthorized, manta.ErrorCode(err))
+	})
+
+	for name, claims := range map[string]map[string]interface{}{
+		"audience not match": {"aud": "another"},
+		"expired":            {"exp": time.Now().Add(-time.Hour).Unix()},
+		"issuer not match":   {"iss": "http://another"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			idp.claims = claims
+			defer func() { idp.claims = nil }()
+
+			code, req := login(t)
+			_, err := provider.Exchange(ctx, code, req)
+			assert.Equal(t, manta.EUnauthorized, manta.ErrorCode(err))
+		})
+	}
+
+	t.Run("bad signature", func(t *testing.T) {
+		token := idp.sign(map[string]interface{}{"sub": "subject"})
+		_, err := provider.verify(ctx, token[:len(token)-4]+"AAAA", "")
+		assert.Equal(t, manta.EUnauthorized, manta.ErrorCode(err))
+	})
+}
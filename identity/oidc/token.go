@@ -0,0 +1,322 @@
+package oidc
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/f1shl3gs/manta"
+)
+
+// leeway tolerates the clock skew between manta and the OpenID provider
+const leeway = time.Minute
+
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+func decodeBigInt(s string) (*big.Int, error) {
+	buf, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+
+	return new(big.Int).SetBytes(buf), nil
+}
+
+// publicKey returns the public key of RSA or P-256 keys, nil for the rest
+func (k *jwk) publicKey() (interface{}, error) {
+	if k.Use != "" && k.Use != "sig" {
+		return nil, nil
+	}
+
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeBigInt(k.N)
+		if err != nil {
+			return nil, err
+		}
+
+		e, err := decodeBigInt(k.E)
+		if err != nil {
+			return nil, err
+		}
+
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+
+	case "EC":
+		if k.Crv != "P-256" {
+			return nil, nil
+		}
+
+		x, err := decodeBigInt(k.X)
+		if err != nil {
+			return nil, err
+		}
+
+		y, err := decodeBigInt(k.Y)
+		if err != nil {
+			return nil, err
+		}
+
+		return &ecdsa.PublicKey{Curve: elliptic.P256(), X: x, Y: y}, nil
+
+	default:
+		return nil, nil
+	}
+}
+
+// key returns the key to verify the signature, keys are fetched again if
+// kid is unknown, since the OpenID provider might rotate its keys.
+func (p *Provider) key(ctx context.Context, kid string) (interface{}, error) {
+	d, err := p.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+
+	if key, ok := p.keys[kid]; ok {
+		return key, nil
+	}
+
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err = p.getJSON(ctx, d.JwksURI, &set); err != nil {
+		return nil, err
+	}
+
+	keys := make(map[string]interface{}, len(set.Keys))
+	for _, k := range set.Keys {
+		key, err := k.publicKey()
+		if err != nil {
+			return nil, &manta.Error{
+				Code: manta.EUnavailable,
+				Msg:  fmt.Sprintf("invalid oidc key %q", k.Kid),
+				Err:  err,
+			}
+		}
+
+		if key != nil {
+			keys[k.Kid] = key
+		}
+	}
+	p.keys = keys
+
+	key, ok := keys[kid]
+	if !ok {
+		return nil, errInvalidToken("unknown signing key")
+	}
+
+	return key, nil
+}
+
+func errInvalidToken(msg string) error {
+	return &manta.Error{
+		Code: manta.EUnauthorized,
+		Msg:  "invalid id token, " + msg,
+	}
+}
+
+type header struct {
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+}
+
+// stringList is a string or an array of strings, e.g. the audience
+type stringList []string
+
+func (a *stringList) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err == nil {
+		*a = stringList{s}
+		return nil
+	}
+
+	var list []string
+	if err := json.Unmarshal(data, &list); err != nil {
+		return err
+	}
+
+	*a = list
+	return nil
+}
+
+func (a stringList) contains(s string) bool {
+	for _, v := range a {
+		if v == s {
+			return true
+		}
+	}
+
+	return false
+}
+
+// boolean is a bool or a string of bool, some providers send
+// email_verified as "true"
+type boolean bool
+
+func (b *boolean) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err == nil {
+		*b = s == "true"
+		return nil
+	}
+
+	var v bool
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+
+	*b = boolean(v)
+	return nil
+}
+
+type standardClaims struct {
+	Issuer            string     `json:"iss"`
+	Subject           string     `json:"sub"`
+	Audience          stringList `json:"aud"`
+	Expiry            int64      `json:"exp"`
+	Nonce             string     `json:"nonce"`
+	Email             string     `json:"email"`
+	EmailVerified     boolean    `json:"email_verified"`
+	Name              string     `json:"name"`
+	PreferredUsername string     `json:"preferred_username"`
+}
+
+// verify verifies the signature and claims of the id token
+func (p *Provider) verify(ctx context.Context, token, nonce string) (*Claims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, errInvalidToken("malformed jwt")
+	}
+
+	var hdr header
+	data, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil || json.Unmarshal(data, &hdr) != nil {
+		return nil, errInvalidToken("malformed jwt header")
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, errInvalidToken("malformed jwt payload")
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, errInvalidToken("malformed jwt signature")
+	}
+
+	key, err := p.key(ctx, hdr.Kid)
+	if err != nil {
+		return nil, err
+	}
+
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	switch pub := key.(type) {
+	case *rsa.PublicKey:
+		if hdr.Alg != "RS256" {
+			return nil, errInvalidToken("unexpected algorithm " + hdr.Alg)
+		}
+
+		if err = rsa.VerifyPKCS1v15(pub, crypto.SHA256, digest[:], signature); err != nil {
+			return nil, errInvalidToken("bad signature")
+		}
+
+	case *ecdsa.PublicKey:
+		if hdr.Alg != "ES256" {
+			return nil, errInvalidToken("unexpected algorithm " + hdr.Alg)
+		}
+
+		if len(signature) != 64 {
+			return nil, errInvalidToken("bad signature")
+		}
+
+		r := new(big.Int).SetBytes(signature[:32])
+		s := new(big.Int).SetBytes(signature[32:])
+		if !ecdsa.Verify(pub, digest[:], r, s) {
+			return nil, errInvalidToken("bad signature")
+		}
+
+	default:
+		return nil, errInvalidToken("unsupported signing key")
+	}
+
+	var claims standardClaims
+	if err = json.Unmarshal(payload, &claims); err != nil {
+		return nil, errInvalidToken("malformed claims")
+	}
+
+	if strings.TrimSuffix(claims.Issuer, "/") != p.cf.Issuer {
+		return nil, errInvalidToken("unexpected issuer")
+	}
+
+	if !claims.Audience.contains(p.cf.ClientID) {
+		return nil, errInvalidToken("unexpected audience")
+	}
+
+	if time.Unix(claims.Expiry, 0).Add(leeway).Before(time.Now()) {
+		return nil, errInvalidToken("expired")
+	}
+
+	if claims.Nonce != nonce {
+		return nil, errInvalidToken("nonce not match")
+	}
+
+	if claims.Subject == "" {
+		return nil, errInvalidToken("no subject")
+	}
+
+	groups, err := p.groups(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Claims{
+		Subject:           claims.Subject,
+		Email:             claims.Email,
+		EmailVerified:     bool(claims.EmailVerified),
+		Name:              claims.Name,
+		PreferredUsername: claims.PreferredUsername,
+		Groups:            groups,
+	}, nil
+}
+
+// groups returns the groups claim, which could be a string or an array
+// of strings, or absent.
+func (p *Provider) groups(payload []byte) ([]string, error) {
+	var all map[string]json.RawMessage
+	if err := json.Unmarshal(payload, &all); err != nil {
+		return nil, errInvalidToken("malformed claims")
+	}
+
+	raw, ok := all[p.cf.GroupsClaim]
+	if !ok {
+		return nil, nil
+	}
+
+	var groups stringList
+	if err := json.Unmarshal(raw, &groups); err != nil {
+		return nil, errInvalidToken(fmt.Sprintf("claim %q is not a list of strings", p.cf.GroupsClaim))
+	}
+
+	return groups, nil
+}
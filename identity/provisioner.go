@@ -0,0 +1,334 @@
+// Package identity provisions the users authenticated by external identity
+// providers, e.g. OIDC or LDAP, and syncs their groups to organizations.
+package identity
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"go.uber.org/zap"
+
+	"github.com/f1shl3gs/manta"
+)
+
+// External is a user authenticated by an external identity provider
+type External struct {
+	// Provider is the name of the identity provider, e.g. "oidc" or "ldap"
+	Provider string
+	// Subject identifies the user in the identity provider, it never changes
+	Subject string
+	Email   string
+	// EmailVerified is true if the identity provider verified the user
+	// owns the email, unverified email is never used as the username.
+	EmailVerified bool
+	Name          string
+	Groups        []string
+}
+
+// username returns the name of the manta user, verified email is preferred
+// since it is unique in most identity providers.
+func (ext *External) username() string {
+	if ext.Email != "" && ext.EmailVerified {
+		return ext.Email
+	}
+
+	return ext.Name
+}
+
+// GroupMapping grants the members of the group access to the organization
+type GroupMapping struct {
+	Group    string
+	Org      string
+	UserType manta.UserType
+	// Role is the name of the role assigned to members
+	Role string
+}
+
+// ParseGroupMappings parses mappings of group to "org:role", role can be
+// "owner", "member" or the name of a role of the organization.
+func ParseGroupMappings(m map[string]string) ([]GroupMapping, error) {
+	groups := make([]string, 0, len(m))
+	for group := range m {
+		groups = append(groups, group)
+	}
+	sort.Strings(groups)
+
+	mappings := make([]GroupMapping, 0, len(m))
+	for _, group := range groups {
+		org, role, ok := strings.Cut(m[group], ":")
+		if !ok || org == "" || role == "" {
+			return nil, fmt.Errorf("invalid mapping %q of group %q, expect org:role", m[group], group)
+		}
+
+		mapping := GroupMapping{
+			Group: group,
+			Org:   org,
+		}
+
+		switch manta.UserType(role) {
+		case manta.Owner, manta.Member:
+			mapping.UserType = manta.UserType(role)
+		default:
+			mapping.UserType = manta.Member
+			mapping.Role = role
+		}
+
+		mappings = append(mappings, mapping)
+	}
+
+	return mappings, nil
+}
+
+type Config struct {
+	// AutoProvision creates users the first time they sign in, otherwise
+	// only existing users can sign in.
+	AutoProvision bool
+
+	// LinkLocalUsers links users who have a password to the external
+	// identity with the same name when they sign in the first time. It is
+	// off by default, since whoever controls the name in the identity
+	// provider takes over the local user.
+	LinkLocalUsers bool
+
+	// GroupMappings syncs organizations and roles of users every time they
+	// sign in, organizations not mapped are left untouched.
+	GroupMappings []GroupMapping
+}
+
+type Provisioner struct {
+	logger *zap.Logger
+	cfg    Config
+
+	userService         manta.UserService
+	passwordService     manta.PasswordService
+	userIdentityService manta.UserIdentityService
+	organizationService manta.OrganizationService
+	urmService          manta.UserResourceMappingService
+}
+
+func NewProvisioner(
+	logger *zap.Logger,
+	cfg Config,
+	userService manta.UserService,
+	passwordService manta.PasswordService,
+	userIdentityService manta.UserIdentityService,
+	organizationService manta.OrganizationService,
+	urmService manta.UserResourceMappingService,
+) *Provisioner {
+	return &Provisioner{
+		logger:              logger,
+		cfg:                 cfg,
+		userService:         userService,
+		passwordService:     passwordService,
+		userIdentityService: userIdentityService,
+		organizationService: organizationService,
+		urmService:          urmService,
+	}
+}
+
+// Provision returns the user linked to the external identity. Users signed
+// in the first time are linked by name to users created without password,
+// or created if auto provision is enabled.
+func (p *Provisioner) Provision(ctx context.Context, ext *External) (*manta.User, error) {
+	user, err := p.findUser(ctx, ext)
+	if err != nil {
+		return nil, err
+	}
+
+	if !user.Active() {
+		return nil, manta.ErrUserDisabled
+	}
+
+	if len(p.cfg.GroupMappings) != 0 {
+		if err = p.SyncGroups(ctx, user.ID, ext.Groups); err != nil {
+			return nil, err
+		}
+	}
+
+	return user, nil
+}
+
+func (p *Provisioner) findUser(ctx context.Context, ext *External) (*manta.User, error) {
+	if ext.Provider == "" || ext.Subject == "" {
+		return nil, &manta.Error{
+			Code: manta.EInvalid,
+			Msg:  "provider and subject of external identity are required",
+		}
+	}
+
+	ident, err := p.userIdentityService.FindUserIdentity(ctx, ext.Provider, ext.Subject)
+	if err == nil {
+		return p.userService.FindUserByID(ctx, ident.UserID)
+	}
+	if manta.ErrorCode(err) != manta.ENotFound {
+		return nil, err
+	}
+
+	name := ext.username()
+	if name == "" {
+		return nil, &manta.Error{
+			Code: manta.EInvalid,
+			Msg:  "external identity has neither email nor name",
+		}
+	}
+
+	user, err := p.userService.FindUser(ctx, manta.UserFilter{Name: &name})
+	if manta.ErrorCode(err) == manta.ENotFound {
+		if !p.cfg.AutoProvision {
+			return nil, &manta.Error{
+				Code: manta.EForbidden,
+				Msg:  "user is not provisioned",
+			}
+		}
+
+		user = &manta.User{Name: name}
+		err = p.userService.CreateUser(ctx, user)
+		if err == nil {
+			p.logger.Info("user provisioned",
+				zap.String("provider", ext.Provider),
+				zap.String("user", name))
+		}
+	} else if err == nil {
+		err = p.checkLink(ctx, user)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	err = p.userIdentityService.CreateUserIdentity(ctx, &manta.UserIdentity{
+		Provider: ext.Provider,
+		Subject:  ext.Subject,
+		UserID:   user.ID,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+// checkLink returns EForbidden if the existing user cannot be linked, users
+// with password are linked only if LinkLocalUsers is enabled.
+func (p *Provisioner) checkLink(ctx context.Context, user *manta.User) error {
+	if p.cfg.LinkLocalUsers {
+		return nil
+	}
+
+	has, err := p.passwordService.HasPassword(ctx, user.ID)
+	if err != nil {
+		return err
+	}
+
+	if has {
+		return &manta.Error{
+			Code: manta.EForbidden,
+			Msg:  "user " + user.Name + " has a password, it cannot be linked to the external identity",
+		}
+	}
+
+	return nil
+}
+
+// SyncGroups grants the user access to the organizations mapped by groups,
+// and revokes access to the mapped organizations the groups no longer map
+// to. Owner wins if groups map to different roles of the same organization.
+// The last owner of an organization is never removed or demoted.
+func (p *Provisioner) SyncGroups(ctx context.Context, userID manta.ID, groups []string) error {
+	member := make(map[string]bool, len(groups))
+	for _, group := range groups {
+		member[group] = true
+	}
+
+	var (
+		managed = make(map[manta.ID]bool)
+		desired = make(map[manta.ID]GroupMapping)
+	)
+
+	for _, mapping := range p.cfg.GroupMappings {
+		org, err := p.organizationService.FindOrganization(ctx, manta.OrganizationFilter{Name: &mapping.Org})
+		if manta.ErrorCode(err) == manta.ENotFound {
+			p.logger.Warn("organization of group mapping not found",
+				zap.String("group", mapping.Group),
+				zap.String("org", mapping.Org))
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		managed[org.ID] = true
+		if !member[mapping.Group] {
+			continue
+		}
+
+		prev, exist := desired[org.ID]
+		if !exist || (prev.UserType != manta.Owner && mapping.UserType == manta.Owner) {
+			desired[org.ID] = mapping
+		}
+	}
+
+	urms, _, err := p.urmService.FindUserResourceMappings(ctx, manta.UserResourceMappingFilter{
+		UserID:       userID,
+		ResourceType: manta.OrgsResourceType,
+	}, manta.FindOptions{})
+	if err != nil {
+		return err
+	}
+
+	for _, urm := range urms {
+		if !managed[urm.ResourceID] {
+			continue
+		}
+
+		mapping, ok := desired[urm.ResourceID]
+		if ok && mapping.UserType == urm.UserType && mapping.Role == urm.Role {
+			delete(desired, urm.ResourceID)
+			continue
+		}
+
+		if ok {
+			// it's overwritten below, deleting it first might remove the
+			// last owner
+			continue
+		}
+
+		err = p.urmService.DeleteUserResourceMapping(ctx, urm.ResourceID, userID)
+		if err == manta.ErrLastOwner {
+			p.warnLastOwner(userID, urm.ResourceID)
+			continue
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	for orgID, mapping := range desired {
+		err = p.urmService.CreateUserResourceMapping(ctx, &manta.UserResourceMapping{
+			UserID:       userID,
+			UserType:     mapping.UserType,
+			MappingType:  manta.UserMappingType,
+			ResourceType: manta.OrgsResourceType,
+			ResourceID:   orgID,
+			Role:         mapping.Role,
+		})
+		if err == manta.ErrLastOwner {
+			p.warnLastOwner(userID, orgID)
+			continue
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// warnLastOwner logs the last owner is kept, the mapping will be synced
+// once the organization has another owner.
+func (p *Provisioner) warnLastOwner(userID, orgID manta.ID) {
+	p.logger.Warn("keep the last owner of organization",
+		zap.Stringer("user", userID),
+		zap.Stringer("org", orgID))
+}
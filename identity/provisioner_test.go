@@ -0,0 +1,219 @@
+package identity
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"github.com/f1shl3gs/manta"
+	"github.com/f1shl3gs/manta/bolt"
+	"github.com/f1shl3gs/manta/kv"
+	"github.com/f1shl3gs/manta/kv/migration"
+)
+
+func newTestService(t *testing.T) *kv.Service {
+	logger := zaptest.NewLogger(t)
+	store := bolt.NewKVStore(logger, t.TempDir()+"/manta.db", bolt.WithNoSync)
+	require.NoError(t, store.Open(context.Background()))
+	t.Cleanup(func() { _ = store.Close() })
+
+	migrator := migration.New(logger, store, migration.All...)
+	require.NoError(t, migrator.Up(context.Background()))
+
+	return kv.NewService(logger, store)
+}
+
+func newTestProvisioner(t *testing.T, svc *kv.Service, cfg Config) *Provisioner {
+	return NewProvisioner(zaptest.NewLogger(t), cfg, svc, svc, svc, svc, svc)
+}
+
+func TestParseGroupMappings(t *testing.T) {
+	mappings, err := ParseGroupMappings(map[string]string{
+		"admins": "prod:owner",
+		"devs":   "prod:editor",
+		"ops":    "staging:member",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []GroupMapping{
+		{Group: "admins", Org: "prod", UserType: manta.Owner},
+		{Group: "devs", Org: "prod", UserType: manta.Member, Role: manta.EditorRole},
+		{Group: "ops", Org: "staging", UserType: manta.Member},
+	}, mappings)
+
+	_, err = ParseGroupMappings(map[string]string{"admins": "prod"})
+	assert.Error(t, err)
+}
+
+func TestProvision(t *testing.T) {
+	ctx := context.Background()
+	svc := newTestService(t)
+
+	ext := &External{
+		Provider:      "oidc",
+		Subject:       "subject",
+		Email:         "foo@example.com",
+		EmailVerified: true,
+	}
+
+	t.Run("not provisioned", func(t *testing.T) {
+		p := newTestProvisioner(t, svc, Config{})
+
+		_, err := p.Provision(ctx, ext)
+		assert.Equal(t, manta.EForbidden, manta.ErrorCode(err))
+	})
+
+	t.Run("auto provision", func(t *testing.T) {
+		p := newTestProvisioner(t, svc, Config{AutoProvision: true})
+
+		user, err := p.Provision(ctx, ext)
+		require.NoError(t, err)
+		assert.Equal(t, "foo@example.com", user.Name)
+
+		// renamed in the identity provider, still the same user
+		renamed := *ext
+		renamed.Email = "bar@example.com"
+		found, err := p.Provision(ctx, &renamed)
+		require.NoError(t, err)
+		assert.Equal(t, user.ID, found.ID)
+	})
+
+	t.Run("unverified email", func(t *testing.T) {
+		p := newTestProvisioner(t, svc, Config{AutoProvision: true})
+
+		user, err := p.Provision(ctx, &External{
+			Provider: "oidc",
+			Subject:  "unverified",
+			Email:    "foo@example.com",
+			Name:     "unverified",
+		})
+		require.NoError(t, err)
+		assert.Equal(t, "unverified", user.Name)
+	})
+
+	t.Run("local user with password", func(t *testing.T) {
+		user := &manta.User{Name: "local"}
+		require.NoError(t, svc.CreateUser(ctx, user))
+		require.NoError(t, svc.SetPassword(ctx, user.ID, "password"))
+
+		ext := &External{Provider: "oidc", Subject: "local", Name: "local"}
+		p := newTestProvisioner(t, svc, Config{AutoProvision: true})
+		_, err := p.Provision(ctx, ext)
+		assert.Equal(t, manta.EForbidden, manta.ErrorCode(err))
+
+		// nothing is linked
+		_, err = svc.FindUserIdentity(ctx, "oidc", "local")
+		assert.Equal(t, manta.ErrUserIdentityNotFound, err)
+
+		p = newTestProvisioner(t, svc, Config{LinkLocalUsers: true})
+		found, err := p.Provision(ctx, ext)
+		require.NoError(t, err)
+		assert.Equal(t, user.ID, found.ID)
+	})
+
+	t.Run("link existing user", func(t *testing.T) {
+		user := &manta.User{Name: "bar"}
+		require.NoError(t, svc.CreateUser(ctx, user))
+
+		p := newTestProvisioner(t, svc, Config{})
+		found, err := p.Provision(ctx, &External{Provider: "oidc", Subject: "bar", Name: "bar"})
+		require.NoError(t, err)
+		assert.Equal(t, user.ID, found.ID)
+
+		idents, err := svc.FindUserIdentities(ctx, manta.UserIdentityFilter{UserID: &user.ID})
+		require.NoError(t, err)
+		assert.Len(t, idents, 1)
+
+		inactive := manta.UserInactive
+		_, err = svc.UpdateUser(ctx, user.ID, manta.UserUpdate{Status: &inactive})
+		require.NoError(t, err)
+
+		_, err = p.Provision(ctx, &External{Provider: "oidc", Subject: "bar", Name: "bar"})
+		assert.Equal(t, manta.ErrUserDisabled, err)
+
+		// identities are deleted with the user
+		require.NoError(t, svc.DeleteUser(ctx, user.ID))
+		_, err = svc.FindUserIdentity(ctx, "oidc", "bar")
+		assert.Equal(t, manta.ErrUserIdentityNotFound, err)
+	})
+}
+
+func TestSyncGroups(t *testing.T) {
+	ctx := context.Background()
+	svc := newTestService(t)
+
+	prod := &manta.Organization{Name: "prod"}
+	require.NoError(t, svc.CreateOrganization(ctx, prod))
+	other := &manta.Organization{Name: "other"}
+	require.NoError(t, svc.CreateOrganization(ctx, other))
+
+	user := &manta.User{Name: "foo"}
+	require.NoError(t, svc.CreateUser(ctx, user))
+	require.NoError(t, svc.CreateUserResourceMapping(ctx, &manta.UserResourceMapping{
+		UserID:       user.ID,
+		UserType:     manta.Member,
+		MappingType:  manta.UserMappingType,
+		ResourceType: manta.OrgsResourceType,
+		ResourceID:   other.ID,
+	}))
+
+	mappings, err := ParseGroupMappings(map[string]string{
+		"admins":  "prod:owner",
+		"devs":    "prod:editor",
+		"missing": "missing:member",
+	})
+	require.NoError(t, err)
+	p := newTestProvisioner(t, svc, Config{GroupMappings: mappings})
+
+	orgMapping := func(orgID manta.ID) *manta.UserResourceMapping {
+		urms, _, err := svc.FindUserResourceMappings(ctx, manta.UserResourceMappingFilter{
+			UserID:       user.ID,
+			ResourceType: manta.OrgsResourceType,
+		}, manta.FindOptions{})
+		require.NoError(t, err)
+
+		for _, urm := range urms {
+			if urm.ResourceID == orgID {
+				return urm
+			}
+		}
+
+		return nil
+	}
+
+	require.NoError(t, p.SyncGroups(ctx, user.ID, []string{"devs"}))
+	urm := orgMapping(prod.ID)
+	require.NotNil(t, urm)
+	assert.Equal(t, manta.Member, urm.UserType)
+	assert.Equal(t, manta.EditorRole, urm.Role)
+
+	// owner wins
+	require.NoError(t, p.SyncGroups(ctx, user.ID, []string{"devs", "admins"}))
+	urm = orgMapping(prod.ID)
+	require.NotNil(t, urm)
+	assert.Equal(t, manta.Owner, urm.UserType)
+	assert.Empty(t, urm.Role)
+
+	// not in the groups anymore, but the last owner is kept
+	require.NoError(t, p.SyncGroups(ctx, user.ID, nil))
+	urm = orgMapping(prod.ID)
+	require.NotNil(t, urm)
+	assert.Equal(t, manta.Owner, urm.UserType)
+
+	owner := &manta.User{Name: "owner"}
+	require.NoError(t, svc.CreateUser(ctx, owner))
+	require.NoError(t, svc.CreateUserResourceMapping(ctx, &manta.UserResourceMapping{
+		UserID:       owner.ID,
+		UserType:     manta.Owner,
+		MappingType:  manta.UserMappingType,
+		ResourceType: manta.OrgsResourceType,
+		ResourceID:   prod.ID,
+	}))
+	require.NoError(t, p.SyncGroups(ctx, user.ID, nil))
+	assert.Nil(t, orgMapping(prod.ID))
+
+	// organizations not mapped are untouched
+	assert.NotNil(t, orgMapping(other.ID))
+}
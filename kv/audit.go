@@ -0,0 +1,192 @@
+package kv
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"time"
+
+	"github.com/f1shl3gs/manta"
+	"github.com/f1shl3gs/manta/pkg/tracing"
+)
+
+var (
+	// AuditRecordsBucket stores the audit records of authentication
+	//   key:    Timestamp + ID
+	//   value:  Marshaled AuditRecord
+	AuditRecordsBucket = []byte("auditrecords")
+)
+
+var _ manta.AuditService = (*Service)(nil)
+
+func auditRecordKey(rec *manta.AuditRecord) ([]byte, error) {
+	pk, err := rec.ID.Encode()
+	if err != nil {
+		return nil, err
+	}
+
+	buf := binary.BigEndian.AppendUint64(nil, uint64(rec.Time.UnixNano()))
+	return append(buf, pk...), nil
+}
+
+// AddAuditRecord stores the record, and sets the ID and Time of it
+func (s *Service) AddAuditRecord(ctx context.Context, rec *manta.AuditRecord) error {
+	span, ctx := tracing.StartSpanFromContext(ctx)
+	defer span.Finish()
+
+	rec.ID = s.idGen.ID()
+	rec.Time = time.Now()
+
+	key, err := auditRecordKey(rec)
+	if err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+
+	return s.kv.Update(ctx, func(tx Tx) error {
+		b, err := tx.Bucket(AuditRecordsBucket)
+		if err != nil {
+			return err
+		}
+
+		return b.Put(key, data)
+	})
+}
+
+// FindAuditRecords returns the records match the filter, ordered by time.
+// The time range is used to seek, and the other conditions are checked one
+// by one.
+func (s *Service) FindAuditRecords(ctx context.Context, filter manta.AuditFilter, opts manta.FindOptions) ([]*manta.AuditRecord, error) {
+	span, ctx := tracing.StartSpanFromContext(ctx)
+	defer span.Finish()
+
+	records := []*manta.AuditRecord{}
+	err := s.kv.View(ctx, func(tx Tx) error {
+		b, err := tx.Bucket(AuditRecordsBucket)
+		if err != nil {
+			return err
+		}
+
+		cursor, err := b.Cursor()
+		if err != nil {
+			return err
+		}
+
+		skipped := 0
+		for k, v := seekAuditRecords(cursor, filter, opts.Descending); k != nil; k, v = nextChange(cursor, opts.Descending) {
+			if !auditRecordInRange(k, filter) {
+				break
+			}
+
+			rec := &manta.AuditRecord{}
+			if err = json.Unmarshal(v, rec); err != nil {
+				return err
+			}
+
+			if !filter.Match(rec) {
+				continue
+			}
+
+			if skipped < opts.Offset {
+				skipped += 1
+				continue
+			}
+
+			records = append(records, rec)
+			if opts.Limit > 0 && len(records) >= opts.Limit {
+				break
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return records, nil
+}
+
+// DeleteAuditRecords deletes the records older than the time, and returns
+// how many records are deleted
+func (s *Service) DeleteAuditRecords(ctx context.Context, before time.Time) (int, error) {
+	span, ctx := tracing.StartSpanFromContext(ctx)
+	defer span.Finish()
+
+	var deleted int
+	err := s.kv.Update(ctx, func(tx Tx) error {
+		b, err := tx.Bucket(AuditRecordsBucket)
+		if err != nil {
+			return err
+		}
+
+		cursor, err := b.Cursor()
+		if err != nil {
+			return err
+		}
+
+		var keys [][]byte
+		for k, _ := cursor.First(); k != nil; k, _ = cursor.Next() {
+			if len(k) < 8 || int64(binary.BigEndian.Uint64(k)) >= before.UnixNano() {
+				break
+			}
+
+			keys = append(keys, append([]byte{}, k...))
+		}
+
+		for _, key := range keys {
+			if err = b.Delete(key); err != nil {
+				return err
+			}
+		}
+
+		deleted = len(keys)
+		return nil
+	})
+
+	return deleted, err
+}
+
+// seekAuditRecords moves the cursor to the first key in the time range
+func seekAuditRecords(cursor Cursor, filter manta.AuditFilter, descending bool) ([]byte, []byte) {
+	if !descending {
+		if filter.Since == nil {
+			return cursor.First()
+		}
+
+		return cursor.Seek(binary.BigEndian.AppendUint64(nil, uint64(filter.Since.UnixNano())))
+	}
+
+	if filter.Until == nil {
+		return cursor.Last()
+	}
+
+	// seek to the first key after the range, and step back
+	k, _ := cursor.Seek(binary.BigEndian.AppendUint64(nil, uint64(filter.Until.UnixNano()+1)))
+	if k == nil {
+		return cursor.Last()
+	}
+
+	return cursor.Prev()
+}
+
+func auditRecordInRange(key []byte, filter manta.AuditFilter) bool {
+	if len(key) < 8 {
+		return false
+	}
+
+	ts := int64(binary.BigEndian.Uint64(key))
+	if filter.Since != nil && ts < filter.Since.UnixNano() {
+		return false
+	}
+
+	if filter.Until != nil && ts > filter.Until.UnixNano() {
+		return false
+	}
+
+	return true
+}
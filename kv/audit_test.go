@@ -0,0 +1,72 @@
+package kv_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/f1shl3gs/manta"
+)
+
+func TestAuditRecords(t *testing.T) {
+	svc, closer := NewTestService(t)
+	defer closer()
+
+	ctx := context.Background()
+	for _, rec := range []*manta.AuditRecord{
+		{Action: manta.AuditSigninFailed, Username: "foo", IP: "10.0.0.1"},
+		{Action: manta.AuditSignin, UserID: 1, Username: "foo", IP: "10.0.0.1"},
+		{Action: manta.AuditTokenUsed, UserID: 2, AuthorizationID: 3, IP: "10.0.0.2"},
+		{Action: manta.AuditSignout, UserID: 1, IP: "10.0.0.1"},
+	} {
+		require.NoError(t, svc.AddAuditRecord(ctx, rec))
+		require.True(t, rec.ID.Valid())
+		require.False(t, rec.Time.IsZero())
+	}
+
+	actions := func(records []*manta.AuditRecord) []manta.AuditAction {
+		var list []manta.AuditAction
+		for _, rec := range records {
+			list = append(list, rec.Action)
+		}
+
+		return list
+	}
+
+	records, err := svc.FindAuditRecords(ctx, manta.AuditFilter{}, manta.FindOptions{})
+	require.NoError(t, err)
+	require.Equal(t, []manta.AuditAction{
+		manta.AuditSigninFailed,
+		manta.AuditSignin,
+		manta.AuditTokenUsed,
+		manta.AuditSignout,
+	}, actions(records))
+
+	uid := manta.ID(1)
+	records, err = svc.FindAuditRecords(ctx, manta.AuditFilter{UserID: &uid}, manta.FindOptions{Descending: true})
+	require.NoError(t, err)
+	require.Equal(t, []manta.AuditAction{
+		manta.AuditSignout,
+		manta.AuditSignin,
+	}, actions(records))
+
+	username := "foo"
+	records, err = svc.FindAuditRecords(ctx, manta.AuditFilter{Username: &username}, manta.FindOptions{Limit: 1, Offset: 1})
+	require.NoError(t, err)
+	require.Equal(t, []manta.AuditAction{manta.AuditSignin}, actions(records))
+
+	future := time.Now().Add(time.Minute)
+	records, err = svc.FindAuditRecords(ctx, manta.AuditFilter{Since: &future}, manta.FindOptions{})
+	require.NoError(t, err)
+	require.Empty(t, records)
+
+	deleted, err := svc.DeleteAuditRecords(ctx, future)
+	require.NoError(t, err)
+	require.Equal(t, 4, deleted)
+
+	records, err = svc.FindAuditRecords(ctx, manta.AuditFilter{}, manta.FindOptions{})
+	require.NoError(t, err)
+	require.Empty(t, records)
+}
@@ -30,7 +30,7 @@ func (s *Service) FindAuthorizationByID(ctx context.Context, id manta.ID) (*mant
 		err  error
 	)
 
-	err = s.kv.View(ctx, func(tx Tx) error {
+	err = s.kv.View(WithLinearizableRead(ctx), func(tx Tx) error {
 		auth, err = s.findAuthorizationByID(ctx, tx, id)
 		return err
 	})
@@ -75,7 +75,7 @@ func (s *Service) FindAuthorizationByToken(ctx context.Context, token string) (*
 		err  error
 	)
 
-	err = s.kv.View(ctx, func(tx Tx) error {
+	err = s.kv.View(WithLinearizableRead(ctx), func(tx Tx) error {
 		auth, err = s.findAuthorizationByToken(ctx, tx, token)
 		return err
 	})
@@ -361,3 +361,48 @@ func (s *Service) deleteAuthorization(ctx context.Context, tx Tx, id manta.ID) e
 
 	return b.Delete(pk)
 }
+
+// deleteUserAuthorizations deletes all authorizations of the user, the user
+// index keeps only one authorization per user, so the authorizations are
+// scanned.
+func (s *Service) deleteUserAuthorizations(ctx context.Context, tx Tx, uid manta.ID) error {
+	b, err := tx.Bucket(authorizationBucket)
+	if err != nil {
+		return err
+	}
+
+	cursor, err := b.Cursor()
+	if err != nil {
+		return err
+	}
+
+	var ids []manta.ID
+	for k, v := cursor.First(); k != nil; k, v = cursor.Next() {
+		auth := &manta.Authorization{}
+		if err = json.Unmarshal(v, auth); err != nil {
+			return err
+		}
+
+		if auth.UID == uid {
+			ids = append(ids, auth.ID)
+		}
+	}
+
+	for _, id := range ids {
+		if err = s.deleteAuthorization(ctx, tx, id); err != nil {
+			return err
+		}
+	}
+
+	fk, err := uid.Encode()
+	if err != nil {
+		return err
+	}
+
+	b, err = tx.Bucket(authorizationUserIndexBucket)
+	if err != nil {
+		return err
+	}
+
+	return b.Delete(fk)
+}
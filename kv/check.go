@@ -191,5 +191,9 @@ func (s *Service) deleteCheck(tx Tx, id manta.ID) error {
 		}
 	}
 
+	if err = deleteOperationLogs(tx, id, nil); err != nil {
+		return err
+	}
+
 	return deleteOrgIndexed[manta.Check](tx, id, ChecksBucket, CheckOrgIndexBucket)
 }
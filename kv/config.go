@@ -5,6 +5,7 @@ import (
 	"time"
 
 	"github.com/f1shl3gs/manta"
+	"github.com/f1shl3gs/manta/config/validate"
 )
 
 var (
@@ -24,8 +25,23 @@ func (s *Service) createConfig(ctx context.Context, tx Tx, cf *manta.Config) err
 	cf.ID = s.idGen.ID()
 	cf.Created = now
 	cf.Updated = now
+	cf.Revision = 1
 
-	return putOrgIndexed(tx, cf, ConfigBucket, ConfigOrgIndexBucket)
+	if err := validate.Config(cf); err != nil {
+		return err
+	}
+
+	if cf.Rollout != nil {
+		if err := cf.Rollout.Validate(); err != nil {
+			return err
+		}
+	}
+
+	if err := putOrgIndexed(tx, cf, ConfigBucket, ConfigOrgIndexBucket); err != nil {
+		return err
+	}
+
+	return putConfigRevision(ctx, tx, cf, 0)
 }
 
 func (s *Service) FindConfigByID(ctx context.Context, id manta.ID) (*manta.Config, error) {
@@ -75,10 +91,30 @@ func (s *Service) UpdateConfig(
 			return err
 		}
 
+		from := cf.Revision
 		upd.Apply(cf)
 		cf.Updated = time.Now()
+		cf.Revision += 1
+
+		if err = validate.Config(cf); err != nil {
+			return err
+		}
+
+		if cf.Rollout != nil {
+			if err = cf.Rollout.Validate(); err != nil {
+				return err
+			}
+		}
 
-		return putOrgIndexed(tx, cf, ConfigBucket, ConfigOrgIndexBucket)
+		if err = putOrgIndexed(tx, cf, ConfigBucket, ConfigOrgIndexBucket); err != nil {
+			return err
+		}
+
+		if err = putConfigRevision(ctx, tx, cf, 0); err != nil {
+			return err
+		}
+
+		return startConfigRollout(tx, cf, from)
 	})
 	if err != nil {
 		return nil, err
@@ -94,5 +130,17 @@ func (s *Service) DeleteConfig(ctx context.Context, id manta.ID) error {
 }
 
 func (s *Service) deleteConfig(tx Tx, id manta.ID) error {
-	return deleteOrgIndexed[manta.Config](tx, id, ConfigBucket, ConfigOrgIndexBucket)
+	if err := deleteOrgIndexed[manta.Config](tx, id, ConfigBucket, ConfigOrgIndexBucket); err != nil {
+		return err
+	}
+
+	if err := deleteConfigRevisions(tx, id); err != nil {
+		return err
+	}
+
+	if err := deleteOperationLogs(tx, id, nil); err != nil {
+		return err
+	}
+
+	return deleteConfigRollout(tx, id)
 }
@@ -0,0 +1,316 @@
+package kv
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/f1shl3gs/manta"
+	"github.com/f1shl3gs/manta/config/validate"
+)
+
+var (
+	// ConfigRevisionsBucket stores the revisions of configs, the key is
+	// IndexKey(configID, big endian revision), so revisions of a config
+	// are sorted.
+	ConfigRevisionsBucket = []byte("configrevisions")
+)
+
+func configRevisionPrefix(id manta.ID) ([]byte, error) {
+	pk, err := id.Encode()
+	if err != nil {
+		return nil, err
+	}
+
+	return IndexKey(pk, nil), nil
+}
+
+func configRevisionKey(id manta.ID, revision uint64) ([]byte, error) {
+	prefix, err := configRevisionPrefix(id)
+	if err != nil {
+		return nil, err
+	}
+
+	return binary.BigEndian.AppendUint64(prefix, revision), nil
+}
+
+// putConfigRevision saves a snapshot of the config, the author set by
+// manta.WithAuthor is recorded.
+func putConfigRevision(ctx context.Context, tx Tx, cf *manta.Config, rollbackFrom uint64) error {
+	rev := &manta.ConfigRevision{
+		ConfigID:     cf.ID,
+		Revision:     cf.Revision,
+		Created:      cf.Updated,
+		RollbackFrom: rollbackFrom,
+		Name:         cf.Name,
+		Desc:         cf.Desc,
+		Data:         cf.Data,
+		Format:       cf.Format,
+		Template:     cf.Template,
+		UserID:       manta.AuthorFromContext(ctx),
+	}
+
+	key, err := configRevisionKey(cf.ID, cf.Revision)
+	if err != nil {
+		return err
+	}
+
+	value, err := json.Marshal(rev)
+	if err != nil {
+		return err
+	}
+
+	b, err := tx.Bucket(ConfigRevisionsBucket)
+	if err != nil {
+		return err
+	}
+
+	return b.Put(key, value)
+}
+
+func findConfigRevision(tx Tx, id manta.ID, revision uint64) (*manta.ConfigRevision, error) {
+	key, err := configRevisionKey(id, revision)
+	if err != nil {
+		return nil, err
+	}
+
+	b, err := tx.Bucket(ConfigRevisionsBucket)
+	if err != nil {
+		return nil, err
+	}
+
+	value, err := b.Get(key)
+	if err != nil {
+		if IsNotFound(err) {
+			return nil, &manta.Error{
+				Code: manta.ENotFound,
+				Msg:  "config revision not found",
+			}
+		}
+
+		return nil, err
+	}
+
+	rev := &manta.ConfigRevision{}
+	if err = json.Unmarshal(value, rev); err != nil {
+		return nil, err
+	}
+
+	return rev, nil
+}
+
+func deleteConfigRevisions(tx Tx, id manta.ID) error {
+	prefix, err := configRevisionPrefix(id)
+	if err != nil {
+		return err
+	}
+
+	b, err := tx.Bucket(ConfigRevisionsBucket)
+	if err != nil {
+		return err
+	}
+
+	c, err := b.ForwardCursor(prefix, WithCursorPrefix(prefix))
+	if err != nil {
+		return err
+	}
+
+	var keys [][]byte
+	err = WalkCursor(context.Background(), c, func(k, _ []byte) error {
+		keys = append(keys, k)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, key := range keys {
+		if err = b.Delete(key); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// FindConfigRevisions returns the revisions of the config, newest first
+func (s *Service) FindConfigRevisions(ctx context.Context, id manta.ID) ([]*manta.ConfigRevision, error) {
+	prefix, err := configRevisionPrefix(id)
+	if err != nil {
+		return nil, err
+	}
+
+	var list []*manta.ConfigRevision
+	err = s.kv.View(ctx, func(tx Tx) error {
+		if _, err := findByID[manta.Config](tx, id, ConfigBucket); err != nil {
+			return err
+		}
+
+		b, err := tx.Bucket(ConfigRevisionsBucket)
+		if err != nil {
+			return err
+		}
+
+		c, err := b.ForwardCursor(prefix, WithCursorPrefix(prefix))
+		if err != nil {
+			return err
+		}
+
+		return WalkCursor(ctx, c, func(_, v []byte) error {
+			rev := &manta.ConfigRevision{}
+			if err := json.Unmarshal(v, rev); err != nil {
+				return err
+			}
+
+			list = append(list, rev)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// newest first
+	for i, j := 0, len(list)-1; i < j; i, j = i+1, j-1 {
+		list[i], list[j] = list[j], list[i]
+	}
+
+	return list, nil
+}
+
+// FindConfigRevision returns a specific revision of the config
+func (s *Service) FindConfigRevision(
+	ctx context.Context,
+	id manta.ID,
+	revision uint64,
+) (*manta.ConfigRevision, error) {
+	var (
+		rev *manta.ConfigRevision
+		err error
+	)
+
+	err = s.kv.View(ctx, func(tx Tx) error {
+		rev, err = findConfigRevision(tx, id, revision)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return rev, nil
+}
+
+// RollbackConfig restores name, desc and data of the config to the revision,
+// and saves it as a new revision. The rollback is delivered to all instances
+// immediately, the rollout in progress is rolled back.
+func (s *Service) RollbackConfig(ctx context.Context, id manta.ID, revision uint64) (*manta.Config, error) {
+	var (
+		cf  *manta.Config
+		err error
+	)
+
+	err = s.kv.Update(ctx, func(tx Tx) error {
+		cf, err = rollbackConfig(ctx, tx, id, revision)
+		if err != nil {
+			return err
+		}
+
+		return finishConfigRollout(tx, id, manta.RolloutRolledBack,
+			fmt.Sprintf("config is rolled back to revision %d", revision))
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return cf, nil
+}
+
+func rollbackConfig(ctx context.Context, tx Tx, id manta.ID, revision uint64) (*manta.Config, error) {
+	cf, err := findByID[manta.Config](tx, id, ConfigBucket)
+	if err != nil {
+		return nil, err
+	}
+
+	rev, err := findConfigRevision(tx, id, revision)
+	if err != nil {
+		return nil, err
+	}
+
+	cf.Name = rev.Name
+	cf.Desc = rev.Desc
+	cf.Data = rev.Data
+	cf.Format = rev.Format
+	cf.Template = rev.Template
+	cf.Updated = time.Now()
+	cf.Revision += 1
+
+	// the schema might be changed since the revision
+	if err = validate.Config(cf); err != nil {
+		return nil, err
+	}
+
+	if err = putOrgIndexed(tx, cf, ConfigBucket, ConfigOrgIndexBucket); err != nil {
+		return nil, err
+	}
+
+	if err = putConfigRevision(ctx, tx, cf, revision); err != nil {
+		return nil, err
+	}
+
+	return cf, nil
+}
+
+// InitConfigRevisions saves the first revision of configs created before
+// revisions are kept, it returns the number of configs initialized.
+func InitConfigRevisions(ctx context.Context, store Store) (int, error) {
+	var n int
+	err := store.Update(ctx, func(tx Tx) error {
+		b, err := tx.Bucket(ConfigBucket)
+		if err != nil {
+			return err
+		}
+
+		c, err := b.ForwardCursor(nil)
+		if err != nil {
+			return err
+		}
+
+		var configs []*manta.Config
+		err = WalkCursor(ctx, c, func(_, v []byte) error {
+			cf := &manta.Config{}
+			if err := json.Unmarshal(v, cf); err != nil {
+				return err
+			}
+
+			if cf.Revision == 0 {
+				configs = append(configs, cf)
+			}
+
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		for _, cf := range configs {
+			cf.Revision = 1
+			if err = putOrgIndexed(tx, cf, ConfigBucket, ConfigOrgIndexBucket); err != nil {
+				return err
+			}
+
+			if err = putConfigRevision(ctx, tx, cf, 0); err != nil {
+				return err
+			}
+		}
+
+		n = len(configs)
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return n, nil
+}
@@ -0,0 +1,67 @@
+package kv_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/f1shl3gs/manta"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfigRevisions(t *testing.T) {
+	svc, closer := NewTestService(t)
+	defer closer()
+
+	userID := manta.ID(2)
+	ctx := manta.WithAuthor(context.Background(), userID)
+	orgID := CreateDefaultOrg(t, svc)
+
+	cf := &manta.Config{
+		OrgID: orgID,
+		Name:  "agent",
+		Data:  "foo: 1\n",
+	}
+	err := svc.CreateConfig(ctx, cf)
+	require.NoError(t, err)
+
+	for _, data := range []string{"foo: 2\n", "foo: 3\n"} {
+		data := data
+		_, err = svc.UpdateConfig(ctx, cf.ID, manta.ConfigUpdate{Data: &data})
+		require.NoError(t, err)
+	}
+
+	revisions, err := svc.FindConfigRevisions(ctx, cf.ID)
+	require.NoError(t, err)
+	require.Len(t, revisions, 3)
+	// newest first
+	for i, want := range []uint64{3, 2, 1} {
+		assert.Equal(t, want, revisions[i].Revision)
+		assert.Equal(t, userID, revisions[i].UserID)
+	}
+
+	rev, err := svc.FindConfigRevision(ctx, cf.ID, 1)
+	require.NoError(t, err)
+	assert.Equal(t, "foo: 1\n", rev.Data)
+
+	_, err = svc.FindConfigRevision(ctx, cf.ID, 10)
+	assert.Equal(t, manta.ENotFound, manta.ErrorCode(err))
+
+	// rollback creates a new revision
+	rolled, err := svc.RollbackConfig(ctx, cf.ID, 1)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(4), rolled.Revision)
+	assert.Equal(t, "foo: 1\n", rolled.Data)
+
+	rev, err = svc.FindConfigRevision(ctx, cf.ID, 4)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(1), rev.RollbackFrom)
+	assert.Equal(t, "foo: 1\n", rev.Data)
+
+	// revisions are deleted with the config
+	err = svc.DeleteConfig(ctx, cf.ID)
+	require.NoError(t, err)
+	_, err = svc.FindConfigRevision(ctx, cf.ID, 1)
+	assert.Equal(t, manta.ENotFound, manta.ErrorCode(err))
+}
@@ -0,0 +1,457 @@
+package kv
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/f1shl3gs/manta"
+)
+
+var (
+	// ConfigRolloutsBucket stores the rollout of configs, the key is config id
+	ConfigRolloutsBucket = []byte("configrollouts")
+
+	// ConfigAcksBucket stores the acks reported by instances, the key is
+	// IndexKey(configID, instance uuid)
+	ConfigAcksBucket = []byte("configacks")
+)
+
+func findConfigRollout(tx Tx, id manta.ID) (*manta.ConfigRollout, error) {
+	r, err := findByID[manta.ConfigRollout](tx, id, ConfigRolloutsBucket)
+	if err != nil {
+		if IsNotFound(err) {
+			return nil, &manta.Error{
+				Code: manta.ENotFound,
+				Msg:  "config rollout not found",
+			}
+		}
+
+		return nil, err
+	}
+
+	return r, nil
+}
+
+func putConfigRollout(tx Tx, r *manta.ConfigRollout) error {
+	pk, err := r.ConfigID.Encode()
+	if err != nil {
+		return err
+	}
+
+	value, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+
+	b, err := tx.Bucket(ConfigRolloutsBucket)
+	if err != nil {
+		return err
+	}
+
+	return b.Put(pk, value)
+}
+
+// startConfigRollout is called after the config is updated, the new revision
+// is rolled out if the config is in rollout mode. An update made during the
+// rollout restarts it from the canary stage, and the stable revision is kept.
+func startConfigRollout(tx Tx, cf *manta.Config, from uint64) error {
+	if cf.Rollout == nil {
+		// updates are delivered to all instances now
+		return finishConfigRollout(tx, cf.ID, manta.RolloutCompleted, "")
+	}
+
+	now := time.Now()
+	r, err := findConfigRollout(tx, cf.ID)
+	if err != nil && manta.ErrorCode(err) != manta.ENotFound {
+		return err
+	}
+
+	if r == nil || r.Status != manta.RolloutInProgress {
+		r = &manta.ConfigRollout{
+			ConfigID: cf.ID,
+			OrgID:    cf.OrgID,
+			Created:  now,
+			From:     from,
+		}
+	}
+
+	r.Updated = now
+	r.Status = manta.RolloutInProgress
+	r.To = cf.Revision
+	r.Selector = cf.Rollout.Selector
+	r.Canary = cf.Rollout.Canary
+	r.AutoPromote = cf.Rollout.AutoPromote
+	r.AutoRollback = cf.Rollout.AutoRollback
+
+	return putConfigRollout(tx, r)
+}
+
+// finishConfigRollout sets the status of the rollout in progress, nothing
+// happens if there is no rollout in progress.
+func finishConfigRollout(tx Tx, id manta.ID, status manta.RolloutStatus, msg string) error {
+	r, err := findConfigRollout(tx, id)
+	if err != nil {
+		if manta.ErrorCode(err) == manta.ENotFound {
+			return nil
+		}
+
+		return err
+	}
+
+	if r.Status != manta.RolloutInProgress {
+		return nil
+	}
+
+	r.Status = status
+	r.Message = msg
+	r.Updated = time.Now()
+
+	return putConfigRollout(tx, r)
+}
+
+// promoteConfigRollout delivers the new revision to more targets, the
+// rollout is completed if all targets have been promoted already.
+func promoteConfigRollout(r *manta.ConfigRollout, canary int) error {
+	if canary < 0 || canary > 100 {
+		return &manta.Error{
+			Code: manta.EInvalid,
+			Msg:  "canary must be in range 0 to 100",
+		}
+	}
+
+	r.Updated = time.Now()
+
+	if r.Canary >= 100 {
+		r.Status = manta.RolloutCompleted
+		return nil
+	}
+
+	if canary == 0 {
+		canary = 100
+	}
+
+	if canary <= r.Canary {
+		return &manta.Error{
+			Code: manta.EInvalid,
+			Msg:  fmt.Sprintf("canary must be greater than %d", r.Canary),
+		}
+	}
+
+	r.Canary = canary
+
+	return nil
+}
+
+func inProgressConfigRollout(tx Tx, id manta.ID) (*manta.ConfigRollout, error) {
+	r, err := findConfigRollout(tx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if r.Status != manta.RolloutInProgress {
+		return nil, &manta.Error{
+			Code: manta.EConflict,
+			Msg:  "config rollout is " + string(r.Status),
+		}
+	}
+
+	return r, nil
+}
+
+func deleteConfigRollout(tx Tx, id manta.ID) error {
+	pk, err := id.Encode()
+	if err != nil {
+		return err
+	}
+
+	b, err := tx.Bucket(ConfigRolloutsBucket)
+	if err != nil {
+		return err
+	}
+
+	if err = b.Delete(pk); err != nil && !IsNotFound(err) {
+		return err
+	}
+
+	acks, err := findConfigAcks(tx, id)
+	if err != nil {
+		return err
+	}
+
+	b, err = tx.Bucket(ConfigAcksBucket)
+	if err != nil {
+		return err
+	}
+
+	for _, ack := range acks {
+		if err = b.Delete(IndexKey(pk, []byte(ack.Instance))); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func findConfigAcks(tx Tx, id manta.ID) ([]*manta.ConfigAck, error) {
+	pk, err := id.Encode()
+	if err != nil {
+		return nil, err
+	}
+
+	b, err := tx.Bucket(ConfigAcksBucket)
+	if err != nil {
+		return nil, err
+	}
+
+	prefix := IndexKey(pk, nil)
+	c, err := b.ForwardCursor(prefix, WithCursorPrefix(prefix))
+	if err != nil {
+		return nil, err
+	}
+
+	var list []*manta.ConfigAck
+	err = WalkCursor(context.Background(), c, func(_, v []byte) error {
+		ack := &manta.ConfigAck{}
+		if err := json.Unmarshal(v, ack); err != nil {
+			return err
+		}
+
+		list = append(list, ack)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return list, nil
+}
+
+// canariesSucceeded returns true if all live instances running the new
+// revision reported success.
+func canariesSucceeded(tx Tx, r *manta.ConfigRollout) (bool, error) {
+	acks, err := findConfigAcks(tx, r.ConfigID)
+	if err != nil {
+		return false, err
+	}
+
+	applied := make(map[string]*manta.ConfigAck, len(acks))
+	for _, ack := range acks {
+		applied[ack.Instance] = ack
+	}
+
+	b, err := tx.Bucket(RegistryBucket)
+	if err != nil {
+		return false, err
+	}
+
+	cursor, err := b.Cursor()
+	if err != nil {
+		return false, err
+	}
+
+	var (
+		now      = time.Now()
+		canaries = 0
+	)
+	for k, v := cursor.First(); k != nil; k, v = cursor.Next() {
+		ins := &manta.Instance{}
+		if err := json.Unmarshal(v, ins); err != nil {
+			continue
+		}
+
+		if ins.ExpiredAt(now) || !r.Canaries(ins) {
+			continue
+		}
+
+		canaries += 1
+		ack := applied[ins.UUID]
+		if ack == nil || ack.Revision != r.To || !ack.Success {
+			return false, nil
+		}
+	}
+
+	return canaries > 0, nil
+}
+
+// FindConfigRollout returns the rollout of the config
+func (s *Service) FindConfigRollout(ctx context.Context, id manta.ID) (*manta.ConfigRollout, error) {
+	var (
+		r   *manta.ConfigRollout
+		err error
+	)
+
+	err = s.kv.View(ctx, func(tx Tx) error {
+		r, err = findConfigRollout(tx, id)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+// PromoteConfigRollout delivers the new revision to canary percent of the
+// targets, all targets if canary is 0. The rollout is completed if all
+// targets have been promoted already.
+func (s *Service) PromoteConfigRollout(ctx context.Context, id manta.ID, canary int) (*manta.ConfigRollout, error) {
+	var (
+		r   *manta.ConfigRollout
+		err error
+	)
+
+	err = s.kv.Update(ctx, func(tx Tx) error {
+		r, err = inProgressConfigRollout(tx, id)
+		if err != nil {
+			return err
+		}
+
+		if err = promoteConfigRollout(r, canary); err != nil {
+			return err
+		}
+
+		return putConfigRollout(tx, r)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+// AbortConfigRollout rolls the config back to the stable revision
+func (s *Service) AbortConfigRollout(ctx context.Context, id manta.ID) (*manta.ConfigRollout, error) {
+	var (
+		r   *manta.ConfigRollout
+		err error
+	)
+
+	err = s.kv.Update(ctx, func(tx Tx) error {
+		r, err = inProgressConfigRollout(tx, id)
+		if err != nil {
+			return err
+		}
+
+		if _, err = rollbackConfig(ctx, tx, id, r.From); err != nil {
+			return err
+		}
+
+		r.Status = manta.RolloutRolledBack
+		r.Message = "rollout is aborted"
+		r.Updated = time.Now()
+
+		return putConfigRollout(tx, r)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+// AckConfig records the revision an instance applied. The rollout is rolled
+// back if the instance failed to apply the new revision and auto rollback is
+// enabled, and it is promoted if all instances running the new revision
+// succeeded and auto promotion is enabled.
+func (s *Service) AckConfig(ctx context.Context, ack *manta.ConfigAck) error {
+	if ack.Instance == "" {
+		return &manta.Error{
+			Code: manta.EInvalid,
+			Msg:  "instance is required",
+		}
+	}
+
+	return s.kv.Update(ctx, func(tx Tx) error {
+		if _, err := findByID[manta.Config](tx, ack.ConfigID, ConfigBucket); err != nil {
+			return err
+		}
+
+		pk, err := ack.ConfigID.Encode()
+		if err != nil {
+			return err
+		}
+
+		ack.Updated = time.Now()
+		value, err := json.Marshal(ack)
+		if err != nil {
+			return err
+		}
+
+		b, err := tx.Bucket(ConfigAcksBucket)
+		if err != nil {
+			return err
+		}
+
+		if err = b.Put(IndexKey(pk, []byte(ack.Instance)), value); err != nil {
+			return err
+		}
+
+		r, err := findConfigRollout(tx, ack.ConfigID)
+		if err != nil {
+			if manta.ErrorCode(err) == manta.ENotFound {
+				return nil
+			}
+
+			return err
+		}
+
+		if r.Status != manta.RolloutInProgress || ack.Revision != r.To {
+			return nil
+		}
+
+		if !ack.Success {
+			if !r.AutoRollback {
+				return nil
+			}
+
+			if _, err = rollbackConfig(ctx, tx, ack.ConfigID, r.From); err != nil {
+				return err
+			}
+
+			r.Status = manta.RolloutRolledBack
+			r.Message = fmt.Sprintf("instance %s failed to apply revision %d, %s", ack.Instance, ack.Revision, ack.Message)
+			r.Updated = time.Now()
+
+			return putConfigRollout(tx, r)
+		}
+
+		if !r.AutoPromote {
+			return nil
+		}
+
+		succeeded, err := canariesSucceeded(tx, r)
+		if err != nil || !succeeded {
+			return err
+		}
+
+		if err = promoteConfigRollout(r, 0); err != nil {
+			return err
+		}
+
+		return putConfigRollout(tx, r)
+	})
+}
+
+// FindConfigAcks returns the acks of all instances
+func (s *Service) FindConfigAcks(ctx context.Context, id manta.ID) ([]*manta.ConfigAck, error) {
+	var (
+		list []*manta.ConfigAck
+		err  error
+	)
+
+	err = s.kv.View(ctx, func(tx Tx) error {
+		if _, err := findByID[manta.Config](tx, id, ConfigBucket); err != nil {
+			return err
+		}
+
+		list, err = findConfigAcks(tx, id)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return list, nil
+}
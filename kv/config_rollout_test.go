@@ -0,0 +1,186 @@
+package kv_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/f1shl3gs/manta"
+	"github.com/f1shl3gs/manta/authorizer"
+	"github.com/f1shl3gs/manta/kv"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setupRollout(t *testing.T, policy manta.RolloutPolicy) (context.Context, *kv.Service, *manta.Config) {
+	t.Helper()
+
+	svc, closer := NewTestService(t)
+	t.Cleanup(closer)
+
+	ctx := authorizer.SetAuthorizer(context.Background(), &manta.Session{UserID: 2})
+	orgID := CreateDefaultOrg(t, svc)
+
+	for uuid, group := range map[string]string{"canary": "canary", "main": "main"} {
+		err := svc.Register(ctx, &manta.Instance{
+			UUID:     uuid,
+			Hostname: uuid,
+			Lease:    manta.Duration(time.Minute),
+			Tags:     map[string]string{"group": group},
+		})
+		require.NoError(t, err)
+	}
+
+	cf := &manta.Config{
+		OrgID:   orgID,
+		Name:    "agent",
+		Data:    "v1",
+		Rollout: &policy,
+	}
+	err := svc.CreateConfig(ctx, cf)
+	require.NoError(t, err)
+
+	// the first revision is not rolled out
+	_, err = svc.FindConfigRollout(ctx, cf.ID)
+	assert.Equal(t, manta.ENotFound, manta.ErrorCode(err))
+
+	data := "v2"
+	_, err = svc.UpdateConfig(ctx, cf.ID, manta.ConfigUpdate{Data: &data})
+	require.NoError(t, err)
+
+	r, err := svc.FindConfigRollout(ctx, cf.ID)
+	require.NoError(t, err)
+	assert.Equal(t, manta.RolloutInProgress, r.Status)
+	assert.Equal(t, uint64(1), r.From)
+	assert.Equal(t, uint64(2), r.To)
+
+	return ctx, svc, cf
+}
+
+func TestConfigRollout(t *testing.T) {
+	policy := manta.RolloutPolicy{
+		Selector: map[string]string{"group": "canary"},
+		Canary:   100,
+	}
+
+	t.Run("invalid policy", func(t *testing.T) {
+		svc, closer := NewTestService(t)
+		defer closer()
+
+		ctx := authorizer.SetAuthorizer(context.Background(), &manta.Session{UserID: 2})
+		err := svc.CreateConfig(ctx, &manta.Config{
+			OrgID:   CreateDefaultOrg(t, svc),
+			Rollout: &manta.RolloutPolicy{Canary: 101},
+		})
+		assert.Equal(t, manta.EInvalid, manta.ErrorCode(err))
+	})
+
+	t.Run("promote", func(t *testing.T) {
+		ctx, svc, cf := setupRollout(t, manta.RolloutPolicy{Canary: 10})
+
+		_, err := svc.PromoteConfigRollout(ctx, cf.ID, 5)
+		assert.Equal(t, manta.EInvalid, manta.ErrorCode(err))
+
+		r, err := svc.PromoteConfigRollout(ctx, cf.ID, 50)
+		require.NoError(t, err)
+		assert.Equal(t, 50, r.Canary)
+
+		r, err = svc.PromoteConfigRollout(ctx, cf.ID, 0)
+		require.NoError(t, err)
+		assert.Equal(t, 100, r.Canary)
+		assert.Equal(t, manta.RolloutInProgress, r.Status)
+
+		r, err = svc.PromoteConfigRollout(ctx, cf.ID, 0)
+		require.NoError(t, err)
+		assert.Equal(t, manta.RolloutCompleted, r.Status)
+
+		_, err = svc.PromoteConfigRollout(ctx, cf.ID, 0)
+		assert.Equal(t, manta.EConflict, manta.ErrorCode(err))
+	})
+
+	t.Run("abort", func(t *testing.T) {
+		ctx, svc, cf := setupRollout(t, policy)
+
+		r, err := svc.AbortConfigRollout(ctx, cf.ID)
+		require.NoError(t, err)
+		assert.Equal(t, manta.RolloutRolledBack, r.Status)
+
+		rolled, err := svc.FindConfigByID(ctx, cf.ID)
+		require.NoError(t, err)
+		assert.Equal(t, uint64(3), rolled.Revision)
+		assert.Equal(t, "v1", rolled.Data)
+	})
+
+	t.Run("auto promote", func(t *testing.T) {
+		policy := policy
+		policy.AutoPromote = true
+		ctx, svc, cf := setupRollout(t, policy)
+
+		err := svc.AckConfig(ctx, &manta.ConfigAck{ConfigID: cf.ID, Instance: "main", Revision: 1, Success: true})
+		require.NoError(t, err)
+		r, err := svc.FindConfigRollout(ctx, cf.ID)
+		require.NoError(t, err)
+		assert.Equal(t, manta.RolloutInProgress, r.Status)
+
+		err = svc.AckConfig(ctx, &manta.ConfigAck{ConfigID: cf.ID, Instance: "canary", Revision: 2, Success: true})
+		require.NoError(t, err)
+		r, err = svc.FindConfigRollout(ctx, cf.ID)
+		require.NoError(t, err)
+		assert.Equal(t, manta.RolloutCompleted, r.Status)
+
+		acks, err := svc.FindConfigAcks(ctx, cf.ID)
+		require.NoError(t, err)
+		assert.Len(t, acks, 2)
+	})
+
+	t.Run("auto rollback", func(t *testing.T) {
+		policy := policy
+		policy.AutoRollback = true
+		ctx, svc, cf := setupRollout(t, policy)
+
+		err := svc.AckConfig(ctx, &manta.ConfigAck{ConfigID: cf.ID, Instance: "canary", Revision: 2, Message: "bad config"})
+		require.NoError(t, err)
+
+		r, err := svc.FindConfigRollout(ctx, cf.ID)
+		require.NoError(t, err)
+		assert.Equal(t, manta.RolloutRolledBack, r.Status)
+		assert.Contains(t, r.Message, "bad config")
+
+		rolled, err := svc.FindConfigByID(ctx, cf.ID)
+		require.NoError(t, err)
+		assert.Equal(t, uint64(3), rolled.Revision)
+		assert.Equal(t, "v1", rolled.Data)
+	})
+
+	t.Run("turn off", func(t *testing.T) {
+		ctx, svc, cf := setupRollout(t, policy)
+
+		data := "v3"
+		_, err := svc.UpdateConfig(ctx, cf.ID, manta.ConfigUpdate{
+			Data:    &data,
+			Rollout: &manta.RolloutPolicy{},
+		})
+		require.NoError(t, err)
+
+		r, err := svc.FindConfigRollout(ctx, cf.ID)
+		require.NoError(t, err)
+		assert.Equal(t, manta.RolloutCompleted, r.Status)
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		ctx, svc, cf := setupRollout(t, policy)
+
+		err := svc.AckConfig(ctx, &manta.ConfigAck{ConfigID: cf.ID, Instance: "canary", Revision: 2, Success: true})
+		require.NoError(t, err)
+
+		err = svc.DeleteConfig(ctx, cf.ID)
+		require.NoError(t, err)
+
+		_, err = svc.FindConfigRollout(ctx, cf.ID)
+		assert.Equal(t, manta.ENotFound, manta.ErrorCode(err))
+
+		err = svc.AckConfig(ctx, &manta.ConfigAck{ConfigID: cf.ID, Instance: "canary", Revision: 2})
+		assert.Error(t, err)
+	})
+}
@@ -171,13 +171,15 @@ func (s *Service) CreateDashboard(ctx context.Context, d *manta.Dashboard) error
 }
 
 func (s *Service) createDashboard(ctx context.Context, tx Tx, d *manta.Dashboard) error {
-	now := time.Now()
+	if err := manta.ValidateVariables(d.Variables); err != nil {
+		return err
+	}
 
 	d.ID = s.idGen.ID()
-	d.Created = now
-	d.Updated = now
+	d.Created = time.Now()
+	d.Version = 0
 
-	return s.putDashboard(ctx, tx, d)
+	return s.saveDashboard(ctx, tx, d, 0)
 }
 
 func (s *Service) putDashboard(ctx context.Context, tx Tx, d *manta.Dashboard) error {
@@ -227,10 +229,14 @@ func (s *Service) UpdateDashboard(ctx context.Context, upd manta.DashboardUpdate
 			return err
 		}
 
-		upd.Apply(dash)
-		dash.Updated = time.Now()
+		if upd.Variables != nil {
+			if err = manta.ValidateVariables(*upd.Variables); err != nil {
+				return err
+			}
+		}
 
-		return s.putDashboard(ctx, tx, dash)
+		upd.Apply(dash)
+		return s.saveDashboard(ctx, tx, dash, 0)
 	})
 
 	if err != nil {
@@ -256,9 +262,7 @@ func (s *Service) AddDashboardCell(ctx context.Context, id manta.ID, cell *manta
 		}
 
 		dash.Cells = append(dash.Cells, *cell)
-		dash.Updated = time.Now()
-
-		return s.putDashboard(ctx, tx, dash)
+		return s.saveDashboard(ctx, tx, dash, 0)
 	})
 
 	if err != nil {
@@ -282,9 +286,7 @@ func (s *Service) RemoveDashboardCell(ctx context.Context, did, cellID manta.ID)
 			}
 		}
 
-		dash.Updated = time.Now()
-
-		return s.putDashboard(ctx, tx, dash)
+		return s.saveDashboard(ctx, tx, dash, 0)
 	})
 }
 
@@ -309,8 +311,7 @@ func (s *Service) UpdateDashboardCell(ctx context.Context, upd manta.DashboardCe
 			}
 		}
 
-		dash.Updated = time.Now()
-		return s.putDashboard(ctx, tx, dash)
+		return s.saveDashboard(ctx, tx, dash, 0)
 	})
 
 	if err != nil {
@@ -353,6 +354,15 @@ func (s *Service) removeDashboard(ctx context.Context, tx Tx, id manta.ID) error
 		return err
 	}
 
+	if err = deleteOperationLogs(tx, id, nil); err != nil {
+		return err
+	}
+
+	// versions are kept, so the dashboard can be restored
+	if err = markDashboardDeleted(ctx, tx, d); err != nil {
+		return err
+	}
+
 	// delete dashboard
 	b, err = tx.Bucket(DashboardsBucket)
 	if err != nil {
@@ -373,9 +383,7 @@ func (s *Service) ReplaceDashboardCells(ctx context.Context, did manta.ID, cells
 		}
 
 		dash.Cells = cells
-		dash.Updated = time.Now()
-
-		return s.putDashboard(ctx, tx, dash)
+		return s.saveDashboard(ctx, tx, dash, 0)
 	})
 }
 
@@ -0,0 +1,558 @@
+package kv
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"time"
+
+	"github.com/f1shl3gs/manta"
+	"github.com/f1shl3gs/manta/pkg/tracing"
+)
+
+var (
+	// DashboardVersionsBucket stores the versions of dashboards, the key is
+	// IndexKey(dashboardID, big endian version), so versions of a dashboard
+	// are sorted.
+	DashboardVersionsBucket = []byte("dashboardversions")
+
+	// DeletedDashboardOrgIndexBucket indexes the deleted dashboards by org
+	//   key:    IndexKey(orgID, dashboardID)
+	//   value:  dashboardID
+	DeletedDashboardOrgIndexBucket = []byte("deleteddashboardorgindex")
+)
+
+func dashboardVersionPrefix(id manta.ID) ([]byte, error) {
+	pk, err := id.Encode()
+	if err != nil {
+		return nil, err
+	}
+
+	return IndexKey(pk, nil), nil
+}
+
+func dashboardVersionKey(id manta.ID, version uint64) ([]byte, error) {
+	prefix, err := dashboardVersionPrefix(id)
+	if err != nil {
+		return nil, err
+	}
+
+	return binary.BigEndian.AppendUint64(prefix, version), nil
+}
+
+// saveDashboard stores the dashboard, and saves it as a new version
+func (s *Service) saveDashboard(ctx context.Context, tx Tx, d *manta.Dashboard, restoredFrom uint64) error {
+	d.Version += 1
+	d.Updated = time.Now()
+
+	if err := s.putDashboard(ctx, tx, d); err != nil {
+		return err
+	}
+
+	return putDashboardVersion(ctx, tx, d, restoredFrom, false)
+}
+
+// putDashboardVersion saves a snapshot of the dashboard, the author set by
+// manta.WithAuthor is recorded.
+func putDashboardVersion(ctx context.Context, tx Tx, d *manta.Dashboard, restoredFrom uint64, deleted bool) error {
+	ver := &manta.DashboardVersion{
+		DashboardID:  d.ID,
+		Version:      d.Version,
+		Created:      d.Updated,
+		Message:      manta.VersionMessageFromContext(ctx),
+		RestoredFrom: restoredFrom,
+		Deleted:      deleted,
+		OrgID:        d.OrgID,
+		Name:         d.Name,
+		Desc:         d.Desc,
+		Cells:        d.Cells,
+		Variables:    d.Variables,
+		UserID:       manta.AuthorFromContext(ctx),
+	}
+
+	key, err := dashboardVersionKey(d.ID, d.Version)
+	if err != nil {
+		return err
+	}
+
+	value, err := json.Marshal(ver)
+	if err != nil {
+		return err
+	}
+
+	b, err := tx.Bucket(DashboardVersionsBucket)
+	if err != nil {
+		return err
+	}
+
+	return b.Put(key, value)
+}
+
+func findDashboardVersion(tx Tx, id manta.ID, version uint64) (*manta.DashboardVersion, error) {
+	key, err := dashboardVersionKey(id, version)
+	if err != nil {
+		return nil, err
+	}
+
+	b, err := tx.Bucket(DashboardVersionsBucket)
+	if err != nil {
+		return nil, err
+	}
+
+	value, err := b.Get(key)
+	if err != nil {
+		if IsNotFound(err) {
+			return nil, &manta.Error{
+				Code: manta.ENotFound,
+				Msg:  "dashboard version not found",
+			}
+		}
+
+		return nil, err
+	}
+
+	ver := &manta.DashboardVersion{}
+	if err = json.Unmarshal(value, ver); err != nil {
+		return nil, err
+	}
+
+	return ver, nil
+}
+
+// findLatestDashboardVersion returns the latest version of the dashboard,
+// it works for deleted dashboards too.
+func findLatestDashboardVersion(ctx context.Context, tx Tx, id manta.ID) (*manta.DashboardVersion, error) {
+	prefix, err := dashboardVersionPrefix(id)
+	if err != nil {
+		return nil, err
+	}
+
+	b, err := tx.Bucket(DashboardVersionsBucket)
+	if err != nil {
+		return nil, err
+	}
+
+	c, err := b.ForwardCursor(prefix, WithCursorPrefix(prefix))
+	if err != nil {
+		return nil, err
+	}
+
+	var last []byte
+	err = WalkCursor(ctx, c, func(_, v []byte) error {
+		last = v
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var ver *manta.DashboardVersion
+	if last != nil {
+		ver = &manta.DashboardVersion{}
+		if err = json.Unmarshal(last, ver); err != nil {
+			return nil, err
+		}
+	}
+
+	if ver == nil {
+		return nil, &manta.Error{
+			Code: manta.ENotFound,
+			Msg:  "dashboard version not found",
+		}
+	}
+
+	return ver, nil
+}
+
+func deletedDashboardIndexKey(orgID, id manta.ID) ([]byte, error) {
+	fk, err := orgID.Encode()
+	if err != nil {
+		return nil, err
+	}
+
+	pk, err := id.Encode()
+	if err != nil {
+		return nil, err
+	}
+
+	return IndexKey(fk, pk), nil
+}
+
+// markDashboardDeleted saves the last version of the dashboard, and indexes
+// it as deleted, so it can be found and restored.
+func markDashboardDeleted(ctx context.Context, tx Tx, d *manta.Dashboard) error {
+	d.Version += 1
+	d.Updated = time.Now()
+
+	if err := putDashboardVersion(ctx, tx, d, 0, true); err != nil {
+		return err
+	}
+
+	key, err := deletedDashboardIndexKey(d.OrgID, d.ID)
+	if err != nil {
+		return err
+	}
+
+	b, err := tx.Bucket(DeletedDashboardOrgIndexBucket)
+	if err != nil {
+		return err
+	}
+
+	return b.Put(key, key[len(key)-manta.IDLength:])
+}
+
+// deleteDashboardVersions deletes the versions of the dashboard, it is
+// deleted permanently.
+func deleteDashboardVersions(tx Tx, orgID, id manta.ID) error {
+	prefix, err := dashboardVersionPrefix(id)
+	if err != nil {
+		return err
+	}
+
+	b, err := tx.Bucket(DashboardVersionsBucket)
+	if err != nil {
+		return err
+	}
+
+	c, err := b.ForwardCursor(prefix, WithCursorPrefix(prefix))
+	if err != nil {
+		return err
+	}
+
+	var keys [][]byte
+	err = WalkCursor(context.Background(), c, func(k, _ []byte) error {
+		keys = append(keys, k)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, key := range keys {
+		if err = b.Delete(key); err != nil {
+			return err
+		}
+	}
+
+	key, err := deletedDashboardIndexKey(orgID, id)
+	if err != nil {
+		return err
+	}
+
+	b, err = tx.Bucket(DeletedDashboardOrgIndexBucket)
+	if err != nil {
+		return err
+	}
+
+	return b.Delete(key)
+}
+
+func findDeletedDashboardIDs(ctx context.Context, tx Tx, orgID manta.ID) ([]manta.ID, error) {
+	fk, err := orgID.Encode()
+	if err != nil {
+		return nil, err
+	}
+
+	b, err := tx.Bucket(DeletedDashboardOrgIndexBucket)
+	if err != nil {
+		return nil, err
+	}
+
+	prefix := IndexKey(fk, nil)
+	c, err := b.ForwardCursor(prefix, WithCursorPrefix(prefix))
+	if err != nil {
+		return nil, err
+	}
+
+	var ids []manta.ID
+	err = WalkCursor(ctx, c, func(_, v []byte) error {
+		var id manta.ID
+		if err := id.Decode(v); err != nil {
+			return err
+		}
+
+		ids = append(ids, id)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return ids, nil
+}
+
+// deleteOrgDashboardVersions deletes the versions of deleted dashboards of
+// the organization
+func deleteOrgDashboardVersions(ctx context.Context, tx Tx, orgID manta.ID) error {
+	ids, err := findDeletedDashboardIDs(ctx, tx, orgID)
+	if err != nil {
+		return err
+	}
+
+	for _, id := range ids {
+		if err = deleteDashboardVersions(tx, orgID, id); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// FindDashboardVersions returns the versions of the dashboard, newest first
+func (s *Service) FindDashboardVersions(ctx context.Context, id manta.ID) ([]*manta.DashboardVersion, error) {
+	span, ctx := tracing.StartSpanFromContext(ctx)
+	defer span.Finish()
+
+	prefix, err := dashboardVersionPrefix(id)
+	if err != nil {
+		return nil, err
+	}
+
+	var list []*manta.DashboardVersion
+	err = s.kv.View(ctx, func(tx Tx) error {
+		b, err := tx.Bucket(DashboardVersionsBucket)
+		if err != nil {
+			return err
+		}
+
+		c, err := b.ForwardCursor(prefix, WithCursorPrefix(prefix))
+		if err != nil {
+			return err
+		}
+
+		return WalkCursor(ctx, c, func(_, v []byte) error {
+			ver := &manta.DashboardVersion{}
+			if err := json.Unmarshal(v, ver); err != nil {
+				return err
+			}
+
+			list = append(list, ver)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(list) == 0 {
+		return nil, &manta.Error{
+			Code: manta.ENotFound,
+			Msg:  "dashboard not found",
+		}
+	}
+
+	// newest first
+	for i, j := 0, len(list)-1; i < j; i, j = i+1, j-1 {
+		list[i], list[j] = list[j], list[i]
+	}
+
+	return list, nil
+}
+
+// FindDashboardVersion returns a specific version of the dashboard
+func (s *Service) FindDashboardVersion(
+	ctx context.Context,
+	id manta.ID,
+	version uint64,
+) (*manta.DashboardVersion, error) {
+	span, ctx := tracing.StartSpanFromContext(ctx)
+	defer span.Finish()
+
+	var (
+		ver *manta.DashboardVersion
+		err error
+	)
+
+	err = s.kv.View(ctx, func(tx Tx) error {
+		ver, err = findDashboardVersion(tx, id, version)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return ver, nil
+}
+
+// FindDeletedDashboards returns the last versions of the deleted dashboards
+// of the organization
+func (s *Service) FindDeletedDashboards(
+	ctx context.Context,
+	filter manta.DashboardFilter,
+) ([]*manta.DashboardVersion, error) {
+	span, ctx := tracing.StartSpanFromContext(ctx)
+	defer span.Finish()
+
+	list := []*manta.DashboardVersion{}
+	err := s.kv.View(ctx, func(tx Tx) error {
+		ids, err := findDeletedDashboardIDs(ctx, tx, filter.OrgID)
+		if err != nil {
+			return err
+		}
+
+		for _, id := range ids {
+			ver, err := findLatestDashboardVersion(ctx, tx, id)
+			if err != nil {
+				return err
+			}
+
+			list = append(list, ver)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return list, nil
+}
+
+// RestoreDashboard restores name, desc, cells and variables of the dashboard
+// to the version, and saves it as a new version. The dashboard is recreated
+// if it is deleted.
+func (s *Service) RestoreDashboard(ctx context.Context, id manta.ID, version uint64) (*manta.Dashboard, error) {
+	span, ctx := tracing.StartSpanFromContext(ctx)
+	defer span.Finish()
+
+	var (
+		d   *manta.Dashboard
+		err error
+	)
+
+	err = s.kv.Update(ctx, func(tx Tx) error {
+		d, err = s.restoreDashboard(ctx, tx, id, version)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return d, nil
+}
+
+func (s *Service) restoreDashboard(ctx context.Context, tx Tx, id manta.ID, version uint64) (*manta.Dashboard, error) {
+	ver, err := findDashboardVersion(tx, id, version)
+	if err != nil {
+		return nil, err
+	}
+
+	d, err := s.findDashboardByID(ctx, tx, id)
+	if err != nil {
+		if !IsNotFound(err) {
+			return nil, err
+		}
+
+		// the dashboard is deleted, recreate it with the latest version
+		latest, err := findLatestDashboardVersion(ctx, tx, id)
+		if err != nil {
+			return nil, err
+		}
+
+		first, err := findDashboardVersion(tx, id, 1)
+		if err != nil {
+			return nil, err
+		}
+
+		d = &manta.Dashboard{
+			ID:      id,
+			Created: first.Created,
+			OrgID:   latest.OrgID,
+			Version: latest.Version,
+		}
+
+		key, err := deletedDashboardIndexKey(d.OrgID, id)
+		if err != nil {
+			return nil, err
+		}
+
+		b, err := tx.Bucket(DeletedDashboardOrgIndexBucket)
+		if err != nil {
+			return nil, err
+		}
+
+		if err = b.Delete(key); err != nil {
+			return nil, err
+		}
+	}
+
+	// the variables might be invalid since the version
+	if err = manta.ValidateVariables(ver.Variables); err != nil {
+		return nil, err
+	}
+
+	d.Name = ver.Name
+	d.Desc = ver.Desc
+	d.Cells = ver.Cells
+	d.Variables = ver.Variables
+
+	if err = s.saveDashboard(ctx, tx, d, version); err != nil {
+		return nil, err
+	}
+
+	return d, nil
+}
+
+// InitDashboardVersions saves the first version of dashboards created before
+// versions are kept, it returns the number of dashboards initialized.
+func InitDashboardVersions(ctx context.Context, store Store) (int, error) {
+	var n int
+	err := store.Update(ctx, func(tx Tx) error {
+		b, err := tx.Bucket(DashboardsBucket)
+		if err != nil {
+			return err
+		}
+
+		c, err := b.ForwardCursor(nil)
+		if err != nil {
+			return err
+		}
+
+		var dashboards []*manta.Dashboard
+		err = WalkCursor(ctx, c, func(_, v []byte) error {
+			d := &manta.Dashboard{}
+			if err := json.Unmarshal(v, d); err != nil {
+				return err
+			}
+
+			if d.Version == 0 {
+				dashboards = append(dashboards, d)
+			}
+
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		for _, d := range dashboards {
+			d.Version = 1
+
+			pk, err := d.ID.Encode()
+			if err != nil {
+				return err
+			}
+
+			value, err := json.Marshal(d)
+			if err != nil {
+				return err
+			}
+
+			if err = b.Put(pk, value); err != nil {
+				return err
+			}
+
+			if err = putDashboardVersion(ctx, tx, d, 0, false); err != nil {
+				return err
+			}
+		}
+
+		n = len(dashboards)
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return n, nil
+}
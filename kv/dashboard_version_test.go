@@ -0,0 +1,99 @@
+package kv_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/f1shl3gs/manta"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDashboardVersions(t *testing.T) {
+	svc, closer := NewTestService(t)
+	defer closer()
+
+	userID := manta.ID(2)
+	ctx := manta.WithAuthor(context.Background(), userID)
+	orgID := CreateDefaultOrg(t, svc)
+
+	d := &manta.Dashboard{
+		OrgID: orgID,
+		Name:  "node",
+	}
+	err := svc.CreateDashboard(manta.WithVersionMessage(ctx, "initial"), d)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(1), d.Version)
+
+	cell := &manta.Cell{W: 1, H: 1}
+	err = svc.AddDashboardCell(ctx, d.ID, cell)
+	require.NoError(t, err)
+
+	name := "node exporter"
+	updated, err := svc.UpdateDashboard(manta.WithVersionMessage(ctx, "rename"), manta.DashboardUpdate{
+		ID:   d.ID,
+		Name: &name,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, uint64(3), updated.Version)
+
+	versions, err := svc.FindDashboardVersions(ctx, d.ID)
+	require.NoError(t, err)
+	require.Len(t, versions, 3)
+	// newest first
+	for i, want := range []uint64{3, 2, 1} {
+		assert.Equal(t, want, versions[i].Version)
+		assert.Equal(t, userID, versions[i].UserID)
+	}
+	assert.Equal(t, "rename", versions[0].Message)
+	assert.Equal(t, "initial", versions[2].Message)
+	assert.Len(t, versions[1].Cells, 1)
+
+	_, err = svc.FindDashboardVersion(ctx, d.ID, 10)
+	assert.Equal(t, manta.ENotFound, manta.ErrorCode(err))
+
+	// restore creates a new version
+	restored, err := svc.RestoreDashboard(ctx, d.ID, 1)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(4), restored.Version)
+	assert.Equal(t, "node", restored.Name)
+	assert.Empty(t, restored.Cells)
+
+	ver, err := svc.FindDashboardVersion(ctx, d.ID, 4)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(1), ver.RestoredFrom)
+
+	// deleted dashboards can be restored
+	err = svc.RemoveDashboard(ctx, d.ID)
+	require.NoError(t, err)
+
+	deleted, err := svc.FindDeletedDashboards(ctx, manta.DashboardFilter{OrgID: orgID})
+	require.NoError(t, err)
+	require.Len(t, deleted, 1)
+	assert.Equal(t, d.ID, deleted[0].DashboardID)
+	assert.Equal(t, uint64(5), deleted[0].Version)
+	assert.True(t, deleted[0].Deleted)
+
+	restored, err = svc.RestoreDashboard(ctx, d.ID, 3)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(6), restored.Version)
+	assert.Equal(t, "node exporter", restored.Name)
+	assert.Len(t, restored.Cells, 1)
+	assert.Equal(t, d.Created.Unix(), restored.Created.Unix())
+
+	found, err := svc.FindDashboardByID(ctx, d.ID)
+	require.NoError(t, err)
+	assert.Equal(t, restored.Version, found.Version)
+
+	deleted, err = svc.FindDeletedDashboards(ctx, manta.DashboardFilter{OrgID: orgID})
+	require.NoError(t, err)
+	assert.Empty(t, deleted)
+
+	// versions are deleted with the organization
+	err = svc.DeleteOrganization(ctx, orgID)
+	require.NoError(t, err)
+
+	_, err = svc.FindDashboardVersions(ctx, d.ID)
+	assert.Equal(t, manta.ENotFound, manta.ErrorCode(err))
+}
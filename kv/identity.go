@@ -0,0 +1,153 @@
+package kv
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/f1shl3gs/manta"
+)
+
+var (
+	// UserIdentitiesBucket stores the identities of external identity providers
+	// Key: provider + "/" + subject
+	// Value: json encoded manta.UserIdentity
+	UserIdentitiesBucket = []byte("useridentities")
+)
+
+func userIdentityKey(provider, subject string) []byte {
+	return IndexKey([]byte(provider), []byte(subject))
+}
+
+func (s *Service) FindUserIdentity(ctx context.Context, provider, subject string) (*manta.UserIdentity, error) {
+	ident := &manta.UserIdentity{}
+
+	err := s.kv.View(ctx, func(tx Tx) error {
+		b, err := tx.Bucket(UserIdentitiesBucket)
+		if err != nil {
+			return err
+		}
+
+		data, err := b.Get(userIdentityKey(provider, subject))
+		if IsNotFound(err) {
+			return manta.ErrUserIdentityNotFound
+		}
+		if err != nil {
+			return err
+		}
+
+		return json.Unmarshal(data, ident)
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return ident, nil
+}
+
+func (s *Service) FindUserIdentities(ctx context.Context, filter manta.UserIdentityFilter) ([]*manta.UserIdentity, error) {
+	var list []*manta.UserIdentity
+
+	err := s.kv.View(ctx, func(tx Tx) error {
+		return walkUserIdentities(tx, func(k []byte, ident *manta.UserIdentity) error {
+			if (filter.UserID == nil || *filter.UserID == ident.UserID) &&
+				(filter.Provider == nil || *filter.Provider == ident.Provider) {
+				list = append(list, ident)
+			}
+
+			return nil
+		})
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return list, nil
+}
+
+func walkUserIdentities(tx Tx, fn func(k []byte, ident *manta.UserIdentity) error) error {
+	b, err := tx.Bucket(UserIdentitiesBucket)
+	if err != nil {
+		return err
+	}
+
+	cursor, err := b.Cursor()
+	if err != nil {
+		return err
+	}
+
+	for k, v := cursor.First(); k != nil; k, v = cursor.Next() {
+		ident := &manta.UserIdentity{}
+		if err = json.Unmarshal(v, ident); err != nil {
+			return err
+		}
+
+		if err = fn(k, ident); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *Service) CreateUserIdentity(ctx context.Context, ident *manta.UserIdentity) error {
+	if ident.Provider == "" || ident.Subject == "" {
+		return &manta.Error{
+			Code: manta.EInvalid,
+			Msg:  "provider and subject of user identity are required",
+		}
+	}
+
+	return s.kv.Update(ctx, func(tx Tx) error {
+		_, err := s.findUserByID(ctx, tx, ident.UserID)
+		if IsNotFound(err) {
+			return manta.ErrUserNotFound
+		}
+		if err != nil {
+			return err
+		}
+
+		ident.Created = time.Now()
+		data, err := json.Marshal(ident)
+		if err != nil {
+			return err
+		}
+
+		b, err := tx.Bucket(UserIdentitiesBucket)
+		if err != nil {
+			return err
+		}
+
+		return b.Put(userIdentityKey(ident.Provider, ident.Subject), data)
+	})
+}
+
+// deleteUserIdentities deletes the identities linked to the user
+func deleteUserIdentities(tx Tx, userID manta.ID) error {
+	var keys [][]byte
+	err := walkUserIdentities(tx, func(k []byte, ident *manta.UserIdentity) error {
+		if ident.UserID == userID {
+			keys = append(keys, append([]byte{}, k...))
+		}
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	b, err := tx.Bucket(UserIdentitiesBucket)
+	if err != nil {
+		return err
+	}
+
+	for _, key := range keys {
+		if err = b.Delete(key); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,256 @@
+package kv
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"time"
+
+	"github.com/f1shl3gs/manta"
+	"github.com/f1shl3gs/manta/pkg/tracing"
+)
+
+var (
+	// LockoutsBucket stores the failed sign-ins of usernames and IPs
+	//   key:    Kind + Value
+	//   value:  Marshaled Lockout
+	LockoutsBucket = []byte("signinlockouts")
+)
+
+var defaultLockoutPolicy = manta.LockoutPolicy{
+	MaxUsernameFailures: 5,
+	MaxIPFailures:       20,
+	Duration:            time.Minute,
+	MaxDuration:         time.Hour,
+}
+
+var _ manta.LockoutService = (*Service)(nil)
+
+func lockoutKey(kind manta.LockoutKind, value string) []byte {
+	return IndexKey([]byte(kind), []byte(value))
+}
+
+func getLockout(b Bucket, kind manta.LockoutKind, value string) (*manta.Lockout, error) {
+	data, err := b.Get(lockoutKey(kind, value))
+	if IsNotFound(err) {
+		return &manta.Lockout{
+			Kind:  kind,
+			Value: value,
+		}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	lockout := &manta.Lockout{}
+	if err = json.Unmarshal(data, lockout); err != nil {
+		return nil, err
+	}
+
+	return lockout, nil
+}
+
+func putLockout(b Bucket, lockout *manta.Lockout) error {
+	data, err := json.Marshal(lockout)
+	if err != nil {
+		return err
+	}
+
+	return b.Put(lockoutKey(lockout.Kind, lockout.Value), data)
+}
+
+// AttemptSignin returns ErrSigninLocked if the username or the IP is locked,
+// otherwise the attempt is counted as a failure of both in the same
+// transaction, and SigninSucceeded uncounts it.
+func (s *Service) AttemptSignin(ctx context.Context, username, ip string) error {
+	span, ctx := tracing.StartSpanFromContext(ctx)
+	defer span.Finish()
+
+	now := time.Now()
+	return s.kv.Update(ctx, func(tx Tx) error {
+		b, err := tx.Bucket(LockoutsBucket)
+		if err != nil {
+			return err
+		}
+
+		lockouts := make([]*manta.Lockout, 0, 2)
+		for _, target := range []struct {
+			kind  manta.LockoutKind
+			value string
+		}{
+			{kind: manta.LockoutUsername, value: username},
+			{kind: manta.LockoutIP, value: ip},
+		} {
+			if target.value == "" {
+				continue
+			}
+
+			lockout, err := getLockout(b, target.kind, target.value)
+			if err != nil {
+				return err
+			}
+
+			if lockout.Locked(now) {
+				return manta.ErrSigninLocked
+			}
+
+			lockouts = append(lockouts, lockout)
+		}
+
+		for _, lockout := range lockouts {
+			s.lockoutPolicy.Fail(lockout, now)
+
+			if err = putLockout(b, lockout); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// SigninSucceeded clears the failures of the username, and uncounts the
+// attempt of the IP
+func (s *Service) SigninSucceeded(ctx context.Context, username, ip string) error {
+	span, ctx := tracing.StartSpanFromContext(ctx)
+	defer span.Finish()
+
+	return s.kv.Update(ctx, func(tx Tx) error {
+		b, err := tx.Bucket(LockoutsBucket)
+		if err != nil {
+			return err
+		}
+
+		if err = b.Delete(lockoutKey(manta.LockoutUsername, username)); err != nil {
+			return err
+		}
+
+		if ip == "" {
+			return nil
+		}
+
+		lockout, err := getLockout(b, manta.LockoutIP, ip)
+		if err != nil {
+			return err
+		}
+
+		s.lockoutPolicy.Succeed(lockout)
+		if lockout.Failures == 0 {
+			return b.Delete(lockoutKey(manta.LockoutIP, ip))
+		}
+
+		return putLockout(b, lockout)
+	})
+}
+
+// FindLockouts returns the usernames and IPs failed to sign in recently,
+// the latest failed comes first
+func (s *Service) FindLockouts(ctx context.Context) ([]*manta.Lockout, error) {
+	span, ctx := tracing.StartSpanFromContext(ctx)
+	defer span.Finish()
+
+	now := time.Now()
+	lockouts := []*manta.Lockout{}
+	err := s.kv.View(ctx, func(tx Tx) error {
+		return walkLockouts(tx, func(k []byte, lockout *manta.Lockout) error {
+			if !s.lockoutPolicy.Expired(lockout, now) {
+				lockouts = append(lockouts, lockout)
+			}
+
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(lockouts, func(i, j int) bool {
+		return lockouts[i].LastFailure.After(lockouts[j].LastFailure)
+	})
+
+	return lockouts, nil
+}
+
+// DeleteLockout clears the failures, and unlocks it, nothing is done if
+// there is no failure
+func (s *Service) DeleteLockout(ctx context.Context, kind manta.LockoutKind, value string) error {
+	span, ctx := tracing.StartSpanFromContext(ctx)
+	defer span.Finish()
+
+	if err := kind.Valid(); err != nil {
+		return err
+	}
+
+	return s.kv.Update(ctx, func(tx Tx) error {
+		b, err := tx.Bucket(LockoutsBucket)
+		if err != nil {
+			return err
+		}
+
+		return b.Delete(lockoutKey(kind, value))
+	})
+}
+
+// DeleteExpiredLockouts deletes the lockouts whose failures are too old
+// to count, and returns how many lockouts are deleted
+func (s *Service) DeleteExpiredLockouts(ctx context.Context) (int, error) {
+	span, ctx := tracing.StartSpanFromContext(ctx)
+	defer span.Finish()
+
+	now := time.Now()
+	var deleted int
+	err := s.kv.Update(ctx, func(tx Tx) error {
+		var keys [][]byte
+		err := walkLockouts(tx, func(k []byte, lockout *manta.Lockout) error {
+			if s.lockoutPolicy.Expired(lockout, now) {
+				keys = append(keys, append([]byte{}, k...))
+			}
+
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		b, err := tx.Bucket(LockoutsBucket)
+		if err != nil {
+			return err
+		}
+
+		for _, key := range keys {
+			if err = b.Delete(key); err != nil {
+				return err
+			}
+		}
+
+		deleted = len(keys)
+		return nil
+	})
+
+	return deleted, err
+}
+
+func walkLockouts(tx Tx, fn func(k []byte, lockout *manta.Lockout) error) error {
+	b, err := tx.Bucket(LockoutsBucket)
+	if err != nil {
+		return err
+	}
+
+	cursor, err := b.Cursor()
+	if err != nil {
+		return err
+	}
+
+	for k, v := cursor.First(); k != nil; k, v = cursor.Next() {
+		lockout := &manta.Lockout{}
+		if err = json.Unmarshal(v, lockout); err != nil {
+			return err
+		}
+
+		if err = fn(k, lockout); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
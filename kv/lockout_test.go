@@ -0,0 +1,105 @@
+package kv_test
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/f1shl3gs/manta"
+	"github.com/f1shl3gs/manta/kv"
+)
+
+func TestLockouts(t *testing.T) {
+	svc, closer := NewTestService(t, kv.WithLockoutPolicy(manta.LockoutPolicy{
+		MaxUsernameFailures: 2,
+		MaxIPFailures:       3,
+		Duration:            time.Minute,
+		MaxDuration:         time.Hour,
+	}))
+	defer closer()
+
+	ctx := context.Background()
+	const ip = "10.0.0.1"
+
+	// every attempt counts until it succeeds
+	require.NoError(t, svc.AttemptSignin(ctx, "foo", ip))
+	require.NoError(t, svc.SigninSucceeded(ctx, "foo", ip))
+	lockouts, err := svc.FindLockouts(ctx)
+	require.NoError(t, err)
+	require.Len(t, lockouts, 0)
+
+	require.NoError(t, svc.AttemptSignin(ctx, "foo", ip))
+	require.NoError(t, svc.AttemptSignin(ctx, "foo", ip))
+
+	// the username is locked, but the IP is not yet
+	require.Equal(t, manta.ErrSigninLocked, svc.AttemptSignin(ctx, "foo", ip))
+	require.NoError(t, svc.AttemptSignin(ctx, "bar", ip))
+
+	// the IP is locked for every username
+	require.Equal(t, manta.ErrSigninLocked, svc.AttemptSignin(ctx, "baz", ip))
+	require.NoError(t, svc.AttemptSignin(ctx, "bar", "10.0.0.2"))
+
+	lockouts, err = svc.FindLockouts(ctx)
+	require.NoError(t, err)
+	require.Len(t, lockouts, 4)
+
+	// succeeded signin clears the username, and uncounts the attempt of
+	// the IP only
+	require.NoError(t, svc.SigninSucceeded(ctx, "bar", "10.0.0.2"))
+	require.Equal(t, manta.ErrSigninLocked, svc.AttemptSignin(ctx, "bar", ip))
+
+	require.NoError(t, svc.DeleteLockout(ctx, manta.LockoutIP, ip))
+	require.NoError(t, svc.AttemptSignin(ctx, "bar", ip))
+	require.Equal(t, manta.ErrSigninLocked, svc.AttemptSignin(ctx, "foo", "10.0.0.2"))
+
+	err = svc.DeleteLockout(ctx, "unknown", "foo")
+	require.Equal(t, manta.EInvalid, manta.ErrorCode(err))
+
+	lockouts, err = svc.FindLockouts(ctx)
+	require.NoError(t, err)
+	require.Len(t, lockouts, 3)
+	for _, lockout := range lockouts {
+		if lockout.Value == "foo" {
+			require.Equal(t, 2, lockout.Failures)
+		}
+	}
+
+	// nothing is expired yet
+	deleted, err := svc.DeleteExpiredLockouts(ctx)
+	require.NoError(t, err)
+	require.Equal(t, 0, deleted)
+}
+
+func TestAttemptSigninConcurrently(t *testing.T) {
+	svc, closer := NewTestService(t, kv.WithLockoutPolicy(manta.LockoutPolicy{
+		MaxUsernameFailures: 3,
+		MaxIPFailures:       100,
+		Duration:            time.Minute,
+		MaxDuration:         time.Hour,
+	}))
+	defer closer()
+
+	var (
+		ctx     = context.Background()
+		wg      sync.WaitGroup
+		allowed atomic.Int32
+	)
+
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			if svc.AttemptSignin(ctx, "foo", "10.0.0.1") == nil {
+				allowed.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+
+	require.Equal(t, int32(3), allowed.Load())
+}
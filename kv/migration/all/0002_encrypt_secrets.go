@@ -0,0 +1,36 @@
+package all
+
+import (
+	"context"
+
+	"github.com/f1shl3gs/manta"
+	"github.com/f1shl3gs/manta/kv"
+	"github.com/f1shl3gs/manta/pkg/keyring"
+)
+
+// Migration0001EncryptSecrets creates the bucket of data keys, and encrypts
+// secrets stored in plaintext. Secrets are left as is if kr is nil.
+func Migration0001EncryptSecrets(kr *keyring.Keyring) Spec {
+	return &spec{
+		name: "encrypt secrets",
+		up: func(ctx context.Context, store kv.SchemaStore) error {
+			err := store.CreateBucket(ctx, kv.SecretKeysBucket)
+			if err != nil {
+				return err
+			}
+
+			if kr == nil {
+				return nil
+			}
+
+			_, err = kv.EncryptSecrets(ctx, store, kr)
+			return err
+		},
+		down: func(ctx context.Context, store kv.SchemaStore) error {
+			return &manta.Error{
+				Code: manta.EInvalid,
+				Msg:  "encrypted secrets cannot be downgraded",
+			}
+		},
+	}
+}
@@ -0,0 +1,27 @@
+package all
+
+import (
+	"context"
+
+	"github.com/f1shl3gs/manta/kv"
+)
+
+// Migration0002ConfigRevisions creates the bucket of config revisions, and
+// saves the current state of existing configs as their first revision.
+func Migration0002ConfigRevisions() Spec {
+	return &spec{
+		name: "config revisions",
+		up: func(ctx context.Context, store kv.SchemaStore) error {
+			err := store.CreateBucket(ctx, kv.ConfigRevisionsBucket)
+			if err != nil {
+				return err
+			}
+
+			_, err = kv.InitConfigRevisions(ctx, store)
+			return err
+		},
+		down: func(ctx context.Context, store kv.SchemaStore) error {
+			return store.DeleteBucket(ctx, kv.ConfigRevisionsBucket)
+		},
+	}
+}
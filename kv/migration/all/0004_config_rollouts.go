@@ -0,0 +1,33 @@
+package all
+
+import (
+	"context"
+
+	"github.com/f1shl3gs/manta/kv"
+)
+
+// Migration0003ConfigRollouts creates the buckets of config rollouts and
+// the acks reported by instances.
+func Migration0003ConfigRollouts() Spec {
+	return &spec{
+		name: "config rollouts",
+		up: func(ctx context.Context, store kv.SchemaStore) error {
+			for _, bucket := range [][]byte{kv.ConfigRolloutsBucket, kv.ConfigAcksBucket} {
+				if err := store.CreateBucket(ctx, bucket); err != nil {
+					return err
+				}
+			}
+
+			return nil
+		},
+		down: func(ctx context.Context, store kv.SchemaStore) error {
+			for _, bucket := range [][]byte{kv.ConfigRolloutsBucket, kv.ConfigAcksBucket} {
+				if err := store.DeleteBucket(ctx, bucket); err != nil {
+					return err
+				}
+			}
+
+			return nil
+		},
+	}
+}
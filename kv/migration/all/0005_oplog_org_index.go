@@ -0,0 +1,27 @@
+package all
+
+import (
+	"context"
+
+	"github.com/f1shl3gs/manta/kv"
+)
+
+// Migration0004OplogOrgIndex creates the org index of operation logs, and
+// indexes the existing logs.
+func Migration0004OplogOrgIndex() Spec {
+	return &spec{
+		name: "operation log org index",
+		up: func(ctx context.Context, store kv.SchemaStore) error {
+			err := store.CreateBucket(ctx, kv.ChangesOrgIndexBucket)
+			if err != nil {
+				return err
+			}
+
+			_, err = kv.InitChangesOrgIndex(ctx, store)
+			return err
+		},
+		down: func(ctx context.Context, store kv.SchemaStore) error {
+			return store.DeleteBucket(ctx, kv.ChangesOrgIndexBucket)
+		},
+	}
+}
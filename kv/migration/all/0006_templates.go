@@ -0,0 +1,21 @@
+package all
+
+import (
+	"context"
+
+	"github.com/f1shl3gs/manta/kv"
+)
+
+// Migration0005Templates creates the bucket of templates, which is not
+// created by the initial migration.
+func Migration0005Templates() Spec {
+	return &spec{
+		name: "templates",
+		up: func(ctx context.Context, store kv.SchemaStore) error {
+			return store.CreateBucket(ctx, kv.TemplatesBucket)
+		},
+		down: func(ctx context.Context, store kv.SchemaStore) error {
+			return store.DeleteBucket(ctx, kv.TemplatesBucket)
+		},
+	}
+}
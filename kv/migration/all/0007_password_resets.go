@@ -0,0 +1,20 @@
+package all
+
+import (
+	"context"
+
+	"github.com/f1shl3gs/manta/kv"
+)
+
+// Migration0006PasswordResets creates the bucket of password reset tokens.
+func Migration0006PasswordResets() Spec {
+	return &spec{
+		name: "password resets",
+		up: func(ctx context.Context, store kv.SchemaStore) error {
+			return store.CreateBucket(ctx, kv.PasswordResetsBucket)
+		},
+		down: func(ctx context.Context, store kv.SchemaStore) error {
+			return store.DeleteBucket(ctx, kv.PasswordResetsBucket)
+		},
+	}
+}
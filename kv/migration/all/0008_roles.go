@@ -0,0 +1,20 @@
+package all
+
+import (
+	"context"
+
+	"github.com/f1shl3gs/manta/kv"
+)
+
+// Migration0007Roles creates the bucket of custom roles.
+func Migration0007Roles() Spec {
+	return &spec{
+		name: "roles",
+		up: func(ctx context.Context, store kv.SchemaStore) error {
+			return store.CreateBucket(ctx, kv.RolesBucket)
+		},
+		down: func(ctx context.Context, store kv.SchemaStore) error {
+			return store.DeleteBucket(ctx, kv.RolesBucket)
+		},
+	}
+}
@@ -0,0 +1,21 @@
+package all
+
+import (
+	"context"
+
+	"github.com/f1shl3gs/manta/kv"
+)
+
+// Migration0008UserIdentities creates the bucket of the identities of
+// external identity providers.
+func Migration0008UserIdentities() Spec {
+	return &spec{
+		name: "user identities",
+		up: func(ctx context.Context, store kv.SchemaStore) error {
+			return store.CreateBucket(ctx, kv.UserIdentitiesBucket)
+		},
+		down: func(ctx context.Context, store kv.SchemaStore) error {
+			return store.DeleteBucket(ctx, kv.UserIdentitiesBucket)
+		},
+	}
+}
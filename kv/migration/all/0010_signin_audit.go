@@ -0,0 +1,39 @@
+package all
+
+import (
+	"context"
+
+	"github.com/f1shl3gs/manta/kv"
+)
+
+// Migration0009SigninAudit creates the buckets of audit records and the
+// lockouts of failed sign-ins.
+func Migration0009SigninAudit() Spec {
+	return &spec{
+		name: "signin audit",
+		up: func(ctx context.Context, store kv.SchemaStore) error {
+			for _, bucket := range [][]byte{
+				kv.AuditRecordsBucket,
+				kv.LockoutsBucket,
+			} {
+				if err := store.CreateBucket(ctx, bucket); err != nil {
+					return err
+				}
+			}
+
+			return nil
+		},
+		down: func(ctx context.Context, store kv.SchemaStore) error {
+			for _, bucket := range [][]byte{
+				kv.AuditRecordsBucket,
+				kv.LockoutsBucket,
+			} {
+				if err := store.DeleteBucket(ctx, bucket); err != nil {
+					return err
+				}
+			}
+
+			return nil
+		},
+	}
+}
@@ -0,0 +1,41 @@
+package all
+
+import (
+	"context"
+
+	"github.com/f1shl3gs/manta/kv"
+)
+
+// Migration0010DashboardVersions creates the buckets of dashboard versions
+// and the index of deleted dashboards, and saves the current state of
+// existing dashboards as their first version.
+func Migration0010DashboardVersions() Spec {
+	return &spec{
+		name: "dashboard versions",
+		up: func(ctx context.Context, store kv.SchemaStore) error {
+			for _, bucket := range [][]byte{
+				kv.DashboardVersionsBucket,
+				kv.DeletedDashboardOrgIndexBucket,
+			} {
+				if err := store.CreateBucket(ctx, bucket); err != nil {
+					return err
+				}
+			}
+
+			_, err := kv.InitDashboardVersions(ctx, store)
+			return err
+		},
+		down: func(ctx context.Context, store kv.SchemaStore) error {
+			for _, bucket := range [][]byte{
+				kv.DashboardVersionsBucket,
+				kv.DeletedDashboardOrgIndexBucket,
+			} {
+				if err := store.DeleteBucket(ctx, bucket); err != nil {
+					return err
+				}
+			}
+
+			return nil
+		},
+	}
+}
@@ -10,6 +10,7 @@ import (
 	"github.com/f1shl3gs/manta"
 	"github.com/f1shl3gs/manta/kv"
 	"github.com/f1shl3gs/manta/kv/migration/all"
+	"github.com/f1shl3gs/manta/pkg/keyring"
 )
 
 type Migration struct {
@@ -22,14 +23,31 @@ type Migration struct {
 }
 
 var (
-	All = []all.Spec{
-		all.Migration0000Initial(),
-	}
+	// All is the migrations without master key, secrets are stored
+	// in plaintext
+	All = Specs(nil)
 
 	//
 	migrationBucket = []byte("migrations")
 )
 
+// Specs returns all migrations, secrets are encrypted with the keyring
+func Specs(kr *keyring.Keyring) []all.Spec {
+	return []all.Spec{
+		all.Migration0000Initial(),
+		all.Migration0001EncryptSecrets(kr),
+		all.Migration0002ConfigRevisions(),
+		all.Migration0003ConfigRollouts(),
+		all.Migration0004OplogOrgIndex(),
+		all.Migration0005Templates(),
+		all.Migration0006PasswordResets(),
+		all.Migration0007Roles(),
+		all.Migration0008UserIdentities(),
+		all.Migration0009SigninAudit(),
+		all.Migration0010DashboardVersions(),
+	}
+}
+
 type Migrator struct {
 	logger *zap.Logger
 	store  kv.SchemaStore
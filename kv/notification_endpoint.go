@@ -247,7 +247,7 @@ func (s *Service) DeleteNotificationEndpoint(ctx context.Context, id manta.ID) (
 	)
 
 	err := s.kv.Update(ctx, func(tx Tx) error {
-		ne, err := findNotificationEndpointByID(tx, id)
+		ne, err := deleteNotificationEndpoint(tx, id)
 		if err != nil {
 			return err
 		}
@@ -255,39 +255,52 @@ func (s *Service) DeleteNotificationEndpoint(ctx context.Context, id manta.ID) (
 		orgID = ne.GetOrgID()
 		secrets = ne.SecretFields()
 
-		pk, err := ne.GetID().Encode()
-		if err != nil {
-			return err
-		}
+		return nil
+	})
 
-		fk, err := ne.GetOrgID().Encode()
-		if err != nil {
-			return err
-		}
+	if err != nil {
+		return nil, 0, err
+	}
 
-		// delete index
-		index := IndexKey(fk, pk)
-		b, err := tx.Bucket(NotificationENdpointOrgIndexBucket)
-		if err != nil {
-			return err
-		}
+	return secrets, orgID, nil
+}
 
-		if err = b.Delete(index); err != nil {
-			return err
-		}
+func deleteNotificationEndpoint(tx Tx, id manta.ID) (manta.NotificationEndpoint, error) {
+	ne, err := findNotificationEndpointByID(tx, id)
+	if err != nil {
+		return nil, err
+	}
 
-		// delete entity
-		b, err = tx.Bucket(NotificationEndpointsBucket)
-		if err != nil {
-			return err
-		}
+	pk, err := ne.GetID().Encode()
+	if err != nil {
+		return nil, err
+	}
 
-		return b.Delete(pk)
-	})
+	fk, err := ne.GetOrgID().Encode()
+	if err != nil {
+		return nil, err
+	}
 
+	// delete index
+	index := IndexKey(fk, pk)
+	b, err := tx.Bucket(NotificationENdpointOrgIndexBucket)
 	if err != nil {
-		return nil, 0, err
+		return nil, err
 	}
 
-	return secrets, orgID, nil
+	if err = b.Delete(index); err != nil {
+		return nil, err
+	}
+
+	if err = deleteOperationLogs(tx, id, nil); err != nil {
+		return nil, err
+	}
+
+	// delete entity
+	b, err = tx.Bucket(NotificationEndpointsBucket)
+	if err != nil {
+		return nil, err
+	}
+
+	return ne, b.Delete(pk)
 }
@@ -1,9 +1,11 @@
 package kv
 
 import (
+	"bytes"
 	"context"
 	"encoding/binary"
 	"encoding/json"
+	"time"
 
 	"github.com/f1shl3gs/manta"
 	"github.com/f1shl3gs/manta/pkg/tracing"
@@ -19,6 +21,11 @@ var (
 	//   key:    UserID + Timestamp
 	//   value:  ResourceID + Timestamp (key format of Changes Bucket)
 	ChangesUserIndexBucket = []byte("changesuserindex")
+
+	// ChangesOrgIndexBucket
+	//   key:    OrgID + Timestamp + ResourceID
+	//   value:  ResourceID + Timestamp (key format of Changes Bucket)
+	ChangesOrgIndexBucket = []byte("changesorgindex")
 )
 
 func changeKey(c *manta.OperationLogEntry) ([]byte, error) {
@@ -41,6 +48,21 @@ func changeUserIndexKey(c *manta.OperationLogEntry) ([]byte, error) {
 	return buf, nil
 }
 
+func changeOrgIndexKey(c *manta.OperationLogEntry) ([]byte, error) {
+	buf, err := c.OrgID.Encode()
+	if err != nil {
+		return nil, err
+	}
+
+	pk, err := c.ResourceID.Encode()
+	if err != nil {
+		return nil, err
+	}
+
+	buf = binary.BigEndian.AppendUint64(buf, uint64(c.Time.UnixNano()))
+	return append(buf, pk...), nil
+}
+
 // AddLogEntry add an operation log entry.
 func (s *Service) AddLogEntry(ctx context.Context, ent manta.OperationLogEntry) error {
 	span, ctx := tracing.StartSpanFromContext(ctx)
@@ -60,6 +82,14 @@ func (s *Service) AddLogEntry(ctx context.Context, ent manta.OperationLogEntry)
 		return err
 	}
 
+	orgIndexKey, err := changeOrgIndexKey(&ent)
+	if err != nil {
+		return err
+	}
+
+	// diffs are computed on read
+	ent.Diff = nil
+
 	value, err := json.Marshal(ent)
 	if err != nil {
 		return err
@@ -81,7 +111,16 @@ func (s *Service) AddLogEntry(ctx context.Context, ent manta.OperationLogEntry)
 			return err
 		}
 
-		return b.Put(indexKey, key)
+		if err = b.Put(indexKey, key); err != nil {
+			return err
+		}
+
+		b, err = tx.Bucket(ChangesOrgIndexBucket)
+		if err != nil {
+			return err
+		}
+
+		return b.Put(orgIndexKey, key)
 	})
 }
 
@@ -90,10 +129,50 @@ func (s *Service) FindOperationLogsByID(
 	ctx context.Context,
 	id manta.ID,
 	opts manta.FindOptions,
+) ([]*manta.OperationLogEntry, int, error) {
+	return s.FindOperationLogs(ctx, manta.OperationLogFilter{ResourceID: &id}, opts)
+}
+
+// FindOperationLogsByUser returns operation logs made by a user.
+func (s *Service) FindOperationLogsByUser(
+	ctx context.Context,
+	userID manta.ID,
+	opts manta.FindOptions,
+) ([]*manta.OperationLogEntry, int, error) {
+	return s.FindOperationLogs(ctx, manta.OperationLogFilter{UserID: &userID}, opts)
+}
+
+// FindOperationLogs returns operation logs match the filter, ordered by time.
+// The changes of a resource, the org index or the user index is scanned, the
+// time range is used to seek, and the other conditions are checked one by one.
+func (s *Service) FindOperationLogs(
+	ctx context.Context,
+	filter manta.OperationLogFilter,
+	opts manta.FindOptions,
 ) ([]*manta.OperationLogEntry, int, error) {
 	span, ctx := tracing.StartSpanFromContext(ctx)
 	defer span.Finish()
 
+	var (
+		bucket  []byte
+		indexed = true
+		id      manta.ID
+	)
+
+	switch {
+	case filter.ResourceID != nil:
+		bucket, indexed, id = ChangesBucket, false, *filter.ResourceID
+	case filter.OrgID != nil:
+		bucket, id = ChangesOrgIndexBucket, *filter.OrgID
+	case filter.UserID != nil:
+		bucket, id = ChangesUserIndexBucket, *filter.UserID
+	default:
+		return nil, 0, &manta.Error{
+			Code: manta.EInvalid,
+			Msg:  "org, resource or user is required to find operation logs",
+		}
+	}
+
 	prefix, err := id.Encode()
 	if err != nil {
 		return nil, 0, err
@@ -101,29 +180,56 @@ func (s *Service) FindOperationLogsByID(
 
 	changes := []*manta.OperationLogEntry{}
 	err = s.kv.View(ctx, func(tx Tx) error {
-		b, err := tx.Bucket(ChangesBucket)
+		b, err := tx.Bucket(bucket)
 		if err != nil {
 			return err
 		}
 
-		cursor, err := b.ForwardCursor(prefix, WithCursorPrefix(prefix), WithCursorLimit(opts.Limit))
+		data, err := tx.Bucket(ChangesBucket)
 		if err != nil {
 			return err
 		}
 
-		for k, v := cursor.Next(); k != nil; k, v = cursor.Next() {
+		cursor, err := b.Cursor()
+		if err != nil {
+			return err
+		}
+
+		skipped := 0
+		for k, v := seekChanges(cursor, prefix, filter, opts.Descending); k != nil; k, v = nextChange(cursor, opts.Descending) {
+			if !bytes.HasPrefix(k, prefix) || !changeInRange(k[len(prefix):], filter) {
+				break
+			}
+
+			if indexed {
+				v, err = data.Get(v)
+				if err != nil {
+					return err
+				}
+			}
+
 			c := &manta.OperationLogEntry{}
-			err = json.Unmarshal(v, c)
-			if err != nil {
+			if err = json.Unmarshal(v, c); err != nil {
 				return err
 			}
 
+			if !filter.Match(c) {
+				continue
+			}
+
+			if skipped < opts.Offset {
+				skipped += 1
+				continue
+			}
+
 			changes = append(changes, c)
+			if opts.Limit > 0 && len(changes) >= opts.Limit {
+				break
+			}
 		}
 
 		return nil
 	})
-
 	if err != nil {
 		return nil, 0, err
 	}
@@ -131,57 +237,351 @@ func (s *Service) FindOperationLogsByID(
 	return changes, len(changes), nil
 }
 
-// FindOperationLogsByUser returns operation logs made by a user.
-func (s *Service) FindOperationLogsByUser(
-	ctx context.Context,
-	userID manta.ID,
-	opts manta.FindOptions,
-) ([]*manta.OperationLogEntry, int, error) {
+// DeleteOperationLogs deletes all operation logs of a resource.
+func (s *Service) DeleteOperationLogs(ctx context.Context, resourceID manta.ID) error {
 	span, ctx := tracing.StartSpanFromContext(ctx)
 	defer span.Finish()
 
-	prefix, err := userID.Encode()
+	return s.kv.Update(ctx, func(tx Tx) error {
+		return deleteOperationLogs(tx, resourceID, nil)
+	})
+}
+
+// deleteOperationLogs deletes the operation logs of a resource, which match
+// the function, nil match deletes all of them. It's called when the resource
+// is deleted, so only the deletion entry is kept, which expires by age.
+func deleteOperationLogs(tx Tx, resourceID manta.ID, match func(ent *manta.OperationLogEntry) bool) error {
+	prefix, err := resourceID.Encode()
 	if err != nil {
-		return nil, 0, err
+		return err
 	}
 
-	var changes []*manta.OperationLogEntry
-	err = s.kv.View(ctx, func(tx Tx) error {
-		b, err := tx.Bucket(ChangesUserIndexBucket)
+	b, err := tx.Bucket(ChangesBucket)
+	if err != nil {
+		return err
+	}
+
+	cursor, err := b.Cursor()
+	if err != nil {
+		return err
+	}
+
+	// collect keys first, deleting while iterating is not safe
+	var keys [][]byte
+	for k, _ := cursor.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, _ = cursor.Next() {
+		keys = append(keys, append([]byte{}, k...))
+	}
+
+	return deleteChanges(tx, keys, match)
+}
+
+// deleteOrgOperationLogs deletes all operation logs of the org
+func deleteOrgOperationLogs(tx Tx, orgID manta.ID) error {
+	prefix, err := orgID.Encode()
+	if err != nil {
+		return err
+	}
+
+	b, err := tx.Bucket(ChangesOrgIndexBucket)
+	if err != nil {
+		return err
+	}
+
+	cursor, err := b.Cursor()
+	if err != nil {
+		return err
+	}
+
+	var keys [][]byte
+	for k, v := cursor.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, v = cursor.Next() {
+		keys = append(keys, append([]byte{}, v...))
+	}
+
+	return deleteChanges(tx, keys, nil)
+}
+
+// deleteChanges deletes the entries of keys and their index rows
+func deleteChanges(tx Tx, keys [][]byte, match func(ent *manta.OperationLogEntry) bool) error {
+	changes, err := tx.Bucket(ChangesBucket)
+	if err != nil {
+		return err
+	}
+
+	userIndex, err := tx.Bucket(ChangesUserIndexBucket)
+	if err != nil {
+		return err
+	}
+
+	orgIndex, err := tx.Bucket(ChangesOrgIndexBucket)
+	if err != nil {
+		return err
+	}
+
+	for _, key := range keys {
+		v, err := changes.Get(key)
 		if err != nil {
+			if IsNotFound(err) {
+				continue
+			}
+
+			return err
+		}
+
+		c := &manta.OperationLogEntry{}
+		if err = json.Unmarshal(v, c); err != nil {
 			return err
 		}
 
-		cursor, err := b.ForwardCursor(prefix, WithCursorPrefix(prefix), WithCursorLimit(opts.Limit))
+		if match != nil && !match(c) {
+			continue
+		}
+
+		indexKey, err := changeUserIndexKey(c)
 		if err != nil {
 			return err
 		}
 
-		b, err = tx.Bucket(ChangesBucket)
+		if err = userIndex.Delete(indexKey); err != nil {
+			return err
+		}
+
+		indexKey, err = changeOrgIndexKey(c)
 		if err != nil {
 			return err
 		}
 
-		for k, v := cursor.Next(); k != nil; k, v = cursor.Next() {
-			v, err = b.Get(v)
+		if err = orgIndex.Delete(indexKey); err != nil {
+			return err
+		}
+
+		if err = changes.Delete(key); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// CompactOperationLogs deletes the operation logs expired by the retention,
+// at most batchSize entries are deleted in one transaction, so writes are
+// not blocked for long. It returns the number of deleted and kept entries.
+func (s *Service) CompactOperationLogs(
+	ctx context.Context,
+	retention manta.OperationLogRetention,
+	batchSize int,
+) (int, int, error) {
+	span, ctx := tracing.StartSpanFromContext(ctx)
+	defer span.Finish()
+
+	var (
+		deleted, kept int
+		start         []byte
+		cutoff        = time.Now().Add(-retention.MaxAge).UnixNano()
+	)
+
+	if retention.MaxAge <= 0 {
+		cutoff = 0
+	}
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return deleted, kept, err
+		}
+
+		var (
+			expired [][]byte
+			n       int
+			next    []byte
+		)
+
+		err := s.kv.Update(ctx, func(tx Tx) error {
+			b, err := tx.Bucket(ChangesBucket)
 			if err != nil {
 				return err
 			}
 
+			cursor, err := b.Cursor()
+			if err != nil {
+				return err
+			}
+
+			expired, n, next = expiredChanges(cursor, start, retention.MaxEntries, cutoff, batchSize)
+
+			return deleteChanges(tx, expired, nil)
+		})
+		if err != nil {
+			return deleted, kept, err
+		}
+
+		deleted += len(expired)
+		kept += n
+
+		if next == nil {
+			return deleted, kept, nil
+		}
+
+		start = next
+	}
+}
+
+// expiredChanges scans the changes from the resource start, and returns the
+// keys of expired entries, the number of entries kept, and the resource to
+// continue with if the batch is full. Keys of a resource are ordered by time,
+// so the expired entries are the oldest ones.
+func expiredChanges(cursor Cursor, start []byte, maxEntries int, cutoff int64, batchSize int) ([][]byte, int, []byte) {
+	var (
+		expired  [][]byte
+		kept     int
+		resource []byte
+		group    [][]byte
+	)
+
+	// flush moves the expired entries of the group to the batch, false is
+	// returned if the batch is full
+	flush := func() bool {
+		n := 0
+		for i, key := range group {
+			ts := int64(binary.BigEndian.Uint64(key[manta.IDLength:]))
+			if !(maxEntries > 0 && i < len(group)-maxEntries) && ts >= cutoff {
+				break
+			}
+
+			n += 1
+		}
+
+		if len(expired) > 0 && len(expired)+n > batchSize {
+			return false
+		}
+
+		expired = append(expired, group[:n]...)
+		kept += len(group) - n
+		group = group[:0]
+
+		return true
+	}
+
+	var k []byte
+	if start == nil {
+		k, _ = cursor.First()
+	} else {
+		k, _ = cursor.Seek(start)
+	}
+
+	for ; k != nil; k, _ = cursor.Next() {
+		if len(k) < manta.IDLength+8 {
+			continue
+		}
+
+		if resource != nil && !bytes.Equal(k[:manta.IDLength], resource) {
+			if !flush() {
+				return expired, kept, resource
+			}
+		}
+
+		resource = append([]byte{}, k[:manta.IDLength]...)
+		group = append(group, append([]byte{}, k...))
+	}
+
+	if len(group) > 0 && !flush() {
+		return expired, kept, resource
+	}
+
+	return expired, kept, nil
+}
+
+// seekChanges moves the cursor to the first key in the time range, keys
+// are id + big endian timestamp (+ resource id for the org index).
+func seekChanges(cursor Cursor, prefix []byte, filter manta.OperationLogFilter, descending bool) ([]byte, []byte) {
+	if !descending {
+		seek := prefix
+		if filter.Since != nil {
+			seek = binary.BigEndian.AppendUint64(append([]byte{}, prefix...), uint64(filter.Since.UnixNano()))
+		}
+
+		return cursor.Seek(seek)
+	}
+
+	// seek to the first key after the range, and step back
+	after := append(append([]byte{}, prefix...), 0xff)
+	if filter.Until != nil {
+		after = binary.BigEndian.AppendUint64(append([]byte{}, prefix...), uint64(filter.Until.UnixNano()+1))
+	}
+
+	k, _ := cursor.Seek(after)
+	if k == nil {
+		return cursor.Last()
+	}
+
+	return cursor.Prev()
+}
+
+func nextChange(cursor Cursor, descending bool) ([]byte, []byte) {
+	if descending {
+		return cursor.Prev()
+	}
+
+	return cursor.Next()
+}
+
+// changeInRange returns true if the timestamp of key, whose id prefix is
+// trimmed, is in the time range of filter.
+func changeInRange(key []byte, filter manta.OperationLogFilter) bool {
+	if len(key) < 8 {
+		return false
+	}
+
+	ts := int64(binary.BigEndian.Uint64(key))
+	if filter.Since != nil && ts < filter.Since.UnixNano() {
+		return false
+	}
+
+	if filter.Until != nil && ts > filter.Until.UnixNano() {
+		return false
+	}
+
+	return true
+}
+
+// InitChangesOrgIndex indexes the operation logs by org, it returns the
+// number of logs indexed.
+func InitChangesOrgIndex(ctx context.Context, store Store) (int, error) {
+	var n int
+	err := store.Update(ctx, func(tx Tx) error {
+		b, err := tx.Bucket(ChangesBucket)
+		if err != nil {
+			return err
+		}
+
+		index, err := tx.Bucket(ChangesOrgIndexBucket)
+		if err != nil {
+			return err
+		}
+
+		cursor, err := b.Cursor()
+		if err != nil {
+			return err
+		}
+
+		for k, v := cursor.First(); k != nil; k, v = cursor.Next() {
 			c := &manta.OperationLogEntry{}
-			err = json.Unmarshal(v, c)
+			if err := json.Unmarshal(v, c); err != nil {
+				return err
+			}
+
+			indexKey, err := changeOrgIndexKey(c)
 			if err != nil {
 				return err
 			}
 
-			changes = append(changes, c)
+			if err = index.Put(indexKey, k); err != nil {
+				return err
+			}
+
+			n += 1
 		}
 
 		return nil
 	})
-	if err != nil {
-		return nil, 0, err
-	}
 
-	return changes, len(changes), nil
+	return n, err
 }
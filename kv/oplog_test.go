@@ -6,10 +6,12 @@ import (
 	"time"
 
 	"github.com/f1shl3gs/manta"
+	"github.com/f1shl3gs/manta/authorizer"
 	"github.com/f1shl3gs/manta/kv"
 	"github.com/f1shl3gs/manta/mock"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 var (
@@ -38,6 +40,10 @@ func TestOpLogService(t *testing.T) {
 			name: "FindOperationLogsByUser",
 			fn:   FindOperationLogsByUser,
 		},
+		{
+			name: "FindOperationLogs",
+			fn:   FindOperationLogs,
+		},
 	}
 
 	var initFn initOpLogService = func(t *testing.T) (context.Context, manta.OperationLogService, func()) {
@@ -228,3 +234,212 @@ func FindOperationLogsByUser(t *testing.T, initFn initOpLogService) {
 		})
 	}
 }
+
+func FindOperationLogs(t *testing.T, initFn initOpLogService) {
+	orgID := manta.ID(1)
+	update := manta.ChangeType(manta.Update)
+	dashboards := manta.DashboardsResourceType
+
+	tests := []struct {
+		name string
+		fn   func(t *testing.T, ctx context.Context, svc manta.OperationLogService)
+	}{
+		{
+			name: "org timeline",
+			fn: func(t *testing.T, ctx context.Context, svc manta.OperationLogService) {
+				changes, _, err := svc.FindOperationLogs(ctx, manta.OperationLogFilter{
+					OrgID: &orgID,
+				}, manta.FindOptions{})
+				assert.NoError(t, err)
+				assert.Equal(t, 3, len(changes))
+				assert.Equal(t, testResourceID_1, changes[0].ResourceID)
+
+				changes, _, err = svc.FindOperationLogs(ctx, manta.OperationLogFilter{
+					OrgID: &orgID,
+				}, manta.FindOptions{Descending: true, Limit: 1})
+				assert.NoError(t, err)
+				assert.Equal(t, 1, len(changes))
+				assert.Equal(t, update, changes[0].Type)
+			},
+		},
+		{
+			name: "filter by type",
+			fn: func(t *testing.T, ctx context.Context, svc manta.OperationLogService) {
+				changes, _, err := svc.FindOperationLogs(ctx, manta.OperationLogFilter{
+					OrgID:        &orgID,
+					ResourceType: &dashboards,
+					Type:         &update,
+				}, manta.FindOptions{})
+				assert.NoError(t, err)
+				assert.Equal(t, 1, len(changes))
+				assert.Equal(t, testUserID_1, changes[0].UserID)
+			},
+		},
+		{
+			name: "filter by time range",
+			fn: func(t *testing.T, ctx context.Context, svc manta.OperationLogService) {
+				all, _, err := svc.FindOperationLogs(ctx, manta.OperationLogFilter{
+					ResourceID: &testResourceID_2,
+				}, manta.FindOptions{})
+				assert.NoError(t, err)
+				assert.Equal(t, 2, len(all))
+
+				changes, _, err := svc.FindOperationLogs(ctx, manta.OperationLogFilter{
+					ResourceID: &testResourceID_2,
+					Since:      &all[1].Time,
+				}, manta.FindOptions{})
+				assert.NoError(t, err)
+				assert.Equal(t, 1, len(changes))
+				assert.Equal(t, update, changes[0].Type)
+
+				changes, _, err = svc.FindOperationLogs(ctx, manta.OperationLogFilter{
+					ResourceID: &testResourceID_2,
+					Until:      &all[0].Time,
+				}, manta.FindOptions{})
+				assert.NoError(t, err)
+				assert.Equal(t, 1, len(changes))
+				assert.Equal(t, manta.Create, changes[0].Type)
+			},
+		},
+		{
+			name: "filter required",
+			fn: func(t *testing.T, ctx context.Context, svc manta.OperationLogService) {
+				_, _, err := svc.FindOperationLogs(ctx, manta.OperationLogFilter{}, manta.FindOptions{})
+				assert.Equal(t, manta.EInvalid, manta.ErrorCode(err))
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, svc, closer := initFn(t)
+			defer closer()
+
+			tt.fn(t, ctx, svc)
+		})
+	}
+}
+
+func addLogEntries(t *testing.T, svc manta.OperationLogService, resourceID manta.ID, orgID manta.ID, times ...time.Time) {
+	t.Helper()
+
+	for _, ts := range times {
+		err := svc.AddLogEntry(context.Background(), manta.OperationLogEntry{
+			Type:         manta.Update,
+			ResourceID:   resourceID,
+			ResourceType: manta.DashboardsResourceType,
+			OrgID:        orgID,
+			UserID:       testUserID_1,
+			Time:         ts,
+		})
+		require.NoError(t, err)
+	}
+}
+
+func TestCompactOperationLogs(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	old := now.Add(-48 * time.Hour)
+
+	for _, tc := range []struct {
+		name      string
+		retention manta.OperationLogRetention
+		batchSize int
+		deleted   int
+		remains   map[manta.ID]int
+	}{
+		{
+			name:      "by age",
+			retention: manta.OperationLogRetention{MaxAge: 24 * time.Hour},
+			batchSize: 100,
+			deleted:   2,
+			remains:   map[manta.ID]int{1: 1, 2: 2, 3: 0},
+		},
+		{
+			name:      "by entries",
+			retention: manta.OperationLogRetention{MaxEntries: 1},
+			batchSize: 100,
+			deleted:   2,
+			remains:   map[manta.ID]int{1: 1, 2: 1, 3: 1},
+		},
+		{
+			name:      "both in small batches",
+			retention: manta.OperationLogRetention{MaxAge: 24 * time.Hour, MaxEntries: 1},
+			batchSize: 1,
+			deleted:   3,
+			remains:   map[manta.ID]int{1: 1, 2: 1, 3: 0},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			svc, closer := NewTestService(t)
+			defer closer()
+
+			addLogEntries(t, svc, 1, 1, old, now)
+			addLogEntries(t, svc, 2, 1, now.Add(time.Second), now.Add(2*time.Second))
+			addLogEntries(t, svc, 3, 2, old.Add(time.Second))
+
+			deleted, kept, err := svc.CompactOperationLogs(ctx, tc.retention, tc.batchSize)
+			require.NoError(t, err)
+			assert.Equal(t, tc.deleted, deleted)
+			assert.Equal(t, 5-tc.deleted, kept)
+
+			for id, n := range tc.remains {
+				changes, _, err := svc.FindOperationLogsByID(ctx, id, manta.FindOptions{})
+				require.NoError(t, err)
+				assert.Len(t, changes, n)
+			}
+
+			// index rows are deleted too
+			changes, _, err := svc.FindOperationLogsByUser(ctx, testUserID_1, manta.FindOptions{})
+			require.NoError(t, err)
+			assert.Len(t, changes, kept)
+
+			orgID := manta.ID(2)
+			changes, _, err = svc.FindOperationLogs(ctx, manta.OperationLogFilter{OrgID: &orgID}, manta.FindOptions{})
+			require.NoError(t, err)
+			assert.Len(t, changes, tc.remains[3])
+		})
+	}
+}
+
+func TestDeleteOperationLogsCascade(t *testing.T) {
+	svc, closer := NewTestService(t)
+	defer closer()
+
+	ctx := authorizer.SetAuthorizer(context.Background(), &manta.Session{UserID: testUserID_1})
+	orgID := CreateDefaultOrg(t, svc)
+
+	d := &manta.Dashboard{OrgID: orgID, Name: "foo"}
+	err := svc.CreateDashboard(ctx, d)
+	require.NoError(t, err)
+
+	now := time.Now()
+	addLogEntries(t, svc, d.ID, orgID, now, now.Add(time.Second))
+	addLogEntries(t, svc, 1000, orgID, now.Add(2*time.Second))
+
+	err = svc.RemoveDashboard(ctx, d.ID)
+	require.NoError(t, err)
+
+	changes, _, err := svc.FindOperationLogsByID(ctx, d.ID, manta.FindOptions{})
+	require.NoError(t, err)
+	assert.Empty(t, changes)
+
+	changes, _, err = svc.FindOperationLogs(ctx, manta.OperationLogFilter{OrgID: &orgID}, manta.FindOptions{})
+	require.NoError(t, err)
+	assert.Len(t, changes, 1)
+
+	changes, _, err = svc.FindOperationLogsByUser(ctx, testUserID_1, manta.FindOptions{})
+	require.NoError(t, err)
+	assert.Len(t, changes, 1)
+
+	err = svc.DeleteOrganization(ctx, orgID)
+	require.NoError(t, err)
+
+	changes, _, err = svc.FindOperationLogsByUser(ctx, testUserID_1, manta.FindOptions{})
+	require.NoError(t, err)
+	assert.Empty(t, changes)
+
+	changes, _, err = svc.FindOperationLogsByID(ctx, 1000, manta.FindOptions{})
+	require.NoError(t, err)
+	assert.Empty(t, changes)
+}
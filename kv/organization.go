@@ -1,6 +1,7 @@
 package kv
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"time"
@@ -10,7 +11,10 @@ import (
 )
 
 var (
-	organizationBucket          = []byte("organizations")
+	// OrganizationBucket
+	//   key:   OrgID
+	//   value: Marshaled Organization
+	OrganizationBucket          = []byte("organizations")
 	organizationNameIndexBucket = []byte("organizationnameindex")
 )
 
@@ -23,6 +27,9 @@ func (s *Service) FindOrganizationByID(ctx context.Context, id manta.ID) (*manta
 
 	err := s.kv.View(ctx, func(tx Tx) error {
 		tmp, err := s.findOrganizationByID(ctx, tx, id)
+		if IsNotFound(err) {
+			return manta.ErrOrgNotFound
+		}
 		if err != nil {
 			return err
 		}
@@ -42,7 +49,7 @@ func (s *Service) findOrganizationByID(ctx context.Context, tx Tx, id manta.ID)
 	span, _ := tracing.StartSpanFromContext(ctx)
 	defer span.Finish()
 
-	return findByID[manta.Organization](tx, id, organizationBucket)
+	return findByID[manta.Organization](tx, id, OrganizationBucket)
 }
 
 func (s *Service) findOrganizationByName(ctx context.Context, tx Tx, n string) (*manta.Organization, error) {
@@ -87,6 +94,10 @@ func (s *Service) FindOrganization(ctx context.Context, filter manta.Organizatio
 		return err
 	})
 
+	if IsNotFound(err) {
+		return nil, manta.ErrOrgNotFound
+	}
+
 	if err != nil {
 		return nil, err
 	}
@@ -133,7 +144,7 @@ func (s *Service) findAllOrganizations(ctx context.Context, tx Tx) ([]*manta.Org
 	span, _ := tracing.StartSpanFromContext(ctx)
 	defer span.Finish()
 
-	b, err := tx.Bucket(organizationBucket)
+	b, err := tx.Bucket(OrganizationBucket)
 	if err != nil {
 		return nil, err
 	}
@@ -216,7 +227,7 @@ func (s *Service) putOrganization(ctx context.Context, tx Tx, org *manta.Organiz
 		return err
 	}
 
-	b, err = tx.Bucket(organizationBucket)
+	b, err = tx.Bucket(OrganizationBucket)
 	if err != nil {
 		return err
 	}
@@ -229,51 +240,88 @@ func (s *Service) UpdateOrganization(
 	id manta.ID,
 	u manta.OrganizationUpdate,
 ) (*manta.Organization, error) {
-	var resp *manta.Organization
+	var org *manta.Organization
 
 	err := s.kv.Update(ctx, func(tx Tx) error {
-		current, err := s.findOrganizationByID(ctx, tx, id)
+		var err error
+		org, err = s.findOrganizationByID(ctx, tx, id)
+		if IsNotFound(err) {
+			return manta.ErrOrgNotFound
+		}
 		if err != nil {
 			return err
 		}
 
-		if err := s.deleteOrganization(ctx, tx, id); err != nil {
+		pk, err := org.ID.Encode()
+		if err != nil {
 			return err
 		}
 
-		if u.Name != nil {
-			current.Name = *u.Name
+		if u.Name != nil && *u.Name != org.Name {
+			if *u.Name == "" {
+				return &manta.Error{
+					Code: manta.EInvalid,
+					Msg:  "name of organization is required",
+				}
+			}
+
+			b, err := tx.Bucket(organizationNameIndexBucket)
+			if err != nil {
+				return err
+			}
+
+			// check name conflict
+			_, err = b.Get(orgNameIndexKey(*u.Name))
+			if err == nil {
+				return manta.ErrOrgAlreadyExist
+			}
+
+			if err != ErrKeyNotFound {
+				return err
+			}
+
+			if err = b.Delete(orgNameIndexKey(org.Name)); err != nil {
+				return err
+			}
+
+			if err = b.Put(orgNameIndexKey(*u.Name), pk); err != nil {
+				return err
+			}
+
+			org.Name = *u.Name
 		}
 
 		if u.Description != nil {
-			current.Desc = *u.Description
+			org.Desc = *u.Description
 		}
 
-		current.Updated = time.Now()
-		_, err = s.createOrganization(ctx, tx, current)
+		org.Updated = time.Now()
+
+		data, err := json.Marshal(org)
 		if err != nil {
 			return err
 		}
 
-		resp = current
+		b, err := tx.Bucket(OrganizationBucket)
+		if err != nil {
+			return err
+		}
 
-		return nil
+		return b.Put(pk, data)
 	})
 
 	if err != nil {
 		return nil, err
 	}
 
-	return resp, nil
+	return org, nil
 }
 
+// DeleteOrganization deletes the organization and all resources of it in
+// one transaction.
 func (s *Service) DeleteOrganization(ctx context.Context, id manta.ID) error {
 	return s.kv.Update(ctx, func(tx Tx) error {
-		if err := s.deleteOrganization(ctx, tx, id); err != nil {
-			return err
-		}
-
-		return nil
+		return s.deleteOrganization(ctx, tx, id)
 	})
 }
 
@@ -282,22 +330,197 @@ func (s *Service) deleteOrganization(ctx context.Context, tx Tx, id manta.ID) er
 	defer span.Finish()
 
 	org, err := s.findOrganizationByID(ctx, tx, id)
+	if IsNotFound(err) {
+		return manta.ErrOrgNotFound
+	}
 	if err != nil {
 		return err
 	}
 
+	if err = s.deleteOrgResources(ctx, tx, org.ID); err != nil {
+		return err
+	}
+
+	if err = deleteOrgOperationLogs(tx, org.ID); err != nil {
+		return err
+	}
+
+	// members
+	if err = deleteUserResourceMappings(tx, org.ID); err != nil {
+		return err
+	}
+
 	pk, err := org.ID.Encode()
 	if err != nil {
 		return err
 	}
 
 	// name index
-	fk := []byte(org.Name)
-	nameIdx := IndexKey(fk, pk)
 	b, err := tx.Bucket(organizationNameIndexBucket)
 	if err != nil {
-		return nil
+		return err
+	}
+
+	if err = b.Delete(orgNameIndexKey(org.Name)); err != nil {
+		return err
+	}
+
+	b, err = tx.Bucket(OrganizationBucket)
+	if err != nil {
+		return err
+	}
+
+	return b.Delete(pk)
+}
+
+// deleteOrgResources deletes the resources of the organization, and the
+// user resource mappings of them.
+func (s *Service) deleteOrgResources(ctx context.Context, tx Tx, orgID manta.ID) error {
+	var ids []manta.ID
+
+	dashboards, err := s.findDashboardByOrg(ctx, tx, orgID)
+	if err != nil {
+		return err
+	}
+
+	for _, d := range dashboards {
+		if err = s.removeDashboard(ctx, tx, d.ID); err != nil {
+			return err
+		}
+
+		ids = append(ids, d.ID)
+	}
+
+	// dashboards cannot be restored without the organization
+	if err = deleteOrgDashboardVersions(ctx, tx, orgID); err != nil {
+		return err
+	}
+
+	// tasks of checks are deleted with checks
+	checks, err := findOrgIndexed[manta.Check](ctx, tx, orgID, ChecksBucket, CheckOrgIndexBucket)
+	if err != nil {
+		return err
+	}
+
+	for _, c := range checks {
+		if err = s.deleteCheck(tx, c.ID); err != nil {
+			return err
+		}
+
+		ids = append(ids, c.ID)
+	}
+
+	tasks, err := findOrgIndexed[manta.Task](ctx, tx, orgID, TasksBucket, TaskOrgIndexBucket)
+	if err != nil {
+		return err
+	}
+
+	for _, task := range tasks {
+		runs, _, err := findRuns(tx, manta.RunFilter{Task: task.ID})
+		if err != nil {
+			return err
+		}
+
+		for _, run := range runs {
+			if err = deleteRun(tx, task.ID, run.ID); err != nil {
+				return err
+			}
+		}
+
+		if err = deleteTask(tx, task.ID); err != nil {
+			return err
+		}
+
+		ids = append(ids, task.ID)
+	}
+
+	configs, err := findOrgIndexed[manta.Config](ctx, tx, orgID, ConfigBucket, ConfigOrgIndexBucket)
+	if err != nil {
+		return err
+	}
+
+	for _, cf := range configs {
+		if err = s.deleteConfig(tx, cf.ID); err != nil {
+			return err
+		}
+
+		ids = append(ids, cf.ID)
+	}
+
+	targets, err := findOrgIndexed[manta.ScrapeTarget](ctx, tx, orgID, ScraperBucket, ScrapeOrgIndexBucket)
+	if err != nil {
+		return err
+	}
+
+	for _, target := range targets {
+		if err = s.deleteScrapeTarget(tx, target.ID); err != nil {
+			return err
+		}
+
+		ids = append(ids, target.ID)
+	}
+
+	endpoints, err := findOrgIndexedIDs(tx, orgID, NotificationENdpointOrgIndexBucket)
+	if err != nil {
+		return err
+	}
+
+	for _, id := range endpoints {
+		if _, err = deleteNotificationEndpoint(tx, id); err != nil {
+			return err
+		}
+
+		ids = append(ids, id)
+	}
+
+	if err = deleteOrgSecrets(tx, orgID); err != nil {
+		return err
+	}
+
+	if err = deleteOrgTemplates(tx, orgID); err != nil {
+		return err
+	}
+
+	if err = deleteOrgRoles(tx, orgID); err != nil {
+		return err
+	}
+
+	for _, id := range ids {
+		if err = deleteUserResourceMappings(tx, id); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// findOrgIndexedIDs returns the IDs of the org index, it's used by the
+// resources which can't be decoded by findOrgIndexed.
+func findOrgIndexedIDs(tx Tx, orgID manta.ID, indexBucket []byte) ([]manta.ID, error) {
+	prefix, err := orgID.Encode()
+	if err != nil {
+		return nil, err
+	}
+
+	b, err := tx.Bucket(indexBucket)
+	if err != nil {
+		return nil, err
+	}
+
+	cursor, err := b.Cursor()
+	if err != nil {
+		return nil, err
+	}
+
+	var ids []manta.ID
+	for k, v := cursor.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, v = cursor.Next() {
+		var id manta.ID
+		if err = id.Decode(v); err != nil {
+			return nil, err
+		}
+
+		ids = append(ids, id)
 	}
 
-	return b.Delete(nameIdx)
+	return ids, nil
 }
@@ -5,9 +5,11 @@ import (
 	"testing"
 	"time"
 
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
 	"github.com/f1shl3gs/manta"
+	"github.com/f1shl3gs/manta/authorizer"
 )
 
 func TestOrganization(t *testing.T) {
@@ -47,3 +49,144 @@ func TestOrganization(t *testing.T) {
 		require.Equal(t, manta.ErrOrgAlreadyExist, err)
 	})
 }
+
+func TestUpdateOrganization(t *testing.T) {
+	svc, closer := NewTestService(t)
+	defer closer()
+
+	ctx := context.Background()
+	foo := &manta.Organization{Name: "foo"}
+	err := svc.CreateOrganization(ctx, foo)
+	require.NoError(t, err)
+	err = svc.CreateOrganization(ctx, &manta.Organization{Name: "bar"})
+	require.NoError(t, err)
+
+	t.Run("rename", func(t *testing.T) {
+		name, desc := "baz", "renamed"
+		org, err := svc.UpdateOrganization(ctx, foo.ID, manta.OrganizationUpdate{
+			Name:        &name,
+			Description: &desc,
+		})
+		require.NoError(t, err)
+		assert.Equal(t, name, org.Name)
+		assert.Equal(t, desc, org.Desc)
+
+		found, err := svc.FindOrganization(ctx, manta.OrganizationFilter{Name: &name})
+		require.NoError(t, err)
+		assert.Equal(t, foo.ID, found.ID)
+
+		// the old name is released
+		err = svc.CreateOrganization(ctx, &manta.Organization{Name: "foo"})
+		require.NoError(t, err)
+	})
+
+	t.Run("name conflict", func(t *testing.T) {
+		name := "bar"
+		_, err := svc.UpdateOrganization(ctx, foo.ID, manta.OrganizationUpdate{Name: &name})
+		assert.Equal(t, manta.ErrOrgAlreadyExist, err)
+	})
+
+	t.Run("empty name", func(t *testing.T) {
+		name := ""
+		_, err := svc.UpdateOrganization(ctx, foo.ID, manta.OrganizationUpdate{Name: &name})
+		assert.Equal(t, manta.EInvalid, manta.ErrorCode(err))
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		name := "qux"
+		_, err := svc.UpdateOrganization(ctx, 1, manta.OrganizationUpdate{Name: &name})
+		assert.Equal(t, manta.ENotFound, manta.ErrorCode(err))
+	})
+}
+
+func TestDeleteOrganization(t *testing.T) {
+	svc, closer := NewTestService(t)
+	defer closer()
+
+	ctx := authorizer.SetAuthorizer(context.Background(), &manta.Session{UserID: 2})
+	orgID := CreateDefaultOrg(t, svc)
+
+	dashboard := &manta.Dashboard{OrgID: orgID, Name: "dashboard"}
+	err := svc.CreateDashboard(ctx, dashboard)
+	require.NoError(t, err)
+
+	cf := &manta.Config{OrgID: orgID, Name: "config", Data: "data"}
+	err = svc.CreateConfig(ctx, cf)
+	require.NoError(t, err)
+
+	target := &manta.ScrapeTarget{OrgID: orgID, Name: "target", Targets: []string{"localhost:9090"}}
+	err = svc.CreateScrapeTarget(ctx, target)
+	require.NoError(t, err)
+
+	_, err = svc.PutSecret(ctx, &manta.Secret{OrgID: orgID, Key: "token", Value: "secret"})
+	require.NoError(t, err)
+
+	err = svc.CreateUserResourceMapping(ctx, &manta.UserResourceMapping{
+		UserID:       2,
+		UserType:     manta.Owner,
+		MappingType:  manta.UserMappingType,
+		ResourceType: manta.OrgsResourceType,
+		ResourceID:   orgID,
+	})
+	require.NoError(t, err)
+
+	err = svc.DeleteOrganization(ctx, orgID)
+	require.NoError(t, err)
+
+	_, err = svc.FindOrganizationByID(ctx, orgID)
+	assert.Equal(t, manta.ENotFound, manta.ErrorCode(err))
+
+	_, err = svc.FindDashboardByID(ctx, dashboard.ID)
+	assert.Error(t, err)
+	_, err = svc.FindConfigByID(ctx, cf.ID)
+	assert.Error(t, err)
+	_, err = svc.FindScrapeTargetByID(ctx, target.ID)
+	assert.Error(t, err)
+
+	secrets, err := svc.GetSecrets(ctx, orgID)
+	require.NoError(t, err)
+	assert.Empty(t, secrets)
+
+	urms, _, err := svc.FindUserResourceMappings(ctx, manta.UserResourceMappingFilter{UserID: 2}, manta.FindOptions{})
+	require.NoError(t, err)
+	assert.Empty(t, urms)
+
+	err = svc.DeleteOrganization(ctx, orgID)
+	assert.Equal(t, manta.ENotFound, manta.ErrorCode(err))
+
+	// the name can be reused
+	err = svc.CreateOrganization(ctx, &manta.Organization{Name: "default"})
+	require.NoError(t, err)
+}
+
+func TestLastOwner(t *testing.T) {
+	svc, closer := NewTestService(t)
+	defer closer()
+
+	ctx := context.Background()
+	orgID := CreateTestOrg(t, svc, "foo")
+
+	owner := func(userID manta.ID, userType manta.UserType) error {
+		return svc.CreateUserResourceMapping(ctx, &manta.UserResourceMapping{
+			UserID:       userID,
+			UserType:     userType,
+			MappingType:  manta.UserMappingType,
+			ResourceType: manta.OrgsResourceType,
+			ResourceID:   orgID,
+		})
+	}
+
+	require.NoError(t, owner(10, manta.Owner))
+
+	// neither demoted nor removed
+	assert.Equal(t, manta.ErrLastOwner, owner(10, manta.Member))
+	assert.Equal(t, manta.ErrLastOwner, svc.DeleteUserResourceMapping(ctx, orgID, 10))
+
+	require.NoError(t, owner(11, manta.Owner))
+	require.NoError(t, owner(10, manta.Member))
+	assert.Equal(t, manta.ErrLastOwner, svc.DeleteUserResourceMapping(ctx, orgID, 11))
+	require.NoError(t, svc.DeleteUserResourceMapping(ctx, orgID, 10))
+
+	// the organization is deleted with all its mappings
+	require.NoError(t, svc.DeleteOrganization(ctx, orgID))
+}
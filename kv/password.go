@@ -2,12 +2,21 @@ package kv
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"time"
 
 	"github.com/f1shl3gs/manta"
 )
 
 var (
 	passwordBucket = []byte("passwords")
+
+	// PasswordResetsBucket stores the password reset tokens
+	// Key: sha256 of the token, the token itself is never stored
+	// Value: json encoded manta.PasswordReset without token
+	PasswordResetsBucket = []byte("passwordresets")
 )
 
 func (s *Service) SetPassword(ctx context.Context, uid manta.ID, password string) error {
@@ -17,6 +26,13 @@ func (s *Service) SetPassword(ctx context.Context, uid manta.ID, password string
 }
 
 func (s *Service) setPassword(ctx context.Context, tx Tx, uid manta.ID, password string) error {
+	if password == "" {
+		return &manta.Error{
+			Code: manta.EInvalid,
+			Msg:  "password cannot be empty",
+		}
+	}
+
 	pk, err := uid.Encode()
 	if err != nil {
 		return err
@@ -32,68 +48,212 @@ func (s *Service) setPassword(ctx context.Context, tx Tx, uid manta.ID, password
 
 func (s *Service) ComparePassword(ctx context.Context, uid manta.ID, password string) error {
 	return s.kv.View(ctx, func(tx Tx) error {
-		pk, err := uid.Encode()
-		if err != nil {
-			return err
-		}
+		return comparePassword(tx, uid, password)
+	})
+}
+
+func comparePassword(tx Tx, uid manta.ID, password string) error {
+	pk, err := uid.Encode()
+	if err != nil {
+		return err
+	}
+
+	b, err := tx.Bucket(passwordBucket)
+	if err != nil {
+		return err
+	}
+
+	v, err := b.Get(pk)
+	if err != nil {
+		return err
+	}
+
+	if string(v) == password {
+		return nil
+	}
+
+	return manta.ErrPasswordNotMatch
+}
 
+func (s *Service) HasPassword(ctx context.Context, uid manta.ID) (bool, error) {
+	pk, err := uid.Encode()
+	if err != nil {
+		return false, err
+	}
+
+	var has bool
+	err = s.kv.View(ctx, func(tx Tx) error {
 		b, err := tx.Bucket(passwordBucket)
 		if err != nil {
 			return err
 		}
 
-		v, err := b.Get(pk)
+		_, err = b.Get(pk)
+		if IsNotFound(err) {
+			return nil
+		}
 		if err != nil {
 			return err
 		}
 
-		sp := string(v)
+		has = true
+		return nil
+	})
+
+	return has, err
+}
 
-		if sp == password {
-			return nil
+func (s *Service) CompareAndSetPassword(ctx context.Context, uid manta.ID, old, new string) error {
+	return s.kv.Update(ctx, func(tx Tx) error {
+		if err := comparePassword(tx, uid, old); err != nil {
+			return err
 		}
 
-		return manta.ErrPasswordNotMatch
+		return s.setPassword(ctx, tx, uid, new)
 	})
 }
 
-func (s *Service) CompareAndSetPassword(ctx context.Context, uid manta.ID, old, new string) error {
+func (s *Service) DeletePassword(ctx context.Context, uid manta.ID) error {
 	return s.kv.Update(ctx, func(tx Tx) error {
-		pk, err := uid.Encode()
+		return deletePassword(tx, uid)
+	})
+}
+
+func deletePassword(tx Tx, uid manta.ID) error {
+	pk, err := uid.Encode()
+	if err != nil {
+		return err
+	}
+
+	b, err := tx.Bucket(passwordBucket)
+	if err != nil {
+		return err
+	}
+
+	return b.Delete(pk)
+}
+
+func passwordResetKey(token string) []byte {
+	sum := sha256.Sum256([]byte(token))
+	return []byte(hex.EncodeToString(sum[:]))
+}
+
+func (s *Service) CreatePasswordReset(ctx context.Context, uid manta.ID, ttl time.Duration) (*manta.PasswordReset, error) {
+	if ttl <= 0 {
+		return nil, &manta.Error{
+			Code: manta.EInvalid,
+			Msg:  "ttl of password reset must be positive",
+		}
+	}
+
+	var reset *manta.PasswordReset
+
+	err := s.kv.Update(ctx, func(tx Tx) error {
+		user, err := s.findUserByID(ctx, tx, uid)
+		if IsNotFound(err) {
+			return manta.ErrUserNotFound
+		}
 		if err != nil {
 			return err
 		}
 
-		b, err := tx.Bucket(passwordBucket)
+		token, err := s.tokenGen.Token()
 		if err != nil {
 			return err
 		}
 
-		v, err := b.Get(pk)
+		reset = &manta.PasswordReset{
+			UserID:    user.ID,
+			ExpiresAt: time.Now().Add(ttl),
+		}
+
+		data, err := json.Marshal(reset)
 		if err != nil {
 			return err
 		}
 
-		if string(v) == old {
-			return nil
+		b, err := tx.Bucket(PasswordResetsBucket)
+		if err != nil {
+			return err
 		}
 
-		return b.Put(pk, []byte(new))
+		reset.Token = token
+		return b.Put(passwordResetKey(token), data)
 	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return reset, nil
 }
 
-func (s *Service) DeletePassword(ctx context.Context, uid manta.ID) error {
+func (s *Service) ResetPassword(ctx context.Context, token, password string) error {
 	return s.kv.Update(ctx, func(tx Tx) error {
-		pk, err := uid.Encode()
+		b, err := tx.Bucket(PasswordResetsBucket)
 		if err != nil {
 			return err
 		}
 
-		b, err := tx.Bucket(passwordBucket)
+		key := passwordResetKey(token)
+		data, err := b.Get(key)
+		if IsNotFound(err) {
+			return manta.ErrPasswordResetNotFound
+		}
 		if err != nil {
 			return err
 		}
 
-		return b.Delete(pk)
+		reset := &manta.PasswordReset{}
+		if err = json.Unmarshal(data, reset); err != nil {
+			return err
+		}
+
+		if reset.ExpiresAt.Before(time.Now()) {
+			return manta.ErrPasswordResetNotFound
+		}
+
+		if err = s.setPassword(ctx, tx, reset.UserID, password); err != nil {
+			return err
+		}
+
+		if err = b.Delete(key); err != nil {
+			return err
+		}
+
+		return deleteUserSessions(tx, reset.UserID)
 	})
 }
+
+// deleteUserPasswordResets deletes all reset tokens of the user
+func deleteUserPasswordResets(tx Tx, uid manta.ID) error {
+	b, err := tx.Bucket(PasswordResetsBucket)
+	if err != nil {
+		return err
+	}
+
+	cursor, err := b.Cursor()
+	if err != nil {
+		return err
+	}
+
+	var keys [][]byte
+	for k, v := cursor.First(); k != nil; k, v = cursor.Next() {
+		reset := &manta.PasswordReset{}
+		if err = json.Unmarshal(v, reset); err != nil {
+			return err
+		}
+
+		if reset.UserID == uid {
+			keys = append(keys, append([]byte{}, k...))
+		}
+	}
+
+	for _, key := range keys {
+		if err = b.Delete(key); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -60,4 +60,71 @@ func TestPasswordService(t *testing.T) {
 		err = svc.DeletePassword(ctx, uid)
 		require.NoError(t, err)
 	})
+
+	t.Run("compare and set", func(t *testing.T) {
+		ctx, svc, closer := newTestPasswordService(t, 2*time.Second)
+		defer closer()
+
+		err := svc.SetPassword(ctx, uid, password)
+		require.NoError(t, err)
+
+		err = svc.CompareAndSetPassword(ctx, uid, "aaaa", "new password")
+		require.Equal(t, manta.ErrPasswordNotMatch, err)
+
+		err = svc.ComparePassword(ctx, uid, password)
+		require.NoError(t, err)
+
+		err = svc.CompareAndSetPassword(ctx, uid, password, "new password")
+		require.NoError(t, err)
+
+		err = svc.ComparePassword(ctx, uid, "new password")
+		require.NoError(t, err)
+	})
+}
+
+func TestPasswordReset(t *testing.T) {
+	svc, closer := NewTestService(t)
+	defer closer()
+
+	ctx := context.Background()
+	user := &manta.User{Name: "foo"}
+	err := svc.CreateUser(ctx, user)
+	require.NoError(t, err)
+
+	session, err := svc.CreateSession(ctx, user.ID)
+	require.NoError(t, err)
+
+	t.Run("expired", func(t *testing.T) {
+		reset, err := svc.CreatePasswordReset(ctx, user.ID, time.Nanosecond)
+		require.NoError(t, err)
+
+		time.Sleep(time.Millisecond)
+		err = svc.ResetPassword(ctx, reset.Token, "password")
+		require.Equal(t, manta.ErrPasswordResetNotFound, err)
+	})
+
+	t.Run("reset", func(t *testing.T) {
+		reset, err := svc.CreatePasswordReset(ctx, user.ID, time.Hour)
+		require.NoError(t, err)
+		require.NotEmpty(t, reset.Token)
+
+		err = svc.ResetPassword(ctx, reset.Token, "password")
+		require.NoError(t, err)
+
+		err = svc.ComparePassword(ctx, user.ID, "password")
+		require.NoError(t, err)
+
+		// sessions are revoked
+		_, err = svc.FindSession(ctx, session.ID)
+		require.Equal(t, manta.ErrSessionNotFound, err)
+
+		// the token can be used only once
+		err = svc.ResetPassword(ctx, reset.Token, "another")
+		require.Equal(t, manta.ErrPasswordResetNotFound, err)
+	})
+
+	t.Run("user not found", func(t *testing.T) {
+		_, err := svc.CreatePasswordReset(ctx, 1, time.Hour)
+		require.Equal(t, manta.ErrUserNotFound, err)
+	})
 }
@@ -0,0 +1,293 @@
+package kv
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/f1shl3gs/manta"
+)
+
+var (
+	// RolesBucket stores the custom roles of organizations
+	// Key: orgID + "/" + name
+	// Value: json encoded manta.Role
+	RolesBucket = []byte("roles")
+)
+
+var errBuiltInRole = &manta.Error{
+	Code: manta.EInvalid,
+	Msg:  "built-in roles cannot be changed",
+}
+
+func roleKey(orgID manta.ID, name string) ([]byte, error) {
+	fk, err := orgID.Encode()
+	if err != nil {
+		return nil, err
+	}
+
+	return IndexKey(fk, []byte(name)), nil
+}
+
+func builtInRoleAt(orgID manta.ID, name string) *manta.Role {
+	role := manta.BuiltInRole(name)
+	if role != nil {
+		role.OrgID = orgID
+	}
+
+	return role
+}
+
+func (s *Service) FindRole(ctx context.Context, orgID manta.ID, name string) (*manta.Role, error) {
+	if role := builtInRoleAt(orgID, name); role != nil {
+		return role, nil
+	}
+
+	var (
+		role *manta.Role
+		err  error
+	)
+
+	err = s.kv.View(ctx, func(tx Tx) error {
+		role, err = findRole(tx, orgID, name)
+		return err
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return role, nil
+}
+
+func findRole(tx Tx, orgID manta.ID, name string) (*manta.Role, error) {
+	key, err := roleKey(orgID, name)
+	if err != nil {
+		return nil, err
+	}
+
+	b, err := tx.Bucket(RolesBucket)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := b.Get(key)
+	if IsNotFound(err) {
+		return nil, manta.ErrRoleNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	role := &manta.Role{}
+	if err = json.Unmarshal(data, role); err != nil {
+		return nil, err
+	}
+
+	return role, nil
+}
+
+func (s *Service) FindRoles(ctx context.Context, orgID manta.ID) ([]*manta.Role, error) {
+	roles := manta.BuiltInRoles()
+	for _, r := range roles {
+		r.OrgID = orgID
+	}
+
+	err := s.kv.View(ctx, func(tx Tx) error {
+		prefix, err := roleKey(orgID, "")
+		if err != nil {
+			return err
+		}
+
+		b, err := tx.Bucket(RolesBucket)
+		if err != nil {
+			return err
+		}
+
+		cursor, err := b.Cursor()
+		if err != nil {
+			return err
+		}
+
+		for k, v := cursor.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, v = cursor.Next() {
+			role := &manta.Role{}
+			if err = json.Unmarshal(v, role); err != nil {
+				return err
+			}
+
+			roles = append(roles, role)
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return roles, nil
+}
+
+func (s *Service) CreateRole(ctx context.Context, role *manta.Role) error {
+	if err := role.Validate(); err != nil {
+		return err
+	}
+
+	return s.kv.Update(ctx, func(tx Tx) error {
+		_, err := s.findOrganizationByID(ctx, tx, role.OrgID)
+		if IsNotFound(err) {
+			return manta.ErrOrgNotFound
+		}
+		if err != nil {
+			return err
+		}
+
+		_, err = findRole(tx, role.OrgID, role.Name)
+		if err == nil {
+			return manta.ErrRoleAlreadyExist
+		}
+		if err != manta.ErrRoleNotFound {
+			return err
+		}
+
+		now := time.Now()
+		role.BuiltIn = false
+		role.Created = now
+		role.Updated = now
+
+		return putRole(tx, role)
+	})
+}
+
+func putRole(tx Tx, role *manta.Role) error {
+	key, err := roleKey(role.OrgID, role.Name)
+	if err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(role)
+	if err != nil {
+		return err
+	}
+
+	b, err := tx.Bucket(RolesBucket)
+	if err != nil {
+		return err
+	}
+
+	return b.Put(key, data)
+}
+
+func (s *Service) UpdateRole(ctx context.Context, orgID manta.ID, name string, upd manta.RoleUpdate) (*manta.Role, error) {
+	if manta.IsBuiltInRole(name) {
+		return nil, errBuiltInRole
+	}
+
+	var role *manta.Role
+
+	err := s.kv.Update(ctx, func(tx Tx) error {
+		var err error
+		role, err = findRole(tx, orgID, name)
+		if err != nil {
+			return err
+		}
+
+		upd.Apply(role)
+		if err = role.Validate(); err != nil {
+			return err
+		}
+
+		role.Updated = time.Now()
+
+		return putRole(tx, role)
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return role, nil
+}
+
+func (s *Service) DeleteRole(ctx context.Context, orgID manta.ID, name string) error {
+	if manta.IsBuiltInRole(name) {
+		return errBuiltInRole
+	}
+
+	return s.kv.Update(ctx, func(tx Tx) error {
+		if _, err := findRole(tx, orgID, name); err != nil {
+			return err
+		}
+
+		mappings, _, err := findUserResourceMappingByResource(tx, manta.UserResourceMappingFilter{
+			ResourceID: orgID,
+		}, manta.FindOptions{})
+		if err != nil {
+			return err
+		}
+
+		for _, m := range mappings {
+			if m.Role == name {
+				return manta.ErrRoleInUse
+			}
+		}
+
+		key, err := roleKey(orgID, name)
+		if err != nil {
+			return err
+		}
+
+		b, err := tx.Bucket(RolesBucket)
+		if err != nil {
+			return err
+		}
+
+		return b.Delete(key)
+	})
+}
+
+// deleteOrgRoles deletes all custom roles of the organization
+func deleteOrgRoles(tx Tx, orgID manta.ID) error {
+	prefix, err := roleKey(orgID, "")
+	if err != nil {
+		return err
+	}
+
+	b, err := tx.Bucket(RolesBucket)
+	if err != nil {
+		return err
+	}
+
+	cursor, err := b.Cursor()
+	if err != nil {
+		return err
+	}
+
+	var keys [][]byte
+	for k, _ := cursor.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, _ = cursor.Next() {
+		keys = append(keys, append([]byte{}, k...))
+	}
+
+	for _, key := range keys {
+		if err = b.Delete(key); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// findMappingRole returns the role assigned by the mapping, nil is returned
+// if no role assigned
+func findMappingRole(tx Tx, m *manta.UserResourceMapping) (*manta.Role, error) {
+	if m.Role == "" {
+		return nil, nil
+	}
+
+	if role := builtInRoleAt(m.ResourceID, m.Role); role != nil {
+		return role, nil
+	}
+
+	return findRole(tx, m.ResourceID, m.Role)
+}
@@ -0,0 +1,166 @@
+package kv_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/f1shl3gs/manta"
+)
+
+func TestRoles(t *testing.T) {
+	svc, closer := NewTestService(t)
+	defer closer()
+
+	ctx := context.Background()
+	orgID := CreateDefaultOrg(t, svc)
+
+	user := &manta.User{Name: "foo"}
+	err := svc.CreateUser(ctx, user)
+	require.NoError(t, err)
+
+	session, err := svc.CreateSession(ctx, user.ID)
+	require.NoError(t, err)
+
+	// allowed returns whether the user can do the action on the resources
+	// of the type in the org
+	allowed := func(t *testing.T, action manta.Action, rt manta.ResourceType) bool {
+		t.Helper()
+
+		found, err := svc.FindSession(ctx, session.ID)
+		require.NoError(t, err)
+
+		return manta.PermissionSet(found.Permissions).Allowed(manta.Permission{
+			Action: action,
+			Resource: manta.Resource{
+				Type:  rt,
+				OrgID: &orgID,
+			},
+		})
+	}
+
+	assign := func(role string) error {
+		return svc.CreateUserResourceMapping(ctx, &manta.UserResourceMapping{
+			UserID:       user.ID,
+			UserType:     manta.Member,
+			MappingType:  manta.UserMappingType,
+			ResourceType: manta.OrgsResourceType,
+			ResourceID:   orgID,
+			Role:         role,
+		})
+	}
+
+	t.Run("built-in roles", func(t *testing.T) {
+		roles, err := svc.FindRoles(ctx, orgID)
+		require.NoError(t, err)
+		require.Len(t, roles, 3)
+		for _, r := range roles {
+			assert.True(t, r.BuiltIn)
+			assert.Equal(t, orgID, r.OrgID)
+		}
+
+		err = assign(manta.ViewerRole)
+		require.NoError(t, err)
+		assert.True(t, allowed(t, manta.ReadAction, manta.DashboardsResourceType))
+		assert.False(t, allowed(t, manta.WriteAction, manta.DashboardsResourceType))
+
+		err = assign(manta.AlertManagerRole)
+		require.NoError(t, err)
+		assert.True(t, allowed(t, manta.WriteAction, manta.ChecksResourceType))
+		assert.False(t, allowed(t, manta.WriteAction, manta.DashboardsResourceType))
+
+		err = assign(manta.EditorRole)
+		require.NoError(t, err)
+		assert.True(t, allowed(t, manta.WriteAction, manta.DashboardsResourceType))
+		assert.False(t, allowed(t, manta.WriteAction, manta.UsersResourceType))
+
+		_, err = svc.UpdateRole(ctx, orgID, manta.EditorRole, manta.RoleUpdate{})
+		assert.Equal(t, manta.EInvalid, manta.ErrorCode(err))
+	})
+
+	t.Run("invalid roles", func(t *testing.T) {
+		err := svc.CreateRole(ctx, &manta.Role{OrgID: orgID, Name: manta.ViewerRole})
+		assert.Equal(t, manta.EInvalid, manta.ErrorCode(err))
+
+		err = svc.CreateRole(ctx, &manta.Role{OrgID: orgID, Name: "owner"})
+		assert.Equal(t, manta.EInvalid, manta.ErrorCode(err))
+
+		err = svc.CreateRole(ctx, &manta.Role{
+			OrgID: orgID,
+			Name:  "scoped",
+			Permissions: []manta.Permission{{
+				Action:   manta.ReadAction,
+				Resource: manta.Resource{Type: manta.ChecksResourceType, OrgID: &orgID},
+			}},
+		})
+		assert.Equal(t, manta.EInvalid, manta.ErrorCode(err))
+
+		err = assign("unknown")
+		assert.Equal(t, manta.ErrRoleNotFound, err)
+
+		err = svc.CreateUserResourceMapping(ctx, &manta.UserResourceMapping{
+			UserID:       user.ID,
+			UserType:     manta.Owner,
+			ResourceType: manta.OrgsResourceType,
+			ResourceID:   orgID,
+			Role:         manta.ViewerRole,
+		})
+		assert.Equal(t, manta.EInvalid, manta.ErrorCode(err))
+	})
+
+	t.Run("custom role", func(t *testing.T) {
+		role := &manta.Role{
+			OrgID: orgID,
+			Name:  "dashboard-editor",
+			Permissions: []manta.Permission{
+				{Action: manta.ReadAction, Resource: manta.Resource{Type: manta.DashboardsResourceType}},
+			},
+		}
+		err := svc.CreateRole(ctx, role)
+		require.NoError(t, err)
+
+		err = svc.CreateRole(ctx, role)
+		assert.Equal(t, manta.ErrRoleAlreadyExist, err)
+
+		err = assign(role.Name)
+		require.NoError(t, err)
+		assert.True(t, allowed(t, manta.ReadAction, manta.DashboardsResourceType))
+		assert.False(t, allowed(t, manta.WriteAction, manta.DashboardsResourceType))
+		assert.False(t, allowed(t, manta.ReadAction, manta.ChecksResourceType))
+
+		// changes take effect immediately
+		permissions := append(role.Permissions, manta.Permission{
+			Action:   manta.WriteAction,
+			Resource: manta.Resource{Type: manta.DashboardsResourceType},
+		})
+		_, err = svc.UpdateRole(ctx, orgID, role.Name, manta.RoleUpdate{Permissions: &permissions})
+		require.NoError(t, err)
+		assert.True(t, allowed(t, manta.WriteAction, manta.DashboardsResourceType))
+
+		err = svc.DeleteRole(ctx, orgID, role.Name)
+		assert.Equal(t, manta.ErrRoleInUse, err)
+
+		err = assign(manta.ViewerRole)
+		require.NoError(t, err)
+
+		err = svc.DeleteRole(ctx, orgID, role.Name)
+		require.NoError(t, err)
+
+		_, err = svc.FindRole(ctx, orgID, role.Name)
+		assert.Equal(t, manta.ErrRoleNotFound, err)
+	})
+
+	t.Run("delete organization", func(t *testing.T) {
+		err := svc.CreateRole(ctx, &manta.Role{OrgID: orgID, Name: "temporary"})
+		require.NoError(t, err)
+
+		err = svc.DeleteOrganization(ctx, orgID)
+		require.NoError(t, err)
+
+		roles, err := svc.FindRoles(ctx, orgID)
+		require.NoError(t, err)
+		assert.Len(t, roles, 3)
+	})
+}
@@ -1,17 +1,42 @@
 package kv
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"time"
 
 	"github.com/f1shl3gs/manta"
+	"github.com/f1shl3gs/manta/pkg/keyring"
 )
 
 var (
-	SecretsBucket = []byte("secrets")
+	SecretsBucket    = []byte("secrets")
+	SecretKeysBucket = []byte("secretkeys")
+
+	ErrMasterKeyRequired = &manta.Error{
+		Code: manta.EInternal,
+		Msg:  "secrets are encrypted, but no master key is configured",
+	}
 )
 
+// storedSecret is the persisted form of manta.Secret, the value is
+// encrypted with the data key of the organization if the master key
+// is configured.
+type storedSecret struct {
+	manta.Secret
+
+	Encrypted []byte `json:"encrypted,omitempty"`
+}
+
+// dataKey is used to encrypt secrets of an organization, it is stored
+// wrapped by the master key.
+type dataKey struct {
+	MasterKeyID string    `json:"masterKeyID"`
+	Key         []byte    `json:"key"`
+	Created     time.Time `json:"created"`
+}
+
 func (s *Service) LoadSecret(ctx context.Context, orgID manta.ID, k string) (*manta.Secret, error) {
 	var (
 		secret *manta.Secret
@@ -19,7 +44,7 @@ func (s *Service) LoadSecret(ctx context.Context, orgID manta.ID, k string) (*ma
 	)
 
 	err = s.kv.View(ctx, func(tx Tx) error {
-		secret, err = loadSecret(tx, orgID, k)
+		secret, err = loadSecret(tx, s.keyring, orgID, k)
 		return err
 	})
 
@@ -30,7 +55,7 @@ func (s *Service) LoadSecret(ctx context.Context, orgID manta.ID, k string) (*ma
 	return secret, nil
 }
 
-func loadSecret(tx Tx, orgID manta.ID, k string) (*manta.Secret, error) {
+func loadSecret(tx Tx, kr *keyring.Keyring, orgID manta.ID, k string) (*manta.Secret, error) {
 	key, err := secretKey(orgID, k)
 	if err != nil {
 		return nil, err
@@ -46,13 +71,146 @@ func loadSecret(tx Tx, orgID manta.ID, k string) (*manta.Secret, error) {
 		return nil, err
 	}
 
-	secret := &manta.Secret{}
-	err = json.Unmarshal(value, secret)
+	return decodeSecret(tx, kr, key, value)
+}
+
+// decodeSecret unmarshals the stored secret and decrypts its value,
+// secrets stored before encryption is enabled are returned as is.
+func decodeSecret(tx Tx, kr *keyring.Keyring, key, value []byte) (*manta.Secret, error) {
+	stored := &storedSecret{}
+	err := json.Unmarshal(value, stored)
 	if err != nil {
 		return nil, err
 	}
 
-	return secret, nil
+	if len(stored.Encrypted) == 0 {
+		return &stored.Secret, nil
+	}
+
+	if kr == nil {
+		return nil, ErrMasterKeyRequired
+	}
+
+	dk, err := orgDataKey(tx, kr, stored.OrgID, false)
+	if err != nil {
+		return nil, err
+	}
+
+	plaintext, err := keyring.Open(dk, stored.Encrypted, key)
+	if err != nil {
+		return nil, &manta.Error{
+			Code: manta.EInternal,
+			Msg:  "decrypt secret failed",
+			Err:  err,
+		}
+	}
+
+	stored.Value = string(plaintext)
+
+	return &stored.Secret, nil
+}
+
+// encodeSecret encrypts the value of secret if the master key is
+// configured and marshals it.
+func encodeSecret(tx Tx, kr *keyring.Keyring, key []byte, secret *manta.Secret) ([]byte, error) {
+	stored := storedSecret{Secret: *secret}
+	if kr == nil {
+		return json.Marshal(stored)
+	}
+
+	dk, err := orgDataKey(tx, kr, secret.OrgID, true)
+	if err != nil {
+		return nil, err
+	}
+
+	stored.Encrypted, err = keyring.Seal(dk, []byte(secret.Value), key)
+	if err != nil {
+		return nil, err
+	}
+	stored.Value = ""
+
+	return json.Marshal(stored)
+}
+
+// orgDataKey returns the unwrapped data key of the organization, a new
+// one is generated if create is true and the organization has none.
+func orgDataKey(tx Tx, kr *keyring.Keyring, orgID manta.ID, create bool) ([]byte, error) {
+	pk, err := orgID.Encode()
+	if err != nil {
+		return nil, err
+	}
+
+	b, err := tx.Bucket(SecretKeysBucket)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := b.Get(pk)
+	if err == ErrKeyNotFound && create {
+		return newOrgDataKey(tx, kr, orgID)
+	}
+	if err != nil {
+		return nil, &manta.Error{
+			Code: manta.EInternal,
+			Msg:  "load data key of secrets failed",
+			Err:  err,
+		}
+	}
+
+	dk := &dataKey{}
+	if err = json.Unmarshal(data, dk); err != nil {
+		return nil, err
+	}
+
+	key, err := kr.Unwrap(dk.MasterKeyID, dk.Key, pk)
+	if err != nil {
+		return nil, &manta.Error{
+			Code: manta.EInternal,
+			Msg:  "unwrap data key of secrets failed",
+			Err:  err,
+		}
+	}
+
+	return key, nil
+}
+
+// newOrgDataKey generates a data key for the organization, the previous
+// one will be overwritten.
+func newOrgDataKey(tx Tx, kr *keyring.Keyring, orgID manta.ID) ([]byte, error) {
+	pk, err := orgID.Encode()
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := keyring.GenerateKey()
+	if err != nil {
+		return nil, err
+	}
+
+	id, wrapped, err := kr.Wrap(key, pk)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := json.Marshal(&dataKey{
+		MasterKeyID: id,
+		Key:         wrapped,
+		Created:     time.Now(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	b, err := tx.Bucket(SecretKeysBucket)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = b.Put(pk, data); err != nil {
+		return nil, err
+	}
+
+	return key, nil
 }
 
 func (s *Service) GetSecrets(ctx context.Context, orgID manta.ID) ([]manta.Secret, error) {
@@ -75,14 +233,14 @@ func (s *Service) GetSecrets(ctx context.Context, orgID manta.ID) ([]manta.Secre
 		}
 
 		for k, value := cursor.Seek(fk); k != nil; k, value = cursor.Next() {
-			secret := manta.Secret{}
-			err = json.Unmarshal(value, &secret)
+			stored := storedSecret{}
+			err = json.Unmarshal(value, &stored)
 			if err != nil {
 				return err
 			}
 
-			secret.Desensitize()
-			secrets = append(secrets, secret)
+			stored.Desensitize()
+			secrets = append(secrets, stored.Secret)
 		}
 
 		return nil
@@ -100,7 +258,7 @@ func (s *Service) putSecret(tx Tx, secret *manta.Secret) error {
 		return err
 	}
 
-	value, err := json.Marshal(secret)
+	value, err := encodeSecret(tx, s.keyring, key, secret)
 	if err != nil {
 		return err
 	}
@@ -145,12 +303,82 @@ func (s *Service) DeleteSecret(ctx context.Context, orgID manta.ID, keys ...stri
 			if err = b.Delete(key); err != nil {
 				return err
 			}
+
+			// secrets of different orgs share the resource ID
+			err = deleteOperationLogs(tx, manta.UniqueKeyToID(k), func(ent *manta.OperationLogEntry) bool {
+				return ent.OrgID == orgID
+			})
+			if err != nil {
+				return err
+			}
 		}
 
 		return nil
 	})
 }
 
+// deleteOrgSecrets deletes all secrets of the organization and its data key
+func deleteOrgSecrets(tx Tx, orgID manta.ID) error {
+	pk, err := orgID.Encode()
+	if err != nil {
+		return err
+	}
+
+	b, err := tx.Bucket(SecretsBucket)
+	if err != nil {
+		return err
+	}
+
+	cursor, err := b.Cursor()
+	if err != nil {
+		return err
+	}
+
+	prefix := IndexKey(pk, nil)
+	keys := make([][]byte, 0)
+	for k, _ := cursor.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, _ = cursor.Next() {
+		keys = append(keys, append([]byte{}, k...))
+	}
+
+	for _, key := range keys {
+		if err = b.Delete(key); err != nil {
+			return err
+		}
+	}
+
+	b, err = tx.Bucket(SecretKeysBucket)
+	if err != nil {
+		return err
+	}
+
+	return b.Delete(pk)
+}
+
+// dataKeyOrgs returns the organizations which have data keys
+func dataKeyOrgs(tx Tx) (map[manta.ID]struct{}, error) {
+	b, err := tx.Bucket(SecretKeysBucket)
+	if err != nil {
+		return nil, err
+	}
+
+	cursor, err := b.Cursor()
+	if err != nil {
+		return nil, err
+	}
+
+	orgs := make(map[manta.ID]struct{})
+	for k, _ := cursor.First(); k != nil; k, _ = cursor.Next() {
+		var orgID manta.ID
+		if err = orgID.Decode(k); err != nil {
+			return nil, err
+		}
+
+		orgs[orgID] = struct{}{}
+	}
+
+	return orgs, nil
+}
+
 func secretKey(orgID manta.ID, k string) ([]byte, error) {
 	o, err := orgID.Encode()
 	if err != nil {
@@ -159,3 +387,115 @@ func secretKey(orgID manta.ID, k string) ([]byte, error) {
 
 	return IndexKey(o, []byte(k)), nil
 }
+
+// RotateSecretKeys generates new data keys wrapped by the primary master
+// key, and re-encrypts all secrets with them. Secrets stored in plaintext
+// are encrypted too. It returns the number of secrets re-encrypted.
+func (s *Service) RotateSecretKeys(ctx context.Context) (int, error) {
+	if s.keyring == nil {
+		return 0, ErrMasterKeyRequired
+	}
+
+	var n int
+	err := s.kv.Update(ctx, func(tx Tx) error {
+		var err error
+		n, err = reencryptSecrets(tx, s.keyring, true)
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return n, nil
+}
+
+// EncryptSecrets encrypts secrets stored in plaintext, it returns the
+// number of secrets encrypted.
+func EncryptSecrets(ctx context.Context, store Store, kr *keyring.Keyring) (int, error) {
+	var n int
+	err := store.Update(ctx, func(tx Tx) error {
+		var err error
+		n, err = reencryptSecrets(tx, kr, false)
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return n, nil
+}
+
+// reencryptSecrets encrypts secrets again with the data key of their
+// organization. If rotate is true, new data keys are generated and all
+// secrets are re-encrypted, otherwise only plaintext secrets are encrypted.
+func reencryptSecrets(tx Tx, kr *keyring.Keyring, rotate bool) (int, error) {
+	b, err := tx.Bucket(SecretsBucket)
+	if err != nil {
+		return 0, err
+	}
+
+	cursor, err := b.Cursor()
+	if err != nil {
+		return 0, err
+	}
+
+	type entry struct {
+		key    []byte
+		secret *manta.Secret
+	}
+
+	// collect secrets first, updating the bucket while iterating is unsafe
+	var entries []entry
+	for k, v := cursor.First(); k != nil; k, v = cursor.Next() {
+		stored := storedSecret{}
+		if err = json.Unmarshal(v, &stored); err != nil {
+			return 0, err
+		}
+
+		if !rotate && len(stored.Encrypted) != 0 {
+			continue
+		}
+
+		key := make([]byte, len(k))
+		copy(key, k)
+
+		secret, err := decodeSecret(tx, kr, key, v)
+		if err != nil {
+			return 0, err
+		}
+
+		entries = append(entries, entry{key: key, secret: secret})
+	}
+
+	if rotate {
+		// every data key is rewrapped, including the ones of organizations
+		// without secrets now, so none of them depends on the old master key
+		orgs, err := dataKeyOrgs(tx)
+		if err != nil {
+			return 0, err
+		}
+
+		for _, ent := range entries {
+			orgs[ent.secret.OrgID] = struct{}{}
+		}
+
+		for orgID := range orgs {
+			if _, err = newOrgDataKey(tx, kr, orgID); err != nil {
+				return 0, err
+			}
+		}
+	}
+
+	for _, ent := range entries {
+		value, err := encodeSecret(tx, kr, ent.key, ent.secret)
+		if err != nil {
+			return 0, err
+		}
+
+		if err = b.Put(ent.key, value); err != nil {
+			return 0, err
+		}
+	}
+
+	return len(entries), nil
+}
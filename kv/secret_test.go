@@ -0,0 +1,138 @@
+package kv_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/f1shl3gs/manta"
+	"github.com/f1shl3gs/manta/kv"
+	"github.com/f1shl3gs/manta/kv/migration"
+	"github.com/f1shl3gs/manta/pkg/keyring"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+)
+
+func newTestKeyring(t *testing.T, previous ...[]byte) (*keyring.Keyring, []byte) {
+	key, err := keyring.GenerateKey()
+	require.NoError(t, err)
+
+	kr, err := keyring.New(key, previous...)
+	require.NoError(t, err)
+
+	return kr, key
+}
+
+// rawSecrets returns the stored value of all secrets
+func rawSecrets(t *testing.T, store kv.Store) []string {
+	var values []string
+
+	err := store.View(context.Background(), func(tx kv.Tx) error {
+		b, err := tx.Bucket(kv.SecretsBucket)
+		if err != nil {
+			return err
+		}
+
+		c, err := b.Cursor()
+		if err != nil {
+			return err
+		}
+
+		for k, v := c.First(); k != nil; k, v = c.Next() {
+			values = append(values, string(v))
+		}
+
+		return nil
+	})
+	require.NoError(t, err)
+
+	return values
+}
+
+func TestSecretEncryption(t *testing.T) {
+	ctx := context.Background()
+	store, closer := NewTestBolt(t, true)
+	defer closer()
+
+	// secrets are stored in plaintext before encryption is enabled
+	err := migration.New(zaptest.NewLogger(t), store, migration.All...).Up(ctx)
+	require.NoError(t, err)
+
+	plain := kv.NewService(zaptest.NewLogger(t), store)
+	orgID := CreateDefaultOrg(t, plain)
+
+	_, err = plain.PutSecret(ctx, &manta.Secret{OrgID: orgID, Key: "legacy", Value: "legacy-password"})
+	require.NoError(t, err)
+	assert.Contains(t, rawSecrets(t, store)[0], "legacy-password")
+
+	kr, key := newTestKeyring(t)
+	n, err := kv.EncryptSecrets(ctx, store, kr)
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+
+	svc := kv.NewService(zaptest.NewLogger(t), store, kv.WithKeyring(kr))
+	created, err := svc.PutSecret(ctx, &manta.Secret{OrgID: orgID, Key: "token", Value: "super-secret-token"})
+	require.NoError(t, err)
+	assert.Empty(t, created.Value)
+
+	for _, raw := range rawSecrets(t, store) {
+		assert.NotContains(t, raw, "legacy-password")
+		assert.NotContains(t, raw, "super-secret-token")
+	}
+
+	secret, err := svc.LoadSecret(ctx, orgID, "legacy")
+	require.NoError(t, err)
+	assert.Equal(t, "legacy-password", secret.Value)
+
+	secret, err = svc.LoadSecret(ctx, orgID, "token")
+	require.NoError(t, err)
+	assert.Equal(t, "super-secret-token", secret.Value)
+
+	secrets, err := svc.GetSecrets(ctx, orgID)
+	require.NoError(t, err)
+	assert.Equal(t, 2, len(secrets))
+	for _, s := range secrets {
+		assert.Empty(t, s.Value)
+	}
+
+	// encrypted secrets cannot be read without master key
+	_, err = plain.LoadSecret(ctx, orgID, "token")
+	assert.Equal(t, kv.ErrMasterKeyRequired, err)
+
+	// the organization has a data key, but no secret now
+	emptyOrgID := CreateTestOrg(t, plain, "empty")
+	_, err = svc.PutSecret(ctx, &manta.Secret{OrgID: emptyOrgID, Key: "temp", Value: "temp"})
+	require.NoError(t, err)
+	require.NoError(t, svc.DeleteSecret(ctx, emptyOrgID, "temp"))
+
+	t.Run("rotate", func(t *testing.T) {
+		rotated, newKey := newTestKeyring(t, key)
+		svc := kv.NewService(zaptest.NewLogger(t), store, kv.WithKeyring(rotated))
+
+		n, err := svc.RotateSecretKeys(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, 2, n)
+
+		// previous master key is not needed any more
+		kr, err := keyring.New(newKey)
+		require.NoError(t, err)
+		svc = kv.NewService(zaptest.NewLogger(t), store, kv.WithKeyring(kr))
+
+		secret, err := svc.LoadSecret(ctx, orgID, "token")
+		require.NoError(t, err)
+		assert.Equal(t, "super-secret-token", secret.Value)
+
+		// data keys of organizations without secrets are rewrapped too
+		_, err = svc.PutSecret(ctx, &manta.Secret{OrgID: emptyOrgID, Key: "new", Value: "new"})
+		require.NoError(t, err)
+
+		// and the previous master key cannot decrypt them
+		kr, err = keyring.New(key)
+		require.NoError(t, err)
+		svc = kv.NewService(zaptest.NewLogger(t), store, kv.WithKeyring(kr))
+
+		_, err = svc.LoadSecret(ctx, orgID, "token")
+		assert.Equal(t, manta.EInternal, manta.ErrorCode(err))
+	})
+}
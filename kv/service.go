@@ -1,9 +1,12 @@
 package kv
 
 import (
+	"time"
+
 	"go.uber.org/zap"
 
 	"github.com/f1shl3gs/manta"
+	"github.com/f1shl3gs/manta/pkg/keyring"
 	"github.com/f1shl3gs/manta/pkg/snowflake"
 	"github.com/f1shl3gs/manta/token"
 )
@@ -14,6 +17,19 @@ type Service struct {
 	logger   *zap.Logger
 	idGen    manta.IDGenerator
 	tokenGen token.Generator
+
+	// keyring is used to encrypt secrets, secrets are stored in
+	// plaintext if it is nil.
+	keyring *keyring.Keyring
+
+	// sessions expire if not seen for the idle timeout, or the absolute
+	// timeout passed since created
+	sessionIdleTimeout     time.Duration
+	sessionAbsoluteTimeout time.Duration
+
+	// auditRetention is how long audit records are kept, 0 means forever
+	auditRetention time.Duration
+	lockoutPolicy  manta.LockoutPolicy
 }
 
 type Option func(service *Service)
@@ -24,12 +40,66 @@ func WithIDGenerator(idGen manta.IDGenerator) Option {
 	}
 }
 
+// WithKeyring sets the keyring to encrypt secrets
+func WithKeyring(kr *keyring.Keyring) Option {
+	return func(svc *Service) {
+		svc.keyring = kr
+	}
+}
+
+// WithSessionTimeout sets the idle and absolute timeout of sessions,
+// non-positive values keep the defaults
+func WithSessionTimeout(idle, absolute time.Duration) Option {
+	return func(svc *Service) {
+		if idle > 0 {
+			svc.sessionIdleTimeout = idle
+		}
+
+		if absolute > 0 {
+			svc.sessionAbsoluteTimeout = absolute
+		}
+	}
+}
+
+// WithAuditRetention sets how long audit records are kept, 0 means forever
+func WithAuditRetention(retention time.Duration) Option {
+	return func(svc *Service) {
+		svc.auditRetention = retention
+	}
+}
+
+// WithLockoutPolicy sets the policy of locking out failed sign-ins, zero
+// fields keep the defaults
+func WithLockoutPolicy(policy manta.LockoutPolicy) Option {
+	return func(svc *Service) {
+		if policy.MaxUsernameFailures > 0 {
+			svc.lockoutPolicy.MaxUsernameFailures = policy.MaxUsernameFailures
+		}
+
+		if policy.MaxIPFailures > 0 {
+			svc.lockoutPolicy.MaxIPFailures = policy.MaxIPFailures
+		}
+
+		if policy.Duration > 0 {
+			svc.lockoutPolicy.Duration = policy.Duration
+		}
+
+		if policy.MaxDuration > 0 {
+			svc.lockoutPolicy.MaxDuration = policy.MaxDuration
+		}
+	}
+}
+
 func NewService(logger *zap.Logger, kv Store, opts ...Option) *Service {
 	svc := &Service{
 		kv:       kv,
 		logger:   logger.With(zap.String("service", "kv")),
 		idGen:    snowflake.NewIDGenerator(),
 		tokenGen: token.NewGenerator(0),
+
+		sessionIdleTimeout:     defaultSessionIdleTimeout,
+		sessionAbsoluteTimeout: defaultSessionAbsoluteTimeout,
+		lockoutPolicy:          defaultLockoutPolicy,
 	}
 
 	for _, fn := range opts {
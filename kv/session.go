@@ -3,6 +3,7 @@ package kv
 import (
 	"context"
 	"encoding/json"
+	"sort"
 	"time"
 
 	"github.com/f1shl3gs/manta"
@@ -10,8 +11,12 @@ import (
 )
 
 const (
-	// todo: make tll configurable
-	defaultSessionTTL = 7 * 24 * time.Hour
+	defaultSessionIdleTimeout     = 12 * time.Hour
+	defaultSessionAbsoluteTimeout = 7 * 24 * time.Hour
+
+	// sessionExtendInterval limits how often a session is extended, so
+	// not every request writes
+	sessionExtendInterval = time.Minute
 )
 
 var (
@@ -20,7 +25,7 @@ var (
 	sessionBucket = []byte("sessions")
 )
 
-func (s *Service) CreateSession(ctx context.Context, uid manta.ID) (*manta.Session, error) {
+func (s *Service) CreateSession(ctx context.Context, uid manta.ID, opts ...manta.SessionOptions) (*manta.Session, error) {
 	span, ctx := tracing.StartSpanFromContext(ctx)
 	defer span.Finish()
 
@@ -30,7 +35,7 @@ func (s *Service) CreateSession(ctx context.Context, uid manta.ID) (*manta.Sessi
 	)
 
 	err = s.kv.Update(ctx, func(tx Tx) error {
-		session, err = s.createSession(ctx, tx, uid)
+		session, err = s.createSession(ctx, tx, uid, opts...)
 		return err
 	})
 
@@ -41,7 +46,7 @@ func (s *Service) CreateSession(ctx context.Context, uid manta.ID) (*manta.Sessi
 	return session, nil
 }
 
-func (s *Service) createSession(ctx context.Context, tx Tx, userID manta.ID) (*manta.Session, error) {
+func (s *Service) createSession(ctx context.Context, tx Tx, userID manta.ID, opts ...manta.SessionOptions) (*manta.Session, error) {
 	_, err := s.findUserByID(ctx, tx, userID)
 	if err != nil {
 		return nil, err
@@ -49,10 +54,16 @@ func (s *Service) createSession(ctx context.Context, tx Tx, userID manta.ID) (*m
 
 	now := time.Now()
 	session := &manta.Session{
-		ID:        s.idGen.ID(),
-		Created:   now,
-		ExpiresAt: now.Add(defaultSessionTTL),
-		UserID:    userID,
+		ID:       s.idGen.ID(),
+		Created:  now,
+		LastSeen: now,
+		UserID:   userID,
+	}
+	session.ExpiresAt = s.sessionExpiration(session, now)
+
+	if len(opts) != 0 {
+		session.UserAgent = opts[0].UserAgent
+		session.IP = opts[0].IP
 	}
 
 	if err := s.putSession(ctx, tx, session); err != nil {
@@ -62,6 +73,17 @@ func (s *Service) createSession(ctx context.Context, tx Tx, userID manta.ID) (*m
 	return session, nil
 }
 
+// sessionExpiration returns the expiration of the session seen at, which
+// is capped by the absolute timeout
+func (s *Service) sessionExpiration(session *manta.Session, seen time.Time) time.Time {
+	expiration := seen.Add(s.sessionIdleTimeout)
+	if limit := session.Created.Add(s.sessionAbsoluteTimeout); expiration.After(limit) {
+		return limit
+	}
+
+	return expiration
+}
+
 func (s *Service) putSession(ctx context.Context, tx Tx, session *manta.Session) error {
 	data, err := json.Marshal(session)
 	if err != nil {
@@ -90,7 +112,9 @@ func (s *Service) FindSession(ctx context.Context, id manta.ID) (*manta.Session,
 		err     error
 	)
 
-	err = s.kv.View(ctx, func(tx Tx) error {
+	// a revoked session must never be accepted again, so stale reads
+	// are not acceptable
+	err = s.kv.View(WithLinearizableRead(ctx), func(tx Tx) error {
 		session, err = s.findSession(ctx, tx, id)
 		if err != nil {
 			return err
@@ -109,7 +133,7 @@ func (s *Service) FindSession(ctx context.Context, id manta.ID) (*manta.Session,
 			return err
 		}
 
-		permissions, err := permissionFromMapping(urms)
+		permissions, err := permissionFromMapping(tx, urms)
 		if err != nil {
 			return err
 		}
@@ -127,10 +151,27 @@ func (s *Service) FindSession(ctx context.Context, id manta.ID) (*manta.Session,
 	return session, nil
 }
 
-func permissionFromMapping(mappings []*manta.UserResourceMapping) ([]manta.Permission, error) {
+// permissionFromMapping returns the permissions granted by the mappings, the
+// roles are resolved in the same transaction, so the changes of roles take
+// effect on the next request.
+func permissionFromMapping(tx Tx, mappings []*manta.UserResourceMapping) ([]manta.Permission, error) {
 	ps := make([]manta.Permission, 0, len(mappings))
 
 	for _, m := range mappings {
+		role, err := findMappingRole(tx, m)
+		if err == manta.ErrRoleNotFound {
+			// the role is gone, grant nothing rather than locking the user out
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if role != nil {
+			ps = append(ps, role.PermissionsAt(m.ResourceID)...)
+			continue
+		}
+
 		p, err := m.ToPermissions()
 		if err != nil {
 			return nil, err
@@ -143,27 +184,7 @@ func permissionFromMapping(mappings []*manta.UserResourceMapping) ([]manta.Permi
 }
 
 func (s *Service) findSession(ctx context.Context, tx Tx, id manta.ID) (*manta.Session, error) {
-	pk, err := id.Encode()
-	if err != nil {
-		return nil, err
-	}
-
-	b, err := tx.Bucket(sessionBucket)
-	if err != nil {
-		return nil, err
-	}
-
-	val, err := b.Get(pk)
-	if err != nil {
-		if err == ErrKeyNotFound {
-			return nil, manta.ErrSessionNotFound
-		}
-
-		return nil, err
-	}
-
-	session := &manta.Session{}
-	err = json.Unmarshal(val, session)
+	session, err := getSession(tx, id)
 	if err != nil {
 		return nil, err
 	}
@@ -213,9 +234,198 @@ func (s *Service) RenewSession(ctx context.Context, id manta.ID, expiration time
 			return err
 		}
 
+		if limit := session.Created.Add(s.sessionAbsoluteTimeout); expiration.After(limit) {
+			expiration = limit
+		}
+
 		session.ExpiresAt = expiration
 		session.LastSeen = time.Now()
 
 		return s.putSession(ctx, tx, session)
 	})
 }
+
+func (s *Service) ExtendSession(ctx context.Context, id manta.ID) error {
+	span, ctx := tracing.StartSpanFromContext(ctx)
+	defer span.Finish()
+
+	now := time.Now()
+	var session *manta.Session
+	err := s.kv.View(ctx, func(tx Tx) error {
+		var err error
+		session, err = getSession(tx, id)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	if session.Expired() {
+		return manta.ErrSessionExpired
+	}
+
+	if now.Sub(session.LastSeen) < sessionExtendInterval {
+		return nil
+	}
+
+	return s.kv.Update(ctx, func(tx Tx) error {
+		session, err := getSession(tx, id)
+		if err != nil {
+			return err
+		}
+
+		if session.Expired() {
+			return manta.ErrSessionExpired
+		}
+
+		session.LastSeen = now
+		session.ExpiresAt = s.sessionExpiration(session, now)
+
+		return s.putSession(ctx, tx, session)
+	})
+}
+
+// getSession returns the stored session, without permissions
+func getSession(tx Tx, id manta.ID) (*manta.Session, error) {
+	pk, err := id.Encode()
+	if err != nil {
+		return nil, err
+	}
+
+	b, err := tx.Bucket(sessionBucket)
+	if err != nil {
+		return nil, err
+	}
+
+	val, err := b.Get(pk)
+	if err == ErrKeyNotFound {
+		return nil, manta.ErrSessionNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	session := &manta.Session{}
+	if err = json.Unmarshal(val, session); err != nil {
+		return nil, err
+	}
+
+	return session, nil
+}
+
+func (s *Service) FindSessions(ctx context.Context, uid manta.ID) ([]*manta.Session, error) {
+	span, ctx := tracing.StartSpanFromContext(ctx)
+	defer span.Finish()
+
+	var list []*manta.Session
+	err := s.kv.View(ctx, func(tx Tx) error {
+		return walkSessions(tx, func(k []byte, session *manta.Session) error {
+			if session.UserID == uid && !session.Expired() {
+				list = append(list, session)
+			}
+
+			return nil
+		})
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].LastSeen.After(list[j].LastSeen)
+	})
+
+	return list, nil
+}
+
+func (s *Service) RevokeSessions(ctx context.Context, uid manta.ID) error {
+	span, ctx := tracing.StartSpanFromContext(ctx)
+	defer span.Finish()
+
+	return s.kv.Update(ctx, func(tx Tx) error {
+		return deleteUserSessions(tx, uid)
+	})
+}
+
+// DeleteExpiredSessions deletes the expired sessions, and returns how many
+// sessions are deleted
+func (s *Service) DeleteExpiredSessions(ctx context.Context) (int, error) {
+	span, ctx := tracing.StartSpanFromContext(ctx)
+	defer span.Finish()
+
+	var deleted int
+	err := s.kv.Update(ctx, func(tx Tx) error {
+		var err error
+		deleted, err = deleteSessions(tx, func(session *manta.Session) bool {
+			return session.Expired()
+		})
+
+		return err
+	})
+
+	return deleted, err
+}
+
+func walkSessions(tx Tx, fn func(k []byte, session *manta.Session) error) error {
+	b, err := tx.Bucket(sessionBucket)
+	if err != nil {
+		return err
+	}
+
+	cursor, err := b.Cursor()
+	if err != nil {
+		return err
+	}
+
+	for k, v := cursor.First(); k != nil; k, v = cursor.Next() {
+		session := &manta.Session{}
+		if err = json.Unmarshal(v, session); err != nil {
+			return err
+		}
+
+		if err = fn(k, session); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// deleteUserSessions deletes all sessions of the user
+func deleteUserSessions(tx Tx, userID manta.ID) error {
+	_, err := deleteSessions(tx, func(session *manta.Session) bool {
+		return session.UserID == userID
+	})
+
+	return err
+}
+
+// deleteSessions deletes the sessions matched, and returns how many
+// sessions are deleted
+func deleteSessions(tx Tx, match func(session *manta.Session) bool) (int, error) {
+	var keys [][]byte
+	err := walkSessions(tx, func(k []byte, session *manta.Session) error {
+		if match(session) {
+			keys = append(keys, append([]byte{}, k...))
+		}
+
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	b, err := tx.Bucket(sessionBucket)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, key := range keys {
+		if err = b.Delete(key); err != nil {
+			return 0, err
+		}
+	}
+
+	return len(keys), nil
+}
@@ -47,6 +47,18 @@ func TestSessionService(t *testing.T) {
 			name: "RevokeSession",
 			fn:   RevokeSession,
 		},
+		{
+			name: "FindSessions",
+			fn:   FindSessions,
+		},
+		{
+			name: "RevokeSessions",
+			fn:   RevokeSessions,
+		},
+		{
+			name: "ExtendSession",
+			fn:   ExtendSession,
+		},
 	}
 
 	var init initSessionService = func(t *testing.T) (context.Context, manta.SessionService, func()) {
@@ -214,3 +226,159 @@ func RevokeSession(t *testing.T, init initSessionService) {
 		})
 	}
 }
+
+func FindSessions(t *testing.T, init initSessionService) {
+	tests := []struct {
+		name string
+		fn   func(t *testing.T, ctx context.Context, svc manta.SessionService)
+	}{
+		{
+			name: "find with user agent and ip",
+			fn: func(t *testing.T, ctx context.Context, svc manta.SessionService) {
+				session, err := svc.CreateSession(ctx, mockUID, manta.SessionOptions{
+					UserAgent: "curl/7.88.1",
+					IP:        "10.0.0.1",
+				})
+				require.NoError(t, err)
+
+				sessions, err := svc.FindSessions(ctx, mockUID)
+				require.NoError(t, err)
+				require.Len(t, sessions, 1)
+				require.Equal(t, session.ID, sessions[0].ID)
+				require.Equal(t, "curl/7.88.1", sessions[0].UserAgent)
+				require.Equal(t, "10.0.0.1", sessions[0].IP)
+			},
+		},
+		{
+			name: "expired sessions are excluded",
+			fn: func(t *testing.T, ctx context.Context, svc manta.SessionService) {
+				expired, err := svc.CreateSession(ctx, mockUID)
+				require.NoError(t, err)
+				err = svc.RenewSession(ctx, expired.ID, time.Now().Add(-time.Minute))
+				require.NoError(t, err)
+
+				active, err := svc.CreateSession(ctx, mockUID)
+				require.NoError(t, err)
+
+				sessions, err := svc.FindSessions(ctx, mockUID)
+				require.NoError(t, err)
+				require.Len(t, sessions, 1)
+				require.Equal(t, active.ID, sessions[0].ID)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, svc, closer := init(t)
+			defer closer()
+
+			tt.fn(t, ctx, svc)
+		})
+	}
+}
+
+func RevokeSessions(t *testing.T, init initSessionService) {
+	tests := []struct {
+		name string
+		fn   func(t *testing.T, ctx context.Context, svc manta.SessionService)
+	}{
+		{
+			name: "revoke all",
+			fn: func(t *testing.T, ctx context.Context, svc manta.SessionService) {
+				for i := 0; i < 3; i++ {
+					_, err := svc.CreateSession(ctx, mockUID)
+					require.NoError(t, err)
+				}
+
+				err := svc.RevokeSessions(ctx, mockUID)
+				require.NoError(t, err)
+
+				sessions, err := svc.FindSessions(ctx, mockUID)
+				require.NoError(t, err)
+				require.Empty(t, sessions)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, svc, closer := init(t)
+			defer closer()
+
+			tt.fn(t, ctx, svc)
+		})
+	}
+}
+
+func ExtendSession(t *testing.T, init initSessionService) {
+	tests := []struct {
+		name string
+		fn   func(t *testing.T, ctx context.Context, svc manta.SessionService)
+	}{
+		{
+			name: "extend expired",
+			fn: func(t *testing.T, ctx context.Context, svc manta.SessionService) {
+				session, err := svc.CreateSession(ctx, mockUID)
+				require.NoError(t, err)
+				err = svc.RenewSession(ctx, session.ID, time.Now().Add(-time.Minute))
+				require.NoError(t, err)
+
+				err = svc.ExtendSession(ctx, session.ID)
+				require.Equal(t, manta.ErrSessionExpired, err)
+			},
+		},
+		{
+			name: "extend none exist",
+			fn: func(t *testing.T, ctx context.Context, svc manta.SessionService) {
+				err := svc.ExtendSession(ctx, manta.ID(2))
+				require.Equal(t, manta.ErrSessionNotFound, err)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, svc, closer := init(t)
+			defer closer()
+
+			tt.fn(t, ctx, svc)
+		})
+	}
+}
+
+func TestSessionTimeout(t *testing.T) {
+	ctx := context.Background()
+	svc, closer := NewTestService(t, kv.WithSessionTimeout(time.Hour, 2*time.Hour))
+	defer closer()
+
+	user := &manta.User{Name: "mock"}
+	err := svc.CreateUser(ctx, user)
+	require.NoError(t, err)
+
+	session, err := svc.CreateSession(ctx, user.ID)
+	require.NoError(t, err)
+	require.WithinDuration(t, time.Now().Add(time.Hour), session.ExpiresAt, time.Minute)
+
+	// renew cannot outlive the absolute timeout
+	err = svc.RenewSession(ctx, session.ID, time.Now().Add(24*time.Hour))
+	require.NoError(t, err)
+	found, err := svc.FindSession(ctx, session.ID)
+	require.NoError(t, err)
+	require.Equal(t, session.Created.Add(2*time.Hour).UnixNano(), found.ExpiresAt.UnixNano())
+
+	// sweep
+	err = svc.RenewSession(ctx, session.ID, time.Now().Add(-time.Minute))
+	require.NoError(t, err)
+	active, err := svc.CreateSession(ctx, user.ID)
+	require.NoError(t, err)
+
+	deleted, err := svc.DeleteExpiredSessions(ctx)
+	require.NoError(t, err)
+	require.Equal(t, 1, deleted)
+
+	_, err = svc.FindSession(ctx, session.ID)
+	require.Equal(t, manta.ErrSessionNotFound, err)
+	_, err = svc.FindSession(ctx, active.ID)
+	require.NoError(t, err)
+}
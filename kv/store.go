@@ -54,11 +54,51 @@ type SchemaStore interface {
 type Store interface {
 	// View opens up a transaction that will not write to any data. Implementing interfaces
 	// should take care to ensure that all view transactions do not mutate any data.
+	// The consistency level is carried by the context, see WithReadConsistency.
 	View(context.Context, func(Tx) error) error
 	// Update opens up a transaction that will mutate data.
 	Update(context.Context, func(Tx) error) error
 	// Backup copies all K:Vs to a writer, file format determined by implementation.
 	Backup(ctx context.Context, w io.Writer) error
+	// Watcher notifies the committed changes
+	Watcher
+}
+
+// ReadConsistency is the consistency level of View transactions.
+type ReadConsistency int
+
+const (
+	// SerializableRead reads from local state directly, it is fast but the
+	// result might be stale, e.g. the local node is partitioned from the
+	// cluster. Stores which don't replicate data treat all reads as this.
+	SerializableRead ReadConsistency = iota
+	// LinearizableRead makes sure the read reflects all writes committed
+	// before it starts, it costs a round trip to the leader(ReadIndex).
+	LinearizableRead
+)
+
+type readConsistencyKey struct{}
+
+// WithReadConsistency returns a new context which tells the Store which
+// consistency level the View transaction requires.
+func WithReadConsistency(ctx context.Context, rc ReadConsistency) context.Context {
+	return context.WithValue(ctx, readConsistencyKey{}, rc)
+}
+
+// WithLinearizableRead is a shortcut of WithReadConsistency(ctx, LinearizableRead)
+func WithLinearizableRead(ctx context.Context) context.Context {
+	return WithReadConsistency(ctx, LinearizableRead)
+}
+
+// ReadConsistencyFromContext returns the read consistency level carried by
+// the context, SerializableRead is returned if not set.
+func ReadConsistencyFromContext(ctx context.Context) ReadConsistency {
+	rc, ok := ctx.Value(readConsistencyKey{}).(ReadConsistency)
+	if !ok {
+		return SerializableRead
+	}
+
+	return rc
 }
 
 // Tx is a transaction in the store.
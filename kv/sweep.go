@@ -0,0 +1,74 @@
+package kv
+
+import (
+	"context"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const defaultSweepInterval = time.Hour
+
+// Sweep deletes expired sessions, stale lockouts and audit records older
+// than the retention every interval until ctx is done
+func (s *Service) Sweep(ctx context.Context, interval time.Duration) error {
+	if interval <= 0 {
+		interval = defaultSweepInterval
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+
+		s.sweep(ctx)
+	}
+}
+
+func (s *Service) sweep(ctx context.Context) {
+	sweeps := []struct {
+		name string
+		fn   func(ctx context.Context) (int, error)
+	}{
+		{
+			name: "sessions",
+			fn:   s.DeleteExpiredSessions,
+		},
+		{
+			name: "lockouts",
+			fn:   s.DeleteExpiredLockouts,
+		},
+		{
+			name: "audit records",
+			fn: func(ctx context.Context) (int, error) {
+				if s.auditRetention <= 0 {
+					return 0, nil
+				}
+
+				return s.DeleteAuditRecords(ctx, time.Now().Add(-s.auditRetention))
+			},
+		},
+	}
+
+	for _, sweep := range sweeps {
+		deleted, err := sweep.fn(ctx)
+		if err != nil {
+			if ctx.Err() == nil {
+				s.logger.Warn("Delete expired "+sweep.name+" failed",
+					zap.Error(err))
+			}
+
+			continue
+		}
+
+		if deleted != 0 {
+			s.logger.Debug("Delete expired "+sweep.name+" success",
+				zap.Int("deleted", deleted))
+		}
+	}
+}
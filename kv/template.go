@@ -12,7 +12,7 @@ import (
 
 var (
 	// key is orgID + id
-	templatesBucket = []byte("templates")
+	TemplatesBucket = []byte("templates")
 )
 
 func decodeResourceErr(index int, typ manta.ResourceType, err error) *manta.Error {
@@ -124,7 +124,7 @@ func (s *Service) Install(ctx context.Context, create manta.TemplateCreate) (*ma
 			return err
 		}
 
-		b, err := tx.Bucket(templatesBucket)
+		b, err := tx.Bucket(TemplatesBucket)
 		if err != nil {
 			return err
 		}
@@ -151,7 +151,7 @@ func (s *Service) Uninstall(ctx context.Context, orgID, id manta.ID) error {
 			return err
 		}
 
-		b, err := tx.Bucket(templatesBucket)
+		b, err := tx.Bucket(TemplatesBucket)
 		if err != nil {
 			return err
 		}
@@ -225,6 +225,40 @@ func (s *Service) Uninstall(ctx context.Context, orgID, id manta.ID) error {
 	})
 }
 
+// deleteOrgTemplates deletes the templates of the organization, resources
+// installed by them are deleted with the organization.
+func deleteOrgTemplates(tx Tx, orgID manta.ID) error {
+	b, err := tx.Bucket(TemplatesBucket)
+	if err != nil {
+		return err
+	}
+
+	cursor, err := b.Cursor()
+	if err != nil {
+		return err
+	}
+
+	keys := make([][]byte, 0)
+	for k, v := cursor.First(); k != nil; k, v = cursor.Next() {
+		tmpl := &manta.Template{}
+		if err = json.Unmarshal(v, tmpl); err != nil {
+			return err
+		}
+
+		if tmpl.OrgID == orgID {
+			keys = append(keys, append([]byte{}, k...))
+		}
+	}
+
+	for _, key := range keys {
+		if err = b.Delete(key); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
 func (s *Service) ListTemplate(ctx context.Context, orgID manta.ID) ([]*manta.Template, error) {
 	var templates []*manta.Template
 
@@ -234,7 +268,7 @@ func (s *Service) ListTemplate(ctx context.Context, orgID manta.ID) ([]*manta.Te
 			return err
 		}
 
-		b, err := tx.Bucket(templatesBucket)
+		b, err := tx.Bucket(TemplatesBucket)
 		if err != nil {
 			return err
 		}
@@ -22,6 +22,10 @@ func (s *Service) FindUserByID(ctx context.Context, id manta.ID) (*manta.User, e
 
 	err = s.kv.View(ctx, func(tx Tx) error {
 		user, err = s.findUserByID(ctx, tx, id)
+		if IsNotFound(err) {
+			return manta.ErrUserNotFound
+		}
+
 		return err
 	})
 
@@ -59,6 +63,10 @@ func (s *Service) FindUser(ctx context.Context, filter manta.UserFilter) (*manta
 		return nil
 	})
 
+	if IsNotFound(err) {
+		return nil, manta.ErrUserNotFound
+	}
+
 	if err != nil {
 		return nil, err
 	}
@@ -173,6 +181,14 @@ func (s *Service) createUser(ctx context.Context, tx Tx, user *manta.User) error
 		return err
 	}
 
+	if user.Status == "" {
+		user.Status = manta.UserActive
+	}
+
+	if err = user.Validate(); err != nil {
+		return err
+	}
+
 	// initial user
 	user.ID = s.idGen.ID()
 	now := time.Now()
@@ -234,6 +250,9 @@ func (s *Service) UpdateUser(ctx context.Context, id manta.ID, upd manta.UserUpd
 
 func (s *Service) updateUser(ctx context.Context, tx Tx, id manta.ID, upd manta.UserUpdate) (*manta.User, error) {
 	prev, err := s.findUserByID(ctx, tx, id)
+	if IsNotFound(err) {
+		return nil, manta.ErrUserNotFound
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -242,21 +261,40 @@ func (s *Service) updateUser(ctx context.Context, tx Tx, id manta.ID, upd manta.
 	upd.Apply(&user)
 	user.Updated = time.Now()
 
-	err = s.putUser(ctx, tx, &user)
+	if err = user.Validate(); err != nil {
+		return nil, err
+	}
+
+	b, err := tx.Bucket(userNameIndexBucket)
 	if err != nil {
 		return nil, err
 	}
 
-	// update user name index
-	if prev.Name == user.Name {
-		return &user, nil
+	if prev.Name != user.Name {
+		if _, err = b.Get([]byte(user.Name)); err == nil {
+			return nil, manta.ErrUserAlreadyExist
+		} else if err != ErrKeyNotFound {
+			return nil, err
+		}
 	}
 
-	b, err := tx.Bucket(userNameIndexBucket)
+	err = s.putUser(ctx, tx, &user)
 	if err != nil {
 		return nil, err
 	}
 
+	// a disabled user must sign in again after enabled
+	if prev.Active() && !user.Active() {
+		if err = deleteUserSessions(tx, id); err != nil {
+			return nil, err
+		}
+	}
+
+	// update user name index
+	if prev.Name == user.Name {
+		return &user, nil
+	}
+
 	if err = b.Delete([]byte(prev.Name)); err != nil {
 		return nil, err
 	}
@@ -277,10 +315,37 @@ func (s *Service) DeleteUser(ctx context.Context, id manta.ID) error {
 
 func (s *Service) deleteUser(ctx context.Context, tx Tx, id manta.ID) error {
 	user, err := s.findUserByID(ctx, tx, id)
+	if IsNotFound(err) {
+		return manta.ErrUserNotFound
+	}
 	if err != nil {
 		return err
 	}
 
+	if err = deleteUserResourceMappingsByUser(tx, id); err != nil {
+		return err
+	}
+
+	if err = s.deleteUserAuthorizations(ctx, tx, id); err != nil {
+		return err
+	}
+
+	if err = deleteUserSessions(tx, id); err != nil {
+		return err
+	}
+
+	if err = deletePassword(tx, id); err != nil {
+		return err
+	}
+
+	if err = deleteUserPasswordResets(tx, id); err != nil {
+		return err
+	}
+
+	if err = deleteUserIdentities(tx, id); err != nil {
+		return err
+	}
+
 	// delete user
 	b, err := tx.Bucket(userBucket)
 	if err != nil {
@@ -194,6 +194,21 @@ func (s *Service) CreateUserResourceMapping(ctx context.Context, m *manta.UserRe
 }
 
 func (s *Service) createUserResourceMapping(ctx context.Context, tx Tx, m *manta.UserResourceMapping) error {
+	if err := m.Validate(); err != nil {
+		return err
+	}
+
+	if _, err := findMappingRole(tx, m); err != nil {
+		return err
+	}
+
+	if m.UserType != manta.Owner {
+		// the mapping is overwritten if it exists already
+		if err := checkLastOwner(tx, m.ResourceID, m.UserID); err != nil {
+			return err
+		}
+	}
+
 	data, err := json.Marshal(m)
 	if err != nil {
 		return err
@@ -226,19 +241,149 @@ func (s *Service) createUserResourceMapping(ctx context.Context, tx Tx, m *manta
 	return b.Put(key, nil)
 }
 
-// DeleteUserResourceMapping deletes a user resource mapping.
+// DeleteUserResourceMapping deletes a user resource mapping, the last owner
+// of an organization can't be deleted.
 func (s *Service) DeleteUserResourceMapping(ctx context.Context, resourceID, userID manta.ID) error {
+	return s.kv.Update(ctx, func(tx Tx) error {
+		if err := checkLastOwner(tx, resourceID, userID); err != nil {
+			return err
+		}
+
+		return deleteUserResourceMapping(tx, resourceID, userID)
+	})
+}
+
+// checkLastOwner returns ErrLastOwner if the user is the only owner of the
+// organization, it is called in the same transaction as the mapping is
+// deleted or demoted, so concurrent changes can't remove all owners.
+func checkLastOwner(tx Tx, resourceID, userID manta.ID) error {
 	key, err := indexIDKey(resourceID, userID)
 	if err != nil {
 		return err
 	}
 
-	return s.kv.Update(ctx, func(tx Tx) error {
-		b, err := tx.Bucket(UrmsBucket)
-		if err != nil {
+	b, err := tx.Bucket(UrmsBucket)
+	if err != nil {
+		return err
+	}
+
+	data, err := b.Get(key)
+	if IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	m := &manta.UserResourceMapping{}
+	if err = json.Unmarshal(data, m); err != nil {
+		return err
+	}
+
+	if m.ResourceType != manta.OrgsResourceType || m.UserType != manta.Owner {
+		return nil
+	}
+
+	owners, _, err := findUserResourceMappingByResource(tx, manta.UserResourceMappingFilter{
+		ResourceID:   resourceID,
+		ResourceType: manta.OrgsResourceType,
+		UserType:     manta.Owner,
+	}, manta.FindOptions{})
+	if err != nil {
+		return err
+	}
+
+	if len(owners) <= 1 {
+		return manta.ErrLastOwner
+	}
+
+	return nil
+}
+
+func deleteUserResourceMapping(tx Tx, resourceID, userID manta.ID) error {
+	key, err := indexIDKey(resourceID, userID)
+	if err != nil {
+		return err
+	}
+
+	b, err := tx.Bucket(UrmsBucket)
+	if err != nil {
+		return err
+	}
+
+	if err = b.Delete(key); err != nil {
+		return err
+	}
+
+	key, err = indexIDKey(userID, resourceID)
+	if err != nil {
+		return err
+	}
+
+	b, err = tx.Bucket(UrmUserIndexBucket)
+	if err != nil {
+		return err
+	}
+
+	return b.Delete(key)
+}
+
+// deleteUserResourceMappings deletes all mappings of the resource
+func deleteUserResourceMappings(tx Tx, resourceID manta.ID) error {
+	prefix, err := resourceID.Encode()
+	if err != nil {
+		return err
+	}
+
+	b, err := tx.Bucket(UrmsBucket)
+	if err != nil {
+		return err
+	}
+
+	cursor, err := b.Cursor()
+	if err != nil {
+		return err
+	}
+
+	var mappings []*manta.UserResourceMapping
+	for k, v := cursor.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, v = cursor.Next() {
+		m := &manta.UserResourceMapping{}
+		if err = json.Unmarshal(v, m); err != nil {
 			return err
 		}
 
-		return b.Delete(key)
-	})
+		mappings = append(mappings, m)
+	}
+
+	for _, m := range mappings {
+		if err = deleteUserResourceMapping(tx, m.ResourceID, m.UserID); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// deleteUserResourceMappingsByUser deletes all mappings of the user, it
+// returns ErrLastOwner if the user is the only owner of any organization.
+func deleteUserResourceMappingsByUser(tx Tx, userID manta.ID) error {
+	mappings, _, err := findUserResourceMappingByUser(
+		tx,
+		manta.UserResourceMappingFilter{UserID: userID},
+		manta.FindOptions{})
+	if err != nil {
+		return err
+	}
+
+	for _, m := range mappings {
+		if err = checkLastOwner(tx, m.ResourceID, m.UserID); err != nil {
+			return err
+		}
+
+		if err = deleteUserResourceMapping(tx, m.ResourceID, m.UserID); err != nil {
+			return err
+		}
+	}
+
+	return nil
 }
@@ -1 +1,154 @@
 package kv_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/f1shl3gs/manta"
+	"github.com/f1shl3gs/manta/authorizer"
+)
+
+func TestUserService(t *testing.T) {
+	svc, closer := NewTestService(t)
+	defer closer()
+
+	ctx := context.Background()
+	foo := &manta.User{Name: "foo"}
+	err := svc.CreateUser(ctx, foo)
+	require.NoError(t, err)
+	assert.Equal(t, manta.UserActive, foo.Status)
+
+	bar := &manta.User{Name: "bar"}
+	err = svc.CreateUser(ctx, bar)
+	require.NoError(t, err)
+
+	t.Run("create invalid", func(t *testing.T) {
+		err := svc.CreateUser(ctx, &manta.User{})
+		assert.Equal(t, manta.EInvalid, manta.ErrorCode(err))
+
+		err = svc.CreateUser(ctx, &manta.User{Name: "foo"})
+		assert.Equal(t, manta.ErrUserAlreadyExist, err)
+
+		err = svc.CreateUser(ctx, &manta.User{Name: "baz", Status: "unknown"})
+		assert.Equal(t, manta.EInvalid, manta.ErrorCode(err))
+	})
+
+	t.Run("rename", func(t *testing.T) {
+		name := "bar"
+		_, err := svc.UpdateUser(ctx, foo.ID, manta.UserUpdate{Name: &name})
+		assert.Equal(t, manta.ErrUserAlreadyExist, err)
+
+		name = "qux"
+		user, err := svc.UpdateUser(ctx, foo.ID, manta.UserUpdate{Name: &name})
+		require.NoError(t, err)
+		assert.Equal(t, name, user.Name)
+
+		found, err := svc.FindUser(ctx, manta.UserFilter{Name: &name})
+		require.NoError(t, err)
+		assert.Equal(t, foo.ID, found.ID)
+
+		old := "foo"
+		_, err = svc.FindUser(ctx, manta.UserFilter{Name: &old})
+		assert.Equal(t, manta.ErrUserNotFound, err)
+	})
+
+	t.Run("disable", func(t *testing.T) {
+		session, err := svc.CreateSession(ctx, bar.ID)
+		require.NoError(t, err)
+
+		status := manta.UserInactive
+		user, err := svc.UpdateUser(ctx, bar.ID, manta.UserUpdate{Status: &status})
+		require.NoError(t, err)
+		assert.False(t, user.Active())
+
+		_, err = svc.FindSession(ctx, session.ID)
+		assert.Equal(t, manta.ErrSessionNotFound, err)
+
+		status = manta.UserActive
+		user, err = svc.UpdateUser(ctx, bar.ID, manta.UserUpdate{Status: &status})
+		require.NoError(t, err)
+		assert.True(t, user.Active())
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		actx := authorizer.SetAuthorizer(ctx, &manta.Session{UserID: bar.ID})
+		orgID := CreateDefaultOrg(t, svc)
+
+		err := svc.CreateUserResourceMapping(actx, &manta.UserResourceMapping{
+			UserID:       bar.ID,
+			UserType:     manta.Member,
+			MappingType:  manta.UserMappingType,
+			ResourceType: manta.OrgsResourceType,
+			ResourceID:   orgID,
+		})
+		require.NoError(t, err)
+
+		err = svc.CreateAuthorization(ctx, &manta.Authorization{UID: bar.ID})
+		require.NoError(t, err)
+
+		err = svc.SetPassword(ctx, bar.ID, "password")
+		require.NoError(t, err)
+
+		session, err := svc.CreateSession(ctx, bar.ID)
+		require.NoError(t, err)
+
+		err = svc.DeleteUser(ctx, bar.ID)
+		require.NoError(t, err)
+
+		_, err = svc.FindUserByID(ctx, bar.ID)
+		assert.Equal(t, manta.ErrUserNotFound, err)
+
+		urms, _, err := svc.FindUserResourceMappings(ctx, manta.UserResourceMappingFilter{ResourceID: orgID}, manta.FindOptions{})
+		require.NoError(t, err)
+		assert.Empty(t, urms)
+
+		auths, err := svc.FindAuthorizations(ctx, manta.AuthorizationFilter{UserID: &bar.ID})
+		require.NoError(t, err)
+		assert.Empty(t, auths)
+
+		_, err = svc.FindSession(ctx, session.ID)
+		assert.Equal(t, manta.ErrSessionNotFound, err)
+
+		err = svc.ComparePassword(ctx, bar.ID, "password")
+		assert.Error(t, err)
+
+		err = svc.DeleteUser(ctx, bar.ID)
+		assert.Equal(t, manta.ErrUserNotFound, err)
+
+		// the name can be reused
+		err = svc.CreateUser(ctx, &manta.User{Name: "bar"})
+		require.NoError(t, err)
+	})
+
+	t.Run("delete last owner", func(t *testing.T) {
+		orgID := CreateTestOrg(t, svc, "last-owner")
+		owner := func(userID manta.ID) error {
+			return svc.CreateUserResourceMapping(ctx, &manta.UserResourceMapping{
+				UserID:       userID,
+				UserType:     manta.Owner,
+				MappingType:  manta.UserMappingType,
+				ResourceType: manta.OrgsResourceType,
+				ResourceID:   orgID,
+			})
+		}
+
+		require.NoError(t, owner(foo.ID))
+
+		err := svc.DeleteUser(ctx, foo.ID)
+		assert.Equal(t, manta.ErrLastOwner, err)
+		assert.Equal(t, manta.EConflict, manta.ErrorCode(err))
+
+		// nothing is deleted
+		_, err = svc.FindUserByID(ctx, foo.ID)
+		require.NoError(t, err)
+		urms, _, err := svc.FindUserResourceMappings(ctx, manta.UserResourceMappingFilter{UserID: foo.ID}, manta.FindOptions{})
+		require.NoError(t, err)
+		assert.Len(t, urms, 1)
+
+		require.NoError(t, owner(100))
+		require.NoError(t, svc.DeleteUser(ctx, foo.ID))
+	})
+}
@@ -1,3 +1,167 @@
 package kv
 
-// TODO
+import (
+	"bytes"
+	"context"
+	"sync"
+)
+
+const (
+	// watchBufferSize is the number of events buffered for each watcher,
+	// watchers which can't keep up are closed.
+	watchBufferSize = 128
+)
+
+// EventType is the type of change happened to a key
+type EventType int
+
+const (
+	// EventPut means the key is created or updated
+	EventPut EventType = iota
+	// EventDelete means the key is deleted
+	EventDelete
+)
+
+func (t EventType) String() string {
+	switch t {
+	case EventPut:
+		return "put"
+	case EventDelete:
+		return "delete"
+	default:
+		return "unknown"
+	}
+}
+
+// Event describes a committed change of a key.
+type Event struct {
+	Type   EventType
+	Bucket []byte
+	Key    []byte
+	// Value is the new value of the key, it is nil for EventDelete
+	Value []byte
+	// Revision is the revision of the transaction which produces this
+	// event, it increases monotonically on the same store.
+	Revision uint64
+}
+
+// Watcher is implemented by stores which can notify the committed changes.
+type Watcher interface {
+	// Watch returns a channel which receives events of keys with the prefix
+	// in bucket, an empty prefix matches all keys of the bucket. Events are
+	// sent after the changes are committed, and in the order they are committed.
+	//
+	// The channel is closed when ctx is done, or the watcher can't keep up
+	// with the changes, or the store lost track of changes, e.g. a snapshot
+	// is installed, so the consumer should reload the state and watch again
+	// if ctx is not done.
+	Watch(ctx context.Context, bucket, prefix []byte) (<-chan Event, error)
+}
+
+// WatchHub fans out committed events to watchers, it is shared by the store
+// implementations.
+type WatchHub struct {
+	mtx      sync.Mutex
+	watchers map[*watcher]struct{}
+}
+
+type watcher struct {
+	bucket []byte
+	prefix []byte
+	ch     chan Event
+}
+
+func (w *watcher) match(ev *Event) bool {
+	return bytes.Equal(w.bucket, ev.Bucket) && bytes.HasPrefix(ev.Key, w.prefix)
+}
+
+// NewWatchHub creates a WatchHub
+func NewWatchHub() *WatchHub {
+	return &WatchHub{
+		watchers: make(map[*watcher]struct{}),
+	}
+}
+
+// Watch implements Watcher
+func (h *WatchHub) Watch(ctx context.Context, bucket, prefix []byte) (<-chan Event, error) {
+	w := &watcher{
+		bucket: append([]byte(nil), bucket...),
+		prefix: append([]byte(nil), prefix...),
+		ch:     make(chan Event, watchBufferSize),
+	}
+
+	h.mtx.Lock()
+	h.watchers[w] = struct{}{}
+	h.mtx.Unlock()
+
+	go func() {
+		<-ctx.Done()
+		h.remove(w)
+	}()
+
+	return w.ch, nil
+}
+
+// Publish sends events to the matched watchers, it never blocks.
+func (h *WatchHub) Publish(events []Event) {
+	if len(events) == 0 {
+		return
+	}
+
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+
+	for w := range h.watchers {
+		for i := range events {
+			if !w.match(&events[i]) {
+				continue
+			}
+
+			select {
+			case w.ch <- events[i]:
+				continue
+			default:
+			}
+
+			// the watcher is too slow, close it so the consumer
+			// can reload and watch again
+			h.removeLocked(w)
+			break
+		}
+	}
+}
+
+// Reset closes all watchers, it is called when the store can't tell what
+// changed, e.g. the whole state is replaced.
+func (h *WatchHub) Reset() {
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+
+	for w := range h.watchers {
+		h.removeLocked(w)
+	}
+}
+
+// Len returns the number of watchers
+func (h *WatchHub) Len() int {
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+
+	return len(h.watchers)
+}
+
+func (h *WatchHub) remove(w *watcher) {
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+
+	h.removeLocked(w)
+}
+
+func (h *WatchHub) removeLocked(w *watcher) {
+	if _, ok := h.watchers[w]; !ok {
+		return
+	}
+
+	delete(h.watchers, w)
+	close(w.ch)
+}
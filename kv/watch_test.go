@@ -0,0 +1,62 @@
+package kv_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/f1shl3gs/manta/kv"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWatchHub(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	hub := kv.NewWatchHub()
+	ch, err := hub.Watch(ctx, []byte("bucket"), []byte("foo"))
+	assert.NoError(t, err)
+
+	hub.Publish([]kv.Event{
+		{Type: kv.EventPut, Bucket: []byte("bucket"), Key: []byte("foo1"), Value: []byte("v1"), Revision: 1},
+		{Type: kv.EventPut, Bucket: []byte("bucket"), Key: []byte("bar"), Value: []byte("v2"), Revision: 1},
+		{Type: kv.EventPut, Bucket: []byte("other"), Key: []byte("foo2"), Value: []byte("v3"), Revision: 1},
+		{Type: kv.EventDelete, Bucket: []byte("bucket"), Key: []byte("foo1"), Revision: 2},
+	})
+
+	ev := <-ch
+	assert.Equal(t, kv.EventPut, ev.Type)
+	assert.Equal(t, "foo1", string(ev.Key))
+	assert.Equal(t, "v1", string(ev.Value))
+
+	ev = <-ch
+	assert.Equal(t, kv.EventDelete, ev.Type)
+	assert.Equal(t, uint64(2), ev.Revision)
+
+	cancel()
+	_, ok := <-ch
+	assert.False(t, ok)
+	assert.Eventually(t, func() bool { return hub.Len() == 0 }, time.Second, 10*time.Millisecond)
+}
+
+func TestWatchHubSlowWatcher(t *testing.T) {
+	hub := kv.NewWatchHub()
+	ch, err := hub.Watch(context.Background(), []byte("bucket"), nil)
+	assert.NoError(t, err)
+
+	for i := 0; i < 1000; i++ {
+		hub.Publish([]kv.Event{
+			{Type: kv.EventPut, Bucket: []byte("bucket"), Key: []byte(fmt.Sprintf("key-%d", i))},
+		})
+	}
+
+	// the watcher can't keep up, so it is closed after the buffered events
+	n := 0
+	for range ch {
+		n++
+	}
+	assert.Less(t, n, 1000)
+	assert.Equal(t, 0, hub.Len())
+}
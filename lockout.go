@@ -0,0 +1,133 @@
+package manta
+
+import (
+	"context"
+	"time"
+)
+
+const (
+	LockoutUsername LockoutKind = "username"
+	LockoutIP       LockoutKind = "ip"
+)
+
+var (
+	ErrSigninLocked = &Error{
+		Code: ETooManyRequests,
+		Msg:  "too many failed sign-ins, try again later",
+	}
+
+	ErrLockoutNotFound = &Error{
+		Code: ENotFound,
+		Msg:  "lockout not found",
+	}
+)
+
+type LockoutKind string
+
+func (k LockoutKind) Valid() error {
+	switch k {
+	case LockoutUsername, LockoutIP:
+		return nil
+	default:
+		return &Error{
+			Code: EInvalid,
+			Msg:  "unknown lockout kind " + string(k),
+		}
+	}
+}
+
+// Lockout tracks the failed sign-ins of a username or an IP, it is locked
+// once the failures reach the limit of the policy, and every further failure
+// doubles the duration.
+type Lockout struct {
+	Kind        LockoutKind `json:"kind"`
+	Value       string      `json:"value"`
+	Failures    int         `json:"failures"`
+	LastFailure time.Time   `json:"lastFailure"`
+	LockedUntil time.Time   `json:"lockedUntil,omitempty"`
+}
+
+// Locked returns true if sign-ins are rejected at the time
+func (l *Lockout) Locked(now time.Time) bool {
+	return now.Before(l.LockedUntil)
+}
+
+// LockoutPolicy decides when usernames and IPs are locked out, and for how
+// long. IPs are usually allowed more failures, since users behind a NAT
+// share the IP.
+type LockoutPolicy struct {
+	MaxUsernameFailures int
+	MaxIPFailures       int
+	// Duration is the lockout after reaching the limit, it doubles on
+	// every further failure, but never exceeds MaxDuration
+	Duration    time.Duration
+	MaxDuration time.Duration
+}
+
+// Expired returns true if the failures are too old to count
+func (p LockoutPolicy) Expired(l *Lockout, now time.Time) bool {
+	return !l.Locked(now) && now.Sub(l.LastFailure) > p.MaxDuration
+}
+
+// Fail counts a failure, and locks it if the failures reach the limit
+func (p LockoutPolicy) Fail(l *Lockout, now time.Time) {
+	if p.Expired(l, now) {
+		l.Failures = 0
+	}
+
+	l.Failures += 1
+	l.LastFailure = now
+
+	max := p.limit(l.Kind)
+	if max <= 0 || l.Failures < max {
+		return
+	}
+
+	duration := p.MaxDuration
+	if exp := l.Failures - max; exp < 32 {
+		if d := p.Duration << exp; d > 0 && d < duration {
+			duration = d
+		}
+	}
+
+	l.LockedUntil = now.Add(duration)
+}
+
+// Succeed uncounts an attempt counted by Fail, the lock is lifted if the
+// failures are below the limit again.
+func (p LockoutPolicy) Succeed(l *Lockout) {
+	if l.Failures > 0 {
+		l.Failures -= 1
+	}
+
+	if l.Failures < p.limit(l.Kind) {
+		l.LockedUntil = time.Time{}
+	}
+}
+
+func (p LockoutPolicy) limit(kind LockoutKind) int {
+	if kind == LockoutIP {
+		return p.MaxIPFailures
+	}
+
+	return p.MaxUsernameFailures
+}
+
+type LockoutService interface {
+	// AttemptSignin returns ErrSigninLocked if the username or the IP is
+	// locked, otherwise the attempt is counted as a failure of both, until
+	// SigninSucceeded is called. Checking and counting are done at once, so
+	// concurrent attempts cannot exceed the limit.
+	AttemptSignin(ctx context.Context, username, ip string) error
+
+	// SigninSucceeded clears the failures of the username, and uncounts the
+	// attempt of the IP. The other failures of the IP are kept, so a valid
+	// account cannot be used to reset them.
+	SigninSucceeded(ctx context.Context, username, ip string) error
+
+	// FindLockouts returns the usernames and IPs failed to sign in recently
+	FindLockouts(ctx context.Context) ([]*Lockout, error)
+
+	// DeleteLockout clears the failures, and unlocks it
+	DeleteLockout(ctx context.Context, kind LockoutKind, value string) error
+}
@@ -0,0 +1,77 @@
+package manta
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLockoutPolicy_Fail(t *testing.T) {
+	policy := LockoutPolicy{
+		MaxUsernameFailures: 3,
+		MaxIPFailures:       5,
+		Duration:            time.Minute,
+		MaxDuration:         10 * time.Minute,
+	}
+	now := time.Now()
+
+	lockout := &Lockout{Kind: LockoutUsername, Value: "foo"}
+	for i := 0; i < 2; i++ {
+		policy.Fail(lockout, now)
+	}
+	assert.False(t, lockout.Locked(now))
+
+	// the duration doubles on every failure after the limit
+	for i, want := range []time.Duration{
+		time.Minute,
+		2 * time.Minute,
+		4 * time.Minute,
+		8 * time.Minute,
+		10 * time.Minute,
+		10 * time.Minute,
+	} {
+		policy.Fail(lockout, now)
+		assert.Equal(t, now.Add(want), lockout.LockedUntil, "failure %d", i+3)
+		assert.True(t, lockout.Locked(now))
+	}
+
+	// IPs are allowed more failures
+	lockout = &Lockout{Kind: LockoutIP, Value: "10.0.0.1"}
+	for i := 0; i < 4; i++ {
+		policy.Fail(lockout, now)
+	}
+	assert.False(t, lockout.Locked(now))
+
+	// old failures are forgotten
+	lockout = &Lockout{Kind: LockoutUsername, Value: "bar"}
+	policy.Fail(lockout, now.Add(-time.Hour))
+	policy.Fail(lockout, now.Add(-time.Hour))
+	policy.Fail(lockout, now)
+	assert.Equal(t, 1, lockout.Failures)
+	assert.False(t, lockout.Locked(now))
+}
+
+func TestLockoutPolicy_Succeed(t *testing.T) {
+	policy := LockoutPolicy{
+		MaxUsernameFailures: 3,
+		MaxIPFailures:       5,
+		Duration:            time.Minute,
+		MaxDuration:         10 * time.Minute,
+	}
+	now := time.Now()
+
+	// the lock of the attempt is lifted if it succeeded
+	lockout := &Lockout{Kind: LockoutIP, Value: "10.0.0.1"}
+	for i := 0; i < 5; i++ {
+		policy.Fail(lockout, now)
+	}
+	assert.True(t, lockout.Locked(now))
+	policy.Succeed(lockout)
+	assert.Equal(t, 4, lockout.Failures)
+	assert.False(t, lockout.Locked(now))
+
+	lockout = &Lockout{Kind: LockoutIP, Value: "10.0.0.2"}
+	policy.Succeed(lockout)
+	assert.Equal(t, 0, lockout.Failures)
+}
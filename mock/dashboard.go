@@ -31,6 +31,14 @@ type DashboardService struct {
 	RemoveDashboardFn func(ctx context.Context, id manta.ID) error
 
 	ReplaceDashboardCellsFn func(ctx context.Context, dashboardId manta.ID, cells []manta.Cell) error
+
+	FindDashboardVersionsFn func(ctx context.Context, id manta.ID) ([]*manta.DashboardVersion, error)
+
+	FindDashboardVersionFn func(ctx context.Context, id manta.ID, version uint64) (*manta.DashboardVersion, error)
+
+	FindDeletedDashboardsFn func(ctx context.Context, filter manta.DashboardFilter) ([]*manta.DashboardVersion, error)
+
+	RestoreDashboardFn func(ctx context.Context, id manta.ID, version uint64) (*manta.Dashboard, error)
 }
 
 func (s *DashboardService) FindDashboardByID(ctx context.Context, id manta.ID) (*manta.Dashboard, error) {
@@ -85,3 +93,26 @@ func (s *DashboardService) RemoveDashboard(ctx context.Context, id manta.ID) err
 func (s *DashboardService) ReplaceDashboardCells(ctx context.Context, dashboardID manta.ID, cells []manta.Cell) error {
 	return s.ReplaceDashboardCellsFn(ctx, dashboardID, cells)
 }
+
+func (s *DashboardService) FindDashboardVersions(ctx context.Context, id manta.ID) ([]*manta.DashboardVersion, error) {
+	return s.FindDashboardVersionsFn(ctx, id)
+}
+
+func (s *DashboardService) FindDashboardVersion(
+	ctx context.Context,
+	id manta.ID,
+	version uint64,
+) (*manta.DashboardVersion, error) {
+	return s.FindDashboardVersionFn(ctx, id, version)
+}
+
+func (s *DashboardService) FindDeletedDashboards(
+	ctx context.Context,
+	filter manta.DashboardFilter,
+) ([]*manta.DashboardVersion, error) {
+	return s.FindDeletedDashboardsFn(ctx, filter)
+}
+
+func (s *DashboardService) RestoreDashboard(ctx context.Context, id manta.ID, version uint64) (*manta.Dashboard, error) {
+	return s.RestoreDashboardFn(ctx, id, version)
+}
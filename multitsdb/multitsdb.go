@@ -53,6 +53,58 @@ func (m *MultiTSDB) Appendable(ctx context.Context, id manta.ID) (storage.Append
 	return t.readyS.Get(), nil
 }
 
+// RemoveTenant closes the TSDB of the tenant and removes its data dir
+func (m *MultiTSDB) RemoveTenant(ctx context.Context, id manta.ID) error {
+	m.mtx.Lock()
+	tenant, exist := m.tenants[id]
+	delete(m.tenants, id)
+	m.mtx.Unlock()
+
+	if exist {
+		if err := tenant.readyS.Close(); err != nil {
+			return err
+		}
+	}
+
+	return os.RemoveAll(m.defaultTenantDataDir(id.String()))
+}
+
+// Tenants returns the tenants loaded and the tenants have data dir
+func (m *MultiTSDB) Tenants(ctx context.Context) ([]manta.ID, error) {
+	seen := make(map[manta.ID]struct{})
+
+	m.mtx.RLock()
+	for id := range m.tenants {
+		seen[id] = struct{}{}
+	}
+	m.mtx.RUnlock()
+
+	files, err := os.ReadDir(m.dataDir)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	for _, file := range files {
+		if !file.IsDir() {
+			continue
+		}
+
+		var id manta.ID
+		if err = id.DecodeFromString(file.Name()); err != nil {
+			continue
+		}
+
+		seen[id] = struct{}{}
+	}
+
+	ids := make([]manta.ID, 0, len(seen))
+	for id := range seen {
+		ids = append(ids, id)
+	}
+
+	return ids, nil
+}
+
 func NewMultiTSDB(
 	dataDir string,
 	logger *zap.Logger,
@@ -13,6 +13,13 @@ type TenantStorage interface {
 	Queryable(ctx context.Context, id manta.ID) (storage.Queryable, error)
 
 	Appendable(ctx context.Context, id manta.ID) (storage.Appendable, error)
+
+	// RemoveTenant closes the storage of the tenant and removes its data
+	RemoveTenant(ctx context.Context, id manta.ID) error
+
+	// Tenants returns the tenants which have data, whether they are
+	// loaded or not
+	Tenants(ctx context.Context) ([]manta.ID, error)
 }
 
 type Noop struct{}
@@ -25,6 +32,14 @@ func (n *Noop) Appendable(ctx context.Context, id manta.ID) (storage.Appendable,
 	return nil, ErrNotReady
 }
 
+func (n *Noop) RemoveTenant(ctx context.Context, id manta.ID) error {
+	return nil
+}
+
+func (n *Noop) Tenants(ctx context.Context) ([]manta.ID, error) {
+	return nil, nil
+}
+
 type TenantTargetRetriever interface {
 	TargetsActive(id manta.ID) map[string][]*scrape.Target
 	TargetsDropped(id manta.ID) map[string][]*scrape.Target
@@ -1,6 +1,7 @@
 package notification
 
 import (
+	"context"
 	"encoding/json"
 	"io"
 	"net/http"
@@ -63,6 +64,11 @@ func (h HTTP) SecretFields() []manta.SecretField {
 	return arr
 }
 
+// LoadSecrets fills the values of secret fields with loader
+func (h *HTTP) LoadSecrets(ctx context.Context, loader manta.SecretLoader) error {
+	return manta.LoadSecretFields(ctx, loader, h.OrgID, &h.Token, &h.Username, &h.Password)
+}
+
 var (
 	validMethods = map[string]bool{
 		http.MethodGet:  true,
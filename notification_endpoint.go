@@ -32,6 +32,8 @@ type NotificationEndpoint interface {
 	BackfillSecretKeys()
 	// SecretFields return available secret fields.
 	SecretFields() []SecretField
+	// LoadSecrets fills the values of secret fields with loader
+	LoadSecrets(ctx context.Context, loader SecretLoader) error
 }
 
 type NotificationEndpointFilter struct {
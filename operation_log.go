@@ -2,6 +2,7 @@ package manta
 
 import (
 	"context"
+	"encoding/json"
 	"time"
 
 	"github.com/cespare/xxhash/v2"
@@ -40,6 +41,52 @@ type OperationLogEntry struct {
 	ResourceBody []byte `json:"resourceBody"`
 	// Time when the resource was changed
 	Time time.Time `json:"time"`
+
+	// Diff is the changes of ResourceBody since the previous entry of the
+	// resource, it is computed when requested, and never stored.
+	Diff []OperationLogChange `json:"diff,omitempty"`
+}
+
+// OperationLogChange is a change of a value in the resource body, it looks
+// like a JSON Patch operation, and the old value is kept too.
+type OperationLogChange struct {
+	// Op is one of "add", "remove" and "replace"
+	Op string `json:"op"`
+	// Path is the JSON pointer of the value
+	Path string `json:"path"`
+	// From is the old value, it is empty for "add"
+	From json.RawMessage `json:"from,omitempty"`
+	// Value is the new value, it is empty for "remove"
+	Value json.RawMessage `json:"value,omitempty"`
+}
+
+// OperationLogFilter filters operation logs, at least one of OrgID,
+// ResourceID and UserID is required.
+type OperationLogFilter struct {
+	OrgID        *ID
+	ResourceID   *ID
+	ResourceType *ResourceType
+	Type         *ChangeType
+	UserID       *ID
+	// Since and Until are inclusive
+	Since *time.Time
+	Until *time.Time
+}
+
+// Match returns true if the entry matches all conditions of the filter
+func (f OperationLogFilter) Match(ent *OperationLogEntry) bool {
+	switch {
+	case f.OrgID != nil && *f.OrgID != ent.OrgID,
+		f.ResourceID != nil && *f.ResourceID != ent.ResourceID,
+		f.ResourceType != nil && *f.ResourceType != ent.ResourceType,
+		f.Type != nil && *f.Type != ent.Type,
+		f.UserID != nil && *f.UserID != ent.UserID,
+		f.Since != nil && ent.Time.Before(*f.Since),
+		f.Until != nil && ent.Time.After(*f.Until):
+		return false
+	default:
+		return true
+	}
 }
 
 func (o *OperationLogEntry) Valid() error {
@@ -70,7 +117,24 @@ type OperationLogService interface {
 	// FindOperationLogsByUser returns operation logs made by a user.
 	FindOperationLogsByUser(ctx context.Context, userID ID, opts FindOptions) ([]*OperationLogEntry, int, error)
 
-	// TODO: add a method to delete log entry!?
+	// FindOperationLogs returns operation logs match the filter, ordered by time.
+	FindOperationLogs(ctx context.Context, filter OperationLogFilter, opts FindOptions) ([]*OperationLogEntry, int, error)
+
+	// DeleteOperationLogs deletes all operation logs of a resource.
+	DeleteOperationLogs(ctx context.Context, resourceID ID) error
+}
+
+// OperationLogRetention decides how long operation logs are kept, an entry
+// is expired if it is older than MaxAge, or there are more than MaxEntries
+// newer entries of the same resource. Zero value means no limit.
+type OperationLogRetention struct {
+	MaxAge     time.Duration
+	MaxEntries int
+}
+
+// Enabled returns true if any limit is set
+func (r OperationLogRetention) Enabled() bool {
+	return r.MaxAge > 0 || r.MaxEntries > 0
 }
 
 // UniqueKeyToID transform a key to ID.
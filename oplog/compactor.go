@@ -0,0 +1,149 @@
+package oplog
+
+import (
+	"context"
+	"time"
+
+	"github.com/f1shl3gs/manta"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap"
+)
+
+const (
+	defaultCompactInterval  = time.Hour
+	defaultCompactBatchSize = 1000
+)
+
+// CompactService deletes the operation logs expired by the retention, it is
+// implemented by kv.Service
+type CompactService interface {
+	CompactOperationLogs(ctx context.Context, retention manta.OperationLogRetention, batchSize int) (int, int, error)
+}
+
+// Compactor deletes expired operation logs periodically.
+type Compactor struct {
+	logger    *zap.Logger
+	service   CompactService
+	retention manta.OperationLogRetention
+
+	interval  time.Duration
+	batchSize int
+
+	compactions *prometheus.CounterVec
+	deleted     prometheus.Counter
+	entries     prometheus.Gauge
+	duration    prometheus.Histogram
+}
+
+func NewCompactor(
+	logger *zap.Logger,
+	service CompactService,
+	retention manta.OperationLogRetention,
+	interval time.Duration,
+	reg prometheus.Registerer,
+) *Compactor {
+	if interval <= 0 {
+		interval = defaultCompactInterval
+	}
+
+	c := &Compactor{
+		logger:    logger,
+		service:   service,
+		retention: retention,
+		interval:  interval,
+		batchSize: defaultCompactBatchSize,
+		compactions: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "manta",
+			Subsystem: "oplog",
+			Name:      "compactions_total",
+			Help:      "Total number of operation log compactions",
+		}, []string{"result"}),
+		deleted: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: "manta",
+			Subsystem: "oplog",
+			Name:      "compacted_entries_total",
+			Help:      "Total number of operation log entries deleted by compaction",
+		}),
+		entries: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: "manta",
+			Subsystem: "oplog",
+			Name:      "entries",
+			Help:      "Number of operation log entries kept by the last compaction",
+		}),
+		duration: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Namespace: "manta",
+			Subsystem: "oplog",
+			Name:      "compaction_duration_seconds",
+			Help:      "The latency distributions of operation log compaction",
+			Buckets:   prometheus.ExponentialBuckets(0.001, 2, 16),
+		}),
+	}
+
+	maxAge := prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: "manta",
+		Subsystem: "oplog",
+		Name:      "retention_max_age_seconds",
+		Help:      "Operation log entries older than it are deleted, 0 means no limit",
+	})
+	maxAge.Set(retention.MaxAge.Seconds())
+
+	maxEntries := prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: "manta",
+		Subsystem: "oplog",
+		Name:      "retention_max_entries",
+		Help:      "Operation log entries kept for each resource, 0 means no limit",
+	})
+	maxEntries.Set(float64(retention.MaxEntries))
+
+	reg.MustRegister(c.compactions, c.deleted, c.entries, c.duration, maxAge, maxEntries)
+
+	return c
+}
+
+// Run compacts operation logs every interval until ctx is done
+func (c *Compactor) Run(ctx context.Context) error {
+	if !c.retention.Enabled() {
+		c.logger.Info("Operation log retention is disabled, entries are kept forever")
+		return nil
+	}
+
+	ticker := time.NewTicker(c.interval)
+	defer ticker.Stop()
+
+	for {
+		if err := c.Compact(ctx); err != nil && ctx.Err() == nil {
+			c.logger.Warn("Compact operation logs failed",
+				zap.Error(err))
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// Compact deletes the expired operation logs once
+func (c *Compactor) Compact(ctx context.Context) error {
+	start := time.Now()
+	deleted, kept, err := c.service.CompactOperationLogs(ctx, c.retention, c.batchSize)
+	c.duration.Observe(time.Since(start).Seconds())
+	c.deleted.Add(float64(deleted))
+
+	if err != nil {
+		c.compactions.WithLabelValues("failure").Inc()
+		return err
+	}
+
+	c.compactions.WithLabelValues("success").Inc()
+	c.entries.Set(float64(kept))
+
+	c.logger.Debug("Compact operation logs success",
+		zap.Int("deleted", deleted),
+		zap.Int("kept", kept),
+		zap.Duration("duration", time.Since(start)))
+
+	return nil
+}
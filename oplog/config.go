@@ -103,6 +103,87 @@ func (s *ConfigService) UpdateConfig(ctx context.Context, id manta.ID, upd manta
 	return conf, nil
 }
 
+func (s *ConfigService) RollbackConfig(ctx context.Context, id manta.ID, revision uint64) (*manta.Config, error) {
+	auth, err := authorizer.FromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	conf, err := s.ConfigService.RollbackConfig(ctx, id, revision)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := json.Marshal(conf)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.oplog.AddLogEntry(ctx, manta.OperationLogEntry{
+		Type:         manta.Update,
+		ResourceID:   conf.ID,
+		ResourceType: manta.ConfigsResourceType,
+		OrgID:        conf.OrgID,
+		UserID:       auth.GetUserID(),
+		ResourceBody: data,
+		Time:         now,
+	})
+	if err != nil {
+		s.logger.Error("add rollback config oplog failed",
+			zap.Error(err),
+			zap.Stringer("resourceID", conf.ID),
+			zap.Stringer("orgID", conf.OrgID))
+		return nil, err
+	}
+
+	return conf, nil
+}
+
+// AbortConfigRollout rolls the config back to the stable revision, the
+// rolled back config is logged.
+func (s *ConfigService) AbortConfigRollout(ctx context.Context, id manta.ID) (*manta.ConfigRollout, error) {
+	auth, err := authorizer.FromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	rollout, err := s.ConfigService.AbortConfigRollout(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	conf, err := s.ConfigService.FindConfigByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := json.Marshal(conf)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.oplog.AddLogEntry(ctx, manta.OperationLogEntry{
+		Type:         manta.Update,
+		ResourceID:   conf.ID,
+		ResourceType: manta.ConfigsResourceType,
+		OrgID:        conf.OrgID,
+		UserID:       auth.GetUserID(),
+		ResourceBody: data,
+		Time:         now,
+	})
+	if err != nil {
+		s.logger.Error("add abort config rollout oplog failed",
+			zap.Error(err),
+			zap.Stringer("resourceID", conf.ID),
+			zap.Stringer("orgID", conf.OrgID))
+		return nil, err
+	}
+
+	return rollout, nil
+}
+
 func (s *ConfigService) DeleteConfig(ctx context.Context, id manta.ID) error {
 	auth, err := authorizer.FromContext(ctx)
 	if err != nil {
@@ -121,7 +202,7 @@ func (s *ConfigService) DeleteConfig(ctx context.Context, id manta.ID) error {
 	}
 
 	err = s.oplog.AddLogEntry(ctx, manta.OperationLogEntry{
-		Type:         manta.Create,
+		Type:         manta.Delete,
 		ResourceID:   conf.ID,
 		ResourceType: manta.ConfigsResourceType,
 		OrgID:        conf.OrgID,
@@ -321,3 +321,46 @@ func (s *DashboardService) ReplaceDashboardCells(ctx context.Context, dashboardI
 
 	return nil
 }
+
+func (s *DashboardService) RestoreDashboard(ctx context.Context, id manta.ID, version uint64) (*manta.Dashboard, error) {
+	auth, err := authorizer.FromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// deleted dashboards are recreated, and their oplogs are deleted already
+	var typ manta.ChangeType = manta.Update
+	if _, err = s.DashboardService.FindDashboardByID(ctx, id); err != nil {
+		typ = manta.Create
+	}
+
+	now := time.Now()
+	dashboard, err := s.DashboardService.RestoreDashboard(ctx, id, version)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := json.Marshal(dashboard)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.oplog.AddLogEntry(ctx, manta.OperationLogEntry{
+		Type:         typ,
+		ResourceID:   dashboard.ID,
+		ResourceType: manta.DashboardsResourceType,
+		OrgID:        dashboard.OrgID,
+		UserID:       auth.GetUserID(),
+		ResourceBody: data,
+		Time:         now,
+	})
+	if err != nil {
+		s.logger.Error("add restore dashboard oplog failed",
+			zap.Error(err),
+			zap.Stringer("resourceID", dashboard.ID),
+			zap.Stringer("orgID", dashboard.OrgID))
+		return nil, err
+	}
+
+	return dashboard, nil
+}
@@ -0,0 +1,179 @@
+package oplog
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/f1shl3gs/manta"
+)
+
+// Diff returns the changes from before to after, both of them are JSON
+// documents, empty document means the resource does not exist.
+func Diff(before, after []byte) ([]manta.OperationLogChange, error) {
+	var (
+		from, to any
+		err      error
+	)
+
+	if len(before) == 0 && len(after) == 0 {
+		return nil, nil
+	}
+
+	if len(before) == 0 {
+		return []manta.OperationLogChange{{Op: "add", Value: after}}, nil
+	}
+
+	if len(after) == 0 {
+		return []manta.OperationLogChange{{Op: "remove", From: before}}, nil
+	}
+
+	if from, err = decode(before); err != nil {
+		return nil, err
+	}
+
+	if to, err = decode(after); err != nil {
+		return nil, err
+	}
+
+	var changes []manta.OperationLogChange
+	diff("", from, to, &changes)
+
+	return changes, nil
+}
+
+func decode(data []byte) (any, error) {
+	var v any
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+
+	return v, nil
+}
+
+func diff(path string, from, to any, changes *[]manta.OperationLogChange) {
+	switch a := from.(type) {
+	case map[string]any:
+		b, ok := to.(map[string]any)
+		if !ok {
+			break
+		}
+
+		keys := make([]string, 0, len(a)+len(b))
+		for k := range a {
+			keys = append(keys, k)
+		}
+		for k := range b {
+			if _, ok := a[k]; !ok {
+				keys = append(keys, k)
+			}
+		}
+		sort.Strings(keys)
+
+		for _, k := range keys {
+			child := path + "/" + escape(k)
+			av, inA := a[k]
+			bv, inB := b[k]
+
+			switch {
+			case !inA:
+				*changes = append(*changes, manta.OperationLogChange{Op: "add", Path: child, Value: raw(bv)})
+			case !inB:
+				*changes = append(*changes, manta.OperationLogChange{Op: "remove", Path: child, From: raw(av)})
+			default:
+				diff(child, av, bv, changes)
+			}
+		}
+
+		return
+
+	case []any:
+		b, ok := to.([]any)
+		if !ok {
+			break
+		}
+
+		n := len(a)
+		if len(b) < n {
+			n = len(b)
+		}
+
+		for i := 0; i < n; i++ {
+			diff(path+"/"+strconv.Itoa(i), a[i], b[i], changes)
+		}
+
+		for i := n; i < len(b); i++ {
+			*changes = append(*changes, manta.OperationLogChange{Op: "add", Path: path + "/" + strconv.Itoa(i), Value: raw(b[i])})
+		}
+
+		// remove from the tail, so the pointers are valid when applied in order
+		for i := len(a) - 1; i >= n; i-- {
+			*changes = append(*changes, manta.OperationLogChange{Op: "remove", Path: path + "/" + strconv.Itoa(i), From: raw(a[i])})
+		}
+
+		return
+	}
+
+	if !reflect.DeepEqual(from, to) {
+		*changes = append(*changes, manta.OperationLogChange{
+			Op:    "replace",
+			Path:  path,
+			From:  raw(from),
+			Value: raw(to),
+		})
+	}
+}
+
+func raw(v any) json.RawMessage {
+	data, _ := json.Marshal(v)
+	return data
+}
+
+// escape escapes the key as a JSON pointer token
+func escape(key string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(key, "~", "~0"), "/", "~1")
+}
+
+// AttachDiffs computes the diff of every entry against the previous entry
+// of the same resource.
+func AttachDiffs(ctx context.Context, svc manta.OperationLogService, entries []*manta.OperationLogEntry) error {
+	for _, ent := range entries {
+		// the previous entry might be filtered out, so look it up anyway
+		until := ent.Time.Add(-time.Nanosecond)
+		prev, _, err := svc.FindOperationLogs(ctx, manta.OperationLogFilter{
+			ResourceID: &ent.ResourceID,
+			Until:      &until,
+		}, manta.FindOptions{
+			Limit:      1,
+			Descending: true,
+		})
+		if err != nil {
+			return err
+		}
+
+		var before []byte
+		if len(prev) > 0 && prev[0].Type != manta.Delete {
+			before = prev[0].ResourceBody
+		}
+
+		after := ent.ResourceBody
+		if ent.Type == manta.Delete {
+			after = nil
+		}
+
+		ent.Diff, err = Diff(before, after)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
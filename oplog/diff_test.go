@@ -0,0 +1,73 @@
+package oplog
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/f1shl3gs/manta"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiff(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		before string
+		after  string
+		want   []manta.OperationLogChange
+	}{
+		{
+			name:  "create",
+			after: `{"name":"foo"}`,
+			want: []manta.OperationLogChange{
+				{Op: "add", Value: json.RawMessage(`{"name":"foo"}`)},
+			},
+		},
+		{
+			name:   "delete",
+			before: `{"name":"foo"}`,
+			want: []manta.OperationLogChange{
+				{Op: "remove", From: json.RawMessage(`{"name":"foo"}`)},
+			},
+		},
+		{
+			name:   "unchanged",
+			before: `{"name":"foo","cells":[1,2]}`,
+			after:  `{"cells":[1,2],"name":"foo"}`,
+		},
+		{
+			name:   "fields",
+			before: `{"name":"foo","desc":"bar","a/b":1}`,
+			after:  `{"name":"foo","a/b":2,"labels":{"k":"v"}}`,
+			want: []manta.OperationLogChange{
+				{Op: "replace", Path: "/a~1b", From: json.RawMessage(`1`), Value: json.RawMessage(`2`)},
+				{Op: "remove", Path: "/desc", From: json.RawMessage(`"bar"`)},
+				{Op: "add", Path: "/labels", Value: json.RawMessage(`{"k":"v"}`)},
+			},
+		},
+		{
+			name:   "arrays",
+			before: `{"cells":[{"x":1},{"x":2},{"x":3}]}`,
+			after:  `{"cells":[{"x":1},{"x":4}]}`,
+			want: []manta.OperationLogChange{
+				{Op: "replace", Path: "/cells/1/x", From: json.RawMessage(`2`), Value: json.RawMessage(`4`)},
+				{Op: "remove", Path: "/cells/2", From: json.RawMessage(`{"x":3}`)},
+			},
+		},
+		{
+			name:   "type changed",
+			before: `{"data":[1]}`,
+			after:  `{"data":"1"}`,
+			want: []manta.OperationLogChange{
+				{Op: "replace", Path: "/data", From: json.RawMessage(`[1]`), Value: json.RawMessage(`"1"`)},
+			},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			changes, err := Diff([]byte(tc.before), []byte(tc.after))
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, changes)
+		})
+	}
+}
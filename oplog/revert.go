@@ -0,0 +1,167 @@
+package oplog
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/f1shl3gs/manta"
+	"github.com/f1shl3gs/manta/notification"
+)
+
+// Reverter restores resources to the body of an operation log entry, the
+// resources are updated through their services, so the revert is authorized
+// and logged like other updates.
+type Reverter struct {
+	oplogService                manta.OperationLogService
+	checkService                manta.CheckService
+	dashboardService            manta.DashboardService
+	configService               manta.ConfigService
+	notificationEndpointService manta.NotificationEndpointService
+}
+
+func NewReverter(
+	oplogService manta.OperationLogService,
+	checkService manta.CheckService,
+	dashboardService manta.DashboardService,
+	configService manta.ConfigService,
+	notificationEndpointService manta.NotificationEndpointService,
+) *Reverter {
+	return &Reverter{
+		oplogService:                oplogService,
+		checkService:                checkService,
+		dashboardService:            dashboardService,
+		configService:               configService,
+		notificationEndpointService: notificationEndpointService,
+	}
+}
+
+// Revert restores the resource to the body of the entry logged at the
+// time, the restored resource is returned.
+func (r *Reverter) Revert(ctx context.Context, resourceID manta.ID, at time.Time) (any, error) {
+	entries, _, err := r.oplogService.FindOperationLogs(ctx, manta.OperationLogFilter{
+		ResourceID: &resourceID,
+		Since:      &at,
+		Until:      &at,
+	}, manta.FindOptions{Limit: 1})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(entries) == 0 {
+		return nil, &manta.Error{
+			Code: manta.ENotFound,
+			Msg:  "operation log not found",
+		}
+	}
+
+	ent := entries[0]
+	if ent.Type == manta.Delete {
+		return nil, &manta.Error{
+			Code: manta.EInvalid,
+			Msg:  "reverting to a deletion is not supported",
+		}
+	}
+
+	switch ent.ResourceType {
+	case manta.ChecksResourceType:
+		return r.revertCheck(ctx, ent)
+	case manta.DashboardsResourceType:
+		return r.revertDashboard(ctx, ent)
+	case manta.ConfigsResourceType:
+		return r.revertConfig(ctx, ent)
+	case manta.NotificationEndpointsResourceType:
+		return r.revertNotificationEndpoint(ctx, ent)
+	default:
+		return nil, &manta.Error{
+			Code: manta.EInvalid,
+			Msg:  "reverting " + ent.ResourceType.String() + " is not supported",
+		}
+	}
+}
+
+func (r *Reverter) revertCheck(ctx context.Context, ent *manta.OperationLogEntry) (any, error) {
+	c := &manta.Check{}
+	if err := unmarshalBody(ent, c); err != nil {
+		return nil, err
+	}
+
+	return r.checkService.UpdateCheck(ctx, ent.ResourceID, c)
+}
+
+func (r *Reverter) revertDashboard(ctx context.Context, ent *manta.OperationLogEntry) (any, error) {
+	d := &manta.Dashboard{}
+	if err := unmarshalBody(ent, d); err != nil {
+		return nil, err
+	}
+
+	_, err := r.dashboardService.UpdateDashboard(ctx, manta.DashboardUpdate{
+		ID:        ent.ResourceID,
+		OrgID:     ent.OrgID,
+		Name:      &d.Name,
+		Desc:      &d.Desc,
+		Variables: &d.Variables,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	cells := d.Cells
+	if cells == nil {
+		cells = []manta.Cell{}
+	}
+
+	if err = r.dashboardService.ReplaceDashboardCells(ctx, ent.ResourceID, cells); err != nil {
+		return nil, err
+	}
+
+	return r.dashboardService.FindDashboardByID(ctx, ent.ResourceID)
+}
+
+func (r *Reverter) revertConfig(ctx context.Context, ent *manta.OperationLogEntry) (any, error) {
+	cf := &manta.Config{}
+	if err := unmarshalBody(ent, cf); err != nil {
+		return nil, err
+	}
+
+	rollout := cf.Rollout
+	if rollout == nil {
+		// turns off the rollout mode
+		rollout = &manta.RolloutPolicy{}
+	}
+
+	return r.configService.UpdateConfig(ctx, ent.ResourceID, manta.ConfigUpdate{
+		Name:     &cf.Name,
+		Desc:     &cf.Desc,
+		Data:     &cf.Data,
+		Format:   &cf.Format,
+		Schema:   &cf.Schema,
+		Template: &cf.Template,
+		Rollout:  rollout,
+	})
+}
+
+func (r *Reverter) revertNotificationEndpoint(ctx context.Context, ent *manta.OperationLogEntry) (any, error) {
+	ne, err := notification.UnmarshalJSON(ent.ResourceBody)
+	if err != nil {
+		return nil, &manta.Error{
+			Code: manta.EInternal,
+			Msg:  "decode resource body failed",
+			Err:  err,
+		}
+	}
+
+	return r.notificationEndpointService.UpdateNotificationEndpoint(ctx, ent.ResourceID, ne)
+}
+
+func unmarshalBody(ent *manta.OperationLogEntry, v any) error {
+	if err := json.Unmarshal(ent.ResourceBody, v); err != nil {
+		return &manta.Error{
+			Code: manta.EInternal,
+			Msg:  "decode resource body failed",
+			Err:  err,
+		}
+	}
+
+	return nil
+}
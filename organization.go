@@ -21,6 +21,13 @@ var (
 		Code: ENotFound,
 		Msg:  "Organization not found",
 	}
+
+	// ErrLastOwner is returned if the change leaves the organization
+	// without owner, then nobody can manage it.
+	ErrLastOwner = &Error{
+		Code: EConflict,
+		Msg:  "the last owner of the organization can't be removed or demoted",
+	}
 )
 
 type Organization struct {
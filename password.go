@@ -3,10 +3,16 @@ package manta
 import (
 	"context"
 	"errors"
+	"time"
 )
 
 var (
 	ErrPasswordNotMatch = errors.New("password not match")
+
+	ErrPasswordResetNotFound = &Error{
+		Code: ENotFound,
+		Msg:  "password reset token not found or expired",
+	}
 )
 
 type PasswordService interface {
@@ -16,6 +22,9 @@ type PasswordService interface {
 	// ComparePassword checks if the password matches the password stored
 	ComparePassword(ctx context.Context, uid ID, password string) error
 
+	// HasPassword returns true if the user has a password to sign in
+	HasPassword(ctx context.Context, uid ID) (bool, error)
+
 	// CompareAndSetPassword checks the password and if they match
 	// updates to the new password
 	CompareAndSetPassword(ctx context.Context, uid ID, old, new string) error
@@ -23,3 +32,21 @@ type PasswordService interface {
 	// DeletePassword delete password by user id
 	DeletePassword(ctx context.Context, uid ID) error
 }
+
+// PasswordReset is a one-time token issued by admins, which allows the user
+// to set a new password without the old one.
+type PasswordReset struct {
+	Token     string    `json:"token,omitempty"`
+	UserID    ID        `json:"userID"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+type PasswordResetService interface {
+	// CreatePasswordReset issues a reset token for the user, which expires
+	// after ttl
+	CreatePasswordReset(ctx context.Context, uid ID, ttl time.Duration) (*PasswordReset, error)
+
+	// ResetPassword sets the password of the user the token issued for,
+	// the token is consumed and all sessions of the user are revoked
+	ResetPassword(ctx context.Context, token, password string) error
+}
@@ -0,0 +1,464 @@
+// Package jsonschema implements a subset of JSON Schema which is enough to
+// validate configurations, the supported keywords are type, enum, const,
+// properties, required, additionalProperties, items, minItems, maxItems,
+// minimum, maximum, minLength, maxLength, pattern, allOf, anyOf, oneOf and
+// not. Annotations, e.g. title and description, are allowed but have no
+// effect. Schemas with other keywords, e.g. $ref and format, are rejected,
+// rather than accepting data they are meant to reject.
+package jsonschema
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Schema is a compiled JSON schema
+type Schema struct {
+	Type                 types              `json:"type,omitempty"`
+	Enum                 []any              `json:"enum,omitempty"`
+	Const                *any               `json:"const,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	AdditionalProperties *additional        `json:"additionalProperties,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	MinItems             *int               `json:"minItems,omitempty"`
+	MaxItems             *int               `json:"maxItems,omitempty"`
+	Minimum              *float64           `json:"minimum,omitempty"`
+	Maximum              *float64           `json:"maximum,omitempty"`
+	MinLength            *int               `json:"minLength,omitempty"`
+	MaxLength            *int               `json:"maxLength,omitempty"`
+	Pattern              string             `json:"pattern,omitempty"`
+	AllOf                []*Schema          `json:"allOf,omitempty"`
+	AnyOf                []*Schema          `json:"anyOf,omitempty"`
+	OneOf                []*Schema          `json:"oneOf,omitempty"`
+	Not                  *Schema            `json:"not,omitempty"`
+
+	pattern *regexp.Regexp
+}
+
+// annotations are keywords that don't affect validation
+var annotations = map[string]bool{
+	"$schema":     true,
+	"$id":         true,
+	"$comment":    true,
+	"title":       true,
+	"description": true,
+	"default":     true,
+	"examples":    true,
+	"deprecated":  true,
+	"readOnly":    true,
+	"writeOnly":   true,
+}
+
+// keywords are the keywords implemented, which are the json names of the
+// fields of Schema
+var keywords = func() map[string]bool {
+	m := make(map[string]bool)
+	typ := reflect.TypeOf(Schema{})
+	for i := 0; i < typ.NumField(); i++ {
+		name, _, _ := strings.Cut(typ.Field(i).Tag.Get("json"), ",")
+		if name != "" {
+			m[name] = true
+		}
+	}
+
+	return m
+}()
+
+// UnmarshalJSON rejects the keywords not implemented
+func (s *Schema) UnmarshalJSON(data []byte) error {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return err
+	}
+
+	for _, key := range sortedKeys(fields) {
+		if !keywords[key] && !annotations[key] {
+			return fmt.Errorf("keyword %q is not supported", key)
+		}
+	}
+
+	// alias has no UnmarshalJSON, so it's not called recursively
+	type alias Schema
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+
+	return dec.Decode((*alias)(s))
+}
+
+// Error is a violation of the schema
+type Error struct {
+	// Path is the JSON pointer of the invalid value
+	Path    string
+	Message string
+}
+
+func (e Error) Error() string {
+	if e.Path == "" {
+		return e.Message
+	}
+
+	return e.Path + ": " + e.Message
+}
+
+// types is the type keyword, which is a string or an array of strings
+type types []string
+
+func (t *types) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*t = types{single}
+		return nil
+	}
+
+	var multi []string
+	if err := json.Unmarshal(data, &multi); err != nil {
+		return fmt.Errorf("type must be a string or an array of strings")
+	}
+
+	*t = multi
+	return nil
+}
+
+// additional is the additionalProperties keyword, which is a boolean or a schema
+type additional struct {
+	allowed bool
+	schema  *Schema
+}
+
+func (a *additional) UnmarshalJSON(data []byte) error {
+	if err := json.Unmarshal(data, &a.allowed); err == nil {
+		return nil
+	}
+
+	a.allowed = true
+	a.schema = &Schema{}
+	return json.Unmarshal(data, a.schema)
+}
+
+// Compile parses the schema document
+func Compile(data []byte) (*Schema, error) {
+	s := &Schema{}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err := dec.Decode(s); err != nil {
+		return nil, fmt.Errorf("invalid schema, %w", err)
+	}
+
+	if err := s.compile(); err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+func (s *Schema) compile() error {
+	if s == nil {
+		return nil
+	}
+
+	for _, typ := range s.Type {
+		switch typ {
+		case "null", "boolean", "object", "array", "number", "integer", "string":
+		default:
+			return fmt.Errorf("invalid schema, unknown type %q", typ)
+		}
+	}
+
+	if s.Pattern != "" {
+		re, err := regexp.Compile(s.Pattern)
+		if err != nil {
+			return fmt.Errorf("invalid schema, %w", err)
+		}
+
+		s.pattern = re
+	}
+
+	children := []*Schema{s.Items, s.Not}
+	for _, p := range s.Properties {
+		children = append(children, p)
+	}
+	if s.AdditionalProperties != nil {
+		children = append(children, s.AdditionalProperties.schema)
+	}
+	children = append(children, s.AllOf...)
+	children = append(children, s.AnyOf...)
+	children = append(children, s.OneOf...)
+
+	for _, child := range children {
+		if err := child.compile(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Validate validates the value decoded from JSON, YAML or TOML against
+// the schema, all violations are returned.
+func (s *Schema) Validate(v any) []Error {
+	return s.validate("", normalize(v))
+}
+
+func (s *Schema) validate(path string, v any) []Error {
+	var errs []Error
+	fail := func(format string, args ...any) {
+		errs = append(errs, Error{
+			Path:    path,
+			Message: fmt.Sprintf(format, args...),
+		})
+	}
+
+	if len(s.Type) > 0 && !s.Type.match(v) {
+		fail("expected %s, but got %s", strings.Join(s.Type, " or "), typeOf(v))
+		return errs
+	}
+
+	if len(s.Enum) > 0 {
+		found := false
+		for _, e := range s.Enum {
+			if equal(normalize(e), v) {
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			fail("value must be one of %s", formatValues(s.Enum))
+		}
+	}
+
+	if s.Const != nil && !equal(normalize(*s.Const), v) {
+		fail("value must be %s", formatValues([]any{*s.Const}))
+	}
+
+	switch value := v.(type) {
+	case map[string]any:
+		for _, name := range s.Required {
+			if _, ok := value[name]; !ok {
+				fail("missing required property %q", name)
+			}
+		}
+
+		for _, key := range sortedKeys(value) {
+			child := path + "/" + escape(key)
+			if ps, ok := s.Properties[key]; ok {
+				errs = append(errs, ps.validate(child, value[key])...)
+				continue
+			}
+
+			if ap := s.AdditionalProperties; ap != nil {
+				if !ap.allowed {
+					errs = append(errs, Error{Path: child, Message: "additional property is not allowed"})
+				} else if ap.schema != nil {
+					errs = append(errs, ap.schema.validate(child, value[key])...)
+				}
+			}
+		}
+
+	case []any:
+		if s.MinItems != nil && len(value) < *s.MinItems {
+			fail("expected at least %d items, but got %d", *s.MinItems, len(value))
+		}
+
+		if s.MaxItems != nil && len(value) > *s.MaxItems {
+			fail("expected at most %d items, but got %d", *s.MaxItems, len(value))
+		}
+
+		if s.Items != nil {
+			for i, item := range value {
+				errs = append(errs, s.Items.validate(path+"/"+strconv.Itoa(i), item)...)
+			}
+		}
+
+	case float64:
+		if s.Minimum != nil && value < *s.Minimum {
+			fail("value must be >= %v", *s.Minimum)
+		}
+
+		if s.Maximum != nil && value > *s.Maximum {
+			fail("value must be <= %v", *s.Maximum)
+		}
+
+	case string:
+		n := utf8.RuneCountInString(value)
+		if s.MinLength != nil && n < *s.MinLength {
+			fail("length must be >= %d", *s.MinLength)
+		}
+
+		if s.MaxLength != nil && n > *s.MaxLength {
+			fail("length must be <= %d", *s.MaxLength)
+		}
+
+		if s.pattern != nil && !s.pattern.MatchString(value) {
+			fail("value does not match pattern %q", s.Pattern)
+		}
+	}
+
+	for _, sub := range s.AllOf {
+		errs = append(errs, sub.validate(path, v)...)
+	}
+
+	if len(s.AnyOf) > 0 {
+		matched := false
+		for _, sub := range s.AnyOf {
+			if len(sub.validate(path, v)) == 0 {
+				matched = true
+				break
+			}
+		}
+
+		if !matched {
+			fail("value must match at least one schema of anyOf")
+		}
+	}
+
+	if len(s.OneOf) > 0 {
+		matched := 0
+		for _, sub := range s.OneOf {
+			if len(sub.validate(path, v)) == 0 {
+				matched += 1
+			}
+		}
+
+		if matched != 1 {
+			fail("value must match exactly one schema of oneOf, but matched %d", matched)
+		}
+	}
+
+	if s.Not != nil && len(s.Not.validate(path, v)) == 0 {
+		fail("value must not match the schema of not")
+	}
+
+	return errs
+}
+
+func (t types) match(v any) bool {
+	actual := typeOf(v)
+	for _, typ := range t {
+		if typ == actual {
+			return true
+		}
+
+		if typ == "number" && actual == "integer" {
+			return true
+		}
+	}
+
+	return false
+}
+
+func typeOf(v any) string {
+	switch value := v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case map[string]any:
+		return "object"
+	case []any:
+		return "array"
+	case float64:
+		if value == math.Trunc(value) && !math.IsInf(value, 0) {
+			return "integer"
+		}
+
+		return "number"
+	case string:
+		return "string"
+	default:
+		return fmt.Sprintf("%T", v)
+	}
+}
+
+// normalize converts values decoded by different decoders to the types
+// encoding/json produces, e.g. integers to float64.
+func normalize(v any) any {
+	switch value := v.(type) {
+	case json.Number:
+		f, _ := value.Float64()
+		return f
+	case int:
+		return float64(value)
+	case int8:
+		return float64(value)
+	case int16:
+		return float64(value)
+	case int32:
+		return float64(value)
+	case int64:
+		return float64(value)
+	case uint:
+		return float64(value)
+	case uint8:
+		return float64(value)
+	case uint16:
+		return float64(value)
+	case uint32:
+		return float64(value)
+	case uint64:
+		return float64(value)
+	case float32:
+		return float64(value)
+	case fmt.Stringer:
+		// e.g. time values of TOML
+		return value.String()
+	case map[string]any:
+		m := make(map[string]any, len(value))
+		for k, item := range value {
+			m[k] = normalize(item)
+		}
+		return m
+	case map[any]any:
+		m := make(map[string]any, len(value))
+		for k, item := range value {
+			m[fmt.Sprint(k)] = normalize(item)
+		}
+		return m
+	case []any:
+		list := make([]any, len(value))
+		for i, item := range value {
+			list[i] = normalize(item)
+		}
+		return list
+	default:
+		return v
+	}
+}
+
+func equal(a, b any) bool {
+	return reflect.DeepEqual(a, b)
+}
+
+func formatValues(values []any) string {
+	parts := make([]string, 0, len(values))
+	for _, v := range values {
+		data, _ := json.Marshal(v)
+		parts = append(parts, string(data))
+	}
+
+	return strings.Join(parts, ", ")
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
+
+// escape escapes the key as a JSON pointer token
+func escape(key string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(key, "~", "~0"), "/", "~1")
+}
@@ -0,0 +1,102 @@
+package jsonschema
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testSchema = `{
+  "type": "object",
+  "required": ["name", "port"],
+  "additionalProperties": false,
+  "properties": {
+    "name": {"type": "string", "minLength": 1, "pattern": "^[a-z]+$"},
+    "port": {"type": "integer", "minimum": 1, "maximum": 65535},
+    "level": {"enum": ["debug", "info"]},
+    "tags": {"type": "array", "maxItems": 2, "items": {"type": "string"}},
+    "timeout": {"anyOf": [{"type": "string"}, {"type": "number"}]}
+  }
+}`
+
+func TestValidate(t *testing.T) {
+	schema, err := Compile([]byte(testSchema))
+	require.NoError(t, err)
+
+	for name, tc := range map[string]struct {
+		input string
+		want  []Error
+	}{
+		"valid": {
+			input: `{"name": "foo", "port": 80, "level": "info", "tags": ["a"], "timeout": "1s"}`,
+		},
+		"missing required": {
+			input: `{"name": "foo"}`,
+			want:  []Error{{Path: "", Message: `missing required property "port"`}},
+		},
+		"wrong types": {
+			input: `{"name": 1, "port": 1.5}`,
+			want: []Error{
+				{Path: "/name", Message: "expected string, but got integer"},
+				{Path: "/port", Message: "expected integer, but got number"},
+			},
+		},
+		"constraints": {
+			input: `{"name": "Foo", "port": 70000, "level": "warn", "tags": ["a", "b", "c"], "timeout": true, "extra": 1}`,
+			want: []Error{
+				{Path: "/extra", Message: "additional property is not allowed"},
+				{Path: "/level", Message: `value must be one of "debug", "info"`},
+				{Path: "/name", Message: `value does not match pattern "^[a-z]+$"`},
+				{Path: "/port", Message: "value must be <= 65535"},
+				{Path: "/tags", Message: "expected at most 2 items, but got 3"},
+				{Path: "/timeout", Message: "value must match at least one schema of anyOf"},
+			},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			var v any
+			err := json.Unmarshal([]byte(tc.input), &v)
+			require.NoError(t, err)
+
+			assert.Equal(t, tc.want, schema.Validate(v))
+		})
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	schema, err := Compile([]byte(`{"type": "object", "properties": {"port": {"type": "integer"}}}`))
+	require.NoError(t, err)
+
+	// decoders of YAML and TOML produce int and int64
+	assert.Empty(t, schema.Validate(map[string]any{"port": 80}))
+	assert.Empty(t, schema.Validate(map[string]any{"port": int64(80)}))
+}
+
+func TestCompile(t *testing.T) {
+	_, err := Compile([]byte(`{"type": "foo"}`))
+	assert.Error(t, err)
+
+	_, err = Compile([]byte(`{"pattern": "("}`))
+	assert.Error(t, err)
+
+	_, err = Compile([]byte(`{"additionalProperties": {"type": "string"}}`))
+	assert.NoError(t, err)
+
+	// annotations have no effect
+	_, err = Compile([]byte(`{"$schema": "http://json-schema.org/draft-07/schema#", "title": "foo", "type": "object"}`))
+	assert.NoError(t, err)
+
+	// unsupported keywords are rejected, even they are nested
+	for _, schema := range []string{
+		`{"$ref": "#/definitions/foo"}`,
+		`{"type": "string", "format": "email"}`,
+		`{"properties": {"foo": {"type": "string", "format": "email"}}}`,
+		`{"items": {"uniqueItems": true}}`,
+		`{"additionalProperties": {"$ref": "#"}}`,
+	} {
+		_, err = Compile([]byte(schema))
+		assert.Error(t, err, schema)
+	}
+}
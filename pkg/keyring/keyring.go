@@ -0,0 +1,202 @@
+package keyring
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+const (
+	// KeySize is the size of master keys and data keys, AES-256 is used.
+	KeySize = 32
+)
+
+var (
+	ErrInvalidKeySize = errors.New("invalid key size, 32 bytes are required")
+
+	ErrKeyNotFound = errors.New("master key not found")
+
+	ErrCiphertextTooShort = errors.New("ciphertext is too short")
+)
+
+type masterKey struct {
+	id   string
+	aead cipher.AEAD
+}
+
+// Keyring holds the master keys which are used to wrap data keys. New data
+// keys are always wrapped with the primary key, the previous keys are kept
+// to unwrap data keys during rotation.
+type Keyring struct {
+	primary *masterKey
+	keys    map[string]*masterKey
+}
+
+// New creates a Keyring with the primary key and previous keys
+func New(primary []byte, previous ...[]byte) (*Keyring, error) {
+	kr := &Keyring{
+		keys: make(map[string]*masterKey, len(previous)+1),
+	}
+
+	for i, key := range append([][]byte{primary}, previous...) {
+		aead, err := newAEAD(key)
+		if err != nil {
+			return nil, err
+		}
+
+		mk := &masterKey{
+			id:   KeyID(key),
+			aead: aead,
+		}
+
+		if i == 0 {
+			kr.primary = mk
+		}
+
+		kr.keys[mk.id] = mk
+	}
+
+	return kr, nil
+}
+
+// PrimaryID returns the id of the primary key
+func (kr *Keyring) PrimaryID() string {
+	return kr.primary.id
+}
+
+// Wrap encrypts the data key with the primary key, and returns the id
+// of the primary key.
+func (kr *Keyring) Wrap(dataKey, ad []byte) (string, []byte, error) {
+	ciphertext, err := seal(kr.primary.aead, dataKey, ad)
+	if err != nil {
+		return "", nil, err
+	}
+
+	return kr.primary.id, ciphertext, nil
+}
+
+// Unwrap decrypts the data key with the master key of id
+func (kr *Keyring) Unwrap(id string, wrapped, ad []byte) ([]byte, error) {
+	mk, ok := kr.keys[id]
+	if !ok {
+		return nil, fmt.Errorf("%w, id: %s", ErrKeyNotFound, id)
+	}
+
+	return open(mk.aead, wrapped, ad)
+}
+
+// GenerateKey returns a random key
+func GenerateKey() ([]byte, error) {
+	key := make([]byte, KeySize)
+	if _, err := io.ReadFull(rand.Reader, key); err != nil {
+		return nil, err
+	}
+
+	return key, nil
+}
+
+// KeyID returns a short and stable identifier of the key, the key
+// itself cannot be derived from it.
+func KeyID(key []byte) string {
+	sum := sha256.Sum256(key)
+	return hex.EncodeToString(sum[:4])
+}
+
+// ParseKey decodes the base64 encoded key
+func ParseKey(text []byte) ([]byte, error) {
+	text = bytes.TrimSpace(text)
+	key := make([]byte, base64.StdEncoding.DecodedLen(len(text)))
+	n, err := base64.StdEncoding.Decode(key, text)
+	if err != nil {
+		return nil, fmt.Errorf("decode key failed, %w", err)
+	}
+
+	if n != KeySize {
+		return nil, ErrInvalidKeySize
+	}
+
+	return key[:n], nil
+}
+
+// EncodeKey encodes the key with base64, so it can be stored in
+// a file or environment variable.
+func EncodeKey(key []byte) []byte {
+	buf := make([]byte, base64.StdEncoding.EncodedLen(len(key)))
+	base64.StdEncoding.Encode(buf, key)
+	return buf
+}
+
+// LoadKeyFile reads the base64 encoded key from file
+func LoadKeyFile(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := ParseKey(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid key file %q, %w", path, err)
+	}
+
+	return key, nil
+}
+
+// Seal encrypts plaintext with AES-GCM, the random nonce is prepended
+// to the returned ciphertext.
+func Seal(key, plaintext, ad []byte) ([]byte, error) {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return seal(aead, plaintext, ad)
+}
+
+// Open decrypts ciphertext returned by Seal
+func Open(key, ciphertext, ad []byte) ([]byte, error) {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return open(aead, ciphertext, ad)
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	if len(key) != KeySize {
+		return nil, ErrInvalidKeySize
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
+
+func seal(aead cipher.AEAD, plaintext, ad []byte) ([]byte, error) {
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(plaintext)+aead.Overhead())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+
+	return aead.Seal(nonce, nonce, plaintext, ad), nil
+}
+
+func open(aead cipher.AEAD, ciphertext, ad []byte) ([]byte, error) {
+	ns := aead.NonceSize()
+	if len(ciphertext) < ns+aead.Overhead() {
+		return nil, ErrCiphertextTooShort
+	}
+
+	return aead.Open(nil, ciphertext[:ns], ciphertext[ns:], ad)
+}
@@ -0,0 +1,81 @@
+package keyring
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSealOpen(t *testing.T) {
+	key, err := GenerateKey()
+	require.NoError(t, err)
+
+	ciphertext, err := Seal(key, []byte("password"), []byte("ad"))
+	require.NoError(t, err)
+	assert.NotContains(t, string(ciphertext), "password")
+
+	plaintext, err := Open(key, ciphertext, []byte("ad"))
+	require.NoError(t, err)
+	assert.Equal(t, "password", string(plaintext))
+
+	// additional data must match
+	_, err = Open(key, ciphertext, []byte("other"))
+	assert.Error(t, err)
+
+	_, err = Open(key, ciphertext[:4], []byte("ad"))
+	assert.ErrorIs(t, err, ErrCiphertextTooShort)
+
+	_, err = Seal(key[:16], []byte("password"), nil)
+	assert.ErrorIs(t, err, ErrInvalidKeySize)
+}
+
+func TestRotation(t *testing.T) {
+	oldKey, err := GenerateKey()
+	require.NoError(t, err)
+	newKey, err := GenerateKey()
+	require.NoError(t, err)
+
+	dataKey, err := GenerateKey()
+	require.NoError(t, err)
+
+	old, err := New(oldKey)
+	require.NoError(t, err)
+
+	id, wrapped, err := old.Wrap(dataKey, []byte("org"))
+	require.NoError(t, err)
+	assert.Equal(t, KeyID(oldKey), id)
+
+	// the new keyring can unwrap data keys wrapped by the previous key
+	kr, err := New(newKey, oldKey)
+	require.NoError(t, err)
+	assert.Equal(t, KeyID(newKey), kr.PrimaryID())
+
+	unwrapped, err := kr.Unwrap(id, wrapped, []byte("org"))
+	require.NoError(t, err)
+	assert.Equal(t, dataKey, unwrapped)
+
+	// the previous key is dropped after rotation
+	kr, err = New(newKey)
+	require.NoError(t, err)
+	_, err = kr.Unwrap(id, wrapped, []byte("org"))
+	assert.ErrorIs(t, err, ErrKeyNotFound)
+}
+
+func TestLoadKeyFile(t *testing.T) {
+	key, err := GenerateKey()
+	require.NoError(t, err)
+
+	path := filepath.Join(t.TempDir(), "secret.key")
+	err = os.WriteFile(path, append(EncodeKey(key), '\n'), 0600)
+	require.NoError(t, err)
+
+	loaded, err := LoadKeyFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, key, loaded)
+
+	_, err = ParseKey([]byte("c2hvcnQ="))
+	assert.ErrorIs(t, err, ErrInvalidKeySize)
+}
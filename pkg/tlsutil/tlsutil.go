@@ -0,0 +1,328 @@
+package tlsutil
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const (
+	// reloadInterval is the interval to check whether the certificate
+	// files changed.
+	reloadInterval = 10 * time.Second
+)
+
+var (
+	ErrNoPeerCertificate = errors.New("no peer certificate")
+	ErrSANNotAllowed     = errors.New("peer certificate SAN is not allowed")
+	ErrClientCARequired  = errors.New("CA file is required to verify client certificates")
+)
+
+// Config is the TLS configuration of both server and client side.
+type Config struct {
+	// CertFile and KeyFile is the certificate used to serve or to
+	// identify itself when connecting to peers.
+	CertFile string
+	KeyFile  string
+
+	// CAFile is used to verify the certificate of the other side,
+	// system's CA will be used if not set. It is required if ClientAuth
+	// is enabled, system's CA must not be trusted to identify peers.
+	CAFile string
+
+	// ClientAuth requires client to present a certificate signed by
+	// the CA, aka. mutual TLS
+	ClientAuth bool
+
+	// AllowedSANs restrict the SANs of the certificate of the other
+	// side, a certificate is accepted if one of its DNS names, IP
+	// addresses, URIs or emails is allowed. IP addresses, URIs and
+	// emails must match exactly, DNS names support a wildcard left-most
+	// label like "*.example.com", which matches one label only.
+	AllowedSANs []string
+}
+
+// Enabled returns true if the certificate is configured
+func (cf *Config) Enabled() bool {
+	return cf != nil && cf.CertFile != "" && cf.KeyFile != ""
+}
+
+// Manager loads certificates and CA from files, and reload them
+// once the files changed. The tls.Config returned by Manager
+// always use the latest certificates.
+type Manager struct {
+	cf     Config
+	logger *zap.Logger
+
+	mtx     sync.RWMutex
+	cert    *tls.Certificate
+	pool    *x509.CertPool
+	modTime time.Time
+}
+
+// New creates a Manager and load the certificates
+func New(cf Config, logger *zap.Logger) (*Manager, error) {
+	if cf.ClientAuth && cf.CAFile == "" {
+		return nil, ErrClientCARequired
+	}
+
+	m := &Manager{
+		cf:     cf,
+		logger: logger.With(zap.String("service", "tls")),
+	}
+
+	if err := m.reload(); err != nil {
+		return nil, err
+	}
+
+	return m, nil
+}
+
+// Run checks the certificate files periodically, and reload them
+// if any of them changed.
+func (m *Manager) Run(ctx context.Context) {
+	ticker := time.NewTicker(reloadInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		modTime, err := m.latestModTime()
+		if err != nil {
+			m.logger.Warn("stat certificate files failed",
+				zap.Error(err))
+			continue
+		}
+
+		m.mtx.RLock()
+		changed := modTime.After(m.modTime)
+		m.mtx.RUnlock()
+		if !changed {
+			continue
+		}
+
+		if err = m.reload(); err != nil {
+			m.logger.Warn("reload certificates failed, keep using the previous ones",
+				zap.Error(err))
+			continue
+		}
+
+		m.logger.Info("certificates reloaded",
+			zap.String("cert", m.cf.CertFile),
+			zap.String("ca", m.cf.CAFile))
+	}
+}
+
+func (m *Manager) latestModTime() (time.Time, error) {
+	var latest time.Time
+
+	for _, file := range []string{m.cf.CertFile, m.cf.KeyFile, m.cf.CAFile} {
+		if file == "" {
+			continue
+		}
+
+		fi, err := os.Stat(file)
+		if err != nil {
+			return time.Time{}, err
+		}
+
+		if fi.ModTime().After(latest) {
+			latest = fi.ModTime()
+		}
+	}
+
+	return latest, nil
+}
+
+func (m *Manager) reload() error {
+	modTime, err := m.latestModTime()
+	if err != nil {
+		return err
+	}
+
+	cert, err := tls.LoadX509KeyPair(m.cf.CertFile, m.cf.KeyFile)
+	if err != nil {
+		return fmt.Errorf("load key pair failed, %w", err)
+	}
+
+	var pool *x509.CertPool
+	if m.cf.CAFile != "" {
+		data, err := os.ReadFile(m.cf.CAFile)
+		if err != nil {
+			return fmt.Errorf("read CA file failed, %w", err)
+		}
+
+		pool = x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(data) {
+			return fmt.Errorf("no valid certificate found in CA file %q", m.cf.CAFile)
+		}
+	} else {
+		pool, err = x509.SystemCertPool()
+		if err != nil {
+			return fmt.Errorf("load system CA failed, %w", err)
+		}
+	}
+
+	m.mtx.Lock()
+	m.cert = &cert
+	m.pool = pool
+	m.modTime = modTime
+	m.mtx.Unlock()
+
+	return nil
+}
+
+func (m *Manager) certificate() *tls.Certificate {
+	m.mtx.RLock()
+	defer m.mtx.RUnlock()
+
+	return m.cert
+}
+
+func (m *Manager) certPool() *x509.CertPool {
+	m.mtx.RLock()
+	defer m.mtx.RUnlock()
+
+	return m.pool
+}
+
+// ServerConfig returns a tls.Config for servers.
+//
+// ALPN is not negotiated, so the listener can be shared by HTTP/1 and
+// gRPC clients with cmux.
+func (m *Manager) ServerConfig() *tls.Config {
+	cfg := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		GetCertificate: func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+			return m.certificate(), nil
+		},
+	}
+
+	if m.cf.ClientAuth {
+		// client certificate is verified by VerifyConnection, so the
+		// latest CA is used
+		cfg.ClientAuth = tls.RequireAnyClientCert
+		cfg.VerifyConnection = func(cs tls.ConnectionState) error {
+			return m.verify(cs.PeerCertificates, "", x509.ExtKeyUsageClientAuth)
+		}
+	}
+
+	return cfg
+}
+
+// ClientConfig returns a tls.Config for clients, the certificate is
+// presented if the server requires.
+func (m *Manager) ClientConfig() *tls.Config {
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		GetClientCertificate: func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			return m.certificate(), nil
+		},
+		// The server certificate is verified by VerifyConnection, so the
+		// latest CA is used
+		InsecureSkipVerify: true,
+		VerifyConnection: func(cs tls.ConnectionState) error {
+			return m.verify(cs.PeerCertificates, cs.ServerName, x509.ExtKeyUsageServerAuth)
+		},
+	}
+}
+
+// verify checks the certificates of the other side is signed by the CA,
+// and the SANs are allowed. If AllowedSANs is not set, serverName is
+// verified instead.
+func (m *Manager) verify(certs []*x509.Certificate, serverName string, usage x509.ExtKeyUsage) error {
+	if len(certs) == 0 {
+		return ErrNoPeerCertificate
+	}
+
+	opts := x509.VerifyOptions{
+		Roots:         m.certPool(),
+		Intermediates: x509.NewCertPool(),
+		KeyUsages:     []x509.ExtKeyUsage{usage},
+	}
+	for _, cert := range certs[1:] {
+		opts.Intermediates.AddCert(cert)
+	}
+
+	if len(m.cf.AllowedSANs) == 0 {
+		opts.DNSName = serverName
+	}
+
+	_, err := certs[0].Verify(opts)
+	if err != nil {
+		return err
+	}
+
+	if len(m.cf.AllowedSANs) == 0 {
+		return nil
+	}
+
+	if !m.allowed(certs[0]) {
+		return ErrSANNotAllowed
+	}
+
+	return nil
+}
+
+func (m *Manager) allowed(cert *x509.Certificate) bool {
+	for _, pattern := range m.cf.AllowedSANs {
+		for _, name := range cert.DNSNames {
+			if matchDNSName(pattern, name) {
+				return true
+			}
+		}
+
+		for _, email := range cert.EmailAddresses {
+			if pattern == email {
+				return true
+			}
+		}
+
+		for _, ip := range cert.IPAddresses {
+			if allowed := net.ParseIP(pattern); allowed != nil && allowed.Equal(ip) {
+				return true
+			}
+		}
+
+		for _, uri := range cert.URIs {
+			if pattern == uri.String() {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// matchDNSName matches name with pattern case-insensitively, the wildcard
+// is only allowed as the whole left-most label, and matches exactly one
+// label, e.g. "*.example.com" matches "a.example.com" but neither
+// "example.com" nor "a.b.example.com".
+func matchDNSName(pattern, name string) bool {
+	pattern = strings.TrimSuffix(strings.ToLower(pattern), ".")
+	name = strings.TrimSuffix(strings.ToLower(name), ".")
+
+	if !strings.HasPrefix(pattern, "*.") {
+		return pattern == name
+	}
+
+	label, rest, found := strings.Cut(name, ".")
+	if !found || label == "" || label == "*" {
+		return false
+	}
+
+	return rest == pattern[2:]
+}
@@ -0,0 +1,264 @@
+package tlsutil
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"io"
+	"math/big"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+type certAuthority struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+}
+
+func newCA(t *testing.T, name string) *certAuthority {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	require.NoError(t, err)
+
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+
+	return &certAuthority{cert: cert, key: key}
+}
+
+func (ca *certAuthority) writeCA(t *testing.T, dir string) string {
+	file := filepath.Join(dir, "ca.pem")
+	err := os.WriteFile(file, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ca.cert.Raw}), 0600)
+	require.NoError(t, err)
+
+	return file
+}
+
+// issue issues a certificate for both server and client auth, and write
+// the certificate and key to dir
+func (ca *certAuthority) issue(t *testing.T, dir, name string, dnsNames ...string) (string, string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		DNSNames:     dnsNames,
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, ca.cert, &key.PublicKey, ca.key)
+	require.NoError(t, err)
+
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	certFile := filepath.Join(dir, name+".pem")
+	keyFile := filepath.Join(dir, name+"-key.pem")
+	err = os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600)
+	require.NoError(t, err)
+	err = os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}), 0600)
+	require.NoError(t, err)
+
+	return certFile, keyFile
+}
+
+// handshake runs a TLS server with the server config, and returns the
+// error of server side and client side handshake
+func handshake(t *testing.T, server, client *tls.Config) (error, error) {
+	l, err := tls.Listen("tcp", "127.0.0.1:0", server)
+	require.NoError(t, err)
+	defer l.Close()
+
+	errCh := make(chan error, 1)
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			errCh <- err
+			return
+		}
+		defer conn.Close()
+
+		errCh <- conn.(*tls.Conn).Handshake()
+	}()
+
+	conn, err := tls.Dial("tcp", l.Addr().String(), client)
+	if err == nil {
+		// make sure the server side handshake is finished, the client
+		// side returns before the server verifies client certificate
+		_, err = conn.Read(make([]byte, 1))
+		if errors.Is(err, io.EOF) {
+			err = nil
+		}
+		conn.Close()
+	}
+
+	return <-errCh, err
+}
+
+func TestMutualTLS(t *testing.T) {
+	dir := t.TempDir()
+	ca := newCA(t, "ca")
+	caFile := ca.writeCA(t, dir)
+	serverCert, serverKey := ca.issue(t, dir, "server", "node1.manta.local")
+	clientCert, clientKey := ca.issue(t, dir, "client", "node2.manta.local")
+
+	other := newCA(t, "other")
+	otherCert, otherKey := other.issue(t, dir, "other", "node3.manta.local")
+
+	newManager := func(cert, key string, sans ...string) *Manager {
+		m, err := New(Config{
+			CertFile:    cert,
+			KeyFile:     key,
+			CAFile:      caFile,
+			ClientAuth:  true,
+			AllowedSANs: sans,
+		}, zap.NewNop())
+		require.NoError(t, err)
+		return m
+	}
+
+	t.Run("trusted peer", func(t *testing.T) {
+		server := newManager(serverCert, serverKey, "*.manta.local")
+		client := newManager(clientCert, clientKey, "node1.manta.local")
+
+		serverErr, clientErr := handshake(t, server.ServerConfig(), client.ClientConfig())
+		assert.NoError(t, serverErr)
+		assert.NoError(t, clientErr)
+	})
+
+	t.Run("untrusted CA", func(t *testing.T) {
+		server := newManager(serverCert, serverKey)
+		client := newManager(otherCert, otherKey)
+
+		serverErr, _ := handshake(t, server.ServerConfig(), client.ClientConfig())
+		assert.Error(t, serverErr)
+	})
+
+	t.Run("SAN not allowed", func(t *testing.T) {
+		server := newManager(serverCert, serverKey, "node1.manta.local")
+		client := newManager(clientCert, clientKey)
+
+		serverErr, _ := handshake(t, server.ServerConfig(), client.ClientConfig())
+		assert.ErrorIs(t, serverErr, ErrSANNotAllowed)
+	})
+
+	t.Run("server name mismatch", func(t *testing.T) {
+		server := newManager(serverCert, serverKey)
+		client := newManager(clientCert, clientKey)
+
+		cfg := client.ClientConfig()
+		cfg.ServerName = "node9.manta.local"
+		_, clientErr := handshake(t, server.ServerConfig(), cfg)
+		assert.Error(t, clientErr)
+	})
+}
+
+func TestReload(t *testing.T) {
+	dir := t.TempDir()
+	ca := newCA(t, "ca")
+	caFile := ca.writeCA(t, dir)
+	certFile, keyFile := ca.issue(t, dir, "server", "node1.manta.local")
+
+	m, err := New(Config{
+		CertFile: certFile,
+		KeyFile:  keyFile,
+		CAFile:   caFile,
+	}, zap.NewNop())
+	require.NoError(t, err)
+
+	prev := m.certificate()
+
+	// rotate the CA and certificate
+	time.Sleep(10 * time.Millisecond)
+	ca = newCA(t, "ca")
+	ca.writeCA(t, dir)
+	ca.issue(t, dir, "server", "node1.manta.local")
+
+	err = m.reload()
+	require.NoError(t, err)
+	assert.NotEqual(t, prev.Certificate[0], m.certificate().Certificate[0])
+
+	leaf, err := x509.ParseCertificate(m.certificate().Certificate[0])
+	require.NoError(t, err)
+	err = m.verify([]*x509.Certificate{leaf}, "node1.manta.local", x509.ExtKeyUsageServerAuth)
+	assert.NoError(t, err)
+}
+
+func TestAllowed(t *testing.T) {
+	uri, err := url.Parse("spiffe://manta.local/node/1")
+	require.NoError(t, err)
+
+	cert := &x509.Certificate{
+		DNSNames:       []string{"a.b.manta.local"},
+		EmailAddresses: []string{"node@manta.local"},
+		IPAddresses:    []net.IP{net.ParseIP("10.0.0.1")},
+		URIs:           []*url.URL{uri},
+	}
+
+	for _, tc := range []struct {
+		pattern string
+		want    bool
+	}{
+		{pattern: "a.b.manta.local", want: true},
+		{pattern: "A.B.Manta.Local.", want: true},
+		{pattern: "*.b.manta.local", want: true},
+		{pattern: "*.manta.local", want: false},
+		{pattern: "*", want: false},
+		{pattern: "a.*.manta.local", want: false},
+		{pattern: "node@manta.local", want: true},
+		{pattern: "*@manta.local", want: false},
+		{pattern: "10.0.0.1", want: true},
+		{pattern: "10.0.0.*", want: false},
+		{pattern: "spiffe://manta.local/node/1", want: true},
+		{pattern: "spiffe://manta.local/node/*", want: false},
+		{pattern: "spiffe://manta.local/node", want: false},
+	} {
+		t.Run(tc.pattern, func(t *testing.T) {
+			m := &Manager{cf: Config{AllowedSANs: []string{tc.pattern}}}
+			assert.Equal(t, tc.want, m.allowed(cert))
+		})
+	}
+}
+
+func TestClientAuthRequiresCA(t *testing.T) {
+	dir := t.TempDir()
+	ca := newCA(t, "ca")
+	certFile, keyFile := ca.issue(t, dir, "server", "node1.manta.local")
+
+	_, err := New(Config{
+		CertFile:   certFile,
+		KeyFile:    keyFile,
+		ClientAuth: true,
+	}, zap.NewNop())
+	assert.ErrorIs(t, err, ErrClientCARequired)
+}
@@ -1,10 +1,19 @@
 package raftstore
 
+import (
+	"crypto/tls"
+)
+
 type Config struct {
+	// Peers is the address of all initial members, it is used to bootstrap
+	// a new multi-node cluster, and it must contain the Listen address.
 	Peers   []string
 	DataDir string
 
 	// Listen is the address, grpc server will listen to
 	Listen       string
 	DefragOnBoot bool
+
+	// PeerTLS is used to connect to peers, connections are insecure if it is nil.
+	PeerTLS *tls.Config
 }
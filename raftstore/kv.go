@@ -33,13 +33,23 @@ func (s *Store) DeleteBucket(ctx context.Context, bucket []byte) error {
 
 // View opens up a transaction that will not write to any value. Implementing interfaces
 // should take care to ensure that all view transactions do not mutate any value.
+//
+// Reads are served from local state by default, which might be stale. If
+// linearizable read is required by the context, View waits until the local
+// state catches up with the leader's commit index.
 func (s *Store) View(ctx context.Context, fn func(kv.Tx) error) error {
-	err := s.linearizableReadNotify(ctx)
-	if err != nil {
-		return err
+	if kv.ReadConsistencyFromContext(ctx) == kv.LinearizableRead {
+		err := s.linearizableReadNotify(ctx)
+		if err != nil {
+			return err
+		}
 	}
 
 	db := s.db.Load()
+	if db == nil {
+		return ErrStopped
+	}
+
 	tx, err := db.Begin(false)
 	if err != nil {
 		return err
@@ -99,6 +109,13 @@ func (s *Store) Update(ctx context.Context, fn func(kv.Tx) error) error {
 	})
 }
 
+// Watch implements kv.Watcher, events are published once the entries are
+// applied, so watchers on every member are notified, no matter which member
+// the change is proposed to.
+func (s *Store) Watch(ctx context.Context, bucket, prefix []byte) (<-chan kv.Event, error) {
+	return s.hub.Watch(ctx, bucket, prefix)
+}
+
 // Backup copies all K:Vs to a writer, file format determined by implementation.
 func (s *Store) Backup(ctx context.Context, w io.Writer) error {
 	panic("not implement")
@@ -12,7 +12,8 @@ import (
 )
 
 func initStore(fields tests.KVStoreFields, t *testing.T) (kv.Store, func()) {
-	ctx, _ := context.WithTimeout(context.Background(), time.Minute)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	t.Cleanup(cancel)
 
 	store := setupStore(t)
 	go store.Run(ctx)
@@ -19,6 +19,91 @@ func (c boltCollector) Collect(ch chan<- prometheus.Metric) {
 	c(ch)
 }
 
+var (
+	termDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, subsystem, "term"),
+		"The current term of raft",
+		nil, nil,
+	)
+	leaderDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, subsystem, "leader"),
+		"The leader this member seen, the value is always 1",
+		[]string{"id"}, nil,
+	)
+	snapshotDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, subsystem, "snapshot_index"),
+		"The index of the latest snapshot",
+		nil, nil,
+	)
+	logFilesDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, subsystem, "wal_files"),
+		"The number of WAL files",
+		nil, nil,
+	)
+	peerMatchDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, subsystem, "peer_match_index"),
+		"The highest log index known to be replicated on the peer, only reported by leader",
+		[]string{"peer"}, nil,
+	)
+	peerNextDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, subsystem, "peer_next_index"),
+		"The next log index to send to the peer, only reported by leader",
+		[]string{"peer"}, nil,
+	)
+	peerReachableDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, subsystem, "peer_reachable"),
+		"Whether or not the peer is reachable. 1 if is, 0 otherwise",
+		[]string{"peer"}, nil,
+	)
+)
+
+// statusCollector exports the raft status of the store
+type statusCollector struct {
+	store *Store
+}
+
+func (c *statusCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- termDesc
+	ch <- leaderDesc
+	ch <- snapshotDesc
+	ch <- logFilesDesc
+	ch <- peerMatchDesc
+	ch <- peerNextDesc
+	ch <- peerReachableDesc
+}
+
+func (c *statusCollector) Collect(ch chan<- prometheus.Metric) {
+	// the store is stopped
+	if c.store.db.Load() == nil {
+		return
+	}
+
+	status := c.store.Status()
+
+	ch <- prometheus.MustNewConstMetric(termDesc, prometheus.GaugeValue, float64(status.Term))
+	if status.Leader != "0" {
+		ch <- prometheus.MustNewConstMetric(leaderDesc, prometheus.GaugeValue, 1, status.Leader)
+	}
+	ch <- prometheus.MustNewConstMetric(snapshotDesc, prometheus.GaugeValue, float64(status.Snapshot))
+	ch <- prometheus.MustNewConstMetric(logFilesDesc, prometheus.GaugeValue, float64(status.LogFiles))
+
+	for _, peer := range status.Peers {
+		reachable := float64(0)
+		if peer.Reachable {
+			reachable = 1
+		}
+
+		ch <- prometheus.MustNewConstMetric(peerReachableDesc, prometheus.GaugeValue, reachable, peer.ID)
+
+		if peer.Progress == "" {
+			continue
+		}
+
+		ch <- prometheus.MustNewConstMetric(peerMatchDesc, prometheus.GaugeValue, float64(peer.Match), peer.ID)
+		ch <- prometheus.MustNewConstMetric(peerNextDesc, prometheus.GaugeValue, float64(peer.Next), peer.ID)
+	}
+}
+
 func (s *Store) Collectors() []prometheus.Collector {
 	// trick of closure
 	var bc boltCollector = func(ch chan<- prometheus.Metric) {
@@ -39,6 +124,9 @@ func (s *Store) Collectors() []prometheus.Collector {
 		s.isLeader,
 		s.slowReadInex,
 		s.readIndexFailed,
+		s.slowApplies,
+		s.applyDuration,
+		&statusCollector{store: s},
 
 		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
 			Namespace: namespace,
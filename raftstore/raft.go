@@ -616,13 +616,11 @@ func (s *Store) ReadyNotify() <-chan struct{} {
 }
 
 func (s *Store) stop() {
-	select {
-	case s.stopped <- struct{}{}:
-	// Not already stopped, so trigger it
-	case <-s.done:
-		// has already been stopped - no need to do anything
-		return
-	}
+	// stopped is closed rather than sent, other routines are waiting
+	// on it too, and they must not steal the signal from the raft loop.
+	s.stopOnce.Do(func() {
+		close(s.stopped)
+	})
 
 	// Block until the stop has been acknowledged by start()
 	<-s.done
@@ -0,0 +1,389 @@
+package raftstore
+
+import (
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/f1shl3gs/manta/raftstore/pb"
+	"github.com/f1shl3gs/manta/raftstore/wal"
+
+	"github.com/pkg/errors"
+	bolt "go.etcd.io/bbolt"
+	"go.etcd.io/raft/v3/raftpb"
+	"go.uber.org/zap"
+)
+
+const (
+	// maxRecoverBatchSize is the maximum size of entries read from wal at once
+	maxRecoverBatchSize = 64 << 20
+)
+
+var (
+	ErrNoRaftData = errors.New("no raft data found")
+
+	ErrNothingToRecover = errors.New("nothing committed, no need to recover")
+)
+
+// WALInfo describes the raft metadata persisted in the data dir
+type WALInfo struct {
+	NodeID     uint64
+	Checkpoint uint64
+	HardState  raftpb.HardState
+	Snapshot   raftpb.SnapshotMetadata
+	FirstIndex uint64
+	LastIndex  uint64
+	LogFiles   int
+	Members    []pb.Member
+}
+
+// Inspect reads the raft metadata and membership from the data dir,
+// the store must not be running.
+func Inspect(dir string, logger *zap.Logger) (*WALInfo, error) {
+	if !wal.Exist(dir) {
+		return nil, ErrNoRaftData
+	}
+
+	db, err := openDB(&Config{DataDir: dir})
+	if err != nil {
+		return nil, errors.Wrap(err, "open state db failed")
+	}
+	defer db.Close()
+
+	ds, err := wal.Init(dir, logger)
+	if err != nil {
+		return nil, err
+	}
+	defer ds.Close()
+
+	info := &WALInfo{
+		NodeID:   ds.NodeID(),
+		LogFiles: ds.NumLogFiles(),
+	}
+
+	if info.Checkpoint, err = ds.Checkpoint(); err != nil {
+		return nil, err
+	}
+
+	if info.HardState, err = ds.HardState(); err != nil {
+		return nil, err
+	}
+
+	snap, err := ds.Snapshot()
+	if err != nil {
+		return nil, err
+	}
+	info.Snapshot = snap.Metadata
+
+	if info.FirstIndex, err = ds.FirstIndex(); err != nil {
+		return nil, err
+	}
+
+	if info.LastIndex, err = ds.LastIndex(); err != nil {
+		return nil, err
+	}
+
+	err = db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket(membershipBucket)
+		if b == nil {
+			return nil
+		}
+
+		return b.ForEach(func(k, v []byte) error {
+			info.Members = append(info.Members, pb.Member{
+				ID:   binary.BigEndian.Uint64(k),
+				Addr: string(v),
+			})
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return info, nil
+}
+
+// Dump iterates the entries in wal from index lo, entries compacted by
+// snapshot are skipped.
+func Dump(dir string, lo uint64, fn func(ent raftpb.Entry) error, logger *zap.Logger) error {
+	if !wal.Exist(dir) {
+		return ErrNoRaftData
+	}
+
+	ds, err := wal.Init(dir, logger)
+	if err != nil {
+		return err
+	}
+	defer ds.Close()
+
+	first, _ := ds.FirstIndex()
+	last, _ := ds.LastIndex()
+	if lo < first {
+		lo = first
+	}
+
+	return iterateEntries(ds, lo, last, fn)
+}
+
+func iterateEntries(ds *wal.DiskStorage, lo, hi uint64, fn func(ent raftpb.Entry) error) error {
+	for lo <= hi {
+		ents, err := ds.Entries(lo, hi+1, maxRecoverBatchSize)
+		if err != nil {
+			return err
+		}
+
+		if len(ents) == 0 {
+			return nil
+		}
+
+		for _, ent := range ents {
+			if err = fn(ent); err != nil {
+				return err
+			}
+		}
+
+		lo = ents[len(ents)-1].Index + 1
+	}
+
+	return nil
+}
+
+// ForceNewCluster turns the data dir of a member into a single-node
+// cluster, it is the last resort when the quorum is lost permanently.
+//
+// Committed entries which are not applied yet are replayed to the bolt
+// state, the membership is replaced with the local member only, and the
+// wal is reset to a snapshot at the last committed index. Uncommitted
+// entries are discarded. The previous wal files are moved to a backup
+// dir next to the data dir. If listen is empty, the address of the local
+// member is kept.
+func ForceNewCluster(dir, listen string, logger *zap.Logger) (pb.Member, error) {
+	if !wal.Exist(dir) {
+		return pb.Member{}, ErrNoRaftData
+	}
+
+	// bolt db is locked by the running store, so we can make sure the
+	// store is not running
+	db, err := openDB(&Config{DataDir: dir})
+	if err != nil {
+		return pb.Member{}, errors.Wrap(err, "open state db failed, make sure the store is not running")
+	}
+	defer db.Close()
+
+	ds, err := wal.Init(dir, logger)
+	if err != nil {
+		return pb.Member{}, err
+	}
+
+	hs, err := ds.HardState()
+	if err != nil {
+		return pb.Member{}, err
+	}
+
+	checkpoint, err := ds.Checkpoint()
+	if err != nil {
+		return pb.Member{}, err
+	}
+
+	index := checkpoint
+	if hs.Commit > index {
+		index = hs.Commit
+	}
+	if index == 0 {
+		return pb.Member{}, ErrNothingToRecover
+	}
+
+	first, _ := ds.FirstIndex()
+	lo := checkpoint + 1
+	if lo < first {
+		lo = first
+	}
+
+	err = iterateEntries(ds, lo, hs.Commit, func(ent raftpb.Entry) error {
+		return replayEntry(db, ent, logger)
+	})
+	if err != nil {
+		return pb.Member{}, errors.Wrap(err, "replay committed entries failed")
+	}
+
+	member := pb.Member{
+		ID:   ds.NodeID(),
+		Addr: listen,
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(membershipBucket)
+		if member.Addr == "" && b != nil {
+			member.Addr = string(b.Get(uint64ToBigEndianBytes(member.ID)))
+		}
+
+		if member.Addr == "" {
+			return errors.Errorf("address of member %s not found", formatID(member.ID))
+		}
+
+		if b != nil {
+			if err := tx.DeleteBucket(membershipBucket); err != nil {
+				return err
+			}
+		}
+
+		// the local member is the only voter of the new cluster
+		if tx.Bucket(learnersBucket) != nil {
+			if err := tx.DeleteBucket(learnersBucket); err != nil {
+				return err
+			}
+		}
+
+		b, err := tx.CreateBucket(membershipBucket)
+		if err != nil {
+			return err
+		}
+
+		return b.Put(uint64ToBigEndianBytes(member.ID), []byte(member.Addr))
+	})
+	if err != nil {
+		return pb.Member{}, err
+	}
+
+	if err = db.Sync(); err != nil {
+		return pb.Member{}, err
+	}
+
+	if err = ds.Close(); err != nil {
+		return pb.Member{}, err
+	}
+
+	backup := dir + ".backup-" + time.Now().Format("20060102150405")
+	if err = backupWAL(dir, backup); err != nil {
+		return pb.Member{}, errors.Wrap(err, "backup wal failed")
+	}
+
+	logger.Info("wal moved to backup dir",
+		zap.String("backup", backup))
+
+	ds, err = wal.Init(dir, logger)
+	if err != nil {
+		return pb.Member{}, err
+	}
+	defer ds.Close()
+
+	term := hs.Term
+	if term == 0 {
+		term = 1
+	}
+
+	snap := raftpb.Snapshot{
+		Metadata: raftpb.SnapshotMetadata{
+			Index: index,
+			Term:  term,
+			ConfState: raftpb.ConfState{
+				Voters: []uint64{member.ID},
+			},
+		},
+	}
+
+	ds.SetNodeID(member.ID)
+	ds.SetUint(wal.CheckpointIndex, index)
+	err = ds.Save(&raftpb.HardState{Term: term, Commit: index}, nil, &snap)
+	if err != nil {
+		return pb.Member{}, err
+	}
+
+	logger.Info("force new cluster success",
+		zap.String("id", formatID(member.ID)),
+		zap.String("addr", member.Addr),
+		zap.Uint64("index", index),
+		zap.Uint64("term", term))
+
+	return member, ds.Sync()
+}
+
+// replayEntry applies a committed entry to the bolt db, conf changes
+// are ignored, since the membership will be overwritten.
+func replayEntry(db *bolt.DB, ent raftpb.Entry, logger *zap.Logger) error {
+	if ent.Type != raftpb.EntryNormal || len(ent.Data) == 0 {
+		return nil
+	}
+
+	var req pb.InternalRequest
+	if err := req.Unmarshal(ent.Data); err != nil {
+		return errors.Wrapf(err, "unmarshal entry %d failed", ent.Index)
+	}
+
+	err := db.Update(func(tx *bolt.Tx) error {
+		if txn := req.Txn; txn != nil {
+			return applyTxn(tx, txn)
+		}
+
+		if cb := req.CreateBucket; cb != nil {
+			_, err := tx.CreateBucketIfNotExists(cb.Name)
+			return err
+		}
+
+		if d := req.DeleteBucket; d != nil {
+			err := tx.DeleteBucket(d.Name)
+			if err == bolt.ErrBucketNotFound {
+				return nil
+			}
+
+			return err
+		}
+
+		return nil
+	})
+	if err != nil {
+		// the running store would fail to apply it too, so it is
+		// safe to skip it
+		logger.Warn("replay entry failed, skip it",
+			zap.Uint64("index", ent.Index),
+			zap.Error(err))
+	}
+
+	return nil
+}
+
+// backupWAL moves the wal files to the backup dir
+func backupWAL(dir, backup string) error {
+	files, err := filepath.Glob(filepath.Join(dir, "*.wal"))
+	if err != nil {
+		return err
+	}
+	files = append(files, filepath.Join(dir, "wal.meta"))
+
+	if err = os.MkdirAll(backup, 0700); err != nil {
+		return err
+	}
+
+	for _, file := range files {
+		err = os.Rename(file, filepath.Join(backup, filepath.Base(file)))
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// DecodeEntry decodes the data of normal entries as pb.InternalRequest and
+// conf change entries as raftpb.ConfChange, nil is returned for empty entries.
+func DecodeEntry(ent raftpb.Entry) (any, error) {
+	if len(ent.Data) == 0 {
+		return nil, nil
+	}
+
+	switch ent.Type {
+	case raftpb.EntryNormal:
+		req := &pb.InternalRequest{}
+		return req, req.Unmarshal(ent.Data)
+	case raftpb.EntryConfChange:
+		cc := &raftpb.ConfChange{}
+		return cc, cc.Unmarshal(ent.Data)
+	case raftpb.EntryConfChangeV2:
+		cc := &raftpb.ConfChangeV2{}
+		return cc, cc.Unmarshal(ent.Data)
+	default:
+		return nil, errors.Errorf("unknown entry type %s", ent.Type)
+	}
+}
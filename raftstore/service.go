@@ -4,21 +4,56 @@ import (
 	"context"
 	"crypto/sha1"
 	"encoding/binary"
+	"errors"
 	"time"
 
 	"github.com/f1shl3gs/manta/raftstore/pb"
 
+	bolt "go.etcd.io/bbolt"
 	"go.etcd.io/raft/v3/raftpb"
 )
 
+const (
+	// readyPercent is the minimum percentage of the leader's match index a
+	// learner must have reached before it can be promoted to a voting member.
+	readyPercent = 0.9
+)
+
+var (
+	ErrNotLeader = errors.New("not leader")
+
+	ErrMemberNotFound = errors.New("member not found")
+
+	ErrMemberNotLearner = errors.New("can only promote a learner member")
+
+	ErrLearnerNotReady = errors.New("can only promote a learner member which is in sync with leader")
+
+	ErrTransfereeIsLearner = errors.New("cannot transfer leadership to a learner member")
+
+	ErrUnhealthy = errors.New("unhealthy cluster, removing the member would break quorum")
+
+	ErrTimeoutLeaderTransfer = errors.New("request timed out, leader transfer took too long")
+)
+
 type ClusterService interface {
 	Members() []pb.Member
 
-	// Add add a node to Raft cluster
+	// Add add a node to Raft cluster, the node will join as a learner
+	// if member.Learner is true
 	Add(ctx context.Context, member pb.Member) error
 
 	// Remove removes member of Raft cluster
 	Remove(ctx context.Context, id uint64) error
+
+	// Promote promotes a learner to a voting member, the learner must
+	// be in sync with the leader
+	Promote(ctx context.Context, id uint64) error
+
+	// TransferLeadership transfers the leadership to the member with id
+	TransferLeadership(ctx context.Context, transferee uint64) error
+
+	// Status returns the raft status of the local member and its peers
+	Status() Status
 }
 
 var (
@@ -35,8 +70,42 @@ func (s *Store) Send(ctx context.Context, msg *raftpb.Message) (*pb.Done, error)
 	return done, nil
 }
 
+// Members returns all members of the Raft cluster
 func (s *Store) Members() []pb.Member {
-	panic("not implement")
+	learners := make(map[uint64]struct{})
+	if cs := s.confState.Load(); cs != nil {
+		for _, id := range cs.Learners {
+			learners[id] = struct{}{}
+		}
+	}
+
+	db := s.db.Load()
+	if db == nil {
+		return nil
+	}
+
+	var members []pb.Member
+	_ = db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket(membershipBucket)
+		if b == nil {
+			return nil
+		}
+
+		return b.ForEach(func(k, v []byte) error {
+			id := binary.BigEndian.Uint64(k)
+			_, learner := learners[id]
+
+			members = append(members, pb.Member{
+				ID:      id,
+				Addr:    string(v),
+				Learner: learner,
+			})
+
+			return nil
+		})
+	})
+
+	return members
 }
 
 // Add add a node to Raft cluster
@@ -47,17 +116,183 @@ func (s *Store) Add(ctx context.Context, member pb.Member) error {
 		Context: unsafeStringToBytes(member.Addr),
 	}
 
-	return s.raftNode.ProposeConfChange(ctx, cc)
+	if member.Learner {
+		cc.Type = raftpb.ConfChangeAddLearnerNode
+	}
+
+	return s.proposeConfChange(ctx, cc)
 }
 
 // Remove removes member of Raft cluster
 func (s *Store) Remove(ctx context.Context, id uint64) error {
+	if err := s.mayRemoveMember(id); err != nil {
+		return err
+	}
+
 	cc := raftpb.ConfChange{
 		Type:   raftpb.ConfChangeRemoveNode,
 		NodeID: id,
 	}
 
-	return s.raftNode.ProposeConfChange(ctx, cc)
+	return s.proposeConfChange(ctx, cc)
+}
+
+// Promote promotes a learner to a voting member
+func (s *Store) Promote(ctx context.Context, id uint64) error {
+	member, err := s.member(id)
+	if err != nil {
+		return err
+	}
+
+	if !member.Learner {
+		return ErrMemberNotLearner
+	}
+
+	if err = s.isLearnerReady(id); err != nil {
+		return err
+	}
+
+	cc := raftpb.ConfChange{
+		Type:    raftpb.ConfChangeAddNode,
+		NodeID:  id,
+		Context: unsafeStringToBytes(member.Addr),
+	}
+
+	return s.proposeConfChange(ctx, cc)
+}
+
+// TransferLeadership transfers the leadership to the member with id,
+// and wait until the transferee becomes the leader
+func (s *Store) TransferLeadership(ctx context.Context, transferee uint64) error {
+	lead := s.getLead()
+	if lead != s.self.ID {
+		return ErrNotLeader
+	}
+
+	if transferee == lead {
+		return nil
+	}
+
+	member, err := s.member(transferee)
+	if err != nil {
+		return err
+	}
+
+	if member.Learner {
+		return ErrTransfereeIsLearner
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, s.reqTimeout())
+	defer cancel()
+
+	s.raftNode.TransferLeadership(ctx, lead, transferee)
+
+	ticker := time.NewTicker(heartbeat)
+	defer ticker.Stop()
+
+	for s.getLead() != transferee {
+		select {
+		case <-ctx.Done():
+			return ErrTimeoutLeaderTransfer
+		case <-s.done:
+			return ErrStopped
+		case <-ticker.C:
+		}
+	}
+
+	return nil
+}
+
+// proposeConfChange proposes the conf change and wait until it is applied
+func (s *Store) proposeConfChange(ctx context.Context, cc raftpb.ConfChange) error {
+	cc.ID = s.idGen.Next()
+
+	waitCh := s.wait.Register(cc.ID)
+	if err := s.raftNode.ProposeConfChange(ctx, cc); err != nil {
+		s.wait.Trigger(cc.ID, nil)
+		return err
+	}
+
+	select {
+	case <-ctx.Done():
+		s.wait.Trigger(cc.ID, nil)
+		return ctx.Err()
+	case <-s.done:
+		return ErrStopped
+	case err := <-waitCh:
+		return err
+	}
+}
+
+func (s *Store) member(id uint64) (pb.Member, error) {
+	for _, m := range s.Members() {
+		if m.ID == id {
+			return m, nil
+		}
+	}
+
+	return pb.Member{}, ErrMemberNotFound
+}
+
+// isLearnerReady checks whether the learner has caught up with the leader,
+// only the leader has the progress of the followers.
+func (s *Store) isLearnerReady(id uint64) error {
+	if s.getLead() != s.self.ID {
+		return ErrNotLeader
+	}
+
+	status := s.raftNode.Status()
+	if status.Progress == nil {
+		return ErrNotLeader
+	}
+
+	leader, ok := status.Progress[s.self.ID]
+	if !ok {
+		return ErrNotLeader
+	}
+
+	learner, ok := status.Progress[id]
+	if !ok || !learner.IsLearner {
+		return ErrMemberNotLearner
+	}
+
+	if float64(learner.Match) < float64(leader.Match)*readyPercent {
+		return ErrLearnerNotReady
+	}
+
+	return nil
+}
+
+// mayRemoveMember checks whether the cluster still has the quorum
+// of active voters after the member is removed.
+func (s *Store) mayRemoveMember(id uint64) error {
+	member, err := s.member(id)
+	if err != nil {
+		return err
+	}
+
+	// removing learner doesn't affect the quorum
+	if member.Learner {
+		return nil
+	}
+
+	voters, active := 0, 0
+	for _, m := range s.Members() {
+		if m.Learner || m.ID == id {
+			continue
+		}
+
+		voters += 1
+		if m.ID == s.self.ID || !s.transport.ActiveSince(m.ID).IsZero() {
+			active += 1
+		}
+	}
+
+	if voters == 0 || active < voters/2+1 {
+		return ErrUnhealthy
+	}
+
+	return nil
 }
 
 // generateID generate a new node id with address
@@ -69,3 +304,11 @@ func generateID(addr string) uint64 {
 
 	return binary.BigEndian.Uint64(hash[:8])
 }
+
+// peerID generate node id with address only, it is used to bootstrap
+// a multi-node cluster, so all nodes share the same view of peers.
+func peerID(addr string) uint64 {
+	hash := sha1.Sum([]byte(addr))
+
+	return binary.BigEndian.Uint64(hash[:8])
+}
@@ -0,0 +1,88 @@
+package raftstore
+
+import (
+	"strconv"
+	"time"
+)
+
+// Status describes the raft state of the local member and the
+// replication state of its peers.
+type Status struct {
+	ID       string `json:"id"`
+	Leader   string `json:"leader"`
+	State    string `json:"state"`
+	Term     uint64 `json:"term"`
+	Vote     string `json:"vote"`
+	Commit   uint64 `json:"commit"`
+	Applied  uint64 `json:"applied"`
+	Snapshot uint64 `json:"snapshot"`
+	LogFiles int    `json:"logFiles"`
+
+	Peers []PeerStatus `json:"peers"`
+}
+
+// PeerStatus describes a peer of the local member, Match, Next and
+// Progress is only available when the local member is the leader.
+type PeerStatus struct {
+	ID          string    `json:"id"`
+	Addr        string    `json:"addr"`
+	Learner     bool      `json:"learner"`
+	Reachable   bool      `json:"reachable"`
+	ActiveSince time.Time `json:"activeSince"`
+	Match       uint64    `json:"match"`
+	Next        uint64    `json:"next"`
+	Progress    string    `json:"progress,omitempty"`
+}
+
+// Status returns the raft status of the local member
+func (s *Store) Status() Status {
+	rs := s.raftNode.Status()
+
+	status := Status{
+		ID:       formatID(s.self.ID),
+		Leader:   formatID(rs.Lead),
+		State:    rs.RaftState.String(),
+		Term:     rs.Term,
+		Vote:     formatID(rs.Vote),
+		Commit:   rs.Commit,
+		Applied:  s.appliedIndex.Load(),
+		LogFiles: s.raftStorage.NumLogFiles(),
+	}
+
+	snap, err := s.raftStorage.Snapshot()
+	if err == nil {
+		status.Snapshot = snap.Metadata.Index
+	}
+
+	for _, m := range s.Members() {
+		if m.ID == s.self.ID {
+			continue
+		}
+
+		ps := PeerStatus{
+			ID:      formatID(m.ID),
+			Addr:    m.Addr,
+			Learner: m.Learner,
+		}
+
+		since := s.transport.ActiveSince(m.ID)
+		if !since.IsZero() {
+			ps.Reachable = true
+			ps.ActiveSince = since
+		}
+
+		if pr, ok := rs.Progress[m.ID]; ok {
+			ps.Match = pr.Match
+			ps.Next = pr.Next
+			ps.Progress = pr.State.String()
+		}
+
+		status.Peers = append(status.Peers, ps)
+	}
+
+	return status
+}
+
+func formatID(id uint64) string {
+	return strconv.FormatUint(id, 16)
+}
@@ -40,10 +40,17 @@ const (
 
 	batchLimit    = 10000
 	batchInterval = 100 * time.Millisecond
+
+	// warnApplyDuration is the time duration after which a warning is
+	// logged that committed entries took too long to apply.
+	warnApplyDuration = 100 * time.Millisecond
 )
 
 var (
 	membershipBucket = []byte("__membership")
+	// learnersBucket stores the IDs of learner members, so they are
+	// restored as learners after restart
+	learnersBucket = []byte("__learners")
 )
 
 type Store struct {
@@ -69,6 +76,9 @@ type Store struct {
 	firstCommitInTerm *notifier
 	applyWait         *waitTime
 
+	// hub notifies watchers of applied changes
+	hub *kv.WatchHub
+
 	// raft staff
 	raftNode    raft.Node
 	raftStorage *wal.DiskStorage
@@ -83,6 +93,7 @@ type Store struct {
 	ticker *time.Ticker
 	// contention detectors for raft heartbeat message
 	td        *TimeoutDetector
+	stopOnce  sync.Once
 	stopped   chan struct{}
 	done      chan struct{}
 	transport *transport.Transporter
@@ -98,6 +109,8 @@ type Store struct {
 	isLeader        prometheus.Gauge
 	slowReadInex    prometheus.Counter
 	readIndexFailed prometheus.Counter
+	slowApplies     prometheus.Counter
+	applyDuration   prometheus.Histogram
 }
 
 func New(cf *Config, logger *zap.Logger) (*Store, error) {
@@ -133,7 +146,8 @@ func New(cf *Config, logger *zap.Logger) (*Store, error) {
 		applyWait:         newWaitTime(),
 		firstCommitInTerm: newNotifier(),
 		readNotifier:      newErrNotifier(),
-		transport:         transport.New(logger),
+		hub:               kv.NewWatchHub(),
+		transport:         transport.New(logger, cf.PeerTLS),
 
 		leaderChanges: prometheus.NewCounter(prometheus.CounterOpts{
 			Namespace: namespace,
@@ -165,6 +179,19 @@ func New(cf *Config, logger *zap.Logger) (*Store, error) {
 			Name:      "read_indexes_failed_total",
 			Help:      "The total number of failed read indexes seen.",
 		}),
+		slowApplies: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: subsystem,
+			Name:      "slow_applies_total",
+			Help:      "The total number of slow apply requests (likely overloaded from slow disk).",
+		}),
+		applyDuration: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: subsystem,
+			Name:      "apply_duration_seconds",
+			Help:      "The latency distributions of applying committed entries.",
+			Buckets:   prometheus.ExponentialBuckets(0.0001, 2, 18),
+		}),
 	}
 
 	db, err := openDB(cf)
@@ -219,7 +246,6 @@ func New(cf *Config, logger *zap.Logger) (*Store, error) {
 		logger.Info("start a brand new raft cluster")
 
 		rcf.ID = generateID(cf.Listen)
-		ds.SetNodeID(rcf.ID)
 		peers := []raft.Peer{
 			{
 				ID:      rcf.ID,
@@ -227,6 +253,31 @@ func New(cf *Config, logger *zap.Logger) (*Store, error) {
 			},
 		}
 
+		// bootstrap a multi-node cluster, every node must have the same
+		// peers, so the ID of the peers must be derived from address only.
+		if len(cf.Peers) != 0 {
+			rcf.ID = peerID(cf.Listen)
+			peers = peers[:0]
+
+			found := false
+			for _, addr := range cf.Peers {
+				if addr == cf.Listen {
+					found = true
+				}
+
+				peers = append(peers, raft.Peer{
+					ID:      peerID(addr),
+					Context: unsafeStringToBytes(addr),
+				})
+			}
+
+			if !found {
+				return nil, errors.Errorf("listen address %q is not one of the peers", cf.Listen)
+			}
+		}
+
+		ds.SetNodeID(rcf.ID)
+
 		for _, peer := range peers {
 			err = store.transport.AddPeer(peer.ID, unsafeBytesToString(peer.Context))
 			if err != nil {
@@ -243,7 +294,18 @@ func New(cf *Config, logger *zap.Logger) (*Store, error) {
 
 	// restart raft node
 	rcf.ID = ds.NodeID()
+	learners := make(map[uint64]struct{})
 	err = store.db.Load().View(func(tx *bolt.Tx) error {
+		if b := tx.Bucket(learnersBucket); b != nil {
+			err := b.ForEach(func(k, _ []byte) error {
+				learners[binary.BigEndian.Uint64(k)] = struct{}{}
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+		}
+
 		b := tx.Bucket(membershipBucket)
 
 		return b.ForEach(func(k, v []byte) error {
@@ -301,10 +363,23 @@ func New(cf *Config, logger *zap.Logger) (*Store, error) {
 		But we use disk-based state machine, so we don't need to replay WAL like raftexample does,
 		therefore the voters part will be empty and cluster can never elect a leader.
 	*/
-	for id, peer := range store.transport.Peers() {
-		cc := raftpb.ConfChange{Type: raftpb.ConfChangeAddNode, NodeID: id, Context: unsafeStringToBytes(peer)}
-		cs := store.raftNode.ApplyConfChange(cc)
-		store.confState.Store(cs)
+	// voters are added before learners, raft rejects a configuration
+	// without voters
+	peers := store.transport.Peers()
+	for _, learner := range []bool{false, true} {
+		for id, peer := range peers {
+			if _, ok := learners[id]; ok != learner {
+				continue
+			}
+
+			cc := raftpb.ConfChange{Type: raftpb.ConfChangeAddNode, NodeID: id, Context: unsafeStringToBytes(peer)}
+			if learner {
+				cc.Type = raftpb.ConfChangeAddLearnerNode
+			}
+
+			cs := store.raftNode.ApplyConfChange(cc)
+			store.confState.Store(cs)
+		}
 	}
 
 	return store, nil
@@ -400,7 +475,9 @@ func (s *Store) Run(ctx context.Context) {
 				continue
 			}
 
+			start := time.Now()
 			_, appliedIndex := s.apply(entries)
+			s.observeApply(start, entries)
 
 			s.appliedIndex.Store(appliedIndex)
 			s.applyWait.Trigger(appliedIndex)
@@ -415,6 +492,23 @@ func (s *Store) Run(ctx context.Context) {
 	}
 }
 
+func (s *Store) observeApply(start time.Time, entries []raftpb.Entry) {
+	elapsed := time.Since(start)
+	s.applyDuration.Observe(elapsed.Seconds())
+
+	if elapsed < warnApplyDuration {
+		return
+	}
+
+	s.slowApplies.Inc()
+	s.logger.Warn("apply entries took too long",
+		zap.Duration("took", elapsed),
+		zap.Duration("expected-duration", warnApplyDuration),
+		zap.Int("entries", len(entries)),
+		zap.Uint64("first", entries[0].Index),
+		zap.Uint64("last", entries[len(entries)-1].Index))
+}
+
 // publish registers server information into the cluster.
 // The function keeps attempting to register until it succeeds,
 // or its server is stopped.
@@ -544,6 +638,10 @@ func (s *Store) applyNormal(ent *raftpb.Entry) {
 
 			return errors.New("empty internal request")
 		})
+
+		if err == nil && req.Txn != nil {
+			s.hub.Publish(txnEvents(req.Txn, ent.Index))
+		}
 	}
 
 	s.wait.Trigger(req.ID, err)
@@ -556,7 +654,23 @@ func (s *Store) applyConfChange(cc *raftpb.ConfChange) {
 			return err
 		}
 
+		lb, err := tx.CreateBucketIfNotExists(learnersBucket)
+		if err != nil {
+			return err
+		}
+
 		key := uint64ToBigEndianBytes(cc.NodeID)
+		switch cc.Type {
+		case raftpb.ConfChangeAddLearnerNode:
+			err = lb.Put(key, []byte{})
+		case raftpb.ConfChangeAddNode, raftpb.ConfChangeRemoveNode:
+			// adding an existing learner as node promotes it
+			err = lb.Delete(key)
+		}
+		if err != nil {
+			return err
+		}
+
 		switch cc.Type {
 		case raftpb.ConfChangeAddNode, raftpb.ConfChangeAddLearnerNode, raftpb.ConfChangeUpdateNode:
 			s.logger.Info("add/update node",
@@ -577,8 +691,23 @@ func (s *Store) applyConfChange(cc *raftpb.ConfChange) {
 			zap.Error(err))
 	}
 
+	switch cc.Type {
+	case raftpb.ConfChangeAddNode, raftpb.ConfChangeAddLearnerNode, raftpb.ConfChangeUpdateNode:
+		err = s.transport.AddPeer(cc.NodeID, unsafeBytesToString(cc.Context))
+	case raftpb.ConfChangeRemoveNode:
+		if cc.NodeID != s.self.ID {
+			err = s.transport.RemovePeer(cc.NodeID)
+		}
+	}
+	if err != nil {
+		s.logger.Warn("update transport peer failed",
+			zap.String("id", strconv.FormatUint(cc.NodeID, 16)),
+			zap.Error(err))
+	}
+
 	cs := s.raftNode.ApplyConfChange(cc)
 	s.confState.Store(cs)
+	s.wait.Trigger(cc.ID, nil)
 }
 
 func applyTxn(tx *bolt.Tx, txn *pb.Txn) error {
@@ -603,6 +732,29 @@ func applyTxn(tx *bolt.Tx, txn *pb.Txn) error {
 	return nil
 }
 
+// txnEvents converts the applied operations to watch events, the index
+// of the entry is used as revision, so it is the same on every member.
+func txnEvents(txn *pb.Txn, index uint64) []kv.Event {
+	events := make([]kv.Event, 0, len(txn.Successes))
+	for _, op := range txn.Successes {
+		ev := kv.Event{
+			Type:     kv.EventPut,
+			Bucket:   op.Bucket,
+			Key:      op.Key,
+			Value:    op.Value,
+			Revision: index,
+		}
+		if op.Type != pb.Put {
+			ev.Type = kv.EventDelete
+			ev.Value = nil
+		}
+
+		events = append(events, ev)
+	}
+
+	return events
+}
+
 func (s *Store) syncLoop(ctx context.Context) {
 	ticker := time.NewTicker(batchInterval)
 	defer ticker.Stop()
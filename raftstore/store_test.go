@@ -7,6 +7,7 @@ import (
 	"net/http"
 	_ "net/http/pprof"
 	"os"
+	"path/filepath"
 	"strconv"
 	"sync"
 	"sync/atomic"
@@ -14,11 +15,15 @@ import (
 	"time"
 
 	"github.com/f1shl3gs/manta/kv"
+	"github.com/f1shl3gs/manta/raftstore/pb"
 
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/stretchr/testify/assert"
 	bolt "go.etcd.io/bbolt"
+	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 	"go.uber.org/zap/zaptest"
+	"google.golang.org/grpc"
 )
 
 func setupStore(t testing.TB) *Store {
@@ -174,6 +179,63 @@ func TestRestart(t *testing.T) {
 	dump(t, store.db.Load())
 }
 
+func TestRestartLearner(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	assert.NoError(t, l.Close())
+
+	cf := &Config{
+		Listen:  l.Addr().String(),
+		DataDir: t.TempDir(),
+	}
+	logger := zap.NewNop()
+
+	store, err := New(cf, logger)
+	assert.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	doneCh := make(chan struct{})
+	go func() {
+		store.Run(ctx)
+		close(doneCh)
+	}()
+
+	select {
+	case <-store.ReadyNotify():
+	case <-ctx.Done():
+		t.Fatal("wait for store ready timeout")
+	}
+
+	err = store.Add(ctx, pb.Member{Addr: "127.0.0.1:1", Learner: true})
+	assert.NoError(t, err)
+
+	store.stop()
+	<-doneCh
+
+	store, err = New(cf, logger)
+	assert.NoError(t, err)
+
+	go store.Run(ctx)
+	defer store.stop()
+
+	select {
+	case <-store.ReadyNotify():
+	case <-ctx.Done():
+		t.Fatal("wait for store ready timeout")
+	}
+
+	// the learner is restored as learner, not voter
+	cs := store.confState.Load()
+	assert.Equal(t, []uint64{store.self.ID}, cs.Voters)
+	assert.Len(t, cs.Learners, 1)
+
+	for _, m := range store.Members() {
+		assert.Equal(t, m.ID != store.self.ID, m.Learner)
+	}
+}
+
 func dump(t *testing.T, db *bolt.DB) {
 	err := db.View(func(tx *bolt.Tx) error {
 		return tx.ForEach(func(name []byte, b *bolt.Bucket) error {
@@ -263,3 +325,497 @@ func TestBench(t *testing.T) {
 	fmt.Printf("QPS:            %f\n", float64(total)/elapsed.Seconds())
 	fmt.Printf("DB Size:        %f MB\n", float64(stat.Size())/1024.0/1024.0)
 }
+
+func TestMembership(t *testing.T) {
+	store := setupStore(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	go store.Run(ctx)
+	defer store.stop()
+
+	select {
+	case <-store.ReadyNotify():
+	case <-ctx.Done():
+		t.Fatal("wait for store ready timeout")
+	}
+
+	members := store.Members()
+	assert.Equal(t, 1, len(members))
+	assert.Equal(t, store.self.ID, members[0].ID)
+	assert.False(t, members[0].Learner)
+
+	// remove the only voter will break the quorum
+	err := store.Remove(ctx, store.self.ID)
+	assert.Equal(t, ErrUnhealthy, err)
+
+	err = store.Promote(ctx, store.self.ID)
+	assert.Equal(t, ErrMemberNotLearner, err)
+
+	err = store.Add(ctx, pb.Member{Addr: "127.0.0.1:1", Learner: true})
+	assert.NoError(t, err)
+
+	var learner pb.Member
+	for _, m := range store.Members() {
+		if m.ID != store.self.ID {
+			learner = m
+		}
+	}
+	assert.True(t, learner.Learner)
+	assert.Equal(t, "127.0.0.1:1", learner.Addr)
+
+	// the learner is unreachable, so it can never catch up
+	err = store.Promote(ctx, learner.ID)
+	assert.Equal(t, ErrLearnerNotReady, err)
+
+	err = store.TransferLeadership(ctx, learner.ID)
+	assert.Equal(t, ErrTransfereeIsLearner, err)
+
+	err = store.TransferLeadership(ctx, 1)
+	assert.Equal(t, ErrMemberNotFound, err)
+
+	// removing learner is always safe
+	err = store.Remove(ctx, learner.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(store.Members()))
+}
+
+// setupCluster starts a n-node cluster, stop cancels all the stores and
+// waits until they exit.
+func setupCluster(t *testing.T, ctx context.Context, n int) (stores []*Store, stop func()) {
+	ctx, cancel := context.WithCancel(ctx)
+	t.Cleanup(cancel)
+
+	var (
+		listeners = make([]net.Listener, n)
+		addrs     = make([]string, n)
+		doneChs   = make([]chan struct{}, n)
+	)
+
+	stores = make([]*Store, n)
+	stop = func() {
+		cancel()
+		for _, doneCh := range doneChs {
+			if doneCh != nil {
+				<-doneCh
+			}
+		}
+	}
+	t.Cleanup(stop)
+
+	for i := 0; i < n; i++ {
+		l, err := net.Listen("tcp", "127.0.0.1:0")
+		assert.NoError(t, err)
+
+		listeners[i] = l
+		addrs[i] = l.Addr().String()
+	}
+
+	for i := 0; i < n; i++ {
+		cf := &Config{
+			Listen:  addrs[i],
+			Peers:   addrs,
+			DataDir: t.TempDir(),
+		}
+		// background routines of the store might still log after the test
+		// completed, which will panic with a zaptest logger
+		logger := zap.NewNop()
+
+		store, err := New(cf, logger)
+		assert.NoError(t, err)
+
+		srv := grpc.NewServer()
+		pb.RegisterRaftServer(srv, store)
+		go func(l net.Listener) {
+			_ = srv.Serve(l)
+		}(listeners[i])
+		t.Cleanup(srv.Stop)
+
+		doneCh := make(chan struct{})
+		go func() {
+			store.Run(ctx)
+			close(doneCh)
+		}()
+		doneChs[i] = doneCh
+
+		stores[i] = store
+	}
+
+	for _, store := range stores {
+		select {
+		case <-store.ReadyNotify():
+		case <-ctx.Done():
+			t.Fatal("wait for cluster ready timeout")
+		}
+	}
+
+	return stores, stop
+}
+
+// isolate drops all messages between the target store and the others
+func isolate(target *Store, stores []*Store) {
+	for _, store := range stores {
+		if store == target {
+			continue
+		}
+
+		_ = store.transport.RemovePeer(target.self.ID)
+		_ = target.transport.RemovePeer(store.self.ID)
+	}
+}
+
+func TestLinearizableRead(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	stores, _ := setupCluster(t, ctx, 3)
+
+	var leader *Store
+	for leader == nil {
+		for _, store := range stores {
+			if store.getLead() == store.self.ID {
+				leader = store
+			}
+		}
+
+		time.Sleep(heartbeat)
+	}
+
+	bucket, key := []byte("foo"), []byte("key")
+	err := leader.CreateBucket(ctx, bucket)
+	assert.NoError(t, err)
+
+	put := func(store *Store, value string) error {
+		return store.Update(ctx, func(tx kv.Tx) error {
+			b, err := tx.Bucket(bucket)
+			if err != nil {
+				return err
+			}
+
+			return b.Put(key, []byte(value))
+		})
+	}
+	get := func(ctx context.Context, store *Store) (string, error) {
+		var value string
+		err := store.View(ctx, func(tx kv.Tx) error {
+			b, err := tx.Bucket(bucket)
+			if err != nil {
+				return err
+			}
+
+			v, err := b.Get(key)
+			value = string(v)
+			return err
+		})
+
+		return value, err
+	}
+
+	err = put(leader, "v1")
+	assert.NoError(t, err)
+
+	// partition the old leader, the rest members will elect a new leader
+	isolate(leader, stores)
+
+	var newLeader *Store
+	for newLeader == nil {
+		select {
+		case <-ctx.Done():
+			t.Fatal("wait for new leader timeout")
+		case <-time.After(heartbeat):
+		}
+
+		for _, store := range stores {
+			if store != leader && store.getLead() == store.self.ID {
+				newLeader = store
+			}
+		}
+	}
+
+	err = put(newLeader, "v2")
+	assert.NoError(t, err)
+
+	value, err := get(kv.WithLinearizableRead(ctx), newLeader)
+	assert.NoError(t, err)
+	assert.Equal(t, "v2", value)
+
+	// the old leader still thinks it is the leader, so serializable read
+	// returns the stale value
+	value, err = get(ctx, leader)
+	assert.NoError(t, err)
+	assert.Equal(t, "v1", value)
+
+	// linearizable read cannot be served without the quorum
+	rctx, rcancel := context.WithTimeout(ctx, 3*time.Second)
+	defer rcancel()
+	value, err = get(kv.WithLinearizableRead(rctx), leader)
+	assert.Error(t, err)
+	assert.Empty(t, value)
+}
+
+func TestStatus(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	stores, _ := setupCluster(t, ctx, 3)
+
+	var leader *Store
+	for leader == nil {
+		select {
+		case <-ctx.Done():
+			t.Fatal("wait for leader timeout")
+		case <-time.After(heartbeat):
+		}
+
+		for _, store := range stores {
+			if store.getLead() == store.self.ID {
+				leader = store
+			}
+		}
+	}
+
+	// wait for peers to be reachable
+	for {
+		status := leader.Status()
+		reachable := 0
+		for _, peer := range status.Peers {
+			if peer.Reachable && peer.Match > 0 {
+				reachable += 1
+			}
+		}
+
+		if reachable == 2 {
+			break
+		}
+
+		select {
+		case <-ctx.Done():
+			t.Fatal("wait for peers timeout")
+		case <-time.After(heartbeat):
+		}
+	}
+
+	status := leader.Status()
+	assert.Equal(t, formatID(leader.self.ID), status.ID)
+	assert.Equal(t, status.ID, status.Leader)
+	assert.Equal(t, "StateLeader", status.State)
+	assert.NotZero(t, status.Term)
+	assert.NotZero(t, status.Commit)
+	assert.Equal(t, 2, len(status.Peers))
+	for _, peer := range status.Peers {
+		assert.Equal(t, "StateReplicate", peer.Progress)
+		assert.True(t, peer.Next > peer.Match)
+	}
+
+	reg := prometheus.NewPedanticRegistry()
+	reg.MustRegister(leader.Collectors()...)
+	mfs, err := reg.Gather()
+	assert.NoError(t, err)
+
+	names := make(map[string]struct{})
+	for _, mf := range mfs {
+		names[mf.GetName()] = struct{}{}
+	}
+	for _, name := range []string{
+		"manta_raftstore_term",
+		"manta_raftstore_leader",
+		"manta_raftstore_wal_files",
+		"manta_raftstore_peer_match_index",
+		"manta_raftstore_peer_reachable",
+	} {
+		assert.Contains(t, names, name)
+	}
+}
+
+func TestForceNewCluster(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	stores, stop := setupCluster(t, ctx, 3)
+
+	var leader *Store
+	for leader == nil {
+		select {
+		case <-ctx.Done():
+			t.Fatal("wait for leader timeout")
+		case <-time.After(heartbeat):
+		}
+
+		for _, store := range stores {
+			if store.getLead() == store.self.ID {
+				leader = store
+			}
+		}
+	}
+
+	bucket, key, value := []byte("foo"), []byte("key"), []byte("value")
+	err := leader.CreateBucket(ctx, bucket)
+	assert.NoError(t, err)
+	err = leader.Update(ctx, func(tx kv.Tx) error {
+		b, err := tx.Bucket(bucket)
+		if err != nil {
+			return err
+		}
+
+		return b.Put(key, value)
+	})
+	assert.NoError(t, err)
+
+	// recover from a follower
+	target := stores[0]
+	if target == leader {
+		target = stores[1]
+	}
+	dir := filepath.Dir(target.db.Load().Path())
+	id := target.self.ID
+
+	// wait for the follower to catch up
+	for target.appliedIndex.Load() < leader.appliedIndex.Load() {
+		select {
+		case <-ctx.Done():
+			t.Fatal("wait for follower timeout")
+		case <-time.After(heartbeat):
+		}
+	}
+
+	stop()
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	assert.NoError(t, l.Close())
+	addr := l.Addr().String()
+
+	member, err := ForceNewCluster(dir, addr, zap.NewNop())
+	assert.NoError(t, err)
+	assert.Equal(t, id, member.ID)
+	assert.Equal(t, addr, member.Addr)
+
+	info, err := Inspect(dir, zap.NewNop())
+	assert.NoError(t, err)
+	assert.Equal(t, id, info.NodeID)
+	assert.Equal(t, info.Checkpoint, info.HardState.Commit)
+	assert.Equal(t, []uint64{id}, info.Snapshot.ConfState.Voters)
+	assert.Equal(t, []pb.Member{member}, info.Members)
+
+	store, err := New(&Config{Listen: addr, DataDir: dir}, zap.NewNop())
+	assert.NoError(t, err)
+
+	ctx, cancel = context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	doneCh := make(chan struct{})
+	go func() {
+		store.Run(ctx)
+		close(doneCh)
+	}()
+	defer func() {
+		cancel()
+		<-doneCh
+	}()
+
+	select {
+	case <-store.ReadyNotify():
+	case <-ctx.Done():
+		t.Fatal("wait for single-node cluster ready timeout")
+	}
+
+	// the store can serve both read and write
+	err = store.View(kv.WithLinearizableRead(ctx), func(tx kv.Tx) error {
+		b, err := tx.Bucket(bucket)
+		if err != nil {
+			return err
+		}
+
+		got, err := b.Get(key)
+		if err != nil {
+			return err
+		}
+
+		assert.Equal(t, value, got)
+		return nil
+	})
+	assert.NoError(t, err)
+
+	err = store.Update(ctx, func(tx kv.Tx) error {
+		b, err := tx.Bucket(bucket)
+		if err != nil {
+			return err
+		}
+
+		return b.Put([]byte("another"), value)
+	})
+	assert.NoError(t, err)
+
+	members := store.Members()
+	assert.Equal(t, 1, len(members))
+	assert.Equal(t, id, members[0].ID)
+}
+
+func TestWatch(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	stores, _ := setupCluster(t, ctx, 3)
+
+	var leader *Store
+	for leader == nil {
+		for _, store := range stores {
+			if store.getLead() == store.self.ID {
+				leader = store
+			}
+		}
+
+		time.Sleep(heartbeat)
+	}
+
+	bucket := []byte("foo")
+	err := leader.CreateBucket(ctx, bucket)
+	assert.NoError(t, err)
+
+	// every member is notified, no matter which member the change
+	// is proposed to
+	watches := make([]<-chan kv.Event, 0, len(stores))
+	for _, store := range stores {
+		ch, err := store.Watch(ctx, bucket, []byte("key"))
+		assert.NoError(t, err)
+		watches = append(watches, ch)
+	}
+
+	err = leader.Update(ctx, func(tx kv.Tx) error {
+		b, err := tx.Bucket(bucket)
+		if err != nil {
+			return err
+		}
+
+		if err = b.Put([]byte("key1"), []byte("value")); err != nil {
+			return err
+		}
+
+		return b.Delete([]byte("key2"))
+	})
+	assert.NoError(t, err)
+
+	var revision uint64
+	for _, ch := range watches {
+		// operations of a txn are not ordered
+		events := map[string]kv.Event{}
+		for i := 0; i < 2; i++ {
+			ev := <-ch
+			events[string(ev.Key)] = ev
+		}
+
+		ev := events["key1"]
+		assert.Equal(t, kv.EventPut, ev.Type)
+		assert.Equal(t, "value", string(ev.Value))
+
+		ev = events["key2"]
+		assert.Equal(t, kv.EventDelete, ev.Type)
+
+		// the index of the entry is the same on every member
+		if revision == 0 {
+			revision = ev.Revision
+		}
+		assert.Equal(t, revision, ev.Revision)
+	}
+}
@@ -10,7 +10,7 @@ import (
 	"go.etcd.io/raft/v3/raftpb"
 	"go.uber.org/zap"
 	"google.golang.org/grpc"
-	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/credentials"
 )
 
 const (
@@ -32,31 +32,37 @@ type peer struct {
 	addr   string
 	msgCh  chan raftpb.Message
 	stopCh chan struct{}
+	doneCh chan struct{}
+	cancel context.CancelFunc
 	logger *zap.Logger
 
-	client pb.RaftClient
-
 	mtx         sync.RWMutex
 	active      bool
 	activeSince time.Time
 }
 
-func newPeer(id uint64, addr string, logger *zap.Logger) (*peer, error) {
+func newPeer(id uint64, addr string, creds credentials.TransportCredentials, logger *zap.Logger) (*peer, error) {
 	peer := &peer{
 		id:     id,
 		addr:   addr,
 		logger: logger,
 		msgCh:  make(chan raftpb.Message, 64),
 		stopCh: make(chan struct{}),
+		doneCh: make(chan struct{}),
 	}
 
-	cc, err := grpc.Dial(addr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	cc, err := grpc.Dial(addr, grpc.WithTransportCredentials(creds))
 	if err != nil {
 		return nil, err
 	}
 
+	// in-flight sending will be canceled once the peer is stopped
+	var baseCtx context.Context
+	baseCtx, peer.cancel = context.WithCancel(context.Background())
+
 	// send message asynchronously
 	go func() {
+		defer close(peer.doneCh)
 		defer cc.Close()
 
 		cli := pb.NewRaftClient(cc)
@@ -67,11 +73,14 @@ func newPeer(id uint64, addr string, logger *zap.Logger) (*peer, error) {
 				return
 
 			case msg := <-peer.msgCh:
-				_, err = cli.Send(context.Background(), &msg)
+				ctx, cancel := context.WithTimeout(baseCtx, DefaultConnWriteTimeout)
+				_, err = cli.Send(ctx, &msg)
+				cancel()
 				if err != nil {
 					peer.logger.Warn("send raft message failed",
 						zap.Uint64("to", id),
-						zap.String("addr", addr))
+						zap.String("addr", addr),
+						zap.Error(err))
 					peer.setInactive()
 				} else {
 					peer.setActive()
@@ -84,21 +93,21 @@ func newPeer(id uint64, addr string, logger *zap.Logger) (*peer, error) {
 }
 
 func (peer *peer) send(msg raftpb.Message) {
-	ctx, cancel := context.WithTimeout(context.Background(), DefaultConnWriteTimeout)
-	defer cancel()
-
-	_, err := peer.client.Send(ctx, &msg)
-	if err != nil {
-		peer.logger.Warn("send message failed",
-			zap.Error(err))
-		return
+	select {
+	case peer.msgCh <- msg:
+	case <-peer.stopCh:
+	default:
+		peer.logger.Warn("dropped raft message, sending buffer is full",
+			zap.Uint64("to", peer.id),
+			zap.String("type", msg.Type.String()))
 	}
-
-	return
 }
 
+// stop stops the sending routine and wait for it to exit
 func (peer *peer) stop() {
 	close(peer.stopCh)
+	peer.cancel()
+	<-peer.doneCh
 }
 
 func (peer *peer) setActive() {
@@ -118,3 +127,10 @@ func (peer *peer) setInactive() {
 	peer.active = false
 	peer.activeSince = time.Time{}
 }
+
+func (peer *peer) since() time.Time {
+	peer.mtx.RLock()
+	defer peer.mtx.RUnlock()
+
+	return peer.activeSince
+}
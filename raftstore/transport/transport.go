@@ -1,12 +1,16 @@
 package transport
 
 import (
+	"crypto/tls"
 	"errors"
 	"strconv"
 	"sync"
+	"time"
 
 	"go.etcd.io/raft/v3/raftpb"
 	"go.uber.org/zap"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
 )
 
 var (
@@ -16,14 +20,23 @@ var (
 
 type Transporter struct {
 	logger *zap.Logger
+	creds  credentials.TransportCredentials
 
 	mtx   sync.RWMutex
 	peers map[uint64]*peer
 }
 
-func New(logger *zap.Logger) *Transporter {
+// New creates a Transporter, TLS is used to connect peers if tlsConfig
+// is not nil.
+func New(logger *zap.Logger, tlsConfig *tls.Config) *Transporter {
+	creds := insecure.NewCredentials()
+	if tlsConfig != nil {
+		creds = credentials.NewTLS(tlsConfig)
+	}
+
 	return &Transporter{
 		logger: logger,
+		creds:  creds,
 		peers:  make(map[uint64]*peer),
 	}
 }
@@ -31,7 +44,7 @@ func New(logger *zap.Logger) *Transporter {
 func (t *Transporter) Send(msgs []raftpb.Message) {
 	for _, m := range msgs {
 		if m.To == 0 {
-			return
+			continue
 		}
 
 		t.mtx.RLock()
@@ -81,7 +94,7 @@ func (t *Transporter) AddPeer(id uint64, addr string) error {
 		zap.String("id", strconv.FormatUint(id, 16)),
 		zap.String("addr", addr))
 
-	p, err := newPeer(id, addr, t.logger)
+	p, err := newPeer(id, addr, t.creds, t.logger)
 	if err != nil {
 		return err
 	}
@@ -121,6 +134,21 @@ func (t *Transporter) Peers() map[uint64]string {
 	return m
 }
 
+// ActiveSince returns the time that the connection with the peer
+// of the given id became active. If the peer is not active or
+// not found, zero time is returned.
+func (t *Transporter) ActiveSince(id uint64) time.Time {
+	t.mtx.RLock()
+	p, ok := t.peers[id]
+	t.mtx.RUnlock()
+
+	if !ok {
+		return time.Time{}
+	}
+
+	return p.since()
+}
+
 func (t *Transporter) Removed(id uint64) bool {
 	t.mtx.RLock()
 	_, ok := t.peers[id]
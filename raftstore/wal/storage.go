@@ -3,6 +3,8 @@ package wal
 import (
 	"fmt"
 	"math"
+	"os"
+	"path/filepath"
 	"sync"
 
 	"github.com/pkg/errors"
@@ -351,3 +353,10 @@ func (w *DiskStorage) Sync() error {
 func (w *DiskStorage) Close() error {
 	return w.Sync()
 }
+
+// Exist returns true if the dir contains a wal.meta file, which means
+// the raft storage has been initialized.
+func Exist(dir string) bool {
+	_, err := os.Stat(filepath.Join(dir, metaName))
+	return err == nil
+}
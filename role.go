@@ -0,0 +1,206 @@
+package manta
+
+import (
+	"context"
+	"strconv"
+	"time"
+)
+
+var (
+	ErrRoleNotFound = &Error{
+		Code: ENotFound,
+		Msg:  "role not found",
+	}
+
+	ErrRoleAlreadyExist = &Error{
+		Code: EConflict,
+		Msg:  "role already exist",
+	}
+
+	ErrRoleInUse = &Error{
+		Code: EConflict,
+		Msg:  "role is assigned to members",
+	}
+)
+
+const (
+	ViewerRole       = "viewer"
+	EditorRole       = "editor"
+	AlertManagerRole = "alert-manager"
+)
+
+// Role is a named set of permissions, which is assigned to the members of
+// an organization. The permissions of a role have resource type and action
+// only, they are bound to the organization when the role is assigned.
+type Role struct {
+	Created     time.Time    `json:"created"`
+	Updated     time.Time    `json:"updated"`
+	OrgID       ID           `json:"orgID,omitempty"`
+	Name        string       `json:"name"`
+	Desc        string       `json:"desc,omitempty"`
+	BuiltIn     bool         `json:"builtIn,omitempty"`
+	Permissions []Permission `json:"permissions"`
+}
+
+// Validate returns an error if the custom role is invalid
+func (r *Role) Validate() error {
+	if r.Name == "" {
+		return &Error{
+			Code: EInvalid,
+			Msg:  "name of role is required",
+		}
+	}
+
+	if IsBuiltInRole(r.Name) || UserType(r.Name).Valid() == nil {
+		return &Error{
+			Code: EInvalid,
+			Msg:  "role name " + strconv.Quote(r.Name) + " is reserved",
+		}
+	}
+
+	for _, p := range r.Permissions {
+		if err := p.Valid(); err != nil {
+			return err
+		}
+
+		if p.Resource.ID != nil || p.Resource.OrgID != nil {
+			return &Error{
+				Code: EInvalid,
+				Msg:  "permissions of role cannot have resource or org id",
+			}
+		}
+	}
+
+	return nil
+}
+
+// PermissionsAt binds the permissions of the role to the organization, the
+// organization itself is always readable.
+func (r *Role) PermissionsAt(orgID ID) []Permission {
+	ps := []Permission{
+		{
+			Action: ReadAction,
+			Resource: Resource{
+				Type: OrgsResourceType,
+				ID:   &orgID,
+			},
+		},
+	}
+
+	for _, p := range r.Permissions {
+		resource := Resource{Type: p.Resource.Type}
+		if p.Resource.Type == OrgsResourceType {
+			resource.ID = &orgID
+		} else {
+			resource.OrgID = &orgID
+		}
+
+		ps = append(ps, Permission{
+			Action:   p.Action,
+			Resource: resource,
+		})
+	}
+
+	return ps
+}
+
+func rolePermissions(read []ResourceType, write []ResourceType) []Permission {
+	ps := make([]Permission, 0, len(read)+len(write))
+	for _, rt := range read {
+		ps = append(ps, Permission{Action: ReadAction, Resource: Resource{Type: rt}})
+	}
+
+	for _, rt := range write {
+		ps = append(ps, Permission{Action: WriteAction, Resource: Resource{Type: rt}})
+	}
+
+	return ps
+}
+
+// BuiltInRoles returns the roles available in every organization
+func BuiltInRoles() []*Role {
+	return []*Role{
+		{
+			Name:        ViewerRole,
+			Desc:        "Read all resources of the organization",
+			BuiltIn:     true,
+			Permissions: rolePermissions(AllResourceTypes, nil),
+		},
+		{
+			Name:    EditorRole,
+			Desc:    "Read all resources and write the resources except members and authorizations",
+			BuiltIn: true,
+			Permissions: rolePermissions(AllResourceTypes, []ResourceType{
+				ChecksResourceType,
+				ConfigsResourceType,
+				DashboardsResourceType,
+				NotificationEndpointsResourceType,
+				ScrapesResourceType,
+				SecretsResourceType,
+				TasksResourceType,
+			}),
+		},
+		{
+			Name:    AlertManagerRole,
+			Desc:    "Read all resources and manage checks and notification endpoints",
+			BuiltIn: true,
+			Permissions: rolePermissions(AllResourceTypes, []ResourceType{
+				ChecksResourceType,
+				NotificationEndpointsResourceType,
+				TasksResourceType,
+			}),
+		},
+	}
+}
+
+// BuiltInRole returns the built-in role of the name, nil is returned if
+// no such role
+func BuiltInRole(name string) *Role {
+	for _, r := range BuiltInRoles() {
+		if r.Name == name {
+			return r
+		}
+	}
+
+	return nil
+}
+
+func IsBuiltInRole(name string) bool {
+	return BuiltInRole(name) != nil
+}
+
+type RoleUpdate struct {
+	Desc        *string       `json:"desc,omitempty"`
+	Permissions *[]Permission `json:"permissions,omitempty"`
+}
+
+func (upd *RoleUpdate) Apply(r *Role) {
+	if upd.Desc != nil {
+		r.Desc = *upd.Desc
+	}
+
+	if upd.Permissions != nil {
+		r.Permissions = *upd.Permissions
+	}
+}
+
+// RoleService manages the custom roles of organizations, built-in roles are
+// returned by finds too, but they cannot be changed.
+type RoleService interface {
+	// FindRole returns the role of the organization by name
+	FindRole(ctx context.Context, orgID ID, name string) (*Role, error)
+
+	// FindRoles returns the built-in roles and custom roles of the organization
+	FindRoles(ctx context.Context, orgID ID) ([]*Role, error)
+
+	// CreateRole creates a custom role, the name is unique in the organization
+	CreateRole(ctx context.Context, role *Role) error
+
+	// UpdateRole updates the custom role, the members it assigned to get the
+	// new permissions immediately
+	UpdateRole(ctx context.Context, orgID ID, name string, upd RoleUpdate) (*Role, error)
+
+	// DeleteRole deletes the custom role, roles assigned to members cannot be
+	// deleted
+	DeleteRole(ctx context.Context, orgID ID, name string) error
+}
@@ -2,13 +2,16 @@ package scrape
 
 import (
 	"context"
+	"encoding/json"
 	"sync"
 	"time"
 
 	"github.com/prometheus/prometheus/scrape"
 	"go.uber.org/zap"
+	"golang.org/x/sync/errgroup"
 
 	"github.com/f1shl3gs/manta"
+	"github.com/f1shl3gs/manta/kv"
 	"github.com/f1shl3gs/manta/multitsdb"
 )
 
@@ -16,8 +19,10 @@ type CoordinatingScrapeService struct {
 	logger *zap.Logger
 
 	// services
+	orgService          manta.OrganizationService
 	scrapeTargetService manta.ScrapeTargetService
 	tenantStorage       multitsdb.TenantStorage
+	watcher             kv.Watcher
 
 	mtx      sync.Mutex
 	scrapers map[manta.ID]*Scraper
@@ -29,10 +34,11 @@ func New(
 	orgService manta.OrganizationService,
 	scraperTargetService manta.ScrapeTargetService,
 	tenantStorage multitsdb.TenantStorage,
+	watcher kv.Watcher,
 ) (*CoordinatingScrapeService, error) {
 	orgs, _, err := orgService.FindOrganizations(ctx, manta.OrganizationFilter{})
 	if err != nil {
-		return nil, nil
+		return nil, err
 	}
 
 	scrapers := make(map[manta.ID]*Scraper)
@@ -47,12 +53,177 @@ func New(
 
 	return &CoordinatingScrapeService{
 		logger:              logger,
+		orgService:          orgService,
 		scrapeTargetService: scraperTargetService,
 		tenantStorage:       tenantStorage,
+		watcher:             watcher,
 		scrapers:            scrapers,
 	}, nil
 }
 
+// Run watches the changes of scrape targets and organizations until ctx
+// is done.
+func (s *CoordinatingScrapeService) Run(ctx context.Context) error {
+	group, ctx := errgroup.WithContext(ctx)
+
+	group.Go(func() error {
+		return s.watchTargets(ctx)
+	})
+
+	group.Go(func() error {
+		return s.watchOrganizations(ctx)
+	})
+
+	return group.Wait()
+}
+
+// watchTargets syncs the scrapers of the changed orgs, so targets changed
+// on any node take effect.
+func (s *CoordinatingScrapeService) watchTargets(ctx context.Context) error {
+	for {
+		events, err := s.watcher.Watch(ctx, kv.ScraperBucket, nil)
+		if err != nil {
+			return err
+		}
+
+		for ev := range events {
+			if ev.Type == kv.EventDelete {
+				// the org of deleted target is unknown
+				s.syncAll()
+				continue
+			}
+
+			target := &manta.ScrapeTarget{}
+			if err = json.Unmarshal(ev.Value, target); err != nil {
+				s.logger.Warn("decode watched scrape target failed",
+					zap.ByteString("key", ev.Key),
+					zap.Error(err))
+				continue
+			}
+
+			s.syncScraper(target.OrgID)
+		}
+
+		if ctx.Err() != nil {
+			return nil
+		}
+
+		// the watcher is closed by store, changes might be lost
+		s.syncAll()
+	}
+}
+
+// watchOrganizations stops the scrapers and removes the storage of deleted
+// orgs, the org is deleted on any node.
+func (s *CoordinatingScrapeService) watchOrganizations(ctx context.Context) error {
+	for {
+		events, err := s.watcher.Watch(ctx, kv.OrganizationBucket, nil)
+		if err != nil {
+			return err
+		}
+
+		// orgs might be deleted before watching
+		if err = s.removeDeletedOrgs(ctx); err != nil {
+			s.logger.Warn("remove scrapers of deleted orgs failed",
+				zap.Error(err))
+		}
+
+		for ev := range events {
+			if ev.Type != kv.EventDelete {
+				continue
+			}
+
+			var orgID manta.ID
+			if err = orgID.Decode(ev.Key); err != nil {
+				s.logger.Warn("decode watched organization failed",
+					zap.ByteString("key", ev.Key),
+					zap.Error(err))
+				continue
+			}
+
+			s.removeOrg(ctx, orgID)
+		}
+
+		if ctx.Err() != nil {
+			return nil
+		}
+	}
+}
+
+// removeDeletedOrgs removes the scrapers and the storage of orgs not exist
+// anymore. The tenants of the storage are reconciled too, since orgs deleted
+// when the node was down have storage but no scraper.
+func (s *CoordinatingScrapeService) removeDeletedOrgs(ctx context.Context) error {
+	// tenants are listed before orgs, so the storage of orgs created
+	// in the meantime is not removed
+	tenants, err := s.tenantStorage.Tenants(ctx)
+	if err != nil {
+		return err
+	}
+
+	s.mtx.Lock()
+	for orgID := range s.scrapers {
+		tenants = append(tenants, orgID)
+	}
+	s.mtx.Unlock()
+
+	orgs, _, err := s.orgService.FindOrganizations(ctx, manta.OrganizationFilter{})
+	if err != nil {
+		return err
+	}
+
+	exists := make(map[manta.ID]struct{}, len(orgs))
+	for _, org := range orgs {
+		exists[org.ID] = struct{}{}
+	}
+
+	var deleted []manta.ID
+	for _, orgID := range tenants {
+		if _, ok := exists[orgID]; !ok {
+			// the org might be listed twice
+			exists[orgID] = struct{}{}
+			deleted = append(deleted, orgID)
+		}
+	}
+
+	for _, orgID := range deleted {
+		s.removeOrg(ctx, orgID)
+	}
+
+	return nil
+}
+
+// removeOrg stops the scraper of the org, and removes the storage of it
+func (s *CoordinatingScrapeService) removeOrg(ctx context.Context, orgID manta.ID) {
+	s.mtx.Lock()
+	scraper, exist := s.scrapers[orgID]
+	delete(s.scrapers, orgID)
+	s.mtx.Unlock()
+
+	if exist {
+		scraper.stop()
+	}
+
+	if err := s.tenantStorage.RemoveTenant(ctx, orgID); err != nil {
+		s.logger.Warn("remove storage of deleted org failed",
+			zap.Stringer("org", orgID),
+			zap.Error(err))
+		return
+	}
+
+	s.logger.Info("Organization is deleted, scraper is stopped and storage is removed",
+		zap.Stringer("org", orgID))
+}
+
+func (s *CoordinatingScrapeService) syncAll() {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	for _, scraper := range s.scrapers {
+		go scraper.syncTargets()
+	}
+}
+
 func (s *CoordinatingScrapeService) syncScraper(orgID manta.ID) {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
@@ -93,13 +264,8 @@ func (s *CoordinatingScrapeService) FindScrapeTargets(
 }
 
 func (s *CoordinatingScrapeService) CreateScrapeTarget(ctx context.Context, target *manta.ScrapeTarget) error {
-	if err := s.scrapeTargetService.CreateScrapeTarget(ctx, target); err != nil {
-		return err
-	}
-
-	s.syncScraper(target.OrgID)
-
-	return nil
+	// scrapers are synced by Run once the change is committed
+	return s.scrapeTargetService.CreateScrapeTarget(ctx, target)
 }
 
 // UpdateScrapeTarget update a single ScraperTarget with changeset
@@ -109,31 +275,12 @@ func (s *CoordinatingScrapeService) UpdateScrapeTarget(
 	id manta.ID,
 	upd manta.ScrapeTargetUpdate,
 ) (*manta.ScrapeTarget, error) {
-	target, err := s.scrapeTargetService.UpdateScrapeTarget(ctx, id, upd)
-	if err != nil {
-		return nil, err
-	}
-
-	s.syncScraper(target.OrgID)
-
-	return target, nil
+	return s.scrapeTargetService.UpdateScrapeTarget(ctx, id, upd)
 }
 
 // DeleteScrapeTarget delete a single ScraperTarget by ID
 func (s *CoordinatingScrapeService) DeleteScrapeTarget(ctx context.Context, id manta.ID) error {
-	target, err := s.scrapeTargetService.FindScrapeTargetByID(ctx, id)
-	if err != nil {
-		return err
-	}
-
-	err = s.scrapeTargetService.DeleteScrapeTarget(ctx, id)
-	if err != nil {
-		return err
-	}
-
-	s.syncScraper(target.OrgID)
-
-	return nil
+	return s.scrapeTargetService.DeleteScrapeTarget(ctx, id)
 }
 
 // TargetsActive implement TenantTargetRetriever
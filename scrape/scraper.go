@@ -22,6 +22,7 @@ type Scraper struct {
 	mgr                 *scrape.Manager
 	syncCh              chan map[string][]*targetgroup.Group
 	scrapeTargetService manta.ScrapeTargetService
+	cancel              context.CancelFunc
 }
 
 func newScraper(
@@ -35,7 +36,8 @@ func newScraper(
 
 	mgr := scrape.NewManager(nil, kl, appendable)
 
-	ch := newScrapPool(context.Background(), logger, orgID, mgr, scrapeTargetService)
+	ctx, cancel := context.WithCancel(context.Background())
+	ch := newScrapPool(ctx, logger, orgID, mgr, scrapeTargetService)
 
 	scraper := &Scraper{
 		orgID:  orgID,
@@ -44,6 +46,7 @@ func newScraper(
 		mgr:                 mgr,
 		syncCh:              ch,
 		scrapeTargetService: scrapeTargetService,
+		cancel:              cancel,
 	}
 
 	go func() {
@@ -58,6 +61,12 @@ func newScraper(
 	return scraper
 }
 
+// stop stops scraping all targets
+func (s *Scraper) stop() {
+	s.cancel()
+	s.mgr.Stop()
+}
+
 func (s *Scraper) syncTargets() {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
@@ -61,9 +61,34 @@ func (s *Secret) Desensitize() {
 	s.Value = ""
 }
 
-type SecretService interface {
+// SecretLoader retrieves secret values, it is implemented by SecretService
+// and the external secret providers.
+type SecretLoader interface {
 	// LoadSecret retrieves the secret value v found at key k for organization orgID
 	LoadSecret(ctx context.Context, orgID ID, k string) (*Secret, error)
+}
+
+// LoadSecretFields fills the values of fields with loader, fields without
+// key or with value already are skipped.
+func LoadSecretFields(ctx context.Context, loader SecretLoader, orgID ID, fields ...*SecretField) error {
+	for _, field := range fields {
+		if field.Key == "" || field.Value != nil {
+			continue
+		}
+
+		secret, err := loader.LoadSecret(ctx, orgID, field.Key)
+		if err != nil {
+			return err
+		}
+
+		field.Value = strPtr(secret.Value)
+	}
+
+	return nil
+}
+
+type SecretService interface {
+	SecretLoader
 
 	// GetSecrets retrieves desensitized secrets of 'orgID'
 	GetSecrets(ctx context.Context, orgID ID) ([]Secret, error)
@@ -0,0 +1,67 @@
+package secret
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/f1shl3gs/manta"
+)
+
+// FileProvider loads secrets from a directory of files, the layout is
+// <dir>/<orgID>/<key>, which is the same as a mounted Kubernetes secret
+// per organization. The trailing newline of the file is trimmed.
+type FileProvider struct {
+	dir string
+}
+
+var _ manta.SecretLoader = &FileProvider{}
+
+func NewFileProvider(dir string) *FileProvider {
+	return &FileProvider{dir: dir}
+}
+
+// LoadSecret retrieves the secret value v found at key k for organization orgID
+func (p *FileProvider) LoadSecret(ctx context.Context, orgID manta.ID, k string) (*manta.Secret, error) {
+	// Kubernetes creates hidden files and dirs for atomic updates, and the
+	// key must not escape the dir of the organization
+	if k == "" || strings.HasPrefix(k, ".") || strings.ContainsAny(k, `/\`) {
+		return nil, &manta.Error{
+			Code: manta.EInvalid,
+			Msg:  "invalid secret key",
+		}
+	}
+
+	path := filepath.Join(p.dir, orgID.String(), k)
+	fi, err := os.Stat(path)
+	if os.IsNotExist(err) {
+		return nil, &manta.Error{
+			Code: manta.ENotFound,
+			Msg:  "secret not found",
+		}
+	}
+	if err != nil {
+		return nil, &manta.Error{
+			Code: manta.EInternal,
+			Msg:  "load secret from file failed",
+			Err:  err,
+		}
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, &manta.Error{
+			Code: manta.EInternal,
+			Msg:  "load secret from file failed",
+			Err:  err,
+		}
+	}
+
+	return &manta.Secret{
+		Key:     k,
+		OrgID:   orgID,
+		Updated: fi.ModTime(),
+		Value:   strings.TrimSuffix(string(data), "\n"),
+	}, nil
+}
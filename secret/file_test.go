@@ -0,0 +1,41 @@
+package secret
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/f1shl3gs/manta"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileProvider(t *testing.T) {
+	dir := t.TempDir()
+	orgID := manta.ID(1)
+
+	err := os.MkdirAll(filepath.Join(dir, orgID.String()), 0700)
+	require.NoError(t, err)
+	err = os.WriteFile(filepath.Join(dir, orgID.String(), "token"), []byte("foo\n"), 0600)
+	require.NoError(t, err)
+	err = os.WriteFile(filepath.Join(dir, "shared"), []byte("bar"), 0600)
+	require.NoError(t, err)
+
+	provider := NewFileProvider(dir)
+	ctx := context.Background()
+
+	secret, err := provider.LoadSecret(ctx, orgID, "token")
+	require.NoError(t, err)
+	assert.Equal(t, "foo", secret.Value)
+	assert.Equal(t, orgID, secret.OrgID)
+
+	_, err = provider.LoadSecret(ctx, manta.ID(2), "token")
+	assert.Equal(t, manta.ENotFound, manta.ErrorCode(err))
+
+	for _, key := range []string{"../shared", "..data", "a/b", ""} {
+		_, err = provider.LoadSecret(ctx, orgID, key)
+		assert.Equal(t, manta.EInvalid, manta.ErrorCode(err), key)
+	}
+}
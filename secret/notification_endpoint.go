@@ -0,0 +1,64 @@
+package secret
+
+import (
+	"context"
+
+	"github.com/f1shl3gs/manta"
+)
+
+// NotificationEndpointService resolves the secret fields of the notification
+// endpoints it returns with the loader, which is usually the provider chain.
+// Secret values are never marshaled, so it is safe to expose the endpoints.
+type NotificationEndpointService struct {
+	manta.NotificationEndpointService
+
+	loader manta.SecretLoader
+}
+
+var _ manta.NotificationEndpointService = &NotificationEndpointService{}
+
+func NewNotificationEndpointService(
+	service manta.NotificationEndpointService,
+	loader manta.SecretLoader,
+) *NotificationEndpointService {
+	return &NotificationEndpointService{
+		NotificationEndpointService: service,
+		loader:                      loader,
+	}
+}
+
+// FindNotificationEndpointByID returns a single notification endpoint by ID
+func (s *NotificationEndpointService) FindNotificationEndpointByID(
+	ctx context.Context,
+	id manta.ID,
+) (manta.NotificationEndpoint, error) {
+	ne, err := s.NotificationEndpointService.FindNotificationEndpointByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = ne.LoadSecrets(ctx, s.loader); err != nil {
+		return nil, err
+	}
+
+	return ne, nil
+}
+
+// FindNotificationEndpoints returns a list of notification endpoints that match filter.
+func (s *NotificationEndpointService) FindNotificationEndpoints(
+	ctx context.Context,
+	filter manta.NotificationEndpointFilter,
+) ([]manta.NotificationEndpoint, error) {
+	list, err := s.NotificationEndpointService.FindNotificationEndpoints(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, ne := range list {
+		if err = ne.LoadSecrets(ctx, s.loader); err != nil {
+			return nil, err
+		}
+	}
+
+	return list, nil
+}
@@ -0,0 +1,74 @@
+package secret
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/f1shl3gs/manta"
+	"github.com/f1shl3gs/manta/notification"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type endpointStore struct {
+	manta.NotificationEndpointService
+
+	endpoints []manta.NotificationEndpoint
+}
+
+func (s *endpointStore) FindNotificationEndpointByID(ctx context.Context, id manta.ID) (manta.NotificationEndpoint, error) {
+	for _, ne := range s.endpoints {
+		if ne.GetID() == id {
+			return ne, nil
+		}
+	}
+
+	return nil, &manta.Error{Code: manta.ENotFound, Msg: "notification endpoint not found"}
+}
+
+func (s *endpointStore) FindNotificationEndpoints(ctx context.Context, filter manta.NotificationEndpointFilter) ([]manta.NotificationEndpoint, error) {
+	return s.endpoints, nil
+}
+
+func TestNotificationEndpointService(t *testing.T) {
+	orgID := manta.ID(1)
+	dir := t.TempDir()
+	err := os.MkdirAll(filepath.Join(dir, orgID.String()), 0700)
+	require.NoError(t, err)
+	err = os.WriteFile(filepath.Join(dir, orgID.String(), "password"), []byte("from-file"), 0600)
+	require.NoError(t, err)
+
+	chain := NewService(nil, NewFileProvider(dir), mapLoader{"username": "from-kv"})
+	ne := &notification.HTTP{
+		Username: manta.SecretField{Key: "username"},
+		Password: manta.SecretField{Key: "password"},
+	}
+	ne.ID = 2
+	ne.OrgID = orgID
+
+	svc := NewNotificationEndpointService(&endpointStore{
+		endpoints: []manta.NotificationEndpoint{ne},
+	}, chain)
+	ctx := context.Background()
+
+	found, err := svc.FindNotificationEndpointByID(ctx, ne.ID)
+	require.NoError(t, err)
+	h := found.(*notification.HTTP)
+	assert.Equal(t, "from-kv", *h.Username.Value)
+	assert.Equal(t, "from-file", *h.Password.Value)
+
+	list, err := svc.FindNotificationEndpoints(ctx, manta.NotificationEndpointFilter{OrgID: orgID})
+	require.NoError(t, err)
+	require.Len(t, list, 1)
+	assert.Equal(t, "from-file", *list[0].(*notification.HTTP).Password.Value)
+
+	// resolved values must not be exposed
+	data, err := json.Marshal(found)
+	require.NoError(t, err)
+	assert.NotContains(t, string(data), "from-file")
+	assert.NotContains(t, string(data), "from-kv")
+}
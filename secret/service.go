@@ -0,0 +1,53 @@
+package secret
+
+import (
+	"context"
+	"errors"
+
+	"github.com/f1shl3gs/manta"
+	"github.com/f1shl3gs/manta/kv"
+)
+
+// Service resolves secrets through the providers in order, the first
+// provider which has the secret wins. Other operations are served by
+// the underlying SecretService, which is usually the kv store.
+type Service struct {
+	manta.SecretService
+
+	providers []manta.SecretLoader
+}
+
+var _ manta.SecretService = &Service{}
+
+// NewService creates a Service with the providers, the underlying
+// SecretService must be added to providers explicitly if it should
+// be consulted too.
+func NewService(service manta.SecretService, providers ...manta.SecretLoader) *Service {
+	return &Service{
+		SecretService: service,
+		providers:     providers,
+	}
+}
+
+// LoadSecret retrieves the secret value v found at key k for organization orgID
+func (s *Service) LoadSecret(ctx context.Context, orgID manta.ID, k string) (*manta.Secret, error) {
+	for _, provider := range s.providers {
+		secret, err := provider.LoadSecret(ctx, orgID, k)
+		if err == nil {
+			return secret, nil
+		}
+
+		if !isNotFound(err) {
+			return nil, err
+		}
+	}
+
+	return nil, &manta.Error{
+		Code: manta.ENotFound,
+		Msg:  "secret not found",
+	}
+}
+
+func isNotFound(err error) bool {
+	return errors.Is(err, kv.ErrKeyNotFound) || manta.ErrorCode(err) == manta.ENotFound
+}
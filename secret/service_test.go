@@ -0,0 +1,65 @@
+package secret
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/f1shl3gs/manta"
+	"github.com/f1shl3gs/manta/notification"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type mapLoader map[string]string
+
+func (m mapLoader) LoadSecret(ctx context.Context, orgID manta.ID, k string) (*manta.Secret, error) {
+	value, ok := m[k]
+	if !ok {
+		return nil, &manta.Error{Code: manta.ENotFound, Msg: "secret not found"}
+	}
+
+	return &manta.Secret{Key: k, OrgID: orgID, Value: value}, nil
+}
+
+func TestServiceChain(t *testing.T) {
+	orgID := manta.ID(1)
+	dir := t.TempDir()
+	err := os.MkdirAll(filepath.Join(dir, orgID.String()), 0700)
+	require.NoError(t, err)
+	err = os.WriteFile(filepath.Join(dir, orgID.String(), "password"), []byte("from-file"), 0600)
+	require.NoError(t, err)
+
+	kvLoader := mapLoader{
+		"token":    "from-kv",
+		"password": "shadowed",
+	}
+	svc := NewService(nil, NewFileProvider(dir), kvLoader)
+	ctx := context.Background()
+
+	secret, err := svc.LoadSecret(ctx, orgID, "password")
+	require.NoError(t, err)
+	assert.Equal(t, "from-file", secret.Value)
+
+	secret, err = svc.LoadSecret(ctx, orgID, "token")
+	require.NoError(t, err)
+	assert.Equal(t, "from-kv", secret.Value)
+
+	_, err = svc.LoadSecret(ctx, orgID, "missing")
+	assert.Equal(t, manta.ENotFound, manta.ErrorCode(err))
+
+	// secret fields of notification endpoints are resolved by the chain
+	ne := &notification.HTTP{
+		Token:    manta.SecretField{Key: "token"},
+		Password: manta.SecretField{Key: "password"},
+	}
+	ne.OrgID = orgID
+
+	err = ne.LoadSecrets(ctx, svc)
+	require.NoError(t, err)
+	assert.Equal(t, "from-kv", *ne.Token.Value)
+	assert.Equal(t, "from-file", *ne.Password.Value)
+	assert.Nil(t, ne.Username.Value)
+}
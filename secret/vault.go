@@ -0,0 +1,165 @@
+package secret
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/f1shl3gs/manta"
+)
+
+const (
+	defaultVaultTimeout = 5 * time.Second
+)
+
+// VaultConfig is the configuration of VaultProvider
+type VaultConfig struct {
+	// Address is the address of the Vault server, e.g. https://vault:8200
+	Address string
+
+	// Token is used to authenticate with the Vault server
+	Token string
+
+	// Namespace is the Vault Enterprise namespace, optional
+	Namespace string
+
+	// Mount is the mount path of the KV v2 secrets engine, default is "secret"
+	Mount string
+
+	// Prefix is prepended to the secret path, secrets are read from
+	// <mount>/data/<prefix>/<orgID>/<key>, default is "manta"
+	Prefix string
+
+	// Field is the field of the secret data to use as value, default is "value"
+	Field string
+
+	Timeout time.Duration
+}
+
+// VaultProvider loads secrets from a Vault KV v2 compatible HTTP API
+type VaultProvider struct {
+	cf     VaultConfig
+	client *http.Client
+}
+
+var _ manta.SecretLoader = &VaultProvider{}
+
+func NewVaultProvider(cf VaultConfig) (*VaultProvider, error) {
+	if _, err := url.Parse(cf.Address); err != nil || cf.Address == "" {
+		return nil, fmt.Errorf("invalid vault address %q", cf.Address)
+	}
+
+	if cf.Mount == "" {
+		cf.Mount = "secret"
+	}
+	if cf.Prefix == "" {
+		cf.Prefix = "manta"
+	}
+	if cf.Field == "" {
+		cf.Field = "value"
+	}
+	if cf.Timeout == 0 {
+		cf.Timeout = defaultVaultTimeout
+	}
+
+	return &VaultProvider{
+		cf: cf,
+		client: &http.Client{
+			Timeout: cf.Timeout,
+		},
+	}, nil
+}
+
+// kvV2Response is the response of reading a secret from KV v2 engine
+type kvV2Response struct {
+	Data struct {
+		Data     map[string]interface{} `json:"data"`
+		Metadata struct {
+			CreatedTime time.Time `json:"created_time"`
+			Version     int       `json:"version"`
+		} `json:"metadata"`
+	} `json:"data"`
+}
+
+// LoadSecret retrieves the secret value v found at key k for organization orgID
+func (p *VaultProvider) LoadSecret(ctx context.Context, orgID manta.ID, k string) (*manta.Secret, error) {
+	if k == "" || strings.Contains(k, "..") {
+		return nil, &manta.Error{
+			Code: manta.EInvalid,
+			Msg:  "invalid secret key",
+		}
+	}
+
+	u := strings.TrimSuffix(p.cf.Address, "/") + "/v1/" +
+		path.Join(p.cf.Mount, "data", p.cf.Prefix, orgID.String(), url.PathEscape(k))
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("X-Vault-Token", p.cf.Token)
+	if p.cf.Namespace != "" {
+		req.Header.Set("X-Vault-Namespace", p.cf.Namespace)
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, &manta.Error{
+			Code: manta.EUnavailable,
+			Msg:  "load secret from vault failed",
+			Err:  err,
+		}
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return nil, &manta.Error{
+			Code: manta.ENotFound,
+			Msg:  "secret not found",
+		}
+	default:
+		return nil, &manta.Error{
+			Code: manta.EUnavailable,
+			Msg:  fmt.Sprintf("load secret from vault failed, status code %d", resp.StatusCode),
+		}
+	}
+
+	var body kvV2Response
+	if err = json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, &manta.Error{
+			Code: manta.EInternal,
+			Msg:  "decode vault response failed",
+			Err:  err,
+		}
+	}
+
+	// the latest version is deleted
+	if body.Data.Data == nil {
+		return nil, &manta.Error{
+			Code: manta.ENotFound,
+			Msg:  "secret not found",
+		}
+	}
+
+	value, ok := body.Data.Data[p.cf.Field].(string)
+	if !ok {
+		return nil, &manta.Error{
+			Code: manta.EInternal,
+			Msg:  fmt.Sprintf("field %q of vault secret is not a string", p.cf.Field),
+		}
+	}
+
+	return &manta.Secret{
+		Key:     k,
+		OrgID:   orgID,
+		Updated: body.Data.Metadata.CreatedTime,
+		Value:   value,
+	}, nil
+}
@@ -0,0 +1,80 @@
+package secret
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/f1shl3gs/manta"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newVaultStandIn starts a server serving the KV v2 read API, secrets is
+// keyed by the path after "/v1/"
+func newVaultStandIn(t *testing.T, token string, secrets map[string]map[string]interface{}) *httptest.Server {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Vault-Token") != token {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+
+		data, ok := secrets[strings.TrimPrefix(r.URL.Path, "/v1/")]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"data": map[string]interface{}{
+				"data": data,
+				"metadata": map[string]interface{}{
+					"created_time": "2023-01-01T00:00:00Z",
+					"version":      1,
+				},
+			},
+		})
+	}))
+	t.Cleanup(srv.Close)
+
+	return srv
+}
+
+func TestVaultProvider(t *testing.T) {
+	orgID := manta.ID(1)
+	srv := newVaultStandIn(t, "root", map[string]map[string]interface{}{
+		"secret/data/manta/" + orgID.String() + "/token": {"value": "foo"},
+		"secret/data/manta/" + orgID.String() + "/port":  {"value": 8080},
+	})
+
+	ctx := context.Background()
+	provider, err := NewVaultProvider(VaultConfig{
+		Address: srv.URL,
+		Token:   "root",
+	})
+	require.NoError(t, err)
+
+	secret, err := provider.LoadSecret(ctx, orgID, "token")
+	require.NoError(t, err)
+	assert.Equal(t, "foo", secret.Value)
+	assert.Equal(t, 2023, secret.Updated.Year())
+
+	_, err = provider.LoadSecret(ctx, orgID, "missing")
+	assert.Equal(t, manta.ENotFound, manta.ErrorCode(err))
+
+	_, err = provider.LoadSecret(ctx, orgID, "port")
+	assert.Equal(t, manta.EInternal, manta.ErrorCode(err))
+
+	provider, err = NewVaultProvider(VaultConfig{
+		Address: srv.URL,
+		Token:   "invalid",
+	})
+	require.NoError(t, err)
+
+	_, err = provider.LoadSecret(ctx, orgID, "token")
+	assert.Equal(t, manta.EUnavailable, manta.ErrorCode(err))
+}
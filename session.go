@@ -2,6 +2,8 @@ package manta
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"time"
 )
@@ -17,6 +19,8 @@ type Session struct {
 	LastSeen    time.Time    `json:"lastSeen"`
 	ExpiresAt   time.Time    `json:"expiresAt"`
 	UserID      ID           `json:"userId"`
+	UserAgent   string       `json:"userAgent,omitempty"`
+	IP          string       `json:"ip,omitempty"`
 	Permissions []Permission `json:"permissions,omitempty"`
 }
 
@@ -24,22 +28,43 @@ func (s *Session) Expired() bool {
 	return time.Now().After(s.ExpiresAt)
 }
 
+// SessionOptions records the client which the session is created for
+type SessionOptions struct {
+	UserAgent string
+	IP        string
+}
+
 type SessionService interface {
 	// CreateSession create a new session
-	CreateSession(ctx context.Context, uid ID) (*Session, error)
+	CreateSession(ctx context.Context, uid ID, opts ...SessionOptions) (*Session, error)
 
 	// FindSession find session by key
 	FindSession(ctx context.Context, id ID) (*Session, error)
 
+	// FindSessions returns the active sessions of the user
+	FindSessions(ctx context.Context, uid ID) ([]*Session, error)
+
 	// RevokeSession delete the session, if the session does not
 	// exist then nothing is done and a nil error is returned.
 	RevokeSession(ctx context.Context, id ID) error
 
+	// RevokeSessions deletes all sessions of the user
+	RevokeSessions(ctx context.Context, uid ID) error
+
 	// RenewSession renew the session and update the ExpireAt
 	RenewSession(ctx context.Context, id ID, expiration time.Time) error
 
-	// todo: clean up the sessions which will never be used,
-	//   whose ExpireAt > Now + TTL
+	// ExtendSession marks the session seen, and extends it by the idle
+	// timeout, but never beyond the absolute timeout
+	ExtendSession(ctx context.Context, id ID) error
+}
+
+// Handle identifies the session, but unlike ID it cannot be used to
+// authenticate, since the ID is the value of the session cookie. So it is
+// the one shown to users and admins, and used to revoke the session.
+func (s *Session) Handle() string {
+	sum := sha256.Sum256([]byte(s.ID.String()))
+	return hex.EncodeToString(sum[:16])
 }
 
 func (s *Session) Identifier() ID {
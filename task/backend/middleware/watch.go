@@ -0,0 +1,162 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/f1shl3gs/manta"
+	"github.com/f1shl3gs/manta/kv"
+)
+
+// TaskWatcher notifies the coordinator of task changes committed to the store,
+// so tasks created, updated or deleted on other nodes are scheduled or released
+// too. CoordinatingCheckService notifies the coordinator of local changes
+// immediately, it is fine to be notified twice, since scheduling is idempotent.
+type TaskWatcher struct {
+	logger      *zap.Logger
+	watcher     kv.Watcher
+	taskService manta.TaskService
+	coordinator Coordinator
+
+	// tasks is the last known state of tasks
+	tasks map[manta.ID]*manta.Task
+}
+
+func NewTaskWatcher(
+	logger *zap.Logger,
+	watcher kv.Watcher,
+	ts manta.TaskService,
+	coord Coordinator,
+) *TaskWatcher {
+	return &TaskWatcher{
+		logger:      logger,
+		watcher:     watcher,
+		taskService: ts,
+		coordinator: coord,
+		tasks:       make(map[manta.ID]*manta.Task),
+	}
+}
+
+// Run watches the tasks until ctx is done
+func (w *TaskWatcher) Run(ctx context.Context) error {
+	for {
+		// watching first then list, so we won't miss any changes
+		events, err := w.watcher.Watch(ctx, kv.TasksBucket, nil)
+		if err != nil {
+			return err
+		}
+
+		if err = w.reload(ctx); err != nil {
+			return err
+		}
+
+		for ev := range events {
+			w.handle(ctx, ev)
+		}
+
+		if ctx.Err() != nil {
+			return nil
+		}
+
+		w.logger.Warn("task watcher is closed by store, reload tasks")
+	}
+}
+
+// reload lists all tasks and notifies the coordinator of changes since
+// the last known state.
+func (w *TaskWatcher) reload(ctx context.Context) error {
+	tasks, err := w.taskService.FindTasks(ctx, manta.TaskFilter{})
+	if err != nil {
+		return err
+	}
+
+	seen := make(map[manta.ID]struct{}, len(tasks))
+	for _, task := range tasks {
+		seen[task.ID] = struct{}{}
+		w.update(ctx, task)
+	}
+
+	for id := range w.tasks {
+		if _, ok := seen[id]; !ok {
+			w.delete(ctx, id)
+		}
+	}
+
+	return nil
+}
+
+func (w *TaskWatcher) handle(ctx context.Context, ev kv.Event) {
+	var id manta.ID
+	if err := id.Decode(ev.Key); err != nil {
+		w.logger.Warn("decode watched task id failed",
+			zap.ByteString("key", ev.Key),
+			zap.Error(err))
+		return
+	}
+
+	if ev.Type == kv.EventDelete {
+		w.delete(ctx, id)
+		return
+	}
+
+	task := &manta.Task{}
+	if err := json.Unmarshal(ev.Value, task); err != nil {
+		w.logger.Warn("decode watched task failed",
+			zap.Stringer("task", id),
+			zap.Error(err))
+		return
+	}
+
+	w.update(ctx, task)
+}
+
+func (w *TaskWatcher) update(ctx context.Context, task *manta.Task) {
+	var (
+		err  error
+		from = w.tasks[task.ID]
+	)
+
+	w.tasks[task.ID] = task
+
+	switch {
+	case from == nil:
+		if task.Status != manta.TaskActive {
+			return
+		}
+
+		err = w.coordinator.TaskCreated(ctx, task)
+	case from.Status != task.Status || from.Cron != task.Cron:
+		// the run status of tasks is updated frequently, only changes
+		// of schedule matter
+		if from.Status == manta.TaskInactive && task.Status == manta.TaskActive {
+			task.LatestCompleted = time.Now()
+		}
+
+		err = w.coordinator.TaskUpdated(ctx, from, task)
+	default:
+		return
+	}
+
+	if err != nil {
+		w.logger.Warn("notify coordinator of task change failed",
+			zap.Stringer("task", task.ID),
+			zap.Error(err))
+	}
+}
+
+func (w *TaskWatcher) delete(ctx context.Context, id manta.ID) {
+	if _, ok := w.tasks[id]; !ok {
+		return
+	}
+
+	delete(w.tasks, id)
+
+	if err := w.coordinator.TaskDeleted(ctx, id); err != nil {
+		w.logger.Warn("delete task from coordinator failed",
+			zap.Stringer("task", id),
+			zap.Error(err))
+	}
+}
@@ -1,12 +1,24 @@
 import {RemoteDataState} from '@influxdata/clockface'
 
+export interface RolloutPolicy {
+  selector?: {[key: string]: string}
+  canary: number
+  autoPromote?: boolean
+  autoRollback?: boolean
+}
+
 export interface Config {
   id: string
   created: string
   updated: string
+  revision: number
   name: string
   desc: string
   data: string
+  format?: 'plain' | 'yaml' | 'toml' | 'json'
+  schema?: string
+  template?: boolean
+  rollout?: RolloutPolicy
 
   status: RemoteDataState
 }
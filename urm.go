@@ -53,6 +53,41 @@ type UserResourceMapping struct {
 	MappingType  MappingType  `json:"mappingType"`
 	ResourceType ResourceType `json:"resourceType"`
 	ResourceID   ID           `json:"resourceID"`
+
+	// Role is the name of the role assigned to the member of organization,
+	// the permissions of member are replaced by the permissions of the role.
+	Role string `json:"role,omitempty"`
+}
+
+// Validate returns an error if the mapping is invalid
+func (m *UserResourceMapping) Validate() error {
+	if err := m.UserType.Valid(); err != nil {
+		return &Error{
+			Code: EInvalid,
+			Msg:  "invalid user type",
+			Err:  err,
+		}
+	}
+
+	if m.Role == "" {
+		return nil
+	}
+
+	if m.ResourceType != OrgsResourceType {
+		return &Error{
+			Code: EInvalid,
+			Msg:  "roles can only be assigned to the members of organizations",
+		}
+	}
+
+	if m.UserType != Member {
+		return &Error{
+			Code: EInvalid,
+			Msg:  "roles can only be assigned to members, owners have all permissions",
+		}
+	}
+
+	return nil
 }
 
 type UserResourceMappingFilter struct {
@@ -122,7 +157,8 @@ func (m *UserResourceMapping) memberPerms() ([]Permission, error) {
 	return ps, nil
 }
 
-// ToPermissions converts a user resource mapping into a set of permissions
+// ToPermissions converts a user resource mapping into a set of permissions,
+// the role of the mapping is not resolved, use Role.PermissionsAt instead.
 func (m *UserResourceMapping) ToPermissions() ([]Permission, error) {
 	switch m.UserType {
 	case Owner:
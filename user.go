@@ -2,6 +2,7 @@ package manta
 
 import (
 	"context"
+	"strconv"
 	"time"
 )
 
@@ -20,6 +21,16 @@ var (
 		Code: EInvalid,
 		Msg:  "invalid user ID",
 	}
+
+	ErrUserDisabled = &Error{
+		Code: EForbidden,
+		Msg:  "user is disabled",
+	}
+)
+
+const (
+	UserActive   = "active"
+	UserInactive = "inactive"
 )
 
 type User struct {
@@ -30,6 +41,35 @@ type User struct {
 	Status  string    `json:"status,omitempty"`
 }
 
+// Active returns true if the user is allowed to sign in, users created
+// before the status is introduced have no status, and they are active.
+func (u *User) Active() bool {
+	return u.Status == "" || u.Status == UserActive
+}
+
+func validUserStatus(status string) error {
+	if status == UserActive || status == UserInactive {
+		return nil
+	}
+
+	return &Error{
+		Code: EInvalid,
+		Msg:  "invalid user status " + strconv.Quote(status),
+	}
+}
+
+// Validate returns an error if the user is invalid
+func (u *User) Validate() error {
+	if u.Name == "" {
+		return &Error{
+			Code: EInvalid,
+			Msg:  "name of user is required",
+		}
+	}
+
+	return validUserStatus(u.Status)
+}
+
 // UserFilter represents a set of filter that restrict the returned results.
 type UserFilter struct {
 	ID   *ID
@@ -37,13 +77,18 @@ type UserFilter struct {
 }
 
 type UserUpdate struct {
-	Name *string
+	Name   *string `json:"name,omitempty"`
+	Status *string `json:"status,omitempty"`
 }
 
 func (upd *UserUpdate) Apply(user *User) {
 	if upd.Name != nil {
 		user.Name = *upd.Name
 	}
+
+	if upd.Status != nil {
+		user.Status = *upd.Status
+	}
 }
 
 type UserService interface {
@@ -59,9 +104,11 @@ type UserService interface {
 	CreateUser(ctx context.Context, user *User) error
 
 	// UpdateUser a single user with changeset
-	// Return the new User after update
+	// Return the new User after update, the sessions of the user are revoked
+	// if the user is disabled
 	UpdateUser(ctx context.Context, id ID, upd UserUpdate) (*User, error)
 
-	// DeleteUser a user by ID
+	// DeleteUser a user by ID, the resource mappings, authorizations and
+	// sessions of the user are deleted too
 	DeleteUser(ctx context.Context, id ID) error
 }
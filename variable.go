@@ -0,0 +1,154 @@
+package manta
+
+import (
+	"regexp"
+
+	"github.com/pkg/errors"
+	"github.com/prometheus/common/model"
+)
+
+const (
+	// VariableLabelValues are the values of a label, of the series
+	// matched by the selector if set
+	VariableLabelValues VariableType = "label_values"
+	// VariableQuery are the values of a label of the series returned by the
+	// query, or the series themselves if label is not set
+	VariableQuery VariableType = "query"
+	// VariableConstant is a hidden variable with a single value
+	VariableConstant VariableType = "constant"
+	// VariableCustom are the values listed
+	VariableCustom VariableType = "custom"
+	// VariableInterval are the durations listed, e.g. 1m, 5m and 1h
+	VariableInterval VariableType = "interval"
+)
+
+// VariableAll is the value selecting all options of a variable
+const VariableAll = "$__all"
+
+var variableNameRE = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+type VariableType string
+
+// Variable is a template variable of dashboards, it is referenced as $name
+// or ${name} in the queries of cells, and replaced by the selected values
+// when the queries are interpolated.
+//
+// The selector and query can reference variables defined before.
+type Variable struct {
+	Name string       `json:"name"`
+	Desc string       `json:"desc,omitempty"`
+	Type VariableType `json:"type"`
+
+	// Label is the label whose values are the options, it is required by
+	// label_values, and optional for query
+	Label string `json:"label,omitempty"`
+	// Selector filters the series of label_values, e.g. up{job="node"}
+	Selector string `json:"selector,omitempty"`
+	// Query is the PromQL of query
+	Query string `json:"query,omitempty"`
+	// Regex filters the options of label_values and query, if it has a
+	// capture group, the first group is used as the option
+	Regex string `json:"regex,omitempty"`
+	// Values are the options of custom and interval, or the value of
+	// constant
+	Values []string `json:"values,omitempty"`
+
+	// Multi allows selecting more than one option
+	Multi bool `json:"multi,omitempty"`
+	// IncludeAll adds the "All" option, which selects all options
+	IncludeAll bool `json:"includeAll,omitempty"`
+	// AllValue replaces the variable if "All" is selected, the regex
+	// matches all options is used if it is empty, e.g. ".*"
+	AllValue string `json:"allValue,omitempty"`
+	// Selected are the options selected by default, the first option is
+	// selected if it is empty
+	Selected []string `json:"selected,omitempty"`
+}
+
+func (v *Variable) Validate() error {
+	if !variableNameRE.MatchString(v.Name) {
+		return errors.Errorf("invalid variable name %q", v.Name)
+	}
+
+	switch v.Type {
+	case VariableLabelValues:
+		if !model.LabelName(v.Label).IsValid() {
+			return errors.Errorf("invalid label %q of variable %s", v.Label, v.Name)
+		}
+	case VariableQuery:
+		if v.Query == "" {
+			return errors.Errorf("query of variable %s is required", v.Name)
+		}
+
+		if v.Label != "" && !model.LabelName(v.Label).IsValid() {
+			return errors.Errorf("invalid label %q of variable %s", v.Label, v.Name)
+		}
+	case VariableConstant:
+		if len(v.Values) != 1 {
+			return errors.Errorf("constant variable %s must have one value", v.Name)
+		}
+	case VariableCustom:
+		if len(v.Values) == 0 {
+			return errors.Errorf("values of variable %s is required", v.Name)
+		}
+	case VariableInterval:
+		if len(v.Values) == 0 {
+			return errors.Errorf("values of variable %s is required", v.Name)
+		}
+
+		for _, value := range v.Values {
+			if _, err := model.ParseDuration(value); err != nil {
+				return errors.Wrapf(err, "invalid interval of variable %s", v.Name)
+			}
+		}
+	default:
+		return errors.Errorf("unknown type %q of variable %s", v.Type, v.Name)
+	}
+
+	if v.Regex != "" {
+		if _, err := regexp.Compile(v.Regex); err != nil {
+			return errors.Wrapf(err, "invalid regex of variable %s", v.Name)
+		}
+	}
+
+	if len(v.Selected) > 1 && !v.Multi {
+		return errors.Errorf("variable %s cannot select more than one option", v.Name)
+	}
+
+	return nil
+}
+
+// ValidateVariables validates the variables, and their names are unique
+func ValidateVariables(variables []Variable) error {
+	names := make(map[string]struct{}, len(variables))
+	for i := range variables {
+		if err := variables[i].Validate(); err != nil {
+			return &Error{
+				Code: EInvalid,
+				Msg:  err.Error(),
+			}
+		}
+
+		if _, ok := names[variables[i].Name]; ok {
+			return &Error{
+				Code: EInvalid,
+				Msg:  "variable " + variables[i].Name + " is defined more than once",
+			}
+		}
+
+		names[variables[i].Name] = struct{}{}
+	}
+
+	return nil
+}
+
+// VariableValue is a resolved variable
+type VariableValue struct {
+	Name    string   `json:"name"`
+	Options []string `json:"options"`
+	// Selected are the selected options, it is VariableAll if all options
+	// are selected
+	Selected []string `json:"selected"`
+	// Value replaces the variable in queries
+	Value string `json:"value"`
+}
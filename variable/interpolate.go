@@ -0,0 +1,102 @@
+package variable
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/f1shl3gs/manta"
+)
+
+var referenceRE = regexp.MustCompile(`\$\{(\w+)\}|\$(\w+)`)
+
+// Interpolate replaces the references of variables, e.g. $job and ${job},
+// with the values of the variables, unknown references are kept.
+func Interpolate(text string, values []manta.VariableValue) string {
+	if len(values) == 0 || !strings.Contains(text, "$") {
+		return text
+	}
+
+	return referenceRE.ReplaceAllStringFunc(text, func(ref string) string {
+		name := strings.Trim(ref, "${}")
+		for _, value := range values {
+			if value.Name == name {
+				return value.Value
+			}
+		}
+
+		return ref
+	})
+}
+
+// InterpolateCells returns the cells whose queries are interpolated, the
+// cells passed in are not changed
+func InterpolateCells(cells []manta.Cell, values []manta.VariableValue) []manta.Cell {
+	interpolated := make([]manta.Cell, 0, len(cells))
+	for _, cell := range cells {
+		switch props := cell.ViewProperties.(type) {
+		case *manta.XYViewProperties:
+			p := *props
+			p.Queries = interpolateQueries(props.Queries, values)
+			cell.ViewProperties = &p
+		case *manta.GaugeViewProperties:
+			p := *props
+			p.Queries = interpolateQueries(props.Queries, values)
+			cell.ViewProperties = &p
+		case *manta.SingleStatViewProperties:
+			p := *props
+			p.Queries = interpolateQueries(props.Queries, values)
+			cell.ViewProperties = &p
+		case *manta.LinePlusSingleStatViewProperties:
+			p := *props
+			p.Queries = interpolateQueries(props.Queries, values)
+			cell.ViewProperties = &p
+		case *manta.TableViewProperties:
+			p := *props
+			p.Queries = interpolateQueries(props.Queries, values)
+			cell.ViewProperties = &p
+		case *manta.HeatmapViewProperties:
+			p := *props
+			p.Queries = interpolateQueries(props.Queries, values)
+			cell.ViewProperties = &p
+		case *manta.HistogramViewProperties:
+			p := *props
+			p.Queries = interpolateQueries(props.Queries, values)
+			cell.ViewProperties = &p
+		}
+
+		interpolated = append(interpolated, cell)
+	}
+
+	return interpolated
+}
+
+func interpolateQueries(queries []manta.Query, values []manta.VariableValue) []manta.Query {
+	if queries == nil {
+		return nil
+	}
+
+	interpolated := make([]manta.Query, len(queries))
+	for i, query := range queries {
+		query.Text = Interpolate(query.Text, values)
+		interpolated[i] = query
+	}
+
+	return interpolated
+}
+
+// escapeString escapes the value, so it can be used in double-quoted
+// strings of PromQL, e.g. job="$job"
+func escapeString(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(value)
+}
+
+// regexAlternation returns the regex matches any of the values, it is used
+// with regex matchers, e.g. job=~"$job"
+func regexAlternation(values []string) string {
+	quoted := make([]string, 0, len(values))
+	for _, value := range values {
+		quoted = append(quoted, escapeString(regexp.QuoteMeta(value)))
+	}
+
+	return "(" + strings.Join(quoted, "|") + ")"
+}
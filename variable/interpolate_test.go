@@ -0,0 +1,60 @@
+package variable
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/f1shl3gs/manta"
+)
+
+func TestInterpolate(t *testing.T) {
+	values := []manta.VariableValue{
+		{Name: "job", Value: "node"},
+		{Name: "instance", Value: "(a|b)"},
+		{Name: "interval", Value: "5m"},
+	}
+
+	for name, tc := range map[string]struct {
+		input string
+		want  string
+	}{
+		"no reference": {
+			input: "up",
+			want:  "up",
+		},
+		"dollar": {
+			input: `up{job="$job"}`,
+			want:  `up{job="node"}`,
+		},
+		"braces": {
+			input: `rate(http_requests_total{instance=~"${instance}"}[${interval}])`,
+			want:  `rate(http_requests_total{instance=~"(a|b)"}[5m])`,
+		},
+		"unknown": {
+			input: `up{job="$jobs", env="${env}"}`,
+			want:  `up{job="$jobs", env="${env}"}`,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, tc.want, Interpolate(tc.input, values))
+		})
+	}
+}
+
+func TestInterpolateCells(t *testing.T) {
+	props := &manta.XYViewProperties{
+		Queries: []manta.Query{{Text: `up{job="$job"}`}},
+	}
+	cells := []manta.Cell{
+		{ID: 1, ViewProperties: props},
+		{ID: 2, ViewProperties: &manta.MarkdownViewProperties{Content: "$job"}},
+	}
+
+	interpolated := InterpolateCells(cells, []manta.VariableValue{{Name: "job", Value: "node"}})
+	assert.Equal(t, `up{job="node"}`, interpolated[0].ViewProperties.(*manta.XYViewProperties).Queries[0].Text)
+	assert.Equal(t, "$job", interpolated[1].ViewProperties.(*manta.MarkdownViewProperties).Content)
+
+	// cells passed in are not changed
+	assert.Equal(t, `up{job="$job"}`, props.Queries[0].Text)
+}
@@ -0,0 +1,281 @@
+// Package variable resolves the template variables of dashboards, and
+// interpolates them into the queries of cells.
+package variable
+
+import (
+	"context"
+	"regexp"
+	"sort"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/model/timestamp"
+	"github.com/prometheus/prometheus/promql"
+	"github.com/prometheus/prometheus/promql/parser"
+	"github.com/prometheus/prometheus/storage"
+
+	"github.com/f1shl3gs/manta"
+	"github.com/f1shl3gs/manta/multitsdb"
+	"github.com/f1shl3gs/manta/pkg/tracing"
+)
+
+// Resolver resolves the options of variables through the storage of the
+// tenant, which is the organization of the dashboard
+type Resolver struct {
+	tenantStorage multitsdb.TenantStorage
+	engine        *promql.Engine
+}
+
+func NewResolver(tenantStorage multitsdb.TenantStorage, engine *promql.Engine) *Resolver {
+	return &Resolver{
+		tenantStorage: tenantStorage,
+		engine:        engine,
+	}
+}
+
+// Resolve resolves the variables in order, so the selector and query of a
+// variable can reference the variables defined before. The selected are the
+// options chosen by the viewer, keyed by the variable name, the default
+// selected of the variable is used if there is none.
+func (r *Resolver) Resolve(
+	ctx context.Context,
+	orgID manta.ID,
+	variables []manta.Variable,
+	selected map[string][]string,
+	start, end time.Time,
+) ([]manta.VariableValue, error) {
+	span, ctx := tracing.StartSpanFromContext(ctx)
+	defer span.Finish()
+
+	var (
+		queryable storage.Queryable
+		values    = make([]manta.VariableValue, 0, len(variables))
+	)
+
+	for i := range variables {
+		v := &variables[i]
+
+		var options []string
+		switch v.Type {
+		case manta.VariableLabelValues, manta.VariableQuery:
+			if queryable == nil {
+				var err error
+				queryable, err = r.tenantStorage.Queryable(ctx, orgID)
+				if err != nil {
+					return nil, err
+				}
+			}
+
+			found, err := r.options(ctx, queryable, v, values, start, end)
+			if err != nil {
+				return nil, &manta.Error{
+					Code: manta.EInvalid,
+					Msg:  "resolve variable " + v.Name + " failed",
+					Err:  err,
+				}
+			}
+
+			options = found
+		default:
+			options = v.Values
+		}
+
+		chosen, ok := selected[v.Name]
+		if !ok {
+			chosen = v.Selected
+		}
+
+		values = append(values, Select(v, options, chosen))
+	}
+
+	return values, nil
+}
+
+// options returns the sorted options of label_values and query variables
+func (r *Resolver) options(
+	ctx context.Context,
+	queryable storage.Queryable,
+	v *manta.Variable,
+	values []manta.VariableValue,
+	start, end time.Time,
+) ([]string, error) {
+	var (
+		found []string
+		err   error
+	)
+
+	if v.Type == manta.VariableLabelValues {
+		found, err = labelValues(ctx, queryable, v.Label, Interpolate(v.Selector, values), start, end)
+	} else {
+		found, err = r.queryValues(ctx, queryable, v.Label, Interpolate(v.Query, values), end)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return filterOptions(found, v.Regex)
+}
+
+func labelValues(ctx context.Context, queryable storage.Queryable, name, selector string, start, end time.Time) ([]string, error) {
+	var matchers []*labels.Matcher
+	if selector != "" {
+		var err error
+		matchers, err = parser.ParseMetricSelector(selector)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid selector %s", selector)
+		}
+	}
+
+	q, err := queryable.Querier(ctx, timestamp.FromTime(start), timestamp.FromTime(end))
+	if err != nil {
+		return nil, err
+	}
+	defer q.Close()
+
+	values, _, err := q.LabelValues(name, matchers...)
+	return values, err
+}
+
+// queryValues runs the query at the end, and returns the values of the label
+// of the series, or the series themselves if label is empty
+func (r *Resolver) queryValues(ctx context.Context, queryable storage.Queryable, label, query string, end time.Time) ([]string, error) {
+	qry, err := r.engine.NewInstantQuery(queryable, nil, query, end)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid query %s", query)
+	}
+	defer qry.Close()
+
+	res := qry.Exec(ctx)
+	if res.Err != nil {
+		return nil, res.Err
+	}
+
+	var series []labels.Labels
+	switch value := res.Value.(type) {
+	case promql.Vector:
+		for _, sample := range value {
+			series = append(series, sample.Metric)
+		}
+	case promql.Matrix:
+		for _, s := range value {
+			series = append(series, s.Metric)
+		}
+	default:
+		return []string{value.String()}, nil
+	}
+
+	values := make([]string, 0, len(series))
+	for _, lset := range series {
+		if label == "" {
+			values = append(values, lset.String())
+			continue
+		}
+
+		if value := lset.Get(label); value != "" {
+			values = append(values, value)
+		}
+	}
+
+	return values, nil
+}
+
+// filterOptions keeps the options match the regex, and replaces them with
+// the first capture group if there is any, the options returned are unique
+// and sorted
+func filterOptions(options []string, regex string) ([]string, error) {
+	var re *regexp.Regexp
+	if regex != "" {
+		var err error
+		re, err = regexp.Compile(regex)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	set := make(map[string]struct{}, len(options))
+	for _, option := range options {
+		if re != nil {
+			matches := re.FindStringSubmatch(option)
+			if matches == nil {
+				continue
+			}
+
+			if len(matches) > 1 {
+				option = matches[1]
+			}
+		}
+
+		set[option] = struct{}{}
+	}
+
+	filtered := make([]string, 0, len(set))
+	for option := range set {
+		filtered = append(filtered, option)
+	}
+	sort.Strings(filtered)
+
+	return filtered, nil
+}
+
+// Select selects the chosen options of the variable, the options not exist
+// are dropped, and the first option is selected if nothing is left.
+//
+// A single option replaces the variable as it is, more than one option are
+// joined as a regex, e.g. (a|b), so they are used with regex matchers.
+func Select(v *manta.Variable, options []string, chosen []string) manta.VariableValue {
+	value := manta.VariableValue{
+		Name:     v.Name,
+		Options:  options,
+		Selected: []string{},
+	}
+	if value.Options == nil {
+		value.Options = []string{}
+	}
+
+	for _, option := range chosen {
+		if option == manta.VariableAll && v.IncludeAll {
+			value.Selected = []string{manta.VariableAll}
+			value.Value = v.AllValue
+			if value.Value == "" {
+				value.Value = regexAlternation(options)
+			}
+
+			return value
+		}
+	}
+
+	for _, option := range chosen {
+		if contains(options, option) && !contains(value.Selected, option) {
+			value.Selected = append(value.Selected, option)
+		}
+	}
+
+	if len(value.Selected) > 1 && !v.Multi {
+		value.Selected = value.Selected[:1]
+	}
+
+	if len(value.Selected) == 0 && len(options) > 0 {
+		value.Selected = []string{options[0]}
+	}
+
+	switch len(value.Selected) {
+	case 0:
+	case 1:
+		value.Value = escapeString(value.Selected[0])
+	default:
+		value.Value = regexAlternation(value.Selected)
+	}
+
+	return value
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
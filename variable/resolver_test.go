@@ -0,0 +1,172 @@
+package variable
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/prometheus/prometheus/promql"
+	"github.com/prometheus/prometheus/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/f1shl3gs/manta"
+)
+
+type testTenantStorage struct {
+	queryable storage.Queryable
+}
+
+func (s *testTenantStorage) Queryable(ctx context.Context, id manta.ID) (storage.Queryable, error) {
+	return s.queryable, nil
+}
+
+func (s *testTenantStorage) Appendable(ctx context.Context, id manta.ID) (storage.Appendable, error) {
+	panic("not implemented")
+}
+
+func (s *testTenantStorage) Tenants(ctx context.Context) ([]manta.ID, error) {
+	return nil, nil
+}
+
+func (s *testTenantStorage) RemoveTenant(ctx context.Context, id manta.ID) error {
+	panic("not implemented")
+}
+
+func newTestResolver(t *testing.T) *Resolver {
+	test, err := promql.NewTest(t, `
+load 1m
+	up{job="node", instance="a:9100"} 1 1 1
+	up{job="node", instance="b:9100"} 1 1 1
+	up{job="prometheus", instance="c:9090"} 1 1 1
+`)
+	require.NoError(t, err)
+	t.Cleanup(test.Close)
+	require.NoError(t, test.Run())
+
+	return NewResolver(&testTenantStorage{queryable: test.Queryable()}, test.QueryEngine())
+}
+
+func TestResolver_Resolve(t *testing.T) {
+	var (
+		resolver   = newTestResolver(t)
+		start, end = time.Unix(0, 0), time.Unix(120, 0)
+		variables  = []manta.Variable{
+			{
+				Name:  "job",
+				Type:  manta.VariableLabelValues,
+				Label: "job",
+			},
+			{
+				Name:       "instance",
+				Type:       manta.VariableQuery,
+				Label:      "instance",
+				Query:      `up{job="$job"}`,
+				Regex:      `^([^:]+):.*`,
+				Multi:      true,
+				IncludeAll: true,
+			},
+			{
+				Name:     "interval",
+				Type:     manta.VariableInterval,
+				Values:   []string{"1m", "5m"},
+				Selected: []string{"5m"},
+			},
+		}
+	)
+
+	for name, tc := range map[string]struct {
+		selected map[string][]string
+		want     []manta.VariableValue
+	}{
+		"default": {
+			want: []manta.VariableValue{
+				{Name: "job", Options: []string{"node", "prometheus"}, Selected: []string{"node"}, Value: "node"},
+				{Name: "instance", Options: []string{"a", "b"}, Selected: []string{"a"}, Value: "a"},
+				{Name: "interval", Options: []string{"1m", "5m"}, Selected: []string{"5m"}, Value: "5m"},
+			},
+		},
+		"chained": {
+			selected: map[string][]string{
+				"job":      {"prometheus"},
+				"interval": {"1m"},
+			},
+			want: []manta.VariableValue{
+				{Name: "job", Options: []string{"node", "prometheus"}, Selected: []string{"prometheus"}, Value: "prometheus"},
+				{Name: "instance", Options: []string{"c"}, Selected: []string{"c"}, Value: "c"},
+				{Name: "interval", Options: []string{"1m", "5m"}, Selected: []string{"1m"}, Value: "1m"},
+			},
+		},
+		"multi and all": {
+			selected: map[string][]string{
+				"instance": {manta.VariableAll},
+				"interval": {"1m", "5m", "1h"},
+			},
+			want: []manta.VariableValue{
+				{Name: "job", Options: []string{"node", "prometheus"}, Selected: []string{"node"}, Value: "node"},
+				{Name: "instance", Options: []string{"a", "b"}, Selected: []string{manta.VariableAll}, Value: "(a|b)"},
+				{Name: "interval", Options: []string{"1m", "5m"}, Selected: []string{"1m"}, Value: "1m"},
+			},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			values, err := resolver.Resolve(context.Background(), 1, variables, tc.selected, start, end)
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, values)
+		})
+	}
+}
+
+func TestResolver_InvalidQuery(t *testing.T) {
+	resolver := newTestResolver(t)
+
+	_, err := resolver.Resolve(context.Background(), 1, []manta.Variable{
+		{Name: "job", Type: manta.VariableQuery, Query: "up{"},
+	}, nil, time.Unix(0, 0), time.Unix(120, 0))
+	assert.Equal(t, manta.EInvalid, manta.ErrorCode(err))
+}
+
+func TestSelect(t *testing.T) {
+	options := []string{"a.b", `c"d`, "e"}
+
+	for name, tc := range map[string]struct {
+		variable manta.Variable
+		chosen   []string
+		want     manta.VariableValue
+	}{
+		"escaped": {
+			variable: manta.Variable{Name: "v"},
+			chosen:   []string{`c"d`},
+			want:     manta.VariableValue{Name: "v", Options: options, Selected: []string{`c"d`}, Value: `c\"d`},
+		},
+		"regex alternation": {
+			variable: manta.Variable{Name: "v", Multi: true},
+			chosen:   []string{"a.b", "e"},
+			want:     manta.VariableValue{Name: "v", Options: options, Selected: []string{"a.b", "e"}, Value: `(a\\.b|e)`},
+		},
+		"single": {
+			variable: manta.Variable{Name: "v"},
+			chosen:   []string{"e", "a.b"},
+			want:     manta.VariableValue{Name: "v", Options: options, Selected: []string{"e"}, Value: "e"},
+		},
+		"unknown option": {
+			variable: manta.Variable{Name: "v"},
+			chosen:   []string{"x"},
+			want:     manta.VariableValue{Name: "v", Options: options, Selected: []string{"a.b"}, Value: "a.b"},
+		},
+		"all value": {
+			variable: manta.Variable{Name: "v", IncludeAll: true, AllValue: ".*"},
+			chosen:   []string{manta.VariableAll},
+			want:     manta.VariableValue{Name: "v", Options: options, Selected: []string{manta.VariableAll}, Value: ".*"},
+		},
+		"all not included": {
+			variable: manta.Variable{Name: "v"},
+			chosen:   []string{manta.VariableAll},
+			want:     manta.VariableValue{Name: "v", Options: options, Selected: []string{"a.b"}, Value: "a.b"},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, tc.want, Select(&tc.variable, options, tc.chosen))
+		})
+	}
+}
@@ -0,0 +1,61 @@
+package manta
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateVariables(t *testing.T) {
+	for name, tc := range map[string]struct {
+		variables []Variable
+		valid     bool
+	}{
+		"valid": {
+			variables: []Variable{
+				{Name: "job", Type: VariableLabelValues, Label: "job", Selector: `up`},
+				{Name: "instance", Type: VariableQuery, Query: `up{job="$job"}`, Label: "instance", Multi: true, IncludeAll: true},
+				{Name: "interval", Type: VariableInterval, Values: []string{"1m", "5m"}},
+				{Name: "env", Type: VariableConstant, Values: []string{"prod"}},
+			},
+			valid: true,
+		},
+		"invalid name": {
+			variables: []Variable{{Name: "1job", Type: VariableCustom, Values: []string{"a"}}},
+		},
+		"duplicate name": {
+			variables: []Variable{
+				{Name: "job", Type: VariableCustom, Values: []string{"a"}},
+				{Name: "job", Type: VariableCustom, Values: []string{"b"}},
+			},
+		},
+		"unknown type": {
+			variables: []Variable{{Name: "job", Type: "foo"}},
+		},
+		"invalid label": {
+			variables: []Variable{{Name: "job", Type: VariableLabelValues, Label: "a-b"}},
+		},
+		"query required": {
+			variables: []Variable{{Name: "job", Type: VariableQuery}},
+		},
+		"invalid interval": {
+			variables: []Variable{{Name: "interval", Type: VariableInterval, Values: []string{"5x"}}},
+		},
+		"invalid regex": {
+			variables: []Variable{{Name: "job", Type: VariableLabelValues, Label: "job", Regex: "("}},
+		},
+		"multiple selected": {
+			variables: []Variable{{Name: "job", Type: VariableCustom, Values: []string{"a", "b"}, Selected: []string{"a", "b"}}},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			err := ValidateVariables(tc.variables)
+			if tc.valid {
+				assert.NoError(t, err)
+				return
+			}
+
+			assert.Equal(t, EInvalid, ErrorCode(err))
+		})
+	}
+}